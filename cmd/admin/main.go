@@ -0,0 +1,415 @@
+// Command admin runs maintenance tasks against the Cash Track database.
+//
+// Usage:
+//
+//	go run ./cmd/admin <command> [flags]
+//
+// Commands:
+//
+//	hash-slips     compute perceptual hashes for slips uploaded before hashing existed
+//	reparse        re-run OCR and/or LLM parsing and print which fields would change
+//	reparse-apply  apply changes proposed by an earlier reparse run
+//	import-fx      import exchange rates shared by all users from a CSV file
+//	purge-trash    permanently delete transactions trashed longer than the retention period
+//	accuracy       compare parsed values with confirmed ones per field, channel and model
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"cash-track/internal/config"
+	"cash-track/internal/database"
+	"cash-track/internal/fx"
+	"cash-track/internal/llm"
+	"cash-track/internal/models"
+	"cash-track/internal/ocr"
+	"cash-track/internal/reparse"
+	"cash-track/internal/storage"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	cfg := config.Load()
+
+	var err error
+	switch os.Args[1] {
+	case "hash-slips":
+		err = runHashSlips(cfg, os.Args[2:])
+	case "reparse":
+		err = runReparse(cfg, os.Args[2:])
+	case "reparse-apply":
+		err = runReparseApply(cfg, os.Args[2:])
+	case "import-fx":
+		err = runImportFX(cfg, os.Args[2:])
+	case "purge-trash":
+		err = runPurgeTrash(cfg, os.Args[2:])
+	case "accuracy":
+		err = runAccuracy(cfg, os.Args[2:])
+	case "help", "-h", "--help":
+		usage()
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", os.Args[1])
+		usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		log.Fatalf("%s: %v", os.Args[1], err)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, `Usage: admin <command> [flags]
+
+Commands:
+  hash-slips     compute perceptual hashes for slips uploaded before hashing existed
+  reparse        re-run OCR and/or LLM parsing and print which fields would change
+  reparse-apply  apply changes proposed by an earlier reparse run
+  import-fx      import exchange rates shared by all users from a CSV file
+  purge-trash    permanently delete transactions trashed longer than the retention period
+  accuracy       compare parsed values with confirmed ones per field, channel and model
+
+Run "admin <command> -h" for the flags of a command.`)
+}
+
+func openRepository(cfg *config.Config) (*database.Repository, func(), error) {
+	db, err := database.New(cfg.DatabaseURL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	return database.NewRepository(db), func() { db.Close() }, nil
+}
+
+func runHashSlips(cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("hash-slips", flag.ExitOnError)
+	distance := fs.Int("distance", cfg.SlipHashDistance, "maximum hash distance (bits) to flag a slip as a duplicate")
+	fs.Parse(args)
+
+	repo, closeDB, err := openRepository(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	store, err := storage.NewLocalStorage(cfg.UploadDir)
+	if err != nil {
+		return err
+	}
+
+	transactions, err := repo.ListUnhashedSlips()
+	if err != nil {
+		return err
+	}
+
+	var hashed, duplicates, failed int
+	for _, tx := range transactions {
+		hash, err := store.Hash(tx.SlipImagePath.String)
+		if err != nil {
+			log.Printf("transaction %d: %v", tx.ID, err)
+			failed++
+			continue
+		}
+
+		// Only flag against earlier uploads so the original stays unflagged
+		dupID, err := repo.FindSimilarSlip(tx.UserID.Int64, hash, *distance)
+		if err != nil {
+			return err
+		}
+		if dupID >= tx.ID {
+			dupID = 0
+		}
+
+		if err := repo.UpdateSlipHash(tx.ID, storage.FormatHash(hash), dupID); err != nil {
+			return err
+		}
+		hashed++
+		if dupID != 0 {
+			duplicates++
+			log.Printf("transaction %d looks like a duplicate of %d", tx.ID, dupID)
+		}
+	}
+
+	log.Printf("hashed %d slips (%d possible duplicates, %d failed)", hashed, duplicates, failed)
+	return nil
+}
+
+func runReparse(cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("reparse", flag.ExitOnError)
+	mode := fs.String("mode", models.ReparseLLM, "what to re-run: ocr, llm or both")
+	userID := fs.Int64("user", 0, "only transactions of this user ID (0 = all users)")
+	from := fs.String("from", "", "only transactions dated on or after YYYY-MM-DD")
+	to := fs.String("to", "", "only transactions dated on or before YYYY-MM-DD")
+	status := fs.String("status", "", "only transactions with this status (pending, confirmed)")
+	maxConfidence := fs.Float64("max-confidence", 0, "only transactions with LLM confidence below this value")
+	limit := fs.Int("limit", 0, "maximum number of transactions (0 = no limit)")
+	fs.Parse(args)
+
+	repo, closeDB, err := openRepository(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	store, err := storage.NewLocalStorage(cfg.UploadDir)
+	if err != nil {
+		return err
+	}
+
+	ocrEngine, err := ocr.New(cfg.OCREngine, ocr.Options{
+		Endpoint:      cfg.OCREndpoint,
+		Timeout:       cfg.OCRTimeout,
+		Retries:       cfg.OCRRetries,
+		TesseractCmd:  cfg.TesseractCmd,
+		TesseractLang: cfg.TesseractLang,
+		FixtureDir:    cfg.OCRFixtureDir,
+	})
+	if err != nil {
+		return err
+	}
+
+	runner := reparse.NewRunner(repo, store, ocrEngine, llm.NewClient(cfg.OllamaURL, cfg.OllamaModel))
+	run, err := runner.Create(*mode, models.ReparseFilter{
+		UserID:        *userID,
+		From:          *from,
+		To:            *to,
+		Status:        *status,
+		MaxConfidence: *maxConfidence,
+		Limit:         *limit,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := runner.Run(context.Background(), run.ID); err != nil {
+		return err
+	}
+	if run, err = repo.GetReparseRun(0, run.ID); err != nil {
+		return err
+	}
+	changes, err := repo.ListReparseChanges(run.ID)
+	if err != nil {
+		return err
+	}
+
+	printChanges(changes)
+	fmt.Printf("\nRun %d: %d transactions checked, %d with changes\n", run.ID, run.Processed, run.Changed)
+	if len(changes) > 0 {
+		fmt.Printf("Apply with: admin reparse-apply -run %d [-changes IDs] [-transactions IDs] [-fields names]\n", run.ID)
+	}
+	return nil
+}
+
+func runReparseApply(cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("reparse-apply", flag.ExitOnError)
+	runID := fs.Int64("run", 0, "ID of the reparse run to apply (required)")
+	changeIDs := fs.String("changes", "", "comma-separated change IDs to apply (default all)")
+	txIDs := fs.String("transactions", "", "comma-separated transaction IDs to apply (default all)")
+	fields := fs.String("fields", "", "comma-separated field names to apply (default all)")
+	fs.Parse(args)
+
+	if *runID == 0 {
+		return fmt.Errorf("-run is required")
+	}
+
+	sel := models.ReparseSelection{Fields: splitList(*fields)}
+	var err error
+	if sel.ChangeIDs, err = parseIDs(*changeIDs); err != nil {
+		return err
+	}
+	if sel.TransactionIDs, err = parseIDs(*txIDs); err != nil {
+		return err
+	}
+
+	repo, closeDB, err := openRepository(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	run, err := repo.GetReparseRun(0, *runID)
+	if err != nil {
+		return fmt.Errorf("run %d not found: %w", *runID, err)
+	}
+	if run.Status != models.ReparseDone {
+		return fmt.Errorf("run %d is %s, not done", run.ID, run.Status)
+	}
+
+	applied, skipped, err := repo.ApplyReparseChanges(run.ID, sel)
+	if err != nil {
+		return err
+	}
+	log.Printf("applied %d changes (%d skipped as protected or out of date)", applied, skipped)
+	return nil
+}
+
+func printChanges(changes []models.ReparseChange) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "CHANGE\tTXN\tFIELD\tOLD\tNEW\tNOTE")
+	for _, c := range changes {
+		note := ""
+		if c.Protected {
+			note = "user-edited, will not apply"
+		}
+		fmt.Fprintf(w, "%d\t%d\t%s\t%s\t%s\t%s\n", c.ID, c.TransactionID, c.Field, preview(c.OldValue), preview(c.NewValue), note)
+	}
+	w.Flush()
+}
+
+// preview shortens multi-line values such as OCR text to fit on one line
+func preview(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if r := []rune(s); len(r) > 40 {
+		return string(r[:37]) + "..."
+	}
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+func parseIDs(s string) ([]int64, error) {
+	var ids []int64
+	for _, part := range splitList(s) {
+		id, err := strconv.ParseInt(part, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ID %q", part)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func runImportFX(cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("import-fx", flag.ExitOnError)
+	file := fs.String("file", "", "CSV file with date,currency,rate[,base] rows (required)")
+	base := fs.String("base", fx.DefaultCurrency, "base currency for rows without a base column")
+	fs.Parse(args)
+
+	if *file == "" {
+		return fmt.Errorf("-file is required")
+	}
+	f, err := os.Open(*file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	rates, err := fx.ParseRatesCSV(f, *base)
+	if err != nil {
+		return err
+	}
+
+	repo, closeDB, err := openRepository(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	imported, err := repo.ImportFXRates(0, rates)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Imported %d shared exchange rates\n", imported)
+	return nil
+}
+
+func runPurgeTrash(cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("purge-trash", flag.ExitOnError)
+	days := fs.Int("days", int(cfg.TrashRetention.Hours()/24), "delete transactions trashed more than this many days ago")
+	fs.Parse(args)
+
+	repo, closeDB, err := openRepository(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	store, err := storage.NewLocalStorage(cfg.UploadDir)
+	if err != nil {
+		return err
+	}
+
+	purged, slips, err := repo.PurgeTrash(time.Now().AddDate(0, 0, -*days))
+	if err != nil {
+		return err
+	}
+	for _, path := range slips {
+		if err := store.Delete(path); err != nil {
+			log.Printf("slip %s: %v", path, err)
+		}
+	}
+	fmt.Printf("Purged %d transactions and %d slips\n", purged, len(slips))
+	return nil
+}
+
+func runAccuracy(cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("accuracy", flag.ExitOnError)
+	userID := fs.Int64("user", 0, "only transactions of this user ID (0 = all users)")
+	from := fs.String("from", "", "only transactions dated on or after YYYY-MM-DD")
+	to := fs.String("to", "", "only transactions dated on or before YYYY-MM-DD")
+	fs.Parse(args)
+
+	repo, closeDB, err := openRepository(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	report, err := repo.AccuracyReport(*userID, *from, *to)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "GROUP\tTXNS\tLATENCY\tACCURACY\t"+strings.ToUpper(strings.Join(models.AccuracyFields, "\t")))
+	printGroup := func(label string, g models.AccuracyGroup) {
+		fmt.Fprintf(w, "%s\t%d\t%dms\t%s", label, g.Transactions, g.AvgLatencyMS, percent(g.Accuracy, g.Transactions))
+		byField := map[string]models.FieldAccuracy{}
+		for _, f := range g.Fields {
+			byField[f.Field] = f
+		}
+		for _, field := range models.AccuracyFields {
+			f := byField[field]
+			fmt.Fprintf(w, "\t%s", percent(f.Accuracy, f.Total))
+		}
+		fmt.Fprintln(w)
+	}
+	printGroup("all", report.Overall)
+	for _, g := range report.ByModel {
+		printGroup("model "+g.Key, g)
+	}
+	for _, g := range report.ByChannel {
+		printGroup("channel "+g.Key, g)
+	}
+	return w.Flush()
+}
+
+// percent formats a ratio, or "-" when nothing was counted
+func percent(ratio float64, n int) string {
+	if n == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.0f%%", ratio*100)
+}
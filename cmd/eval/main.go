@@ -0,0 +1,241 @@
+// Command eval measures how well chat messages and slip text are parsed
+// against a labelled dataset.
+//
+// Usage:
+//
+//	go run ./cmd/eval -data cmd/eval/testdata/sample.jsonl [flags]
+//
+// Each dataset line is one example:
+//
+//	{"id": "food-1", "message": "ข้าวมันไก่ 60 บาท", "intent": "add_transaction",
+//	 "expected": {"amount": "60", "category": "food", "channel": ""}}
+//
+// Slip examples set "ocr_text" instead of "message". Only the fields listed
+// in "expected" are scored; "" means the parser should leave the field empty.
+// txn_date may be "today" or "yesterday".
+//
+// Modes:
+//
+//	regex   the regex rules alone
+//	llm     ParseChatMessage against Ollama; -record saves the model's answers
+//	replay  ParseChatMessage against answers saved earlier with -record
+//
+// -out writes the scores as JSON and -compare prints the change from an
+// earlier -out file, so prompt versions and models can be compared.
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"cash-track/internal/config"
+	"cash-track/internal/llm"
+)
+
+// Evaluation modes
+const (
+	modeRegex  = "regex"
+	modeLLM    = "llm"
+	modeReplay = "replay"
+)
+
+// example is one labelled dataset line
+type example struct {
+	ID       string            `json:"id"`
+	Message  string            `json:"message"`
+	OCRText  string            `json:"ocr_text"`
+	Lang     string            `json:"lang"`
+	Intent   string            `json:"intent"`
+	Expected map[string]string `json:"expected"`
+}
+
+func main() {
+	cfg := config.Load()
+
+	dataPath := flag.String("data", "", "labelled dataset (JSONL)")
+	mode := flag.String("mode", modeRegex, "parser to evaluate: regex, llm or replay")
+	endpoint := flag.String("ollama", cfg.OllamaURL, "Ollama URL for -mode llm")
+	model := flag.String("model", cfg.OllamaModel, "Ollama model for -mode llm")
+	recordPath := flag.String("record", "", "save the model's answers to this file (-mode llm)")
+	replayPath := flag.String("replay", "", "answers saved with -record (-mode replay)")
+	outPath := flag.String("out", "", "write the scores as JSON to this file")
+	comparePath := flag.String("compare", "", "show the change from scores written earlier with -out")
+	verbose := flag.Bool("v", false, "list every mismatch")
+	flag.Parse()
+
+	if *dataPath == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	examples, err := readDataset(*dataPath)
+	if err != nil {
+		log.Fatalf("dataset: %v", err)
+	}
+
+	var parse parser
+	switch *mode {
+	case modeRegex:
+		parse = regexParser{}
+	case modeLLM:
+		parse, err = newLLMParser(*endpoint, *model, *recordPath)
+	case modeReplay:
+		if *replayPath == "" {
+			log.Fatal("-mode replay needs -replay")
+		}
+		parse, err = newReplayParser(*replayPath)
+	default:
+		log.Fatalf("unknown mode %q", *mode)
+	}
+	if err != nil {
+		log.Fatalf("%s: %v", *mode, err)
+	}
+	defer parse.Close()
+
+	result := evaluate(examples, parse)
+	result.Dataset = *dataPath
+	result.Mode = *mode
+	result.RunAt = time.Now().Format(time.RFC3339)
+
+	var baseline *report
+	if *comparePath != "" {
+		if baseline, err = readReport(*comparePath); err != nil {
+			log.Fatalf("compare: %v", err)
+		}
+	}
+	printReport(result, baseline, *verbose)
+
+	if *outPath != "" {
+		if err := writeReport(*outPath, result); err != nil {
+			log.Fatalf("out: %v", err)
+		}
+	}
+}
+
+func readDataset(path string) ([]example, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var examples []example
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 1024*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "//") {
+			continue
+		}
+		var ex example
+		if err := json.Unmarshal([]byte(text), &ex); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if ex.ID == "" {
+			ex.ID = fmt.Sprintf("line-%d", line)
+		}
+		if ex.Lang == "" {
+			ex.Lang = "th"
+		}
+		examples = append(examples, ex)
+	}
+	return examples, scanner.Err()
+}
+
+func readReport(path string) (*report, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var r report
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func writeReport(path string, r *report) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+func printReport(r *report, baseline *report, verbose bool) {
+	fmt.Printf("%s: %d examples, mode %s", r.Dataset, r.Examples, r.Mode)
+	if r.Model != "" {
+		fmt.Printf(", model %s", r.Model)
+	}
+	if r.PromptVersion != "" {
+		fmt.Printf(", prompt %s", r.PromptVersion)
+	}
+	fmt.Println()
+	if r.Fallbacks > 0 {
+		fmt.Printf("%d answers were unusable and fell back to regex parsing\n", r.Fallbacks)
+	}
+	if r.Skipped > 0 {
+		fmt.Printf("%d examples skipped (no recorded answer)\n", r.Skipped)
+	}
+	if baseline != nil {
+		fmt.Printf("Compared with %s (mode %s, model %s, prompt %s)\n", baseline.RunAt, baseline.Mode, baseline.Model, baseline.PromptVersion)
+	}
+	fmt.Println()
+
+	before := map[string]fieldScore{}
+	if baseline != nil {
+		for _, f := range baseline.Fields {
+			before[f.Field] = f
+		}
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "FIELD\tTP\tFP\tFN\tPRECISION\tRECALL")
+	for _, f := range r.Fields {
+		precision, recall := ratio(f.Precision), ratio(f.Recall)
+		if old, ok := before[f.Field]; ok {
+			precision += delta(f.Precision, old.Precision)
+			recall += delta(f.Recall, old.Recall)
+		}
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%s\t%s\n", f.Field, f.TP, f.FP, f.FN, precision, recall)
+	}
+	w.Flush()
+
+	if verbose && len(r.Mismatches) > 0 {
+		fmt.Println()
+		w = tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "EXAMPLE\tFIELD\tEXPECTED\tGOT")
+		for _, m := range r.Mismatches {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", m.Example, m.Field, m.Expected, m.Got)
+		}
+		w.Flush()
+	}
+}
+
+func ratio(v *float64) string {
+	if v == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f%%", *v*100)
+}
+
+func delta(now, before *float64) string {
+	if now == nil || before == nil {
+		return ""
+	}
+	return fmt.Sprintf(" (%+.1f)", (*now-*before)*100)
+}
+
+// parser turns one example into a chat response
+type parser interface {
+	Parse(ex example) (*llm.ChatResponse, error)
+	// Describe returns the model and prompt version behind the answers
+	Describe() (model, promptVersion string)
+	Close() error
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+
+	"cash-track/internal/llm"
+)
+
+// errNoRecording skips an example that has no recorded answer to replay
+var errNoRecording = errors.New("no recorded answer")
+
+// recording is one model answer saved by -record, a JSONL line
+type recording struct {
+	ID            string `json:"id"`
+	Model         string `json:"model"`
+	PromptVersion string `json:"prompt_version"`
+	Response      string `json:"response"`
+}
+
+// parse runs the client over an example the way the chat and slip handlers do
+func parse(client *llm.Client, ex example) (*llm.ChatResponse, error) {
+	if ex.OCRText != "" {
+		return client.ParseChatMessage("", &ex.OCRText, ex.Lang)
+	}
+	return client.ParseChatMessage(ex.Message, nil, ex.Lang)
+}
+
+type regexParser struct{}
+
+func (regexParser) Parse(ex example) (*llm.ChatResponse, error) {
+	if ex.OCRText != "" {
+		return llm.ParseRegex("", &ex.OCRText), nil
+	}
+	return llm.ParseRegex(ex.Message, nil), nil
+}
+
+func (regexParser) Describe() (string, string) { return "regex", "" }
+
+func (regexParser) Close() error { return nil }
+
+// llmParser asks Ollama. When recording, requests go through a local proxy
+// that keeps each answer.
+type llmParser struct {
+	client *llm.Client
+	model  string
+	proxy  *httptest.Server
+	out    *os.File
+
+	mu      sync.Mutex
+	current string // ID of the example being parsed
+}
+
+func newLLMParser(endpoint, model, recordPath string) (*llmParser, error) {
+	p := &llmParser{model: model}
+	if recordPath == "" {
+		p.client = llm.NewClient(endpoint, model)
+		return p, nil
+	}
+
+	out, err := os.Create(recordPath)
+	if err != nil {
+		return nil, err
+	}
+	p.out = out
+	p.proxy = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reqBody, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		resp, err := http.Post(endpoint+r.URL.Path, r.Header.Get("Content-Type"), bytes.NewReader(reqBody))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		if resp.StatusCode == http.StatusOK {
+			p.record(body)
+		}
+		w.WriteHeader(resp.StatusCode)
+		w.Write(body)
+	}))
+	p.client = llm.NewClient(p.proxy.URL, model)
+	return p, nil
+}
+
+func (p *llmParser) record(body []byte) {
+	var gen llm.GenerateResponse
+	if err := json.Unmarshal(body, &gen); err != nil {
+		log.Printf("record: %v", err)
+		return
+	}
+	p.mu.Lock()
+	id := p.current
+	p.mu.Unlock()
+
+	line, err := json.Marshal(recording{ID: id, Model: p.model, PromptVersion: llm.PromptVersion, Response: gen.Response})
+	if err != nil {
+		log.Printf("record: %v", err)
+		return
+	}
+	if _, err := p.out.Write(append(line, '\n')); err != nil {
+		log.Printf("record: %v", err)
+	}
+}
+
+func (p *llmParser) Parse(ex example) (*llm.ChatResponse, error) {
+	p.mu.Lock()
+	p.current = ex.ID
+	p.mu.Unlock()
+	return parse(p.client, ex)
+}
+
+func (p *llmParser) Describe() (string, string) { return p.model, llm.PromptVersion }
+
+func (p *llmParser) Close() error {
+	if p.proxy != nil {
+		p.proxy.Close()
+	}
+	if p.out != nil {
+		return p.out.Close()
+	}
+	return nil
+}
+
+// replayParser runs the real client against a local server that answers
+// with the recorded response for the example being parsed
+type replayParser struct {
+	client     *llm.Client
+	server     *httptest.Server
+	recordings map[string]recording
+	model      string
+	prompt     string
+
+	mu      sync.Mutex
+	current recording
+}
+
+func newReplayParser(path string) (*replayParser, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	p := &replayParser{recordings: map[string]recording{}}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 1024*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var rec recording
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if p.model == "" {
+			p.model, p.prompt = rec.Model, rec.PromptVersion
+		} else if rec.Model != p.model || rec.PromptVersion != p.prompt {
+			log.Printf("recording mixes %s/%s with %s/%s; scores are reported as the first", p.model, p.prompt, rec.Model, rec.PromptVersion)
+		}
+		p.recordings[rec.ID] = rec
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	p.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p.mu.Lock()
+		rec := p.current
+		p.mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(llm.GenerateResponse{Response: rec.Response, Done: true})
+	}))
+	p.client = llm.NewClient(p.server.URL, p.model)
+	return p, nil
+}
+
+func (p *replayParser) Parse(ex example) (*llm.ChatResponse, error) {
+	rec, ok := p.recordings[ex.ID]
+	if !ok {
+		return nil, errNoRecording
+	}
+	p.mu.Lock()
+	p.current = rec
+	p.mu.Unlock()
+	return parse(p.client, ex)
+}
+
+func (p *replayParser) Describe() (string, string) { return p.model, p.prompt }
+
+func (p *replayParser) Close() error {
+	p.server.Close()
+	return nil
+}
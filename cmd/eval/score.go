@@ -0,0 +1,176 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"cash-track/internal/llm"
+	"cash-track/internal/models"
+)
+
+// fieldScore counts one field over the dataset. A prediction is a true
+// positive when it matches the label, a false positive when it is set but
+// wrong and a false negative when a labelled value was missed or wrong.
+// Precision and recall are null when there is nothing to divide by.
+type fieldScore struct {
+	Field     string   `json:"field"`
+	TP        int      `json:"tp"`
+	FP        int      `json:"fp"`
+	FN        int      `json:"fn"`
+	Precision *float64 `json:"precision"`
+	Recall    *float64 `json:"recall"`
+}
+
+type mismatch struct {
+	Example  string `json:"example"`
+	Field    string `json:"field"`
+	Expected string `json:"expected"`
+	Got      string `json:"got"`
+}
+
+// report is the result of one evaluation run, written by -out
+type report struct {
+	Dataset       string       `json:"dataset"`
+	Mode          string       `json:"mode"`
+	Model         string       `json:"model"`
+	PromptVersion string       `json:"prompt_version"`
+	RunAt         string       `json:"run_at"`
+	Examples      int          `json:"examples"`
+	Skipped       int          `json:"skipped"`
+	Fallbacks     int          `json:"fallbacks"`
+	Fields        []fieldScore `json:"fields"`
+	Mismatches    []mismatch   `json:"mismatches"`
+}
+
+func evaluate(examples []example, p parser) *report {
+	r := &report{Mismatches: []mismatch{}}
+	r.Model, r.PromptVersion = p.Describe()
+	_, regexOnly := p.(regexParser)
+
+	counts := map[string]*fieldScore{}
+	score := func(ex example, field, want, got string) {
+		fs, ok := counts[field]
+		if !ok {
+			fs = &fieldScore{Field: field}
+			counts[field] = fs
+		}
+		want, got = strings.TrimSpace(want), strings.TrimSpace(got)
+		switch {
+		case want != "" && strings.EqualFold(want, got):
+			fs.TP++
+			return
+		case got != "":
+			fs.FP++
+			if want != "" {
+				fs.FN++
+			}
+		case want != "":
+			fs.FN++
+		default:
+			return
+		}
+		r.Mismatches = append(r.Mismatches, mismatch{Example: ex.ID, Field: field, Expected: want, Got: got})
+	}
+
+	for _, ex := range examples {
+		resp, err := p.Parse(ex)
+		if err != nil {
+			if !errors.Is(err, errNoRecording) {
+				log.Printf("%s: %v", ex.ID, err)
+			}
+			r.Skipped++
+			continue
+		}
+		r.Examples++
+		if !regexOnly && resp.Source == models.ActorRegex {
+			r.Fallbacks++
+		}
+
+		got := predicted(resp)
+		if ex.Intent != "" {
+			score(ex, "intent", ex.Intent, got["intent"])
+		}
+		fields := make([]string, 0, len(ex.Expected))
+		for field := range ex.Expected {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+		for _, field := range fields {
+			score(ex, field, expectedValue(field, ex.Expected[field]), got[field])
+		}
+	}
+
+	for _, field := range fieldOrder(counts) {
+		fs := counts[field]
+		if n := fs.TP + fs.FP; n > 0 {
+			v := float64(fs.TP) / float64(n)
+			fs.Precision = &v
+		}
+		if n := fs.TP + fs.FN; n > 0 {
+			v := float64(fs.TP) / float64(n)
+			fs.Recall = &v
+		}
+		r.Fields = append(r.Fields, *fs)
+	}
+	return r
+}
+
+// predicted returns the intent and transaction fields of a response
+func predicted(resp *llm.ChatResponse) map[string]string {
+	values := map[string]string{}
+	if resp.Transaction != nil {
+		values = resp.Transaction.Values()
+	}
+	values["intent"] = resp.Intent
+	return values
+}
+
+// expectedValue formats a label the way ParsedTransaction.Values does
+func expectedValue(field, value string) string {
+	switch field {
+	case "amount":
+		if amount, err := models.ParseMoney(value); err == nil {
+			return amount.String()
+		}
+	case "currency":
+		return strings.ToUpper(value)
+	case "txn_date":
+		switch value {
+		case "today":
+			return time.Now().Format("2006-01-02")
+		case "yesterday":
+			return time.Now().AddDate(0, 0, -1).Format("2006-01-02")
+		}
+	}
+	return value
+}
+
+// fieldOrder lists intent, then the transaction fields, then anything else
+func fieldOrder(counts map[string]*fieldScore) []string {
+	known := append([]string{"intent"}, models.AccuracyFields...)
+	var order, extra []string
+	for _, field := range known {
+		if _, ok := counts[field]; ok {
+			order = append(order, field)
+		}
+	}
+	for field := range counts {
+		if !contains(known, field) {
+			extra = append(extra, field)
+		}
+	}
+	sort.Strings(extra)
+	return append(order, extra...)
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -1,9 +1,11 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net"
 	"net/http"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
@@ -11,6 +13,7 @@ import (
 	"cash-track/internal/config"
 	"cash-track/internal/database"
 	"cash-track/internal/handlers"
+	"cash-track/internal/jobs"
 	"cash-track/internal/llm"
 	"cash-track/internal/ocr"
 	"cash-track/internal/storage"
@@ -32,14 +35,30 @@ func main() {
 		log.Fatalf("Failed to initialize storage: %v", err)
 	}
 
-	ocrClient := ocr.NewClient(cfg.OCREndpoint)
+	ocrClient, err := newOCREngine(cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize OCR: %v", err)
+	}
 	llmClient := llm.NewClient(cfg.OllamaURL, cfg.OllamaModel)
 
-	h, err := handlers.New(repo, store, ocrClient, llmClient, "web/templates")
+	checkOCR(ocrClient, cfg.OCREngine)
+
+	queue := jobs.NewQueue(repo, cfg.JobWorkers, cfg.JobMaxAttempts)
+
+	h, err := handlers.New(repo, store, ocrClient, llmClient, queue, "web/templates", handlers.Options{
+		SlipHashDistance: cfg.SlipHashDistance,
+		OCRLowConfidence: cfg.OCRLowConfidence,
+		TrashRetention:   cfg.TrashRetention,
+	})
 	if err != nil {
 		log.Fatalf("Failed to initialize handlers: %v", err)
 	}
 
+	if err := queue.Start(context.Background()); err != nil {
+		log.Fatalf("Failed to start job queue: %v", err)
+	}
+	go h.RunTrashPurge(context.Background(), time.Hour)
+
 	r := chi.NewRouter()
 
 	r.Use(middleware.Logger)
@@ -55,31 +74,103 @@ func main() {
 	r.Get("/chat", h.ChatPage)
 	r.Get("/dashboard", h.DashboardPage)
 	r.Get("/history", h.History)
+	r.Get("/trash", h.TrashPage)
 	r.Get("/users", h.UsersPage)
 	r.Get("/transactions/{id}/confirm", h.ConfirmPage)
 
+	// API - Health
+	r.Get("/api/health", h.Health)
+
 	// API - Chat
 	r.Post("/api/chat", h.Chat)
 
 	// API - Transactions
 	r.Post("/api/transactions/slip", h.UploadSlip)
+	r.Get("/api/transactions", h.ListTransactions)
 	r.Get("/api/transactions/recent", h.GetRecentTransactions)
+	r.Get("/api/transactions/search", h.SearchTransactions)
+	r.Post("/api/transactions/bulk", h.BulkTransactions)
+	r.Post("/api/transactions/bulk/undo", h.UndoBulkTransactions)
+	r.Get("/api/transactions/trash", h.ListTrash)
 	r.Get("/api/transactions/{id}", h.GetTransaction)
+	r.Get("/api/transactions/{id}/status", h.TransactionStatus)
+	r.Post("/api/transactions/{id}/retry", h.RetryProcessing)
+	r.Post("/api/transactions/{id}/restore", h.RestoreTransaction)
+	r.Get("/api/transactions/{id}/revisions", h.ListRevisions)
+	r.Post("/api/transactions/{id}/revisions/{rev}/revert", h.RevertTransaction)
+	r.Get("/api/transactions/{id}/extractions", h.ListExtractions)
+	r.Get("/api/transactions/{id}/receipt", h.GetReceipt)
+	r.Put("/api/transactions/{id}/receipt", h.SetReceipt)
 	r.Patch("/api/transactions/{id}/confirm", h.ConfirmTransaction)
 	r.Delete("/api/transactions/{id}", h.DeleteTransaction)
 
+	// API - Claims
+	r.Get("/api/claims", h.ListClaims)
+	r.Post("/api/claims", h.CreateClaim)
+	r.Get("/api/claims/{id}", h.GetClaim)
+	r.Delete("/api/claims/{id}", h.DeleteClaim)
+	r.Post("/api/claims/{id}/transactions", h.AddClaimTransactions)
+	r.Delete("/api/claims/{id}/transactions/{txID}", h.RemoveClaimTransaction)
+	r.Post("/api/claims/{id}/submit", h.SubmitClaim)
+	r.Post("/api/claims/{id}/paid", h.PayClaim)
+	r.Get("/api/claims/{id}/report", h.ClaimReport)
+
+	// API - Tax
+	r.Get("/api/tax/items", h.ListTaxItems)
+	r.Get("/api/tax/categories", h.ListTaxCategories)
+	r.Put("/api/tax/categories/{category}", h.SetTaxCategory)
+	r.Delete("/api/tax/categories/{category}", h.DeleteTaxCategory)
+	r.Get("/api/tax/report", h.TaxReport)
+	r.Get("/api/tax/slips", h.TaxSlips)
+
+	// API - Receipts
+	r.Get("/api/receipts", h.ListReceipts)
+	r.Get("/api/receipts/export", h.ExportReceipts)
+
+	// API - Tags
+	r.Get("/api/tags", h.ListTags)
+
 	// API - Users
 	r.Get("/api/users", h.ListUsers)
 	r.Post("/api/users", h.CreateUser)
 	r.Post("/api/users/select", h.SelectUser)
-	r.Patch("/api/users/{id}", h.UpdateCutoff)
+	r.Patch("/api/users/{id}", h.UpdateUser)
 	r.Delete("/api/users/{id}", h.DeleteUser)
 
+	// API - Exchange rates
+	r.Get("/api/fx-rates", h.ListFXRates)
+	r.Post("/api/fx-rates", h.CreateFXRate)
+	r.Post("/api/fx-rates/import", h.ImportFXRates)
+	r.Delete("/api/fx-rates/{id}", h.DeleteFXRate)
+
+	// API - Admin
+	r.Get("/api/admin/reparse", h.ListReparses)
+	r.Post("/api/admin/reparse", h.StartReparse)
+	r.Get("/api/admin/reparse/{id}", h.GetReparse)
+	r.Post("/api/admin/reparse/{id}/apply", h.ApplyReparse)
+
 	// API - Dashboard
 	r.Get("/api/dashboard/summary", h.DashboardSummary)
 	r.Get("/api/dashboard/by-category", h.DashboardByCategory)
 	r.Get("/api/dashboard/by-channel", h.DashboardByChannel)
+	r.Get("/api/dashboard/by-tag", h.DashboardByTag)
 	r.Get("/api/dashboard/transactions", h.DashboardTransactions)
+	r.Get("/api/dashboard/timeseries", h.DashboardTimeSeries)
+
+	// API - Forecast
+	r.Get("/api/forecast", h.Forecast)
+
+	// API - Holdings
+	r.Get("/api/holdings", h.ListHoldings)
+	r.Post("/api/holdings", h.CreateHolding)
+	r.Delete("/api/holdings/{id}", h.DeleteHolding)
+	r.Get("/api/holdings/{id}/valuations", h.ListValuations)
+	r.Post("/api/holdings/{id}/valuations", h.CreateValuation)
+	r.Delete("/api/holdings/{id}/valuations/{valuationID}", h.DeleteValuation)
+	r.Get("/api/networth", h.NetWorth)
+
+	// API - Reports
+	r.Get("/api/reports/accuracy", h.AccuracyReport)
 
 	log.Printf("Server starting on http://localhost:%s", cfg.ServerPort)
 	for _, ip := range lanIPs() {
@@ -90,6 +181,34 @@ func main() {
 	}
 }
 
+func newOCREngine(cfg *config.Config) (ocr.OCREngine, error) {
+	return ocr.New(cfg.OCREngine, ocr.Options{
+		Endpoint:      cfg.OCREndpoint,
+		Timeout:       cfg.OCRTimeout,
+		Retries:       cfg.OCRRetries,
+		TesseractCmd:  cfg.TesseractCmd,
+		TesseractLang: cfg.TesseractLang,
+		FixtureDir:    cfg.OCRFixtureDir,
+	})
+}
+
+// checkOCR probes the OCR engine once at startup. Slips uploaded while it is
+// down stay queued and are retried, so a failure is only logged.
+func checkOCR(client ocr.OCREngine, engine string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	health, err := client.Health(ctx)
+	switch {
+	case err != nil:
+		log.Printf("OCR engine %q is unavailable: %v", engine, err)
+	case !health.Ready():
+		log.Printf("OCR engine %q is not ready (models or language data missing)", engine)
+	default:
+		log.Printf("OCR engine %q is ready", engine)
+	}
+}
+
 func lanIPs() []string {
 	var ips []string
 	ifaces, err := net.Interfaces()
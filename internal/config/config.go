@@ -2,25 +2,63 @@ package config
 
 import (
 	"os"
+	"strconv"
+	"time"
 )
 
 type Config struct {
-	ServerPort    string
-	DatabaseURL   string
-	UploadDir     string
-	OCREndpoint   string
-	OllamaURL     string
-	OllamaModel   string
+	ServerPort  string
+	DatabaseURL string
+	UploadDir   string
+	OCREndpoint string
+	OllamaURL   string
+	OllamaModel string
+	// OCREngine selects the OCR backend: http (EasyOCR sidecar), tesseract
+	// or fixture.
+	OCREngine string
+	// OCRFixtureDir holds canned OCR results for the fixture engine.
+	OCRFixtureDir string
+	// TesseractCmd and TesseractLang configure the tesseract engine.
+	TesseractCmd  string
+	TesseractLang string
+	// OCRTimeout limits each request to the OCR service.
+	OCRTimeout time.Duration
+	// OCRRetries is how many times a failed OCR request is retried.
+	OCRRetries int
+	// OCRLowConfidence is the block confidence below which the confirm page
+	// highlights OCR text for checking.
+	OCRLowConfidence float64
+	// SlipHashDistance is the maximum perceptual-hash distance (in bits) at
+	// which two slip images are treated as the same upload.
+	SlipHashDistance int
+	// JobWorkers is the number of background workers processing slips.
+	JobWorkers int
+	// JobMaxAttempts is how many times a failing job runs before it is marked failed.
+	JobMaxAttempts int
+	// TrashRetention is how long deleted transactions stay in the trash
+	// before they and their slips are removed for good.
+	TrashRetention time.Duration
 }
 
 func Load() *Config {
 	return &Config{
-		ServerPort:    getEnv("SERVER_PORT", "8080"),
-		DatabaseURL:   getEnv("DATABASE_URL", "./cash-track.db"),
-		UploadDir:     getEnv("UPLOAD_DIR", "./uploads"),
-		OCREndpoint:   getEnv("OCR_ENDPOINT", "http://localhost:8001"),
-		OllamaURL:     getEnv("OLLAMA_URL", "http://localhost:11434"),
-		OllamaModel:   getEnv("OLLAMA_MODEL", "llama3.2"),
+		ServerPort:       getEnv("SERVER_PORT", "8080"),
+		DatabaseURL:      getEnv("DATABASE_URL", "./cash-track.db"),
+		UploadDir:        getEnv("UPLOAD_DIR", "./uploads"),
+		OCREndpoint:      getEnv("OCR_ENDPOINT", "http://localhost:8001"),
+		OllamaURL:        getEnv("OLLAMA_URL", "http://localhost:11434"),
+		OllamaModel:      getEnv("OLLAMA_MODEL", "llama3.2"),
+		OCREngine:        getEnv("OCR_ENGINE", "http"),
+		OCRFixtureDir:    getEnv("OCR_FIXTURE_DIR", ""),
+		TesseractCmd:     getEnv("TESSERACT_CMD", "tesseract"),
+		TesseractLang:    getEnv("TESSERACT_LANG", "tha+eng"),
+		OCRTimeout:       time.Duration(getEnvInt("OCR_TIMEOUT_SECONDS", 60)) * time.Second,
+		OCRRetries:       getEnvInt("OCR_RETRIES", 2),
+		OCRLowConfidence: getEnvFloat("OCR_LOW_CONFIDENCE", 0.5),
+		SlipHashDistance: getEnvInt("SLIP_HASH_DISTANCE", 4),
+		JobWorkers:       getEnvInt("JOB_WORKERS", 2),
+		JobMaxAttempts:   getEnvInt("JOB_MAX_ATTEMPTS", 5),
+		TrashRetention:   time.Duration(getEnvInt("TRASH_RETENTION_DAYS", 30)) * 24 * time.Hour,
 	}
 }
 
@@ -30,3 +68,21 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+func getEnvInt(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
+
+func getEnvFloat(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
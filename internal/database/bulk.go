@@ -0,0 +1,562 @@
+package database
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"cash-track/internal/models"
+)
+
+// BulkLimit caps how many transactions one bulk action may touch
+const BulkLimit = 1000
+
+// ErrBulkLimit is returned when a bulk action selects more than BulkLimit
+// transactions
+var ErrBulkLimit = fmt.Errorf("at most %d transactions can be changed at once", BulkLimit)
+
+// bulkFieldColumns maps the set actions to the column they change
+var bulkFieldColumns = map[string]string{
+	models.BulkCategorize: "category",
+	models.BulkSetChannel: "channel",
+	models.BulkSetAccount: "account_label",
+}
+
+// bulkUndoColumns are the columns each bulk action changes, which undo puts
+// back. Tags and trashed transactions are restored separately.
+var bulkUndoColumns = map[string][]string{
+	models.BulkConfirm:         {"status"},
+	models.BulkCategorize:      {"category", "user_edited_fields"},
+	models.BulkSetChannel:      {"channel", "user_edited_fields"},
+	models.BulkSetAccount:      {"account_label", "user_edited_fields"},
+	models.BulkSetReimbursable: {"reimbursable"},
+	models.BulkSetTaxItem:      {"tax_item"},
+}
+
+// bulkSnapshot is a transaction as it was before a bulk action, kept so the
+// action can be undone. AddedTags are the tags add_tags put on it, and
+// Revision the revision the action recorded; any later one means the
+// transaction has changed since.
+type bulkSnapshot struct {
+	Row       map[string]interface{} `json:"row"`
+	Tags      []int64                `json:"tags"`
+	AddedTags []int64                `json:"added_tags,omitempty"`
+	Revision  int64                  `json:"revision,omitempty"`
+}
+
+// id returns the ID of the snapshotted transaction
+func (s bulkSnapshot) id() (int64, error) {
+	id, _ := s.Row["id"].(json.Number)
+	txID, err := id.Int64()
+	if err != nil {
+		return 0, fmt.Errorf("bulk snapshot has no transaction id")
+	}
+	return txID, nil
+}
+
+// bulkRevisionActions maps bulk actions to the revision they record
+var bulkRevisionActions = map[string]string{
+	models.BulkConfirm: models.RevisionConfirm,
+	models.BulkDelete:  models.RevisionDelete,
+}
+
+// ApplyBulk runs req in one database transaction against the transactions
+// in req.IDs or, when there are none, those matching filter. A preview rolls
+// back instead of committing and returns no undo token.
+func (r *Repository) ApplyBulk(userID int64, req models.BulkRequest, filter models.SearchQuery) (*models.BulkResult, error) {
+	dbTx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer dbTx.Rollback()
+
+	matched, err := bulkTargets(dbTx, userID, req.IDs, filter)
+	if err != nil {
+		return nil, err
+	}
+	result := &models.BulkResult{Action: req.Action, Matched: len(matched), IDs: []int64{}, Preview: req.Preview}
+	if len(matched) == 0 {
+		return result, nil
+	}
+
+	var tagIDs []int64
+	if req.Action == models.BulkAddTags {
+		if tagIDs, err = ensureTags(dbTx, userID, req.Tags); err != nil {
+			return nil, err
+		}
+	}
+
+	affected, err := bulkEligible(dbTx, req, matched, tagIDs)
+	if err != nil {
+		return nil, err
+	}
+	result.Affected = len(affected)
+	if len(affected) > 0 {
+		result.IDs = affected
+	}
+	if len(affected) == 0 || req.Preview {
+		return result, nil
+	}
+
+	snapshots, err := snapshotTransactions(dbTx, affected)
+	if err != nil {
+		return nil, err
+	}
+	if req.Action == models.BulkAddTags {
+		for i := range snapshots {
+			snapshots[i].AddedTags = missingIDs(tagIDs, snapshots[i].Tags)
+		}
+	}
+	before := map[int64]map[string]string{}
+	for _, id := range affected {
+		if before[id], err = revisionState(dbTx, id); err != nil {
+			return nil, err
+		}
+	}
+	if err := applyBulkAction(dbTx, userID, req, affected, tagIDs); err != nil {
+		return nil, err
+	}
+	revisionAction, ok := bulkRevisionActions[req.Action]
+	if !ok {
+		revisionAction = models.RevisionUpdate
+	}
+	for i, id := range affected {
+		if err := recordRevision(dbTx, id, revisionAction, models.ActorUser, before[id]); err != nil {
+			return nil, err
+		}
+		if snapshots[i].Revision, err = latestRevision(dbTx, id); err != nil {
+			return nil, err
+		}
+	}
+
+	snapshotJSON, err := json.Marshal(snapshots)
+	if err != nil {
+		return nil, err
+	}
+	token, err := newUndoToken()
+	if err != nil {
+		return nil, err
+	}
+	_, err = dbTx.Exec(`
+		INSERT INTO bulk_operations (user_id, token, action, affected, snapshot)
+		VALUES (?, ?, ?, ?, ?)
+	`, userID, token, req.Action, len(affected), string(snapshotJSON))
+	if err != nil {
+		return nil, err
+	}
+	if err := dbTx.Commit(); err != nil {
+		return nil, err
+	}
+
+	result.UndoToken = token
+	return result, nil
+}
+
+// UndoBulk puts back what a bulk action changed on each transaction.
+// Transactions edited since the action and those purged from the trash are
+// skipped. Each token works once; sql.ErrNoRows means it is unknown, used
+// or expired.
+func (r *Repository) UndoBulk(userID int64, token string) (*models.BulkResult, error) {
+	dbTx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer dbTx.Rollback()
+
+	var (
+		id           int64
+		action       string
+		snapshotJSON string
+		createdAt    string
+	)
+	err = dbTx.QueryRow(`
+		SELECT id, action, snapshot, created_at FROM bulk_operations
+		WHERE token = ? AND user_id = ? AND undone_at IS NULL AND snapshot IS NOT NULL
+	`, token, userID).Scan(&id, &action, &snapshotJSON, &createdAt)
+	if err != nil {
+		return nil, err
+	}
+
+	var snapshots []bulkSnapshot
+	if err := decodeSnapshot(snapshotJSON, &snapshots); err != nil {
+		return nil, fmt.Errorf("failed to read bulk snapshot: %w", err)
+	}
+
+	result := &models.BulkResult{Action: action, Matched: len(snapshots), IDs: []int64{}}
+	for _, snapshot := range snapshots {
+		txID, err := snapshot.id()
+		if err != nil {
+			return nil, err
+		}
+		var updatedAt string
+		err = dbTx.QueryRow(`SELECT updated_at FROM transactions WHERE id = ? AND user_id = ?`, txID, userID).Scan(&updatedAt)
+		if err == sql.ErrNoRows {
+			result.Skipped++
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		revision, err := latestRevision(dbTx, txID)
+		if err != nil {
+			return nil, err
+		}
+		// Snapshots from before Revision was kept compare edit times, which
+		// miss changes made within the same second
+		changed := revision != snapshot.Revision
+		if snapshot.Revision == 0 {
+			changed = updatedAt > createdAt
+		}
+		if changed {
+			result.Skipped++
+			continue
+		}
+
+		before, err := revisionState(dbTx, txID)
+		if err != nil {
+			return nil, err
+		}
+		if err := restoreTransaction(dbTx, userID, action, snapshot); err != nil {
+			return nil, err
+		}
+		if err := recordRevision(dbTx, txID, models.RevisionRevert, models.ActorUser, before); err != nil {
+			return nil, err
+		}
+		result.IDs = append(result.IDs, txID)
+	}
+	result.Affected = len(result.IDs)
+
+	if _, err := dbTx.Exec(`UPDATE bulk_operations SET undone_at = datetime('now') WHERE id = ?`, id); err != nil {
+		return nil, err
+	}
+	return result, dbTx.Commit()
+}
+
+// latestRevision returns the ID of the newest revision of a transaction, or
+// 0 when it has none
+func latestRevision(dbTx *sql.Tx, txID int64) (int64, error) {
+	var id int64
+	err := dbTx.QueryRow(`SELECT COALESCE(MAX(id), 0) FROM transaction_revisions WHERE transaction_id = ?`, txID).Scan(&id)
+	return id, err
+}
+
+// ExpireBulkUndo drops the undo data of bulk actions made before cutoff
+func (r *Repository) ExpireBulkUndo(cutoff time.Time) error {
+	_, err := r.db.Exec(`
+		UPDATE bulk_operations SET snapshot = NULL
+		WHERE snapshot IS NOT NULL AND created_at < ?
+	`, cutoff.UTC().Format("2006-01-02 15:04:05"))
+	return err
+}
+
+// bulkTargets returns the IDs of the user's transactions selected by ids or,
+// when ids is empty, by filter
+func bulkTargets(dbTx *sql.Tx, userID int64, ids []int64, filter models.SearchQuery) ([]int64, error) {
+	if len(ids) == 0 {
+		results, err := searchTransactions(dbTx, userID, filter, BulkLimit+1)
+		if err != nil {
+			return nil, err
+		}
+		if len(results) > BulkLimit {
+			return nil, ErrBulkLimit
+		}
+		var matched []int64
+		for _, result := range results {
+			matched = append(matched, result.ID)
+		}
+		return matched, nil
+	}
+
+	if len(ids) > BulkLimit {
+		return nil, ErrBulkLimit
+	}
+	placeholders, args := idList(ids)
+	return queryIDs(dbTx, `SELECT id FROM transactions WHERE user_id = ? AND deleted_at IS NULL AND id IN (`+placeholders+`) ORDER BY id`,
+		append([]interface{}{userID}, args...)...)
+}
+
+// bulkEligible narrows matched to the transactions req would change
+func bulkEligible(dbTx *sql.Tx, req models.BulkRequest, matched []int64, tagIDs []int64) ([]int64, error) {
+	placeholders, args := idList(matched)
+	query := `SELECT id FROM transactions WHERE id IN (` + placeholders + `)`
+
+	switch req.Action {
+	case models.BulkConfirm:
+		// Transactions still missing an amount stay pending
+		query += ` AND status <> 'confirmed' AND amount_minor IS NOT NULL`
+	case models.BulkDelete:
+		query += ` AND NOT ` + claimLocked
+	case models.BulkSetReimbursable:
+		query += ` AND reimbursable <> ?`
+		args = append(args, req.Value == "true")
+		if req.Value != "true" {
+			query += ` AND claim_id IS NULL`
+		}
+	case models.BulkSetTaxItem:
+		query += ` AND tax_item IS NOT ?`
+		args = append(args, nullString(req.Value))
+	case models.BulkAddTags:
+		tagPlaceholders, tagArgs := idList(tagIDs)
+		query += ` AND (SELECT COUNT(*) FROM transaction_tags
+			WHERE transaction_id = transactions.id AND tag_id IN (` + tagPlaceholders + `)) < ?`
+		args = append(append(args, tagArgs...), len(tagIDs))
+	default:
+		column, ok := bulkFieldColumns[req.Action]
+		if !ok {
+			return nil, fmt.Errorf("unknown bulk action %q", req.Action)
+		}
+		query += ` AND ` + column + ` IS NOT ?`
+		args = append(args, nullString(req.Value))
+	}
+	return queryIDs(dbTx, query+` ORDER BY id`, args...)
+}
+
+// applyBulkAction changes the affected transactions
+func applyBulkAction(dbTx *sql.Tx, userID int64, req models.BulkRequest, affected []int64, tagIDs []int64) error {
+	placeholders, args := idList(affected)
+	where := ` WHERE user_id = ? AND id IN (` + placeholders + `)`
+	whereArgs := append([]interface{}{userID}, args...)
+
+	switch req.Action {
+	case models.BulkConfirm:
+		for _, id := range affected {
+			current, err := scanTransaction(dbTx.QueryRow(`SELECT `+transactionColumns+` FROM transactions WHERE id = ?`, id))
+			if err != nil {
+				return err
+			}
+			if err := confirmTransaction(dbTx, userID, current, current.ConfirmRequest()); err != nil {
+				return err
+			}
+		}
+		return nil
+	case models.BulkDelete:
+		for _, id := range affected {
+			if err := trashTransaction(dbTx, userID, id); err != nil {
+				return err
+			}
+		}
+		return nil
+	case models.BulkSetReimbursable:
+		_, err := dbTx.Exec(`UPDATE transactions SET reimbursable = ?, updated_at = datetime('now')`+where,
+			append([]interface{}{req.Value == "true"}, whereArgs...)...)
+		return err
+	case models.BulkSetTaxItem:
+		_, err := dbTx.Exec(`UPDATE transactions SET tax_item = ?, updated_at = datetime('now')`+where,
+			append([]interface{}{nullString(req.Value)}, whereArgs...)...)
+		return err
+	case models.BulkAddTags:
+		for _, txID := range affected {
+			for _, tagID := range tagIDs {
+				_, err := dbTx.Exec(`INSERT OR IGNORE INTO transaction_tags (transaction_id, tag_id) VALUES (?, ?)`, txID, tagID)
+				if err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	// A value set by hand is a user edit, so re-parsing leaves it alone
+	column := bulkFieldColumns[req.Action]
+	_, err := dbTx.Exec(`
+		UPDATE transactions
+		SET `+column+` = ?, updated_at = datetime('now'),
+		    user_edited_fields = CASE
+		        WHEN COALESCE(user_edited_fields, '') = '' THEN ?
+		        WHEN ',' || user_edited_fields || ',' LIKE ? THEN user_edited_fields
+		        ELSE user_edited_fields || ',' || ?
+		    END`+where,
+		append([]interface{}{nullString(req.Value), column, "%," + column + ",%", column}, whereArgs...)...)
+	return err
+}
+
+// ensureTags returns the IDs of the user's tags with the given names,
+// creating any that are missing
+func ensureTags(dbTx *sql.Tx, userID int64, names []string) ([]int64, error) {
+	var ids []int64
+	for _, name := range names {
+		if _, err := dbTx.Exec(`INSERT OR IGNORE INTO tags (user_id, name) VALUES (?, ?)`, userID, name); err != nil {
+			return nil, err
+		}
+		var id int64
+		if err := dbTx.QueryRow(`SELECT id FROM tags WHERE user_id = ? AND name = ?`, userID, name).Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// snapshotTransactions records every column and tag of the given transactions
+func snapshotTransactions(dbTx *sql.Tx, ids []int64) ([]bulkSnapshot, error) {
+	columns, err := transactionTableColumns(dbTx)
+	if err != nil {
+		return nil, err
+	}
+	var pairs []string
+	for _, column := range columns {
+		pairs = append(pairs, `'`+column+`', "`+column+`"`)
+	}
+
+	var snapshots []bulkSnapshot
+	for _, id := range ids {
+		var rowJSON string
+		err := dbTx.QueryRow(`SELECT json_object(`+strings.Join(pairs, ", ")+`) FROM transactions WHERE id = ?`, id).Scan(&rowJSON)
+		if err != nil {
+			return nil, err
+		}
+		snapshot := bulkSnapshot{}
+		if err := decodeSnapshot(rowJSON, &snapshot.Row); err != nil {
+			return nil, err
+		}
+		if snapshot.Tags, err = queryIDs(dbTx, `SELECT tag_id FROM transaction_tags WHERE transaction_id = ? ORDER BY tag_id`, id); err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	return snapshots, nil
+}
+
+// restoreTransaction puts back the columns and tags action changed from
+// the transaction's snapshot. A deleted transaction comes out of the trash
+// as it does one at a time.
+func restoreTransaction(dbTx *sql.Tx, userID int64, action string, snapshot bulkSnapshot) error {
+	txID, err := snapshot.id()
+	if err != nil {
+		return err
+	}
+
+	if action == models.BulkDelete {
+		return untrashTransaction(dbTx, userID, txID)
+	}
+	if action == models.BulkAddTags {
+		// Snapshots from before AddedTags was kept drop every tag added since
+		added := snapshot.AddedTags
+		if added == nil {
+			current, err := queryIDs(dbTx, `SELECT tag_id FROM transaction_tags WHERE transaction_id = ?`, txID)
+			if err != nil {
+				return err
+			}
+			added = missingIDs(current, snapshot.Tags)
+		}
+		for _, tagID := range added {
+			if _, err := dbTx.Exec(`DELETE FROM transaction_tags WHERE transaction_id = ? AND tag_id = ?`, txID, tagID); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	var sets []string
+	var args []interface{}
+	for _, column := range bulkUndoColumns[action] {
+		value, ok := snapshot.Row[column]
+		if !ok {
+			continue
+		}
+		sets = append(sets, column+` = ?`)
+		args = append(args, snapshotValue(value))
+	}
+	if len(sets) == 0 {
+		return nil
+	}
+	_, err = dbTx.Exec(`UPDATE transactions SET `+strings.Join(sets, ", ")+`, updated_at = datetime('now') WHERE id = ? AND user_id = ?`,
+		append(args, txID, userID)...)
+	return err
+}
+
+// missingIDs returns the IDs in ids that are not in other
+func missingIDs(ids, other []int64) []int64 {
+	seen := map[int64]bool{}
+	for _, id := range other {
+		seen[id] = true
+	}
+	var missing []int64
+	for _, id := range ids {
+		if !seen[id] {
+			missing = append(missing, id)
+		}
+	}
+	return missing
+}
+
+// decodeSnapshot reads snapshot JSON keeping numbers exact
+func decodeSnapshot(data string, v interface{}) error {
+	decoder := json.NewDecoder(strings.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
+
+// snapshotValue converts a decoded column back to what was stored
+func snapshotValue(value interface{}) interface{} {
+	number, ok := value.(json.Number)
+	if !ok {
+		return value
+	}
+	if i, err := number.Int64(); err == nil {
+		return i
+	}
+	f, _ := number.Float64()
+	return f
+}
+
+// transactionTableColumns lists the columns of the transactions table,
+// including legacy ones
+func transactionTableColumns(dbTx *sql.Tx) ([]string, error) {
+	rows, err := dbTx.Query(`SELECT name FROM pragma_table_info('transactions')`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var columns []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		columns = append(columns, name)
+	}
+	return columns, rows.Err()
+}
+
+// idList returns "?, ?, ..." and the arguments for an IN clause
+func idList(ids []int64) (string, []interface{}) {
+	placeholders := make([]string, len(ids))
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		placeholders[i] = "?"
+		args[i] = id
+	}
+	return strings.Join(placeholders, ", "), args
+}
+
+func queryIDs(db querier, query string, args ...interface{}) ([]int64, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func newUndoToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
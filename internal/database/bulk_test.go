@@ -0,0 +1,235 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"cash-track/internal/models"
+)
+
+// bulkState returns the columns and tags a bulk action may change
+func bulkState(t *testing.T, r *Repository, id int64) string {
+	t.Helper()
+	var state string
+	err := r.db.QueryRow(`
+		SELECT json_array(category, channel, account_label, status, deleted_at, reimbursable, tax_item, user_edited_fields,
+			(SELECT group_concat(tag_id) FROM transaction_tags WHERE transaction_id = transactions.id))
+		FROM transactions WHERE id = ?
+	`, id).Scan(&state)
+	if err != nil {
+		t.Fatalf("state of transaction %d: %v", id, err)
+	}
+	return state
+}
+
+func TestApplyBulkPreview(t *testing.T) {
+	r := newTestRepository(t)
+	var pending []int64
+	for _, day := range []string{"2026-10-01", "2026-10-02", "2026-10-03"} {
+		pending = append(pending, addTestTransaction(t, r, testTransaction{day: day, amount: 5000, category: "food"}))
+	}
+	addTestTransaction(t, r, testTransaction{day: "2026-10-04", amount: 7000, status: "confirmed"})
+	filter := models.SearchQuery{Status: "pending"}
+	req := models.BulkRequest{Action: models.BulkConfirm, Preview: true}
+
+	preview, err := r.ApplyBulk(1, req, filter)
+	if err != nil {
+		t.Fatalf("preview: %v", err)
+	}
+	if preview.Matched != 3 || preview.Affected != 3 || preview.UndoToken != "" || !reflect.DeepEqual(preview.IDs, pending) {
+		t.Errorf("preview = %+v", preview)
+	}
+	for _, id := range pending {
+		if tx, _ := r.GetTransaction(1, id); tx.Status != "pending" {
+			t.Errorf("preview confirmed transaction %d", id)
+		}
+	}
+
+	req.Preview = false
+	applied, err := r.ApplyBulk(1, req, filter)
+	if err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	if applied.Matched != preview.Matched || applied.Affected != preview.Affected || applied.UndoToken == "" {
+		t.Errorf("apply = %+v, preview was %+v", applied, preview)
+	}
+	for _, id := range pending {
+		if tx, _ := r.GetTransaction(1, id); tx.Status != "confirmed" {
+			t.Errorf("transaction %d was not confirmed", id)
+		}
+	}
+}
+
+func TestApplyBulkLimit(t *testing.T) {
+	r := newTestRepository(t)
+	_, err := r.db.Exec(`
+		WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < ?)
+		INSERT INTO transactions (user_id, txn_date, amount_minor, direction, status)
+		SELECT 1, '2026-10-01', i, 'expense', 'pending' FROM n
+	`, BulkLimit+1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := make([]int64, BulkLimit+1)
+	for i := range ids {
+		ids[i] = int64(i + 1)
+	}
+
+	tests := []struct {
+		name   string
+		ids    []int64
+		filter models.SearchQuery
+	}{
+		{"ids", ids, models.SearchQuery{}},
+		{"filter", nil, models.SearchQuery{Status: "pending"}},
+	}
+	for _, tt := range tests {
+		req := models.BulkRequest{Action: models.BulkConfirm, IDs: tt.ids}
+		if _, err := r.ApplyBulk(1, req, tt.filter); err == nil {
+			t.Errorf("%s: changing %d transactions succeeded", tt.name, BulkLimit+1)
+		}
+	}
+	if _, err := r.ApplyBulk(1, models.BulkRequest{Action: models.BulkConfirm, IDs: ids[:BulkLimit]}, models.SearchQuery{}); err != nil {
+		t.Errorf("changing %d transactions: %v", BulkLimit, err)
+	}
+}
+
+func TestUndoBulk(t *testing.T) {
+	tests := []models.BulkRequest{
+		{Action: models.BulkConfirm},
+		{Action: models.BulkDelete},
+		{Action: models.BulkCategorize, Value: "travel"},
+		{Action: models.BulkSetChannel, Value: "scb"},
+		{Action: models.BulkSetAccount, Value: "kbank-savings"},
+		{Action: models.BulkAddTags, Tags: []string{"trip", "work"}},
+		{Action: models.BulkSetReimbursable, Value: "true"},
+		{Action: models.BulkSetTaxItem, Value: "donation"},
+	}
+
+	for _, req := range tests {
+		t.Run(req.Action, func(t *testing.T) {
+			r := newTestRepository(t)
+			id := addTestTransaction(t, r, testTransaction{day: "2026-10-01", amount: 5000, category: "food"})
+			// A tag the transaction already had stays after the undo
+			if _, err := r.ApplyBulk(1, models.BulkRequest{Action: models.BulkAddTags, IDs: []int64{id}, Tags: []string{"work"}}, models.SearchQuery{}); err != nil {
+				t.Fatal(err)
+			}
+			before := bulkState(t, r, id)
+
+			req.IDs = []int64{id}
+			applied, err := r.ApplyBulk(1, req, models.SearchQuery{})
+			if err != nil {
+				t.Fatalf("apply: %v", err)
+			}
+			if applied.Affected != 1 || bulkState(t, r, id) == before {
+				t.Fatalf("apply changed nothing: %+v", applied)
+			}
+
+			undone, err := r.UndoBulk(1, applied.UndoToken)
+			if err != nil {
+				t.Fatalf("undo: %v", err)
+			}
+			if undone.Affected != 1 || undone.Skipped != 0 {
+				t.Errorf("undo = %+v", undone)
+			}
+			if after := bulkState(t, r, id); after != before {
+				t.Errorf("after undo %s, want %s", after, before)
+			}
+
+			if _, err := r.UndoBulk(1, applied.UndoToken); err != sql.ErrNoRows {
+				t.Errorf("second undo: err = %v, want sql.ErrNoRows", err)
+			}
+		})
+	}
+}
+
+func TestUndoBulkExpired(t *testing.T) {
+	r := newTestRepository(t)
+	id := addTestTransaction(t, r, testTransaction{day: "2026-10-01", amount: 5000, category: "food"})
+	applied, err := r.ApplyBulk(1, models.BulkRequest{Action: models.BulkCategorize, IDs: []int64{id}, Value: "travel"}, models.SearchQuery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := r.ExpireBulkUndo(time.Now().Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.UndoBulk(1, applied.UndoToken); err != sql.ErrNoRows {
+		t.Errorf("undo after expiry: err = %v, want sql.ErrNoRows", err)
+	}
+	if _, err := r.UndoBulk(1, "unknown"); err != sql.ErrNoRows {
+		t.Errorf("undo of unknown token: err = %v, want sql.ErrNoRows", err)
+	}
+}
+
+func TestUndoBulkSkipsChanged(t *testing.T) {
+	r := newTestRepository(t)
+	edited := addTestTransaction(t, r, testTransaction{day: "2026-10-01", amount: 5000, category: "food"})
+	purged := addTestTransaction(t, r, testTransaction{day: "2026-10-02", amount: 5000, category: "food"})
+	untouched := addTestTransaction(t, r, testTransaction{day: "2026-10-03", amount: 5000, category: "food"})
+	ids := []int64{edited, purged, untouched}
+
+	applied, err := r.ApplyBulk(1, models.BulkRequest{Action: models.BulkCategorize, IDs: ids, Value: "travel"}, models.SearchQuery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Edited within the same second as the action, and removed for good
+	// from the trash
+	if err := r.UpdateOCRResult(edited, "", models.SomeMoney(6000), "THB", "2026-10-01", "", "", "test", 0.9, models.ActorRegex); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.db.Exec(`DELETE FROM transactions WHERE id = ?`, purged); err != nil {
+		t.Fatal(err)
+	}
+
+	undone, err := r.UndoBulk(1, applied.UndoToken)
+	if err != nil {
+		t.Fatalf("undo: %v", err)
+	}
+	if undone.Matched != 3 || undone.Affected != 1 || undone.Skipped != 2 || !reflect.DeepEqual(undone.IDs, []int64{untouched}) {
+		t.Errorf("undo = %+v", undone)
+	}
+	if tx, _ := r.GetTransaction(1, edited); tx.Category.String != "travel" || tx.Amount.Money != 6000 {
+		t.Errorf("edited transaction = %s %s", tx.Category.String, tx.Amount)
+	}
+	var count int
+	r.db.QueryRow(`SELECT COUNT(*) FROM transactions WHERE id = ?`, purged).Scan(&count)
+	if count != 0 {
+		t.Error("purged transaction was recreated")
+	}
+}
+
+func TestDecodeSnapshot(t *testing.T) {
+	var row map[string]interface{}
+	data := `{"id": 9007199254740993, "llm_confidence": 0.85, "category": "food", "deleted_at": null}`
+	if err := decodeSnapshot(data, &row); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		column string
+		want   interface{}
+	}{
+		// Beyond float64 precision
+		{"id", int64(9007199254740993)},
+		{"llm_confidence", 0.85},
+		{"category", "food"},
+		{"deleted_at", nil},
+	}
+	for _, tt := range tests {
+		if got := snapshotValue(row[tt.column]); got != tt.want {
+			t.Errorf("%s = %#v, want %#v", tt.column, got, tt.want)
+		}
+	}
+
+	id, err := bulkSnapshot{Row: row}.id()
+	if err != nil || id != 9007199254740993 {
+		t.Errorf("id = %d, %v", id, err)
+	}
+	if _, err := (bulkSnapshot{Row: map[string]interface{}{"id": json.Number("x")}}).id(); err == nil {
+		t.Error("id of a snapshot without one succeeded")
+	}
+}
@@ -0,0 +1,378 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+
+	"cash-track/internal/models"
+)
+
+var (
+	// ErrClaimNotDraft is returned when changing the expenses of a claim
+	// that has been submitted, or deleting it
+	ErrClaimNotDraft = errors.New("claim has already been submitted")
+	// ErrClaimStatus is returned for a status change the claim is not ready
+	// for, such as paying a draft or submitting an empty claim
+	ErrClaimStatus = errors.New("claim cannot change to that status")
+	// ErrInvalidReimbursement is returned when the transaction given as a
+	// claim's reimbursement is not the user's income or already pays
+	// another claim
+	ErrInvalidReimbursement = errors.New("reimbursement must be an income transaction not linked to another claim")
+)
+
+// personal is true for transactions that are the user's own spending or
+// income. Expenses on a paid claim and the income that paid it net out, so
+// they are left out of dashboards and summaries.
+const personal = `NOT EXISTS (
+	SELECT 1 FROM claims WHERE claims.status = 'paid'
+	AND (claims.id = transactions.claim_id OR claims.reimbursement_id = transactions.id))`
+
+// claimLocked is true for expenses on a submitted or paid claim and for the
+// income that paid a claim. The claim still counts them, so they cannot be
+// deleted.
+const claimLocked = `EXISTS (
+	SELECT 1 FROM claims WHERE claims.reimbursement_id = transactions.id
+	OR (claims.id = transactions.claim_id AND claims.status <> 'draft'))`
+
+var claimColumns = `c.id, c.user_id, c.title, COALESCE(c.note, ''), c.status,
+	COALESCE(c.submitted_at, ''), COALESCE(c.paid_at, ''), COALESCE(c.reimbursement_id, 0),
+	c.created_at, c.updated_at,
+	COALESCE((SELECT base_currency FROM users WHERE users.id = c.user_id), 'THB'),
+	(SELECT COUNT(*) FROM transactions
+	 WHERE transactions.claim_id = c.id AND transactions.deleted_at IS NULL),
+	(SELECT COALESCE(SUM(` + amountInBase + `), 0) FROM transactions
+	 WHERE transactions.claim_id = c.id AND transactions.deleted_at IS NULL),
+	(SELECT COALESCE(SUM(` + amountInBase + `), 0) FROM transactions
+	 WHERE transactions.id = c.reimbursement_id AND transactions.deleted_at IS NULL),
+	(SELECT COUNT(*) FROM transactions
+	 WHERE transactions.claim_id = c.id AND transactions.deleted_at IS NULL AND ` + fxRate + ` IS NULL)`
+
+func scanClaim(row rowScanner) (models.Claim, error) {
+	var c models.Claim
+	err := row.Scan(&c.ID, &c.UserID, &c.Title, &c.Note, &c.Status,
+		&c.SubmittedAt, &c.PaidAt, &c.ReimbursementID, &c.CreatedAt, &c.UpdatedAt,
+		&c.Currency, &c.Count, &c.Total, &c.Reimbursed, &c.Unconverted)
+	if err != nil {
+		return c, err
+	}
+	c.Outstanding = c.Owed()
+	return c, nil
+}
+
+// CreateClaim stores a new draft claim holding the user's expenses in ids,
+// picked as AddClaimTransactions does. Either both are stored or neither.
+func (r *Repository) CreateClaim(c models.Claim, ids []int64) (*models.Claim, error) {
+	dbTx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer dbTx.Rollback()
+
+	result, err := dbTx.Exec(`INSERT INTO claims (user_id, title, note) VALUES (?, ?, ?)`,
+		c.UserID, c.Title, nullString(c.Note))
+	if err != nil {
+		return nil, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := addClaimTransactions(dbTx, c.UserID, id, ids); err != nil {
+		return nil, err
+	}
+	if err := dbTx.Commit(); err != nil {
+		return nil, err
+	}
+	return r.GetClaim(c.UserID, id)
+}
+
+// GetClaim returns one of the user's claims with its totals. It returns
+// sql.ErrNoRows when the user has no such claim.
+func (r *Repository) GetClaim(userID, id int64) (*models.Claim, error) {
+	c, err := scanClaim(r.db.QueryRow(`SELECT `+claimColumns+` FROM claims c WHERE c.user_id = ? AND c.id = ?`, userID, id))
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// ListClaims returns the user's claims, newest first, limited to those with
+// status unless it is empty
+func (r *Repository) ListClaims(userID int64, status string) ([]models.Claim, error) {
+	query := `SELECT ` + claimColumns + ` FROM claims c WHERE c.user_id = ?`
+	args := []interface{}{userID}
+	if status != "" {
+		query += ` AND c.status = ?`
+		args = append(args, status)
+	}
+	rows, err := r.db.Query(query+` ORDER BY c.created_at DESC, c.id DESC`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var claims []models.Claim
+	for rows.Next() {
+		c, err := scanClaim(rows)
+		if err != nil {
+			return nil, err
+		}
+		claims = append(claims, c)
+	}
+	return claims, rows.Err()
+}
+
+// ClaimTransactions returns the expenses on one of the user's claims, oldest
+// first
+func (r *Repository) ClaimTransactions(userID, claimID int64) ([]models.Transaction, error) {
+	rows, err := r.db.Query(`
+		SELECT `+transactionColumns+`
+		FROM transactions
+		WHERE user_id = ? AND claim_id = ? AND deleted_at IS NULL
+		ORDER BY `+txnDay+` ASC, id ASC
+	`, userID, claimID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var transactions []models.Transaction
+	for rows.Next() {
+		tx, err := scanTransaction(rows)
+		if err != nil {
+			return nil, err
+		}
+		transactions = append(transactions, *tx)
+	}
+	return transactions, rows.Err()
+}
+
+// DeleteClaim removes one of the user's draft claims. Its expenses stay
+// marked reimbursable and can go on another claim. It returns sql.ErrNoRows
+// when the user has no such claim and ErrClaimNotDraft once it has been
+// submitted.
+func (r *Repository) DeleteClaim(userID, id int64) error {
+	dbTx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer dbTx.Rollback()
+
+	if err := draftClaim(dbTx, userID, id); err != nil {
+		return err
+	}
+	ids, err := queryIDs(dbTx, `SELECT id FROM transactions WHERE user_id = ? AND claim_id = ?`, userID, id)
+	if err != nil {
+		return err
+	}
+	befores := make(map[int64]map[string]string, len(ids))
+	for _, txID := range ids {
+		if befores[txID], err = revisionState(dbTx, txID); err != nil {
+			return err
+		}
+	}
+	if _, err := dbTx.Exec(`DELETE FROM claims WHERE user_id = ? AND id = ?`, userID, id); err != nil {
+		return err
+	}
+	_, err = dbTx.Exec(`
+		UPDATE transactions SET claim_id = NULL, updated_at = datetime('now')
+		WHERE user_id = ? AND claim_id = ?
+	`, userID, id)
+	if err != nil {
+		return err
+	}
+	for _, txID := range ids {
+		if err := recordRevision(dbTx, txID, models.RevisionUpdate, models.ActorUser, befores[txID]); err != nil {
+			return err
+		}
+	}
+	return dbTx.Commit()
+}
+
+// draftClaim checks that one of the user's claims exists and is a draft
+func draftClaim(dbTx *sql.Tx, userID, id int64) error {
+	var status string
+	if err := dbTx.QueryRow(`SELECT status FROM claims WHERE user_id = ? AND id = ?`, userID, id).Scan(&status); err != nil {
+		return err
+	}
+	if status != models.ClaimDraft {
+		return ErrClaimNotDraft
+	}
+	return nil
+}
+
+// AddClaimTransactions puts the user's expenses in ids on a draft claim and
+// marks them reimbursable, returning the IDs added. Transactions that are
+// not expenses, are in the trash or are already on another claim are
+// skipped.
+func (r *Repository) AddClaimTransactions(userID, claimID int64, ids []int64) ([]int64, error) {
+	dbTx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer dbTx.Rollback()
+
+	if err := draftClaim(dbTx, userID, claimID); err != nil {
+		return nil, err
+	}
+	added, err := addClaimTransactions(dbTx, userID, claimID, ids)
+	if err != nil {
+		return nil, err
+	}
+	return added, dbTx.Commit()
+}
+
+// addClaimTransactions puts the eligible expenses in ids on a claim
+func addClaimTransactions(dbTx *sql.Tx, userID, claimID int64, ids []int64) ([]int64, error) {
+	if len(ids) == 0 {
+		return []int64{}, nil
+	}
+	placeholders, args := idList(ids)
+	added, err := queryIDs(dbTx, `
+		SELECT id FROM transactions
+		WHERE user_id = ? AND deleted_at IS NULL AND direction = 'expense'
+		  AND (claim_id IS NULL OR claim_id = ?)
+		  AND id IN (`+placeholders+`)
+		ORDER BY id
+	`, append([]interface{}{userID, claimID}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	if len(added) == 0 {
+		return []int64{}, nil
+	}
+
+	befores := make(map[int64]map[string]string, len(added))
+	for _, id := range added {
+		if befores[id], err = revisionState(dbTx, id); err != nil {
+			return nil, err
+		}
+	}
+	placeholders, args = idList(added)
+	_, err = dbTx.Exec(`
+		UPDATE transactions SET claim_id = ?, reimbursable = 1, updated_at = datetime('now')
+		WHERE id IN (`+placeholders+`)
+	`, append([]interface{}{claimID}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range added {
+		if err := recordRevision(dbTx, id, models.RevisionUpdate, models.ActorUser, befores[id]); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := dbTx.Exec(`UPDATE claims SET updated_at = datetime('now') WHERE id = ?`, claimID); err != nil {
+		return nil, err
+	}
+	return added, nil
+}
+
+// RemoveClaimTransaction takes an expense off a draft claim. It stays
+// marked reimbursable. It returns sql.ErrNoRows when the expense is not on
+// the claim.
+func (r *Repository) RemoveClaimTransaction(userID, claimID, txID int64) error {
+	dbTx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer dbTx.Rollback()
+
+	if err := draftClaim(dbTx, userID, claimID); err != nil {
+		return err
+	}
+	before, err := revisionState(dbTx, txID)
+	if err != nil {
+		return err
+	}
+	result, err := dbTx.Exec(`
+		UPDATE transactions SET claim_id = NULL, updated_at = datetime('now')
+		WHERE user_id = ? AND claim_id = ? AND id = ?
+	`, userID, claimID, txID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	if err := recordRevision(dbTx, txID, models.RevisionUpdate, models.ActorUser, before); err != nil {
+		return err
+	}
+	if _, err := dbTx.Exec(`UPDATE claims SET updated_at = datetime('now') WHERE id = ?`, claimID); err != nil {
+		return err
+	}
+	return dbTx.Commit()
+}
+
+// SubmitClaim marks a draft claim with at least one expense as submitted,
+// after which its expenses can no longer change
+func (r *Repository) SubmitClaim(userID, id int64) (*models.Claim, error) {
+	result, err := r.db.Exec(`
+		UPDATE claims SET status = ?, submitted_at = datetime('now'), updated_at = datetime('now')
+		WHERE user_id = ? AND id = ? AND status = ?
+		  AND EXISTS (SELECT 1 FROM transactions WHERE claim_id = claims.id AND deleted_at IS NULL)
+	`, models.ClaimSubmitted, userID, id, models.ClaimDraft)
+	if err != nil {
+		return nil, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	c, err := r.GetClaim(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if affected == 0 {
+		return nil, ErrClaimStatus
+	}
+	return c, nil
+}
+
+// MarkClaimPaid marks a submitted claim as paid. A non-zero reimbursementID
+// links the income transaction that paid it; from then on the claim's
+// expenses and that income net out of the user's own totals.
+func (r *Repository) MarkClaimPaid(userID, id, reimbursementID int64) (*models.Claim, error) {
+	dbTx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer dbTx.Rollback()
+
+	var status string
+	if err := dbTx.QueryRow(`SELECT status FROM claims WHERE user_id = ? AND id = ?`, userID, id).Scan(&status); err != nil {
+		return nil, err
+	}
+	if status != models.ClaimSubmitted {
+		return nil, ErrClaimStatus
+	}
+	if reimbursementID != 0 {
+		var ok bool
+		err := dbTx.QueryRow(`
+			SELECT COUNT(*) > 0 FROM transactions
+			WHERE id = ? AND user_id = ? AND direction = 'income' AND deleted_at IS NULL
+			  AND NOT EXISTS (SELECT 1 FROM claims WHERE claims.reimbursement_id = transactions.id)
+		`, reimbursementID, userID).Scan(&ok)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, ErrInvalidReimbursement
+		}
+	}
+
+	var reimbursement interface{}
+	if reimbursementID != 0 {
+		reimbursement = reimbursementID
+	}
+	_, err = dbTx.Exec(`
+		UPDATE claims SET status = ?, paid_at = datetime('now'), reimbursement_id = ?, updated_at = datetime('now')
+		WHERE id = ?
+	`, models.ClaimPaid, reimbursement, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := dbTx.Commit(); err != nil {
+		return nil, err
+	}
+	return r.GetClaim(userID, id)
+}
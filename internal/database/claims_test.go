@@ -0,0 +1,143 @@
+package database
+
+import (
+	"database/sql"
+	"strconv"
+	"testing"
+
+	"cash-track/internal/models"
+)
+
+func TestDeleteClaim(t *testing.T) {
+	r := newTestRepository(t)
+	id := addTestTransaction(t, r, testTransaction{day: "2026-10-01", amount: 5000})
+	draft, err := r.CreateClaim(models.Claim{UserID: 1, Title: "Draft"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	submitted, err := r.CreateClaim(models.Claim{UserID: 1, Title: "Submitted"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.AddClaimTransactions(1, submitted.ID, []int64{id}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.SubmitClaim(1, submitted.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		userID int64
+		id     int64
+		want   error
+	}{
+		{"submitted", 1, submitted.ID, ErrClaimNotDraft},
+		{"other user", 2, draft.ID, sql.ErrNoRows},
+		{"draft", 1, draft.ID, nil},
+		{"deleted", 1, draft.ID, sql.ErrNoRows},
+	}
+	for _, tt := range tests {
+		if err := r.DeleteClaim(tt.userID, tt.id); err != tt.want {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+	if tx, _ := r.GetTransaction(1, id); tx.ClaimID.Int64 != submitted.ID {
+		t.Errorf("expense left the submitted claim")
+	}
+}
+
+func TestClaimRevisions(t *testing.T) {
+	r := newTestRepository(t)
+	id := addTestTransaction(t, r, testTransaction{day: "2026-10-01", amount: 5000})
+	claim, err := r.CreateClaim(models.Claim{UserID: 1, Title: "Client lunch"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.AddClaimTransactions(1, claim.ID, []int64{id}); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.RemoveClaimTransaction(1, claim.ID, id); err != nil {
+		t.Fatal(err)
+	}
+
+	revisions, err := r.ListRevisions(1, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claimID := strconv.FormatInt(claim.ID, 10)
+	// Newest first
+	want := []struct{ reimbursable, claimID string }{{"true", ""}, {"true", claimID}}
+	if len(revisions) != len(want) {
+		t.Fatalf("%d revisions, want %d", len(revisions), len(want))
+	}
+	for i, w := range want {
+		after := revisions[i].After
+		if after["reimbursable"] != w.reimbursable || after["claim_id"] != w.claimID {
+			t.Errorf("revision %d: reimbursable %q, claim %q, want %q, %q", i, after["reimbursable"], after["claim_id"], w.reimbursable, w.claimID)
+		}
+	}
+}
+
+func TestSubmitClaim(t *testing.T) {
+	r := newTestRepository(t)
+	id := addTestTransaction(t, r, testTransaction{day: "2026-10-01", amount: 5000})
+	claim, err := r.CreateClaim(models.Claim{UserID: 1, Title: "Client lunch"}, []int64{id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claim.Count != 1 {
+		t.Fatalf("claim created with %d expenses, want 1", claim.Count)
+	}
+	empty, err := r.CreateClaim(models.Claim{UserID: 1, Title: "Empty"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		userID int64
+		id     int64
+		want   error
+	}{
+		{"empty", 1, empty.ID, ErrClaimStatus},
+		{"other user", 2, claim.ID, sql.ErrNoRows},
+		{"draft", 1, claim.ID, nil},
+		{"submitted", 1, claim.ID, ErrClaimStatus},
+	}
+	for _, tt := range tests {
+		if _, err := r.SubmitClaim(tt.userID, tt.id); err != tt.want {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestDeleteClaimedTransaction(t *testing.T) {
+	r := newTestRepository(t)
+	drafted := addTestTransaction(t, r, testTransaction{day: "2026-10-01", amount: 5000})
+	submitted := addTestTransaction(t, r, testTransaction{day: "2026-10-01", amount: 5000})
+	if _, err := r.CreateClaim(models.Claim{UserID: 1, Title: "Draft"}, []int64{drafted}); err != nil {
+		t.Fatal(err)
+	}
+	claim, err := r.CreateClaim(models.Claim{UserID: 1, Title: "Submitted"}, []int64{submitted})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.SubmitClaim(1, claim.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := r.DeleteTransaction(1, submitted); err != ErrClaimNotDraft {
+		t.Errorf("delete: err = %v, want ErrClaimNotDraft", err)
+	}
+	result, err := r.ApplyBulk(1, models.BulkRequest{Action: models.BulkDelete, IDs: []int64{drafted, submitted}}, models.SearchQuery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Matched != 2 || result.Affected != 1 || result.IDs[0] != drafted {
+		t.Errorf("bulk delete = %+v, want only transaction %d", result, drafted)
+	}
+	if _, err := r.GetTransaction(1, submitted); err != nil {
+		t.Errorf("expense on the submitted claim: %v", err)
+	}
+}
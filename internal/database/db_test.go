@@ -0,0 +1,48 @@
+package database
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+)
+
+var testDBCount atomic.Int64
+
+// newTestRepository returns a repository on a fresh in-memory database
+// shared by its pooled connections
+func newTestRepository(t *testing.T) *Repository {
+	t.Helper()
+	dsn := fmt.Sprintf("file:test%d?mode=memory&cache=shared&_pragma=busy_timeout(5000)", testDBCount.Add(1))
+	db, err := New(dsn)
+	if err != nil {
+		t.Fatalf("database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return NewRepository(db)
+}
+
+// testTransaction is a transaction stored by addTestTransaction
+type testTransaction struct {
+	day      string
+	amount   int64
+	category string
+	status   string
+}
+
+// addTestTransaction stores an expense for the default user and returns its
+// ID
+func addTestTransaction(t *testing.T, r *Repository, tx testTransaction) int64 {
+	t.Helper()
+	if tx.status == "" {
+		tx.status = "pending"
+	}
+	result, err := r.db.Exec(`
+		INSERT INTO transactions (user_id, txn_date, amount_minor, currency, direction, category, status, description)
+		VALUES (1, ?, ?, 'THB', 'expense', ?, ?, 'test')
+	`, tx.day, tx.amount, nullString(tx.category), tx.status)
+	if err != nil {
+		t.Fatalf("insert transaction: %v", err)
+	}
+	id, _ := result.LastInsertId()
+	return id
+}
@@ -0,0 +1,216 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"cash-track/internal/models"
+)
+
+// RecordExtraction stores what a parser made of transaction txID
+func (r *Repository) RecordExtraction(txID int64, e models.Extraction) error {
+	values, err := json.Marshal(e.Values)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.Exec(`
+		INSERT INTO extractions (transaction_id, user_id, source, model, prompt_version, latency_ms, confidence, extracted, raw_response)
+		SELECT id, user_id, ?, ?, ?, ?, ?, ?, ? FROM transactions WHERE id = ?
+	`, e.Source, nullString(e.Model), nullString(e.PromptVersion), e.LatencyMS, nullFloat(e.Confidence),
+		string(values), nullString(e.Raw), txID)
+	return err
+}
+
+// ListExtractions returns the extractions of one of the user's transactions,
+// the original first
+func (r *Repository) ListExtractions(userID, txID int64) ([]models.Extraction, error) {
+	rows, err := r.db.Query(`
+		SELECT id, transaction_id, source, COALESCE(model, ''), COALESCE(prompt_version, ''), latency_ms,
+		       COALESCE(confidence, 0), extracted, COALESCE(raw_response, ''), created_at
+		FROM extractions
+		WHERE transaction_id = ? AND user_id = ?
+		ORDER BY id ASC
+	`, txID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	extractions := []models.Extraction{}
+	for rows.Next() {
+		var e models.Extraction
+		var values string
+		err := rows.Scan(&e.ID, &e.TransactionID, &e.Source, &e.Model, &e.PromptVersion, &e.LatencyMS,
+			&e.Confidence, &values, &e.Raw, &e.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(values), &e.Values); err != nil {
+			return nil, fmt.Errorf("extraction %d: %w", e.ID, err)
+		}
+		extractions = append(extractions, e)
+	}
+	return extractions, rows.Err()
+}
+
+// accuracyTally accumulates one group of an accuracy report
+type accuracyTally struct {
+	transactions int
+	latencyMS    int64
+	fields       map[string]*models.FieldAccuracy
+}
+
+func (t *accuracyTally) add(tx *models.Transaction, values map[string]string, latencyMS int64) {
+	if t.fields == nil {
+		t.fields = map[string]*models.FieldAccuracy{}
+	}
+	t.transactions++
+	t.latencyMS += latencyMS
+	for _, field := range models.AccuracyFields {
+		confirmed := strings.TrimSpace(tx.FieldValue(field))
+		if confirmed == "" {
+			continue
+		}
+		fa, ok := t.fields[field]
+		if !ok {
+			fa = &models.FieldAccuracy{Field: field}
+			t.fields[field] = fa
+		}
+		fa.Total++
+		extracted := strings.TrimSpace(values[field])
+		switch {
+		case extracted == "":
+			fa.Missing++
+		case strings.EqualFold(extracted, confirmed):
+			fa.Correct++
+		}
+	}
+}
+
+func (t *accuracyTally) group(key string) models.AccuracyGroup {
+	g := models.AccuracyGroup{Key: key, Transactions: t.transactions, Fields: []models.FieldAccuracy{}}
+	if t.transactions > 0 {
+		g.AvgLatencyMS = t.latencyMS / int64(t.transactions)
+	}
+	var total, correct int
+	for _, field := range models.AccuracyFields {
+		fa, ok := t.fields[field]
+		if !ok {
+			continue
+		}
+		fa.Accuracy = float64(fa.Correct) / float64(fa.Total)
+		total += fa.Total
+		correct += fa.Correct
+		g.Fields = append(g.Fields, *fa)
+	}
+	if total > 0 {
+		g.Accuracy = float64(correct) / float64(total)
+	}
+	return g
+}
+
+// AccuracyReport compares each reviewed, confirmed transaction's first
+// extraction with its confirmed values, overall, per confirmed channel and
+// per model. userID 0 covers all users; from and to limit the transaction
+// dates and may be empty.
+func (r *Repository) AccuracyReport(userID int64, from, to string) (*models.AccuracyReport, error) {
+	query := `
+		SELECT ` + transactionColumns + `, ext.source, ext.model, ext.latency_ms, ext.extracted
+		FROM transactions
+		JOIN (
+			SELECT transaction_id AS ext_txn, source, COALESCE(model, '') AS model, latency_ms, extracted
+			FROM extractions
+			WHERE id IN (SELECT MIN(id) FROM extractions GROUP BY transaction_id)
+		) AS ext ON ext.ext_txn = transactions.id
+		WHERE status = 'confirmed' AND deleted_at IS NULL
+		  AND EXISTS (
+			SELECT 1 FROM transaction_revisions AS rev
+			WHERE rev.transaction_id = transactions.id AND rev.actor = ?
+			  AND rev.action IN (?, ?, ?)
+		  )`
+	args := []interface{}{models.ActorUser, models.RevisionConfirm, models.RevisionUpdate, models.RevisionRevert}
+	if userID != 0 {
+		query += ` AND user_id = ?`
+		args = append(args, userID)
+	}
+	if from != "" {
+		query += ` AND ` + txnDay + ` >= ?`
+		args = append(args, from)
+	}
+	if to != "" {
+		query += ` AND ` + txnDay + ` <= ?`
+		args = append(args, to)
+	}
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var overall accuracyTally
+	byChannel := map[string]*accuracyTally{}
+	byModel := map[string]*accuracyTally{}
+	tally := func(groups map[string]*accuracyTally, key string) *accuracyTally {
+		t, ok := groups[key]
+		if !ok {
+			t = &accuracyTally{}
+			groups[key] = t
+		}
+		return t
+	}
+
+	for rows.Next() {
+		var (
+			source, model, extracted string
+			latencyMS                int64
+		)
+		tx, err := scanTransaction(rows, &source, &model, &latencyMS, &extracted)
+		if err != nil {
+			return nil, err
+		}
+		var values map[string]string
+		if err := json.Unmarshal([]byte(extracted), &values); err != nil {
+			return nil, err
+		}
+
+		channel := tx.Channel.String
+		if channel == "" {
+			channel = "none"
+		}
+		if source != models.ActorLLM || model == "" {
+			model = source
+		}
+		overall.add(tx, values, latencyMS)
+		tally(byChannel, channel).add(tx, values, latencyMS)
+		tally(byModel, model).add(tx, values, latencyMS)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return &models.AccuracyReport{
+		From:      from,
+		To:        to,
+		Overall:   overall.group("all"),
+		ByChannel: accuracyGroups(byChannel),
+		ByModel:   accuracyGroups(byModel),
+	}, nil
+}
+
+// accuracyGroups returns the groups with the most transactions first
+func accuracyGroups(tallies map[string]*accuracyTally) []models.AccuracyGroup {
+	groups := []models.AccuracyGroup{}
+	for key, t := range tallies {
+		groups = append(groups, t.group(key))
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Transactions != groups[j].Transactions {
+			return groups[i].Transactions > groups[j].Transactions
+		}
+		return groups[i].Key < groups[j].Key
+	})
+	return groups
+}
@@ -0,0 +1,37 @@
+package database
+
+import (
+	"cash-track/internal/models"
+)
+
+// GetFlowItems lists the confirmed expense and income of from..to in the
+// user's base currency, oldest first. Transactions without a rate to the
+// base currency and reimbursed expenses are left out.
+func (r *Repository) GetFlowItems(userID int64, from, to string) ([]models.FlowItem, error) {
+	rows, err := r.db.Query(`
+		SELECT `+txnDay+` AS day, direction, COALESCE(category, ''), COALESCE(channel, ''),
+		       COALESCE(description, ''), `+amountInBase+`
+		FROM transactions
+		WHERE status = 'confirmed' AND deleted_at IS NULL AND `+personal+`
+		  AND user_id = ?
+		  AND direction IN ('expense', 'income')
+		  AND `+txnDay+` >= ?
+		  AND `+txnDay+` <= ?
+		  AND `+fxRate+` IS NOT NULL
+		ORDER BY day ASC, id ASC
+	`, userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []models.FlowItem
+	for rows.Next() {
+		var item models.FlowItem
+		if err := rows.Scan(&item.Day, &item.Direction, &item.Category, &item.Channel, &item.Description, &item.Amount); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
@@ -0,0 +1,141 @@
+package database
+
+import (
+	"database/sql"
+
+	"cash-track/internal/models"
+)
+
+// txnDay is the date a transaction counts on: its txn_date, or the day it
+// was recorded when no date was extracted.
+const txnDay = `COALESCE(NULLIF(transactions.txn_date, ''), date(transactions.created_at))`
+
+// userBaseCurrency is the base currency of the transaction's owner
+const userBaseCurrency = `(SELECT base_currency FROM users WHERE users.id = transactions.user_id)`
+
+// rateToBase is the SQL for the factor converting amounts in currency to
+// base on day for the user owner, all four being SQL expressions. It uses
+// the latest rate on or before day, either direct or inverted, and falls
+// back to the earliest known rate for days older than every rate. The
+// owner's own rates win over shared ones for the same day. It is NULL when
+// no rate exists.
+func rateToBase(owner, currency, base, day string) string {
+	rates := `FROM fx_rates WHERE user_id IN (0, ` + owner + `) AND `
+	return `(CASE WHEN ` + currency + ` = ` + base + ` THEN 1.0 ELSE COALESCE(
+		(SELECT rate ` + rates + `from_currency = ` + currency + ` AND to_currency = ` + base + ` AND rate_date <= ` + day + `
+		 ORDER BY rate_date DESC, user_id DESC LIMIT 1),
+		(SELECT 1.0 / rate ` + rates + `from_currency = ` + base + ` AND to_currency = ` + currency + ` AND rate_date <= ` + day + `
+		 ORDER BY rate_date DESC, user_id DESC LIMIT 1),
+		(SELECT rate ` + rates + `from_currency = ` + currency + ` AND to_currency = ` + base + `
+		 ORDER BY rate_date ASC, user_id DESC LIMIT 1),
+		(SELECT 1.0 / rate ` + rates + `from_currency = ` + base + ` AND to_currency = ` + currency + `
+		 ORDER BY rate_date ASC, user_id DESC LIMIT 1)
+	) END)`
+}
+
+// fxRate is the factor converting a transaction's amount to its owner's base
+// currency on the transaction date, or NULL when no rate exists
+var fxRate = rateToBase(`transactions.user_id`, `transactions.currency`, userBaseCurrency, txnDay)
+
+// amountInBase is a transaction's amount in its owner's base currency, in
+// whole minor units. Converted amounts are rounded per transaction so that
+// aggregates sum integers. Use it in every aggregate instead of summing
+// amount_minor directly.
+var amountInBase = `CAST(ROUND(transactions.amount_minor * ` + fxRate + `) AS INTEGER)`
+
+// fxRateColumns lists the columns read by scanFXRate, in order
+const fxRateColumns = `id, user_id, rate_date, from_currency, to_currency, rate, source, created_at`
+
+func scanFXRate(row rowScanner) (models.FXRate, error) {
+	var rate models.FXRate
+	var userID int64
+	err := row.Scan(&rate.ID, &userID, &rate.RateDate, &rate.FromCurrency, &rate.ToCurrency, &rate.Rate, &rate.Source, &rate.CreatedAt)
+	rate.Shared = userID == 0
+	return rate, err
+}
+
+// UpsertFXRate stores one of the user's rates, replacing any rate of theirs
+// for the same pair and date. userID 0 stores a shared rate.
+func (r *Repository) UpsertFXRate(userID int64, rate models.FXRate) (*models.FXRate, error) {
+	saved, err := scanFXRate(r.db.QueryRow(`
+		INSERT INTO fx_rates (user_id, rate_date, from_currency, to_currency, rate, source)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (user_id, from_currency, to_currency, rate_date)
+		DO UPDATE SET rate = excluded.rate, source = excluded.source, created_at = datetime('now')
+		RETURNING `+fxRateColumns,
+		userID, rate.RateDate, rate.FromCurrency, rate.ToCurrency, rate.Rate, rate.Source))
+	if err != nil {
+		return nil, err
+	}
+	return &saved, nil
+}
+
+// ImportFXRates stores many of the user's rates in one database
+// transaction, replacing their rates for the same pair and date. userID 0
+// stores shared rates.
+func (r *Repository) ImportFXRates(userID int64, rates []models.FXRate) (int, error) {
+	dbTx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer dbTx.Rollback()
+
+	for _, rate := range rates {
+		_, err := dbTx.Exec(`
+			INSERT INTO fx_rates (user_id, rate_date, from_currency, to_currency, rate, source)
+			VALUES (?, ?, ?, ?, ?, ?)
+			ON CONFLICT (user_id, from_currency, to_currency, rate_date)
+			DO UPDATE SET rate = excluded.rate, source = excluded.source, created_at = datetime('now')
+		`, userID, rate.RateDate, rate.FromCurrency, rate.ToCurrency, rate.Rate, rate.Source)
+		if err != nil {
+			return 0, err
+		}
+	}
+	if err := dbTx.Commit(); err != nil {
+		return 0, err
+	}
+	return len(rates), nil
+}
+
+// ListFXRates returns the user's rates and the shared ones, newest first.
+// currency limits the result to rates from or to that currency when
+// non-empty.
+func (r *Repository) ListFXRates(userID int64, currency string, limit int) ([]models.FXRate, error) {
+	query := `SELECT ` + fxRateColumns + ` FROM fx_rates WHERE user_id IN (0, ?)`
+	args := []interface{}{userID}
+	if currency != "" {
+		query += ` AND (from_currency = ? OR to_currency = ?)`
+		args = append(args, currency, currency)
+	}
+	query += ` ORDER BY rate_date DESC, from_currency ASC, user_id DESC LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rates []models.FXRate
+	for rows.Next() {
+		rate, err := scanFXRate(rows)
+		if err != nil {
+			return nil, err
+		}
+		rates = append(rates, rate)
+	}
+	return rates, rows.Err()
+}
+
+// DeleteFXRate removes one of the user's rates. Shared rates cannot be
+// removed this way. It returns sql.ErrNoRows when the user has no such rate.
+func (r *Repository) DeleteFXRate(userID, id int64) error {
+	result, err := r.db.Exec(`DELETE FROM fx_rates WHERE user_id = ? AND id = ?`, userID, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package database
+
+import (
+	"database/sql"
+	"testing"
+
+	"cash-track/internal/models"
+)
+
+func TestFXRateOwners(t *testing.T) {
+	r := newTestRepository(t)
+	other, err := r.CreateUser("other")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, userID := range []int64{1, other.ID} {
+		tx := addTestTransaction(t, r, testTransaction{day: "2026-10-01", amount: 10000, status: "confirmed"})
+		r.db.Exec(`UPDATE transactions SET user_id = ?, currency = 'JPY' WHERE id = ?`, userID, tx)
+	}
+	rate := func(value float64) models.FXRate {
+		return models.FXRate{RateDate: "2026-10-01", FromCurrency: "JPY", ToCurrency: "THB", Rate: value, Source: models.FXSourceManual}
+	}
+	if _, err := r.UpsertFXRate(0, rate(0.25)); err != nil {
+		t.Fatal(err)
+	}
+	own, err := r.UpsertFXRate(1, rate(0.2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The user's own rate wins over the shared one; others use the shared one
+	tests := []struct {
+		userID int64
+		want   int64
+		rates  int
+	}{
+		{1, 2000, 2},
+		{other.ID, 2500, 1},
+	}
+	for _, tt := range tests {
+		var amount int64
+		if err := r.db.QueryRow(`SELECT `+amountInBase+` FROM transactions WHERE user_id = ?`, tt.userID).Scan(&amount); err != nil {
+			t.Fatal(err)
+		}
+		if amount != tt.want {
+			t.Errorf("user %d: amount in base = %d, want %d", tt.userID, amount, tt.want)
+		}
+		rates, err := r.ListFXRates(tt.userID, "", 100)
+		if err != nil || len(rates) != tt.rates {
+			t.Errorf("user %d: %d rates, %v, want %d", tt.userID, len(rates), err, tt.rates)
+		}
+	}
+
+	if err := r.DeleteFXRate(other.ID, own.ID); err != sql.ErrNoRows {
+		t.Errorf("deleting another user's rate: err = %v, want sql.ErrNoRows", err)
+	}
+	rates, _ := r.ListFXRates(1, "", 100)
+	for _, rate := range rates {
+		if err := r.DeleteFXRate(1, rate.ID); (err == nil) == rate.Shared {
+			t.Errorf("deleting rate shared=%t: err = %v", rate.Shared, err)
+		}
+	}
+}
+
+func TestMigrateFXRateOwners(t *testing.T) {
+	r := newTestRepository(t)
+	// The table as it was before rates had owners
+	for _, query := range []string{
+		`DROP TABLE fx_rates`,
+		`CREATE TABLE fx_rates (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			rate_date TEXT NOT NULL,
+			from_currency TEXT NOT NULL,
+			to_currency TEXT NOT NULL,
+			rate REAL NOT NULL,
+			source TEXT NOT NULL DEFAULT 'manual',
+			created_at TEXT NOT NULL DEFAULT (datetime('now')),
+			UNIQUE (from_currency, to_currency, rate_date)
+		)`,
+		`INSERT INTO fx_rates (rate_date, from_currency, to_currency, rate) VALUES ('2026-10-01', 'JPY', 'THB', 0.25)`,
+	} {
+		if _, err := r.db.Exec(query); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := migrate(r.db); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	rates, err := r.ListFXRates(1, "JPY", 100)
+	if err != nil || len(rates) != 1 || !rates[0].Shared || rates[0].Rate != 0.25 {
+		t.Fatalf("rates = %+v, %v", rates, err)
+	}
+	// The same pair and day can now be added per user
+	if _, err := r.UpsertFXRate(1, models.FXRate{RateDate: "2026-10-01", FromCurrency: "JPY", ToCurrency: "THB", Rate: 0.2}); err != nil {
+		t.Errorf("own rate: %v", err)
+	}
+}
@@ -0,0 +1,248 @@
+package database
+
+import (
+	"database/sql"
+
+	"cash-track/internal/models"
+)
+
+const holdingColumns = `h.id, h.user_id, h.name, h.kind, h.class, h.currency, COALESCE(h.account_label, ''), h.created_at`
+
+func scanHolding(row interface{ Scan(...interface{}) error }) (models.Holding, error) {
+	var h models.Holding
+	err := row.Scan(&h.ID, &h.UserID, &h.Name, &h.Kind, &h.Class, &h.Currency, &h.AccountLabel, &h.CreatedAt)
+	return h, err
+}
+
+// CreateHolding stores a new holding
+func (r *Repository) CreateHolding(h models.Holding) (*models.Holding, error) {
+	result, err := r.db.Exec(`
+		INSERT INTO holdings (user_id, name, kind, class, currency, account_label)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, h.UserID, h.Name, h.Kind, h.Class, h.Currency, nullString(h.AccountLabel))
+	if err != nil {
+		return nil, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	return r.GetHolding(h.UserID, id)
+}
+
+// GetHolding returns one of the user's holdings with its latest valuation.
+// It returns sql.ErrNoRows when the user has no such holding.
+func (r *Repository) GetHolding(userID, id int64) (*models.Holding, error) {
+	h, err := scanHolding(r.db.QueryRow(`SELECT `+holdingColumns+` FROM holdings h WHERE h.user_id = ? AND h.id = ?`, userID, id))
+	if err != nil {
+		return nil, err
+	}
+	holdings := []models.Holding{h}
+	if err := r.attachLatestValuations(holdings); err != nil {
+		return nil, err
+	}
+	return &holdings[0], nil
+}
+
+// ListHoldings returns the user's holdings, assets first, with their latest
+// valuations
+func (r *Repository) ListHoldings(userID int64) ([]models.Holding, error) {
+	rows, err := r.db.Query(`
+		SELECT `+holdingColumns+` FROM holdings h
+		WHERE h.user_id = ?
+		ORDER BY h.class ASC, h.name ASC, h.id ASC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var holdings []models.Holding
+	for rows.Next() {
+		h, err := scanHolding(rows)
+		if err != nil {
+			return nil, err
+		}
+		holdings = append(holdings, h)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if err := r.attachLatestValuations(holdings); err != nil {
+		return nil, err
+	}
+	return holdings, nil
+}
+
+func (r *Repository) attachLatestValuations(holdings []models.Holding) error {
+	for i := range holdings {
+		v, err := scanValuation(r.db.QueryRow(`
+			SELECT id, holding_id, valued_on, value_minor, COALESCE(note, ''), created_at
+			FROM holding_valuations WHERE holding_id = ?
+			ORDER BY valued_on DESC LIMIT 1
+		`, holdings[i].ID))
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		holdings[i].Latest = &v
+	}
+	return nil
+}
+
+// DeleteHolding removes one of the user's holdings and its valuations. It
+// returns sql.ErrNoRows when the user has no such holding.
+func (r *Repository) DeleteHolding(userID, id int64) error {
+	dbTx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer dbTx.Rollback()
+
+	result, err := dbTx.Exec(`DELETE FROM holdings WHERE user_id = ? AND id = ?`, userID, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	if _, err := dbTx.Exec(`DELETE FROM holding_valuations WHERE holding_id = ?`, id); err != nil {
+		return err
+	}
+	return dbTx.Commit()
+}
+
+func scanValuation(row interface{ Scan(...interface{}) error }) (models.Valuation, error) {
+	var v models.Valuation
+	err := row.Scan(&v.ID, &v.HoldingID, &v.ValuedOn, &v.Value, &v.Note, &v.CreatedAt)
+	return v, err
+}
+
+// UpsertValuation stores the value of one of the user's holdings on a day,
+// replacing any valuation of the same day. It returns sql.ErrNoRows when the
+// user has no such holding.
+func (r *Repository) UpsertValuation(userID int64, v models.Valuation) (*models.Valuation, error) {
+	if _, err := r.GetHolding(userID, v.HoldingID); err != nil {
+		return nil, err
+	}
+	saved, err := scanValuation(r.db.QueryRow(`
+		INSERT INTO holding_valuations (holding_id, valued_on, value_minor, note)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT (holding_id, valued_on)
+		DO UPDATE SET value_minor = excluded.value_minor, note = excluded.note, created_at = datetime('now')
+		RETURNING id, holding_id, valued_on, value_minor, COALESCE(note, ''), created_at
+	`, v.HoldingID, v.ValuedOn, v.Value, nullString(v.Note)))
+	if err != nil {
+		return nil, err
+	}
+	return &saved, nil
+}
+
+// ListValuations returns the valuations of one of the user's holdings,
+// newest first
+func (r *Repository) ListValuations(userID, holdingID int64) ([]models.Valuation, error) {
+	rows, err := r.db.Query(`
+		SELECT v.id, v.holding_id, v.valued_on, v.value_minor, COALESCE(v.note, ''), v.created_at
+		FROM holding_valuations v
+		JOIN holdings h ON h.id = v.holding_id
+		WHERE h.user_id = ? AND v.holding_id = ?
+		ORDER BY v.valued_on DESC
+	`, userID, holdingID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var valuations []models.Valuation
+	for rows.Next() {
+		v, err := scanValuation(rows)
+		if err != nil {
+			return nil, err
+		}
+		valuations = append(valuations, v)
+	}
+	return valuations, rows.Err()
+}
+
+// DeleteValuation removes a valuation of one of the user's holdings
+func (r *Repository) DeleteValuation(userID, holdingID, id int64) error {
+	result, err := r.db.Exec(`
+		DELETE FROM holding_valuations
+		WHERE id = ? AND holding_id = ?
+		  AND holding_id IN (SELECT id FROM holdings WHERE user_id = ?)
+	`, id, holdingID, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// GetNetWorthData loads what the net worth up to the day to is worked out
+// from: the user's holdings, their valuations converted to the base
+// currency on the day of each valuation, oldest first, and the daily flows
+// of the accounts that holdings are tied to. Valuations without a rate to
+// the base currency are left out.
+func (r *Repository) GetNetWorthData(userID int64, to string) ([]models.Holding, map[int64][]models.Valuation, []models.AccountFlow, error) {
+	holdings, err := r.ListHoldings(userID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	rate := rateToBase(`h.user_id`, `h.currency`, `(SELECT base_currency FROM users WHERE users.id = h.user_id)`, `v.valued_on`)
+	rows, err := r.db.Query(`
+		SELECT v.id, v.holding_id, v.valued_on, CAST(ROUND(v.value_minor * `+rate+`) AS INTEGER), COALESCE(v.note, ''), v.created_at
+		FROM holding_valuations v
+		JOIN holdings h ON h.id = v.holding_id
+		WHERE h.user_id = ? AND v.valued_on <= ?
+		  AND `+rate+` IS NOT NULL
+		ORDER BY v.valued_on ASC
+	`, userID, to)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	defer rows.Close()
+
+	valuations := map[int64][]models.Valuation{}
+	for rows.Next() {
+		v, err := scanValuation(rows)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		valuations[v.HoldingID] = append(valuations[v.HoldingID], v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, nil, err
+	}
+
+	flowRows, err := r.db.Query(`
+		SELECT transactions.account_label, `+txnDay+` AS day,
+		       COALESCE(SUM(CASE WHEN direction = 'income' THEN `+amountInBase+` ELSE -`+amountInBase+` END), 0)
+		FROM transactions
+		WHERE status = 'confirmed' AND deleted_at IS NULL
+		  AND user_id = ?
+		  AND direction IN ('expense', 'income')
+		  AND account_label IN (SELECT account_label FROM holdings WHERE user_id = ? AND account_label IS NOT NULL)
+		  AND `+txnDay+` <= ?
+		GROUP BY transactions.account_label, day
+		ORDER BY day ASC
+	`, userID, userID, to)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	defer flowRows.Close()
+
+	var flows []models.AccountFlow
+	for flowRows.Next() {
+		var f models.AccountFlow
+		if err := flowRows.Scan(&f.AccountLabel, &f.Day, &f.Net); err != nil {
+			return nil, nil, nil, err
+		}
+		flows = append(flows, f)
+	}
+	return holdings, valuations, flows, flowRows.Err()
+}
@@ -0,0 +1,105 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"cash-track/internal/models"
+)
+
+const jobColumns = `id, kind, user_id, transaction_id, payload, status, attempts, max_attempts,
+		       last_error, run_after, created_at, updated_at`
+
+func scanJob(row rowScanner) (*models.Job, error) {
+	job := &models.Job{}
+	err := row.Scan(
+		&job.ID, &job.Kind, &job.UserID, &job.TransactionID, &job.Payload, &job.Status,
+		&job.Attempts, &job.MaxAttempts, &job.LastError, &job.RunAfter, &job.CreatedAt, &job.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// EnqueueJob stores a new job that is ready to run immediately
+func (r *Repository) EnqueueJob(kind string, userID, transactionID int64, payload string, maxAttempts int) (*models.Job, error) {
+	return scanJob(r.db.QueryRow(`
+		INSERT INTO jobs (kind, user_id, transaction_id, payload, max_attempts)
+		VALUES (?, ?, ?, ?, ?)
+		RETURNING `+jobColumns,
+		kind, userID, nullInt(transactionID), payload, maxAttempts,
+	))
+}
+
+// ClaimJob atomically marks the oldest runnable job as running and returns it.
+// It returns nil when no job is ready.
+func (r *Repository) ClaimJob() (*models.Job, error) {
+	job, err := scanJob(r.db.QueryRow(`
+		UPDATE jobs
+		SET status = 'running', attempts = attempts + 1, updated_at = datetime('now')
+		WHERE id = (
+			SELECT id FROM jobs
+			WHERE status = 'queued' AND run_after <= datetime('now')
+			ORDER BY run_after ASC, id ASC
+			LIMIT 1
+		)
+		RETURNING ` + jobColumns))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return job, err
+}
+
+// CompleteJob marks a job as finished successfully
+func (r *Repository) CompleteJob(id int64) error {
+	_, err := r.db.Exec(`
+		UPDATE jobs SET status = 'done', last_error = NULL, updated_at = datetime('now')
+		WHERE id = ?
+	`, id)
+	return err
+}
+
+// RetryJob puts a failed job back in the queue to run again after delay
+func (r *Repository) RetryJob(id int64, errMsg string, delay time.Duration) error {
+	_, err := r.db.Exec(`
+		UPDATE jobs
+		SET status = 'queued', last_error = ?, run_after = datetime('now', ?), updated_at = datetime('now')
+		WHERE id = ?
+	`, errMsg, fmt.Sprintf("+%d seconds", int(delay.Seconds())), id)
+	return err
+}
+
+// FailJob marks a job as permanently failed
+func (r *Repository) FailJob(id int64, errMsg string) error {
+	_, err := r.db.Exec(`
+		UPDATE jobs SET status = 'failed', last_error = ?, updated_at = datetime('now')
+		WHERE id = ?
+	`, errMsg, id)
+	return err
+}
+
+// RequeueRunningJobs returns jobs left running by a crashed or stopped
+// process to the queue. It must only be called before workers start.
+func (r *Repository) RequeueRunningJobs() (int64, error) {
+	result, err := r.db.Exec(`
+		UPDATE jobs SET status = 'queued', updated_at = datetime('now')
+		WHERE status = 'running'
+	`)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// SetProcessingState records how far a transaction's slip has progressed
+// through background processing. errMsg is stored as-is, so an empty string
+// clears a previous error.
+func (r *Repository) SetProcessingState(transactionID int64, state, errMsg string) error {
+	_, err := r.db.Exec(`
+		UPDATE transactions SET processing_state = ?, processing_error = ?, updated_at = datetime('now')
+		WHERE id = ?
+	`, state, nullString(errMsg), transactionID)
+	return err
+}
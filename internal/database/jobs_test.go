@@ -0,0 +1,87 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestJobLifecycle(t *testing.T) {
+	r := newTestRepository(t)
+	first, err := r.EnqueueJob("slip_ocr", 1, 0, `{}`, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := r.EnqueueJob("reparse", 1, 0, `{}`, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Status != "queued" || first.TransactionID.Valid {
+		t.Errorf("enqueued job = %+v", first)
+	}
+
+	// Oldest first, and a claimed job is not handed out again
+	job, err := r.ClaimJob()
+	if err != nil || job == nil || job.ID != first.ID || job.Status != "running" || job.Attempts != 1 {
+		t.Fatalf("first claim = %+v, %v", job, err)
+	}
+	if job, err = r.ClaimJob(); err != nil || job == nil || job.ID != second.ID {
+		t.Fatalf("second claim = %+v, %v", job, err)
+	}
+	if job, err = r.ClaimJob(); job != nil || err != nil {
+		t.Fatalf("claim of an empty queue = %+v, %v", job, err)
+	}
+
+	// A retry waits out its delay, one without a delay runs again at once
+	if err := r.RetryJob(first.ID, "timeout", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.RetryJob(second.ID, "timeout", 0); err != nil {
+		t.Fatal(err)
+	}
+	job, err = r.ClaimJob()
+	if err != nil || job == nil || job.ID != second.ID || job.Attempts != 2 || job.LastError.String != "timeout" {
+		t.Fatalf("claim after retry = %+v, %v", job, err)
+	}
+	if job, err = r.ClaimJob(); job != nil || err != nil {
+		t.Fatalf("claimed job %+v before its delay, %v", job, err)
+	}
+
+	if err := r.CompleteJob(second.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.FailJob(first.ID, "gave up"); err != nil {
+		t.Fatal(err)
+	}
+	for id, want := range map[int64]string{first.ID: "failed", second.ID: "done"} {
+		var status string
+		r.db.QueryRow(`SELECT status FROM jobs WHERE id = ?`, id).Scan(&status)
+		if status != want {
+			t.Errorf("job %d status = %s, want %s", id, status, want)
+		}
+	}
+}
+
+func TestRequeueRunningJobs(t *testing.T) {
+	r := newTestRepository(t)
+	for i := 0; i < 2; i++ {
+		if _, err := r.EnqueueJob("slip_ocr", 1, 0, `{}`, 3); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := r.ClaimJob(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := r.EnqueueJob("slip_ocr", 1, 0, `{}`, 3); err != nil {
+		t.Fatal(err)
+	}
+
+	requeued, err := r.RequeueRunningJobs()
+	if err != nil || requeued != 2 {
+		t.Fatalf("requeued %d, %v", requeued, err)
+	}
+	for i := 0; i < 3; i++ {
+		if job, err := r.ClaimJob(); job == nil || err != nil {
+			t.Errorf("claim %d after requeue = %+v, %v", i, job, err)
+		}
+	}
+}
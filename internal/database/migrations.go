@@ -1,6 +1,9 @@
 package database
 
-import "database/sql"
+import (
+	"database/sql"
+	"fmt"
+)
 
 func migrate(db *sql.DB) error {
 	schema := `
@@ -8,6 +11,7 @@ func migrate(db *sql.DB) error {
 		id INTEGER PRIMARY KEY AUTOINCREMENT,
 		name TEXT NOT NULL UNIQUE,
 		cutoff_day INTEGER NOT NULL DEFAULT 1,
+		base_currency TEXT NOT NULL DEFAULT 'THB',
 		created_at TEXT NOT NULL DEFAULT (datetime('now'))
 	);
 
@@ -15,7 +19,7 @@ func migrate(db *sql.DB) error {
 		id INTEGER PRIMARY KEY AUTOINCREMENT,
 		user_id INTEGER,
 		txn_date TEXT,
-		amount REAL,
+		amount_minor INTEGER,
 		currency TEXT NOT NULL DEFAULT 'THB',
 		direction TEXT NOT NULL DEFAULT 'expense',
 		channel TEXT,
@@ -25,10 +29,22 @@ func migrate(db *sql.DB) error {
 		chat_message TEXT,
 		slip_image_path TEXT,
 		raw_ocr_text TEXT,
+		ocr_blocks TEXT,
+		payee TEXT,
 		llm_confidence REAL,
+		slip_hash TEXT,
+		slip_ref TEXT,
+		duplicate_of INTEGER,
+		processing_state TEXT,
+		processing_error TEXT,
+		user_edited_fields TEXT,
 		status TEXT NOT NULL DEFAULT 'pending',
 		created_at TEXT NOT NULL DEFAULT (datetime('now')),
-		updated_at TEXT NOT NULL DEFAULT (datetime('now'))
+		updated_at TEXT NOT NULL DEFAULT (datetime('now')),
+		deleted_at TEXT,
+		reimbursable INTEGER NOT NULL DEFAULT 0,
+		claim_id INTEGER,
+		tax_item TEXT
 	);
 
 	CREATE INDEX IF NOT EXISTS idx_transactions_status ON transactions(status);
@@ -36,6 +52,176 @@ func migrate(db *sql.DB) error {
 	CREATE INDEX IF NOT EXISTS idx_transactions_txn_date ON transactions(txn_date);
 	CREATE INDEX IF NOT EXISTS idx_transactions_direction ON transactions(direction);
 	CREATE INDEX IF NOT EXISTS idx_transactions_category ON transactions(category);
+
+	CREATE TABLE IF NOT EXISTS jobs (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		kind TEXT NOT NULL,
+		user_id INTEGER NOT NULL,
+		transaction_id INTEGER,
+		payload TEXT NOT NULL DEFAULT '{}',
+		status TEXT NOT NULL DEFAULT 'queued',
+		attempts INTEGER NOT NULL DEFAULT 0,
+		max_attempts INTEGER NOT NULL DEFAULT 5,
+		last_error TEXT,
+		run_after TEXT NOT NULL DEFAULT (datetime('now')),
+		created_at TEXT NOT NULL DEFAULT (datetime('now')),
+		updated_at TEXT NOT NULL DEFAULT (datetime('now'))
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_jobs_status_run_after ON jobs(status, run_after);
+	CREATE INDEX IF NOT EXISTS idx_jobs_transaction_id ON jobs(transaction_id);
+
+	CREATE TABLE IF NOT EXISTS reparse_runs (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER,
+		mode TEXT NOT NULL,
+		filter TEXT NOT NULL DEFAULT '{}',
+		status TEXT NOT NULL DEFAULT 'queued',
+		processed INTEGER NOT NULL DEFAULT 0,
+		changed INTEGER NOT NULL DEFAULT 0,
+		error TEXT,
+		created_at TEXT NOT NULL DEFAULT (datetime('now')),
+		finished_at TEXT
+	);
+
+	CREATE TABLE IF NOT EXISTS reparse_changes (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		run_id INTEGER NOT NULL,
+		transaction_id INTEGER NOT NULL,
+		field TEXT NOT NULL,
+		old_value TEXT,
+		new_value TEXT,
+		protected INTEGER NOT NULL DEFAULT 0,
+		applied INTEGER NOT NULL DEFAULT 0
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_reparse_changes_run_id ON reparse_changes(run_id, transaction_id);
+
+	CREATE TABLE IF NOT EXISTS fx_rates (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL DEFAULT 0,
+		rate_date TEXT NOT NULL,
+		from_currency TEXT NOT NULL,
+		to_currency TEXT NOT NULL,
+		rate REAL NOT NULL,
+		source TEXT NOT NULL DEFAULT 'manual',
+		created_at TEXT NOT NULL DEFAULT (datetime('now')),
+		UNIQUE (user_id, from_currency, to_currency, rate_date)
+	);
+
+	CREATE TABLE IF NOT EXISTS tags (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		name TEXT NOT NULL,
+		created_at TEXT NOT NULL DEFAULT (datetime('now')),
+		UNIQUE (user_id, name)
+	);
+
+	CREATE TABLE IF NOT EXISTS transaction_tags (
+		transaction_id INTEGER NOT NULL,
+		tag_id INTEGER NOT NULL,
+		PRIMARY KEY (transaction_id, tag_id)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_transaction_tags_tag_id ON transaction_tags(tag_id);
+
+	CREATE TABLE IF NOT EXISTS bulk_operations (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		token TEXT NOT NULL UNIQUE,
+		action TEXT NOT NULL,
+		affected INTEGER NOT NULL DEFAULT 0,
+		snapshot TEXT,
+		created_at TEXT NOT NULL DEFAULT (datetime('now')),
+		undone_at TEXT
+	);
+
+	CREATE TABLE IF NOT EXISTS transaction_revisions (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		transaction_id INTEGER NOT NULL,
+		user_id INTEGER NOT NULL,
+		action TEXT NOT NULL,
+		actor TEXT NOT NULL,
+		before TEXT,
+		after TEXT,
+		created_at TEXT NOT NULL DEFAULT (datetime('now'))
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_transaction_revisions_transaction_id ON transaction_revisions(transaction_id, id);
+
+	CREATE TABLE IF NOT EXISTS extractions (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		transaction_id INTEGER NOT NULL,
+		user_id INTEGER NOT NULL,
+		source TEXT NOT NULL,
+		model TEXT,
+		prompt_version TEXT,
+		latency_ms INTEGER NOT NULL DEFAULT 0,
+		confidence REAL,
+		extracted TEXT NOT NULL,
+		raw_response TEXT,
+		created_at TEXT NOT NULL DEFAULT (datetime('now'))
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_extractions_transaction_id ON extractions(transaction_id, id);
+
+	CREATE TABLE IF NOT EXISTS holdings (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		name TEXT NOT NULL,
+		kind TEXT NOT NULL,
+		class TEXT NOT NULL,
+		currency TEXT NOT NULL DEFAULT 'THB',
+		account_label TEXT,
+		created_at TEXT NOT NULL DEFAULT (datetime('now'))
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_holdings_user_id ON holdings(user_id);
+
+	CREATE TABLE IF NOT EXISTS holding_valuations (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		holding_id INTEGER NOT NULL,
+		valued_on TEXT NOT NULL,
+		value_minor INTEGER NOT NULL,
+		note TEXT,
+		created_at TEXT NOT NULL DEFAULT (datetime('now')),
+		UNIQUE (holding_id, valued_on)
+	);
+
+	CREATE TABLE IF NOT EXISTS claims (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		title TEXT NOT NULL,
+		note TEXT,
+		status TEXT NOT NULL DEFAULT 'draft',
+		submitted_at TEXT,
+		paid_at TEXT,
+		reimbursement_id INTEGER,
+		created_at TEXT NOT NULL DEFAULT (datetime('now')),
+		updated_at TEXT NOT NULL DEFAULT (datetime('now'))
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_claims_user_id ON claims(user_id);
+
+	CREATE TABLE IF NOT EXISTS tax_categories (
+		user_id INTEGER NOT NULL,
+		category TEXT NOT NULL,
+		item TEXT NOT NULL,
+		PRIMARY KEY (user_id, category)
+	);
+
+	CREATE TABLE IF NOT EXISTS receipt_details (
+		transaction_id INTEGER PRIMARY KEY,
+		seller_tax_id TEXT,
+		branch TEXT,
+		invoice_number TEXT,
+		vat_minor INTEGER,
+		pre_vat_minor INTEGER,
+		updated_at TEXT NOT NULL DEFAULT (datetime('now'))
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_receipt_details_seller_tax_id ON receipt_details(seller_tax_id);
+	CREATE INDEX IF NOT EXISTS idx_receipt_details_invoice_number ON receipt_details(invoice_number);
 	`
 
 	_, err := db.Exec(schema)
@@ -46,6 +232,7 @@ func migrate(db *sql.DB) error {
 	// Migration: add new columns if they don't exist (for existing databases)
 	migrations := []string{
 		`ALTER TABLE users ADD COLUMN cutoff_day INTEGER DEFAULT 1`,
+		`ALTER TABLE users ADD COLUMN base_currency TEXT DEFAULT 'THB'`,
 		`ALTER TABLE transactions ADD COLUMN user_id INTEGER`,
 		`ALTER TABLE transactions ADD COLUMN txn_date TEXT`,
 		`ALTER TABLE transactions ADD COLUMN currency TEXT DEFAULT 'THB'`,
@@ -56,6 +243,19 @@ func migrate(db *sql.DB) error {
 		`ALTER TABLE transactions ADD COLUMN chat_message TEXT`,
 		`ALTER TABLE transactions ADD COLUMN slip_image_path TEXT`,
 		`ALTER TABLE transactions ADD COLUMN llm_confidence REAL`,
+		`ALTER TABLE transactions ADD COLUMN slip_hash TEXT`,
+		`ALTER TABLE transactions ADD COLUMN slip_ref TEXT`,
+		`ALTER TABLE transactions ADD COLUMN duplicate_of INTEGER`,
+		`ALTER TABLE transactions ADD COLUMN processing_state TEXT`,
+		`ALTER TABLE transactions ADD COLUMN processing_error TEXT`,
+		`ALTER TABLE transactions ADD COLUMN user_edited_fields TEXT`,
+		`ALTER TABLE transactions ADD COLUMN ocr_blocks TEXT`,
+		`ALTER TABLE transactions ADD COLUMN amount_minor INTEGER`,
+		`ALTER TABLE transactions ADD COLUMN payee TEXT`,
+		`ALTER TABLE transactions ADD COLUMN deleted_at TEXT`,
+		`ALTER TABLE transactions ADD COLUMN reimbursable INTEGER NOT NULL DEFAULT 0`,
+		`ALTER TABLE transactions ADD COLUMN claim_id INTEGER`,
+		`ALTER TABLE transactions ADD COLUMN tax_item TEXT`,
 	}
 
 	for _, m := range migrations {
@@ -65,10 +265,22 @@ func migrate(db *sql.DB) error {
 
 	// Index after ensuring user_id exists
 	db.Exec(`CREATE INDEX IF NOT EXISTS idx_transactions_user_id ON transactions(user_id)`)
+	db.Exec(`CREATE INDEX IF NOT EXISTS idx_transactions_slip_ref ON transactions(user_id, slip_ref)`)
+	db.Exec(`CREATE INDEX IF NOT EXISTS idx_transactions_deleted_at ON transactions(deleted_at)`)
+	db.Exec(`CREATE INDEX IF NOT EXISTS idx_transactions_claim_id ON transactions(claim_id)`)
+
+	if err := migrateSearch(db); err != nil {
+		return err
+	}
+	if err := migrateFXRateOwners(db); err != nil {
+		return err
+	}
 
 	// Ensure default user exists
 	db.Exec(`INSERT OR IGNORE INTO users (name) VALUES ('default')`)
 	db.Exec(`UPDATE users SET cutoff_day = 1 WHERE cutoff_day IS NULL`)
+	db.Exec(`UPDATE users SET base_currency = 'THB' WHERE base_currency IS NULL OR base_currency = ''`)
+	db.Exec(`UPDATE transactions SET currency = 'THB' WHERE currency IS NULL OR currency = ''`)
 
 	// Migrate old data: copy image_path to slip_image_path if exists
 	db.Exec(`UPDATE transactions SET slip_image_path = image_path WHERE slip_image_path IS NULL AND image_path IS NOT NULL`)
@@ -76,8 +288,108 @@ func migrate(db *sql.DB) error {
 	// Migrate old data: copy transaction_date to txn_date
 	db.Exec(`UPDATE transactions SET txn_date = date(transaction_date) WHERE txn_date IS NULL AND transaction_date IS NOT NULL`)
 
+	// Migrate old data: amounts were REAL major units, now integer minor
+	// units. The old amount is cleared as it is copied so a later edit that
+	// empties amount_minor does not bring it back on the next start.
+	if hasColumn(db, "transactions", "amount") {
+		if _, err := db.Exec(`
+			UPDATE transactions
+			SET amount_minor = COALESCE(amount_minor, CAST(ROUND(amount * 100) AS INTEGER)), amount = NULL
+			WHERE amount IS NOT NULL
+		`); err != nil {
+			return fmt.Errorf("failed to migrate amounts to minor units: %w", err)
+		}
+	}
+
 	// Assign existing transactions to default user
 	db.Exec(`UPDATE transactions SET user_id = (SELECT id FROM users WHERE name = 'default') WHERE user_id IS NULL`)
 
 	return nil
 }
+
+// hasColumn reports whether table has the column, for data migrations from
+// schemas older than the CREATE TABLE statements above
+func hasColumn(db *sql.DB, table, column string) bool {
+	var n int
+	db.QueryRow(`SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?`, table, column).Scan(&n)
+	return n > 0
+}
+
+// migrateFXRateOwners gives exchange rates an owner. SQLite cannot change a
+// UNIQUE constraint, so the table is rebuilt; rates stored before then
+// become shared ones (user_id 0), which apply to every user.
+func migrateFXRateOwners(db *sql.DB) error {
+	if hasColumn(db, "fx_rates", "user_id") {
+		return nil
+	}
+	dbTx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer dbTx.Rollback()
+
+	for _, query := range []string{
+		`CREATE TABLE fx_rates_owned (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL DEFAULT 0,
+			rate_date TEXT NOT NULL,
+			from_currency TEXT NOT NULL,
+			to_currency TEXT NOT NULL,
+			rate REAL NOT NULL,
+			source TEXT NOT NULL DEFAULT 'manual',
+			created_at TEXT NOT NULL DEFAULT (datetime('now')),
+			UNIQUE (user_id, from_currency, to_currency, rate_date)
+		)`,
+		`INSERT INTO fx_rates_owned (id, rate_date, from_currency, to_currency, rate, source, created_at)
+		 SELECT id, rate_date, from_currency, to_currency, rate, source, created_at FROM fx_rates`,
+		`DROP TABLE fx_rates`,
+		`ALTER TABLE fx_rates_owned RENAME TO fx_rates`,
+	} {
+		if _, err := dbTx.Exec(query); err != nil {
+			return fmt.Errorf("failed to add owners to exchange rates: %w", err)
+		}
+	}
+	return dbTx.Commit()
+}
+
+// migrateSearch creates the full-text index over the searchable transaction
+// columns and the triggers that keep it in sync. The trigram tokenizer
+// matches substrings, which suits Thai text written without spaces. The index
+// is rebuilt from the transactions table when it is first created.
+func migrateSearch(db *sql.DB) error {
+	var exists int
+	db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE name = 'transactions_fts'`).Scan(&exists)
+
+	_, err := db.Exec(`
+	CREATE VIRTUAL TABLE IF NOT EXISTS transactions_fts USING fts5(
+		description, chat_message, raw_ocr_text, payee,
+		content = 'transactions', content_rowid = 'id', tokenize = 'trigram'
+	);
+
+	CREATE TRIGGER IF NOT EXISTS transactions_fts_insert AFTER INSERT ON transactions BEGIN
+		INSERT INTO transactions_fts (rowid, description, chat_message, raw_ocr_text, payee)
+		VALUES (new.id, new.description, new.chat_message, new.raw_ocr_text, new.payee);
+	END;
+
+	CREATE TRIGGER IF NOT EXISTS transactions_fts_delete AFTER DELETE ON transactions BEGIN
+		INSERT INTO transactions_fts (transactions_fts, rowid, description, chat_message, raw_ocr_text, payee)
+		VALUES ('delete', old.id, old.description, old.chat_message, old.raw_ocr_text, old.payee);
+	END;
+
+	CREATE TRIGGER IF NOT EXISTS transactions_fts_update
+	AFTER UPDATE OF description, chat_message, raw_ocr_text, payee ON transactions BEGIN
+		INSERT INTO transactions_fts (transactions_fts, rowid, description, chat_message, raw_ocr_text, payee)
+		VALUES ('delete', old.id, old.description, old.chat_message, old.raw_ocr_text, old.payee);
+		INSERT INTO transactions_fts (rowid, description, chat_message, raw_ocr_text, payee)
+		VALUES (new.id, new.description, new.chat_message, new.raw_ocr_text, new.payee);
+	END;
+	`)
+	if err != nil {
+		return err
+	}
+
+	if exists == 0 {
+		_, err = db.Exec(`INSERT INTO transactions_fts (transactions_fts) VALUES ('rebuild')`)
+	}
+	return err
+}
@@ -0,0 +1,47 @@
+package database
+
+import (
+	"database/sql"
+	"testing"
+)
+
+func TestMigrateLegacyAmounts(t *testing.T) {
+	r := newTestRepository(t)
+	if _, err := r.db.Exec(`ALTER TABLE transactions ADD COLUMN amount REAL`); err != nil {
+		t.Fatal(err)
+	}
+	legacy := addTestTransaction(t, r, testTransaction{day: "2026-10-01"})
+	migrated := addTestTransaction(t, r, testTransaction{day: "2026-10-01", amount: 4200})
+	r.db.Exec(`UPDATE transactions SET amount_minor = NULL, amount = 12.345 WHERE id = ?`, legacy)
+	r.db.Exec(`UPDATE transactions SET amount = 99 WHERE id = ?`, migrated)
+
+	if err := migrate(r.db); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	amounts := func() map[int64]sql.NullInt64 {
+		t.Helper()
+		got := map[int64]sql.NullInt64{}
+		for _, id := range []int64{legacy, migrated} {
+			var minor sql.NullInt64
+			var amount sql.NullFloat64
+			r.db.QueryRow(`SELECT amount_minor, amount FROM transactions WHERE id = ?`, id).Scan(&minor, &amount)
+			if amount.Valid {
+				t.Errorf("transaction %d kept its old amount %v", id, amount.Float64)
+			}
+			got[id] = minor
+		}
+		return got
+	}
+	if got := amounts(); got[legacy].Int64 != 1235 || got[migrated].Int64 != 4200 {
+		t.Errorf("amounts = %v, want 1235 and 4200", got)
+	}
+
+	// Clearing an amount is not undone by the next start
+	r.db.Exec(`UPDATE transactions SET amount_minor = NULL WHERE id = ?`, legacy)
+	if err := migrate(r.db); err != nil {
+		t.Fatalf("second migrate: %v", err)
+	}
+	if got := amounts(); got[legacy].Valid {
+		t.Errorf("cleared amount came back as %d", got[legacy].Int64)
+	}
+}
@@ -0,0 +1,220 @@
+package database
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"cash-track/internal/models"
+)
+
+// ErrInvalidCursor is returned for a cursor that is malformed or was issued
+// for a different sort order
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// sortKeys maps a TransactionQuery sort to the expression it orders by
+var sortKeys = map[string]string{
+	models.SortTxnDate:   txnDay,
+	models.SortAmount:    `COALESCE(transactions.amount_minor, 0)`,
+	models.SortCreatedAt: `transactions.created_at`,
+}
+
+// hasSlip is true for transactions with an uploaded slip image
+const hasSlip = `COALESCE(transactions.slip_image_path, '') <> ''`
+
+// taggedWith is true for transactions carrying the tag named by its argument
+const taggedWith = `transactions.id IN (
+	SELECT transaction_tags.transaction_id FROM transaction_tags
+	JOIN tags ON tags.id = transaction_tags.tag_id
+	WHERE tags.user_id = transactions.user_id AND tags.name = ?)`
+
+// tagFilter returns the condition restricting a query on transactions to
+// those tagged tag, and its argument. Both are empty when tag is.
+func tagFilter(tag string) (string, []interface{}) {
+	if tag == "" {
+		return "", nil
+	}
+	return ` AND ` + taggedWith, []interface{}{tag}
+}
+
+// QueryTransactions returns one page of transactions matching q and the
+// cursor for the next page, which is empty once there are no more.
+func (r *Repository) QueryTransactions(q models.TransactionQuery) ([]models.Transaction, string, error) {
+	sort := q.Sort
+	if sort == "" {
+		sort = models.SortTxnDate
+	}
+	key, ok := sortKeys[sort]
+	if !ok {
+		return nil, "", fmt.Errorf("unknown sort %q", q.Sort)
+	}
+	limit := q.Limit
+	if limit <= 0 || limit > 500 {
+		limit = 50
+	}
+
+	where, args := transactionFilter(q)
+	order, compare := "DESC", "<"
+	if q.Ascending {
+		order, compare = "ASC", ">"
+	}
+	if q.Cursor != "" {
+		after, id, err := decodeCursor(q.Cursor, sort, q.Ascending)
+		if err != nil {
+			return nil, "", err
+		}
+		where += ` AND (` + key + `, transactions.id) ` + compare + ` (?, ?)`
+		args = append(args, after, id)
+	}
+
+	query := `SELECT ` + transactionColumns + `, ` + key + `
+		FROM transactions
+		WHERE ` + where + `
+		ORDER BY ` + key + ` ` + order + `, transactions.id ` + order + `
+		LIMIT ?`
+	// One extra row tells us whether there is a next page
+	args = append(args, limit+1)
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	var transactions []models.Transaction
+	var keys []string
+	for rows.Next() {
+		var sortValue string
+		tx, err := scanTransaction(rows, &sortValue)
+		if err != nil {
+			return nil, "", err
+		}
+		transactions = append(transactions, *tx)
+		keys = append(keys, sortValue)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+
+	if len(transactions) <= limit {
+		return transactions, "", nil
+	}
+	transactions = transactions[:limit]
+	last := transactions[limit-1]
+	return transactions, encodeCursor(sort, q.Ascending, last.ID, keys[limit-1]), nil
+}
+
+// transactionFilter builds the WHERE clause for the filters in q
+func transactionFilter(q models.TransactionQuery) (string, []interface{}) {
+	where := `transactions.user_id = ? AND transactions.deleted_at IS NULL`
+	args := []interface{}{q.UserID}
+
+	if q.From != "" {
+		where += ` AND ` + txnDay + ` >= ?`
+		args = append(args, q.From)
+	}
+	if q.To != "" {
+		where += ` AND ` + txnDay + ` <= ?`
+		args = append(args, q.To)
+	}
+	columns := []struct {
+		column string
+		value  string
+	}{
+		{"status", q.Status},
+		{"direction", q.Direction},
+		{"category", q.Category},
+		{"channel", q.Channel},
+		{"account_label", q.AccountLabel},
+	}
+	for _, c := range columns {
+		if c.value != "" {
+			where += ` AND transactions.` + c.column + ` = ?`
+			args = append(args, c.value)
+		}
+	}
+	if q.Tag != "" {
+		where += ` AND ` + taggedWith
+		args = append(args, q.Tag)
+	}
+	if q.TaxItem != "" {
+		where += ` AND ` + taxItem + ` = ?`
+		args = append(args, q.TaxItem)
+	}
+	if q.MinAmount.Valid {
+		where += ` AND transactions.amount_minor >= ?`
+		args = append(args, q.MinAmount.Money)
+	}
+	if q.MaxAmount.Valid {
+		where += ` AND transactions.amount_minor <= ?`
+		args = append(args, q.MaxAmount.Money)
+	}
+	if q.HasSlip != nil {
+		if *q.HasSlip {
+			where += ` AND ` + hasSlip
+		} else {
+			where += ` AND NOT ` + hasSlip
+		}
+	}
+	if q.Reimbursable != nil {
+		where += ` AND transactions.reimbursable = ?`
+		args = append(args, *q.Reimbursable)
+	}
+	if q.Claimed != nil {
+		if *q.Claimed {
+			where += ` AND transactions.claim_id IS NOT NULL`
+		} else {
+			where += ` AND transactions.claim_id IS NULL`
+		}
+	}
+	if q.MinConfidence > 0 {
+		where += ` AND transactions.llm_confidence >= ?`
+		args = append(args, q.MinConfidence)
+	}
+	if q.MaxConfidence > 0 {
+		where += ` AND transactions.llm_confidence <= ?`
+		args = append(args, q.MaxConfidence)
+	}
+	return where, args
+}
+
+// encodeCursor records the sort and the position of the last row of a page
+func encodeCursor(sort string, ascending bool, id int64, key string) string {
+	order := "desc"
+	if ascending {
+		order = "asc"
+	}
+	raw := fmt.Sprintf("%s:%s:%d:%s", sort, order, id, key)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeCursor returns the sort key value and ID to continue after
+func decodeCursor(cursor, sort string, ascending bool) (interface{}, int64, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, 0, ErrInvalidCursor
+	}
+	parts := strings.SplitN(string(raw), ":", 4)
+	order := "desc"
+	if ascending {
+		order = "asc"
+	}
+	if len(parts) != 4 || parts[0] != sort || parts[1] != order {
+		return nil, 0, ErrInvalidCursor
+	}
+	id, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return nil, 0, ErrInvalidCursor
+	}
+	if sort == models.SortAmount {
+		// Compare amounts as integers, not text
+		amount, err := strconv.ParseInt(parts[3], 10, 64)
+		if err != nil {
+			return nil, 0, ErrInvalidCursor
+		}
+		return amount, id, nil
+	}
+	return parts[3], id, nil
+}
@@ -0,0 +1,92 @@
+package database
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"cash-track/internal/models"
+)
+
+func TestQueryTransactionsPaging(t *testing.T) {
+	r := newTestRepository(t)
+	// Repeated days and amounts make the ID tie-break matter
+	days := []string{"2026-10-03", "2026-10-01", "2026-10-03", "2026-10-02", "2026-10-01", "2026-10-03", "2026-10-02"}
+	amounts := []int64{500, 1200, 500, 90000, 1200, 7, 500}
+	type row struct {
+		id     int64
+		day    string
+		amount int64
+	}
+	var rows []row
+	for i, day := range days {
+		id := addTestTransaction(t, r, testTransaction{day: day, amount: amounts[i]})
+		rows = append(rows, row{id, day, amounts[i]})
+	}
+
+	tests := []struct {
+		sort      string
+		ascending bool
+		less      func(a, b row) bool
+	}{
+		{models.SortTxnDate, false, func(a, b row) bool { return a.day > b.day || (a.day == b.day && a.id > b.id) }},
+		{models.SortTxnDate, true, func(a, b row) bool { return a.day < b.day || (a.day == b.day && a.id < b.id) }},
+		{models.SortAmount, false, func(a, b row) bool { return a.amount > b.amount || (a.amount == b.amount && a.id > b.id) }},
+		{models.SortAmount, true, func(a, b row) bool { return a.amount < b.amount || (a.amount == b.amount && a.id < b.id) }},
+	}
+
+	for _, tt := range tests {
+		sort.Slice(rows, func(i, j int) bool { return tt.less(rows[i], rows[j]) })
+		var want []int64
+		for _, row := range rows {
+			want = append(want, row.id)
+		}
+
+		for _, limit := range []int{1, 2, 3, len(rows), 500} {
+			var got []int64
+			q := models.TransactionQuery{UserID: 1, Sort: tt.sort, Ascending: tt.ascending, Limit: limit}
+			for pages := 0; ; pages++ {
+				if pages > len(rows) {
+					t.Fatalf("%s ascending=%t limit %d: paging does not end", tt.sort, tt.ascending, limit)
+				}
+				page, next, err := r.QueryTransactions(q)
+				if err != nil {
+					t.Fatalf("%s ascending=%t limit %d: %v", tt.sort, tt.ascending, limit, err)
+				}
+				for _, tx := range page {
+					got = append(got, tx.ID)
+				}
+				if next == "" {
+					break
+				}
+				q.Cursor = next
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("%s ascending=%t limit %d: %v, want %v", tt.sort, tt.ascending, limit, got, want)
+			}
+		}
+	}
+}
+
+func TestQueryTransactionsInvalidCursor(t *testing.T) {
+	r := newTestRepository(t)
+	for i := 0; i < 3; i++ {
+		addTestTransaction(t, r, testTransaction{day: "2026-10-01", amount: 100})
+	}
+	_, next, err := r.QueryTransactions(models.TransactionQuery{UserID: 1, Sort: models.SortAmount, Limit: 1})
+	if err != nil || next == "" {
+		t.Fatalf("first page: cursor %q, %v", next, err)
+	}
+
+	tests := []models.TransactionQuery{
+		{UserID: 1, Cursor: "not a cursor"},
+		// Issued for a different sort order
+		{UserID: 1, Sort: models.SortTxnDate, Cursor: next},
+		{UserID: 1, Sort: models.SortAmount, Ascending: true, Cursor: next},
+	}
+	for _, q := range tests {
+		if _, _, err := r.QueryTransactions(q); err != ErrInvalidCursor {
+			t.Errorf("%+v: err = %v, want ErrInvalidCursor", q, err)
+		}
+	}
+}
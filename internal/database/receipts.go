@@ -0,0 +1,108 @@
+package database
+
+import "cash-track/internal/models"
+
+// receiptColumns lists the columns read by scanReceiptDetails, in order
+const receiptColumns = `receipt_details.transaction_id, COALESCE(receipt_details.seller_tax_id, ''),
+		COALESCE(receipt_details.branch, ''), COALESCE(receipt_details.invoice_number, ''),
+		receipt_details.vat_minor, receipt_details.pre_vat_minor, receipt_details.updated_at`
+
+func scanReceiptDetails(row rowScanner, extra ...interface{}) (models.ReceiptDetails, error) {
+	var d models.ReceiptDetails
+	dest := []interface{}{&d.TransactionID, &d.SellerTaxID, &d.Branch, &d.InvoiceNumber, &d.VAT, &d.PreVAT, &d.UpdatedAt}
+	err := row.Scan(append(dest, extra...)...)
+	return d, err
+}
+
+// SetReceiptDetails stores the receipt details of a transaction, replacing
+// any it had. Empty details remove them.
+func (r *Repository) SetReceiptDetails(d models.ReceiptDetails) error {
+	if d.IsEmpty() {
+		_, err := r.db.Exec(`DELETE FROM receipt_details WHERE transaction_id = ?`, d.TransactionID)
+		return err
+	}
+	_, err := r.db.Exec(`
+		INSERT INTO receipt_details (transaction_id, seller_tax_id, branch, invoice_number, vat_minor, pre_vat_minor)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (transaction_id) DO UPDATE SET
+			seller_tax_id = excluded.seller_tax_id,
+			branch = excluded.branch,
+			invoice_number = excluded.invoice_number,
+			vat_minor = excluded.vat_minor,
+			pre_vat_minor = excluded.pre_vat_minor,
+			updated_at = datetime('now')
+	`, d.TransactionID, nullString(d.SellerTaxID), nullString(d.Branch), nullString(d.InvoiceNumber), d.VAT, d.PreVAT)
+	return err
+}
+
+// FillReceiptDetails stores receipt details read from a slip, keeping any
+// field the transaction already has. Those may have been corrected by the
+// user, so reading the slip again only fills in what is missing.
+func (r *Repository) FillReceiptDetails(d models.ReceiptDetails) error {
+	if d.IsEmpty() {
+		return nil
+	}
+	_, err := r.db.Exec(`
+		INSERT INTO receipt_details (transaction_id, seller_tax_id, branch, invoice_number, vat_minor, pre_vat_minor)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (transaction_id) DO UPDATE SET
+			seller_tax_id = COALESCE(receipt_details.seller_tax_id, excluded.seller_tax_id),
+			branch = COALESCE(receipt_details.branch, excluded.branch),
+			invoice_number = COALESCE(receipt_details.invoice_number, excluded.invoice_number),
+			vat_minor = COALESCE(receipt_details.vat_minor, excluded.vat_minor),
+			pre_vat_minor = COALESCE(receipt_details.pre_vat_minor, excluded.pre_vat_minor),
+			updated_at = datetime('now')
+	`, d.TransactionID, nullString(d.SellerTaxID), nullString(d.Branch), nullString(d.InvoiceNumber), d.VAT, d.PreVAT)
+	return err
+}
+
+// GetReceiptDetails returns the receipt details of one of the user's
+// transactions. It returns sql.ErrNoRows when the transaction has none or
+// is not the user's.
+func (r *Repository) GetReceiptDetails(userID, txID int64) (*models.ReceiptDetails, error) {
+	d, err := scanReceiptDetails(r.db.QueryRow(`
+		SELECT `+receiptColumns+` FROM receipt_details
+		JOIN transactions ON transactions.id = receipt_details.transaction_id
+		WHERE transactions.user_id = ? AND receipt_details.transaction_id = ?
+	`, userID, txID))
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// ListReceipts returns the receipt details of the user's transactions
+// between from and to, oldest first. Either bound may be empty.
+func (r *Repository) ListReceipts(userID int64, from, to string) ([]models.Receipt, error) {
+	query := `
+		SELECT ` + receiptColumns + `, ` + txnDay + `, COALESCE(transactions.amount_minor, 0), transactions.currency,
+			COALESCE(transactions.payee, ''), COALESCE(transactions.description, '')
+		FROM receipt_details
+		JOIN transactions ON transactions.id = receipt_details.transaction_id
+		WHERE transactions.user_id = ? AND transactions.deleted_at IS NULL`
+	args := []interface{}{userID}
+	if from != "" {
+		query += ` AND ` + txnDay + ` >= ?`
+		args = append(args, from)
+	}
+	if to != "" {
+		query += ` AND ` + txnDay + ` <= ?`
+		args = append(args, to)
+	}
+	rows, err := r.db.Query(query+` ORDER BY `+txnDay+` ASC, transactions.id ASC`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var receipts []models.Receipt
+	for rows.Next() {
+		var rc models.Receipt
+		rc.ReceiptDetails, err = scanReceiptDetails(rows, &rc.Day, &rc.Amount, &rc.Currency, &rc.Payee, &rc.Description)
+		if err != nil {
+			return nil, err
+		}
+		receipts = append(receipts, rc)
+	}
+	return receipts, rows.Err()
+}
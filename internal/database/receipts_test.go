@@ -0,0 +1,44 @@
+package database
+
+import (
+	"testing"
+
+	"cash-track/internal/models"
+)
+
+func TestFillReceiptDetails(t *testing.T) {
+	r := newTestRepository(t)
+	id := addTestTransaction(t, r, testTransaction{day: "2026-10-01", amount: 10700})
+	// Corrected by the user
+	if err := r.SetReceiptDetails(models.ReceiptDetails{TransactionID: id, SellerTaxID: "0107536000315", VAT: models.SomeMoney(700)}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Read again from the slip
+	err := r.FillReceiptDetails(models.ReceiptDetails{
+		TransactionID: id,
+		SellerTaxID:   "0105536092641",
+		InvoiceNumber: "INV-42",
+		VAT:           models.SomeMoney(70),
+		PreVAT:        models.SomeMoney(10000),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := r.GetReceiptDetails(1, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := models.ReceiptDetails{
+		TransactionID: id,
+		SellerTaxID:   "0107536000315",
+		InvoiceNumber: "INV-42",
+		VAT:           models.SomeMoney(700),
+		PreVAT:        models.SomeMoney(10000),
+		UpdatedAt:     got.UpdatedAt,
+	}
+	if *got != want {
+		t.Errorf("details = %+v, want %+v", *got, want)
+	}
+}
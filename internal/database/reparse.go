@@ -0,0 +1,347 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+
+	"cash-track/internal/models"
+)
+
+const reparseRunColumns = `id, COALESCE(user_id, 0), mode, filter, status, processed, changed,
+		       COALESCE(error, ''), created_at, COALESCE(finished_at, '')`
+
+func scanReparseRun(row rowScanner) (*models.ReparseRun, error) {
+	run := &models.ReparseRun{}
+	err := row.Scan(
+		&run.ID, &run.UserID, &run.Mode, &run.Filter, &run.Status, &run.Processed,
+		&run.Changed, &run.Error, &run.CreatedAt, &run.FinishedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+// reparseColumns maps the fields a re-parse or a revert may change to the SQL
+// used to write them. Field names are never interpolated from user input.
+var reparseColumns = map[string]string{
+	"raw_ocr_text":   "raw_ocr_text = ?",
+	"txn_date":       "txn_date = ?",
+	"amount":         "amount_minor = ?",
+	"currency":       "currency = ?",
+	"direction":      "direction = ?",
+	"channel":        "channel = ?",
+	"account_label":  "account_label = ?",
+	"category":       "category = ?",
+	"description":    "description = ?",
+	"llm_confidence": "llm_confidence = CAST(? AS REAL)",
+	"tax_item":       "tax_item = ?",
+}
+
+// reparseValue converts a proposed value to what its column stores
+func reparseValue(field, value string) (interface{}, error) {
+	if field == "amount" && value != "" {
+		amount, err := models.ParseMoney(value)
+		if err != nil {
+			return nil, err
+		}
+		return int64(amount), nil
+	}
+	return nullString(value), nil
+}
+
+// CreateReparseRun stores a new queued re-parse run. userID 0 covers all users.
+func (r *Repository) CreateReparseRun(userID int64, mode, filter string) (*models.ReparseRun, error) {
+	return scanReparseRun(r.db.QueryRow(`
+		INSERT INTO reparse_runs (user_id, mode, filter)
+		VALUES (?, ?, ?)
+		RETURNING `+reparseRunColumns,
+		nullInt(userID), mode, filter,
+	))
+}
+
+// GetReparseRun returns a run. userID 0 skips the ownership check.
+func (r *Repository) GetReparseRun(userID, id int64) (*models.ReparseRun, error) {
+	query := `SELECT ` + reparseRunColumns + ` FROM reparse_runs WHERE id = ?`
+	args := []interface{}{id}
+	if userID != 0 {
+		query += ` AND user_id = ?`
+		args = append(args, userID)
+	}
+	return scanReparseRun(r.db.QueryRow(query, args...))
+}
+
+// ListReparseRuns returns the most recent runs of a user, newest first.
+func (r *Repository) ListReparseRuns(userID int64, limit int) ([]models.ReparseRun, error) {
+	rows, err := r.db.Query(`
+		SELECT `+reparseRunColumns+`
+		FROM reparse_runs
+		WHERE user_id = ?
+		ORDER BY id DESC
+		LIMIT ?
+	`, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var runs []models.ReparseRun
+	for rows.Next() {
+		run, err := scanReparseRun(rows)
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, *run)
+	}
+	return runs, rows.Err()
+}
+
+// StartReparseRun marks a run as running and discards changes left over from
+// an earlier, interrupted attempt.
+func (r *Repository) StartReparseRun(id int64) error {
+	if _, err := r.db.Exec(`DELETE FROM reparse_changes WHERE run_id = ?`, id); err != nil {
+		return err
+	}
+	_, err := r.db.Exec(`
+		UPDATE reparse_runs
+		SET status = 'running', processed = 0, changed = 0, error = NULL, finished_at = NULL
+		WHERE id = ?
+	`, id)
+	return err
+}
+
+// AddReparseChanges records the changes proposed for one transaction and
+// advances the run's progress counters.
+func (r *Repository) AddReparseChanges(runID int64, changes []models.ReparseChange) error {
+	dbTx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer dbTx.Rollback()
+
+	for _, c := range changes {
+		_, err := dbTx.Exec(`
+			INSERT INTO reparse_changes (run_id, transaction_id, field, old_value, new_value, protected)
+			VALUES (?, ?, ?, ?, ?, ?)
+		`, runID, c.TransactionID, c.Field, c.OldValue, c.NewValue, c.Protected)
+		if err != nil {
+			return err
+		}
+	}
+
+	changed := 0
+	if len(changes) > 0 {
+		changed = 1
+	}
+	_, err = dbTx.Exec(`
+		UPDATE reparse_runs SET processed = processed + 1, changed = changed + ? WHERE id = ?
+	`, changed, runID)
+	if err != nil {
+		return err
+	}
+	return dbTx.Commit()
+}
+
+// FinishReparseRun records the final status of a run.
+func (r *Repository) FinishReparseRun(id int64, status, errMsg string) error {
+	_, err := r.db.Exec(`
+		UPDATE reparse_runs SET status = ?, error = ?, finished_at = datetime('now')
+		WHERE id = ?
+	`, status, nullString(errMsg), id)
+	return err
+}
+
+// ListReparseChanges returns every change proposed by a run, grouped by transaction.
+func (r *Repository) ListReparseChanges(runID int64) ([]models.ReparseChange, error) {
+	rows, err := r.db.Query(`
+		SELECT id, run_id, transaction_id, field, COALESCE(old_value, ''), COALESCE(new_value, ''), protected, applied
+		FROM reparse_changes
+		WHERE run_id = ?
+		ORDER BY transaction_id ASC, id ASC
+	`, runID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var changes []models.ReparseChange
+	for rows.Next() {
+		var c models.ReparseChange
+		if err := rows.Scan(&c.ID, &c.RunID, &c.TransactionID, &c.Field, &c.OldValue, &c.NewValue, &c.Protected, &c.Applied); err != nil {
+			return nil, err
+		}
+		changes = append(changes, c)
+	}
+	return changes, rows.Err()
+}
+
+// ListReparseCandidates returns the transactions a run with the given mode and
+// filter should look at. Transactions still being processed are skipped.
+func (r *Repository) ListReparseCandidates(mode string, filter models.ReparseFilter) ([]models.Transaction, error) {
+	query := `
+		SELECT ` + transactionColumns + `
+		FROM transactions
+		WHERE COALESCE(processing_state, '') NOT IN ('queued', 'ocr', 'llm') AND deleted_at IS NULL`
+	var args []interface{}
+
+	switch mode {
+	case models.ReparseOCR, models.ReparseBoth:
+		query += ` AND slip_image_path IS NOT NULL AND slip_image_path <> ''`
+	default:
+		query += ` AND (COALESCE(raw_ocr_text, '') <> '' OR COALESCE(chat_message, '') <> '')`
+	}
+
+	if filter.UserID != 0 {
+		query += ` AND user_id = ?`
+		args = append(args, filter.UserID)
+	}
+	if filter.From != "" {
+		query += ` AND COALESCE(NULLIF(txn_date, ''), date(created_at)) >= ?`
+		args = append(args, filter.From)
+	}
+	if filter.To != "" {
+		query += ` AND COALESCE(NULLIF(txn_date, ''), date(created_at)) <= ?`
+		args = append(args, filter.To)
+	}
+	if filter.Status != "" {
+		query += ` AND status = ?`
+		args = append(args, filter.Status)
+	}
+	if filter.MaxConfidence > 0 {
+		query += ` AND (llm_confidence IS NULL OR llm_confidence < ?)`
+		args = append(args, filter.MaxConfidence)
+	}
+	query += ` ORDER BY id ASC`
+	if filter.Limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, filter.Limit)
+	}
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var transactions []models.Transaction
+	for rows.Next() {
+		tx, err := scanTransaction(rows)
+		if err != nil {
+			return nil, err
+		}
+		transactions = append(transactions, *tx)
+	}
+	return transactions, rows.Err()
+}
+
+// ApplyReparseChanges writes the selected changes of a run in a single
+// database transaction. Protected and already applied changes are skipped, as
+// are changes whose transaction is in the trash or no longer holds the value
+// the run saw.
+func (r *Repository) ApplyReparseChanges(runID int64, sel models.ReparseSelection) (applied, skipped int, err error) {
+	changes, err := r.ListReparseChanges(runID)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	dbTx, err := r.db.Begin()
+	if err != nil {
+		return 0, 0, err
+	}
+	defer dbTx.Rollback()
+
+	current := map[int64]*models.Transaction{}
+	before := map[int64]map[string]string{}
+	var touched []int64
+	for _, c := range changes {
+		if c.Applied || !selected(c, sel) {
+			continue
+		}
+		column, ok := reparseColumns[c.Field]
+		if c.Protected || !ok {
+			skipped++
+			continue
+		}
+
+		tx, ok := current[c.TransactionID]
+		if !ok {
+			tx, err = scanTransaction(dbTx.QueryRow(`
+				SELECT `+transactionColumns+` FROM transactions WHERE id = ? AND deleted_at IS NULL
+			`, c.TransactionID))
+			if err == sql.ErrNoRows {
+				skipped++
+				continue
+			}
+			if err != nil {
+				return 0, 0, err
+			}
+			current[c.TransactionID] = tx
+			if before[c.TransactionID], err = revisionState(dbTx, c.TransactionID); err != nil {
+				return 0, 0, err
+			}
+			touched = append(touched, c.TransactionID)
+		}
+		if tx.IsUserEdited(c.Field) || tx.FieldValue(c.Field) != c.OldValue {
+			skipped++
+			continue
+		}
+
+		value, err := reparseValue(c.Field, c.NewValue)
+		if err != nil {
+			skipped++
+			continue
+		}
+		_, err = dbTx.Exec(fmt.Sprintf(`
+			UPDATE transactions SET %s, updated_at = datetime('now') WHERE id = ?
+		`, column), value, c.TransactionID)
+		if err != nil {
+			return 0, 0, err
+		}
+		if _, err = dbTx.Exec(`UPDATE reparse_changes SET applied = 1 WHERE id = ?`, c.ID); err != nil {
+			return 0, 0, err
+		}
+		applied++
+	}
+
+	// Re-parsed values come from the parser, even though a user applies them
+	for _, id := range touched {
+		if err := recordRevision(dbTx, id, models.RevisionUpdate, models.ActorLLM, before[id]); err != nil {
+			return 0, 0, err
+		}
+	}
+
+	if err := dbTx.Commit(); err != nil {
+		return 0, 0, err
+	}
+	return applied, skipped, nil
+}
+
+func selected(c models.ReparseChange, sel models.ReparseSelection) bool {
+	return matchesInt(sel.ChangeIDs, c.ID) &&
+		matchesInt(sel.TransactionIDs, c.TransactionID) &&
+		matchesString(sel.Fields, c.Field)
+}
+
+func matchesInt(values []int64, v int64) bool {
+	if len(values) == 0 {
+		return true
+	}
+	for _, x := range values {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesString(values []string, v string) bool {
+	if len(values) == 0 {
+		return true
+	}
+	for _, x := range values {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,82 @@
+package database
+
+import (
+	"testing"
+
+	"cash-track/internal/models"
+)
+
+func TestApplyReparseChanges(t *testing.T) {
+	r := newTestRepository(t)
+	parsed := addTestTransaction(t, r, testTransaction{day: "2026-10-01", amount: 5000, category: "food"})
+	edited := addTestTransaction(t, r, testTransaction{day: "2026-10-01", amount: 5000, category: "food"})
+	trashed := addTestTransaction(t, r, testTransaction{day: "2026-10-01", amount: 5000, category: "food"})
+	// Categorized by hand after the run saw it
+	r.db.Exec(`UPDATE transactions SET category = 'health', user_edited_fields = 'category' WHERE id = ?`, edited)
+	r.db.Exec(`UPDATE transactions SET deleted_at = datetime('now') WHERE id = ?`, trashed)
+
+	run, err := r.CreateReparseRun(1, models.ReparseLLM, "{}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = r.AddReparseChanges(run.ID, []models.ReparseChange{
+		{TransactionID: parsed, Field: "category", OldValue: "food", NewValue: "travel"},
+		{TransactionID: parsed, Field: "description", OldValue: "test", NewValue: "Grab"},
+		// The run saw another amount
+		{TransactionID: parsed, Field: "amount", OldValue: "40.00", NewValue: "45.00"},
+		{TransactionID: edited, Field: "channel", OldValue: "", NewValue: "scb", Protected: true},
+		{TransactionID: edited, Field: "category", OldValue: "food", NewValue: "travel"},
+		{TransactionID: trashed, Field: "category", OldValue: "food", NewValue: "travel"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name             string
+		sel              models.ReparseSelection
+		applied, skipped int
+	}{
+		{"one field", models.ReparseSelection{Fields: []string{"description"}}, 1, 0},
+		{"rest", models.ReparseSelection{}, 1, 4},
+		{"again", models.ReparseSelection{}, 0, 4},
+	}
+	for _, tt := range tests {
+		applied, skipped, err := r.ApplyReparseChanges(run.ID, tt.sel)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if applied != tt.applied || skipped != tt.skipped {
+			t.Errorf("%s: applied %d, skipped %d, want %d and %d", tt.name, applied, skipped, tt.applied, tt.skipped)
+		}
+	}
+
+	want := map[int64]map[string]string{
+		parsed: {"category": "travel", "description": "Grab", "amount": "50.00"},
+		edited: {"category": "health", "channel": ""},
+	}
+	for id, fields := range want {
+		tx, err := r.GetTransaction(1, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for field, value := range fields {
+			if got := tx.FieldValue(field); got != value {
+				t.Errorf("transaction %d: %s = %q, want %q", id, field, got, value)
+			}
+		}
+	}
+	var category string
+	r.db.QueryRow(`SELECT category FROM transactions WHERE id = ?`, trashed).Scan(&category)
+	if category != "food" {
+		t.Errorf("trashed transaction was re-parsed to %q", category)
+	}
+
+	revisions, err := r.ListRevisions(1, parsed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revisions) != 2 || revisions[0].Actor != models.ActorLLM {
+		t.Errorf("revisions = %+v, want two by %s", revisions, models.ActorLLM)
+	}
+}
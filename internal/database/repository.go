@@ -1,7 +1,9 @@
 package database
 
 import (
+	"context"
 	"database/sql"
+	"strings"
 
 	"cash-track/internal/models"
 )
@@ -10,10 +12,52 @@ type Repository struct {
 	db *sql.DB
 }
 
+// transactionColumns lists the columns read by scanTransaction, in order.
+// The last is the transaction's tag names, comma-separated and sorted.
+const transactionColumns = `id, user_id, txn_date, amount_minor, currency, direction, channel, account_label,
+		       category, description, chat_message, slip_image_path, raw_ocr_text, ocr_blocks, payee, llm_confidence,
+		       slip_hash, slip_ref, duplicate_of, processing_state, processing_error, user_edited_fields, status, created_at, updated_at,
+		       deleted_at, reimbursable, claim_id, tax_item,
+		       COALESCE((SELECT group_concat(tags.name, ',' ORDER BY tags.name) FROM transaction_tags
+		                 JOIN tags ON tags.id = transaction_tags.tag_id
+		                 WHERE transaction_tags.transaction_id = transactions.id), '')`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanTransaction reads a row selected with transactionColumns. extra
+// receives any columns selected after them.
+func scanTransaction(row rowScanner, extra ...interface{}) (*models.Transaction, error) {
+	tx := &models.Transaction{}
+	var tags string
+	dest := []interface{}{
+		&tx.ID, &tx.UserID, &tx.TxnDate, &tx.Amount, &tx.Currency, &tx.Direction,
+		&tx.Channel, &tx.AccountLabel, &tx.Category, &tx.Description, &tx.ChatMessage,
+		&tx.SlipImagePath, &tx.RawOCRText, &tx.OCRBlocks, &tx.Payee, &tx.LLMConfidence,
+		&tx.SlipHash, &tx.SlipRef, &tx.DuplicateOf, &tx.ProcessingState, &tx.ProcessingError,
+		&tx.UserEditedFields, &tx.Status, &tx.CreatedAt, &tx.UpdatedAt,
+		&tx.DeletedAt, &tx.Reimbursable, &tx.ClaimID, &tx.TaxItem, &tags,
+	}
+	err := row.Scan(append(dest, extra...)...)
+	if err != nil {
+		return nil, err
+	}
+	if tags != "" {
+		tx.Tags = strings.Split(tags, ",")
+	}
+	return tx, nil
+}
+
 func NewRepository(db *sql.DB) *Repository {
 	return &Repository{db: db}
 }
 
+// Ping checks that the database is reachable
+func (r *Repository) Ping(ctx context.Context) error {
+	return r.db.PingContext(ctx)
+}
+
 func (r *Repository) EnsureDefaultUser() (int64, error) {
 	_, err := r.db.Exec(`INSERT OR IGNORE INTO users (name) VALUES ('default')`)
 	if err != nil {
@@ -28,7 +72,7 @@ func (r *Repository) EnsureDefaultUser() (int64, error) {
 }
 
 func (r *Repository) ListUsers() ([]models.User, error) {
-	rows, err := r.db.Query(`SELECT id, name, cutoff_day FROM users ORDER BY name ASC`)
+	rows, err := r.db.Query(`SELECT id, name, cutoff_day, base_currency FROM users ORDER BY name ASC`)
 	if err != nil {
 		return nil, err
 	}
@@ -37,7 +81,7 @@ func (r *Repository) ListUsers() ([]models.User, error) {
 	var users []models.User
 	for rows.Next() {
 		var u models.User
-		if err := rows.Scan(&u.ID, &u.Name, &u.CutoffDay); err != nil {
+		if err := rows.Scan(&u.ID, &u.Name, &u.CutoffDay, &u.BaseCurrency); err != nil {
 			return nil, err
 		}
 		users = append(users, u)
@@ -56,12 +100,12 @@ func (r *Repository) CreateUser(name string) (*models.User, error) {
 		return nil, err
 	}
 
-	return &models.User{ID: id, Name: name, CutoffDay: 1}, nil
+	return r.GetUser(id)
 }
 
 func (r *Repository) GetUser(id int64) (*models.User, error) {
 	var user models.User
-	if err := r.db.QueryRow(`SELECT id, name, cutoff_day FROM users WHERE id = ?`, id).Scan(&user.ID, &user.Name, &user.CutoffDay); err != nil {
+	if err := r.db.QueryRow(`SELECT id, name, cutoff_day, base_currency FROM users WHERE id = ?`, id).Scan(&user.ID, &user.Name, &user.CutoffDay, &user.BaseCurrency); err != nil {
 		return nil, err
 	}
 	return &user, nil
@@ -75,27 +119,56 @@ func (r *Repository) UpdateUserCutoff(id int64, cutoffDay int) (*models.User, er
 	return r.GetUser(id)
 }
 
-func (r *Repository) DeleteUser(id int64) error {
-	_, err := r.db.Exec(`DELETE FROM transactions WHERE user_id = ?`, id)
+// UpdateUserBaseCurrency sets the currency the user's totals are reported in
+func (r *Repository) UpdateUserBaseCurrency(id int64, currency string) (*models.User, error) {
+	_, err := r.db.Exec(`UPDATE users SET base_currency = ? WHERE id = ?`, currency, id)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	_, err = r.db.Exec(`DELETE FROM users WHERE id = ?`, id)
-	return err
+	return r.GetUser(id)
 }
 
-// CreateTransaction creates a new transaction from a slip image
-func (r *Repository) CreateTransaction(userID int64, slipImagePath string) (*models.Transaction, error) {
-	result, err := r.db.Exec(
-		`INSERT INTO transactions (user_id, slip_image_path, direction, currency, status)
-		 VALUES (?, ?, 'expense', 'THB', 'pending')`,
-		userID, slipImagePath,
-	)
+// DeleteUser removes the user and everything they own, all or nothing
+func (r *Repository) DeleteUser(id int64) error {
+	dbTx, err := r.db.Begin()
 	if err != nil {
-		return nil, err
+		return err
 	}
+	defer dbTx.Rollback()
+
+	for _, query := range []string{
+		`DELETE FROM transaction_tags WHERE transaction_id IN (SELECT id FROM transactions WHERE user_id = ?)`,
+		`DELETE FROM tags WHERE user_id = ?`,
+		`DELETE FROM receipt_details WHERE transaction_id IN (SELECT id FROM transactions WHERE user_id = ?)`,
+		`DELETE FROM reparse_changes WHERE transaction_id IN (SELECT id FROM transactions WHERE user_id = ?)`,
+		`DELETE FROM reparse_runs WHERE user_id = ?`,
+		`DELETE FROM bulk_operations WHERE user_id = ?`,
+		`DELETE FROM transactions WHERE user_id = ?`,
+		`DELETE FROM jobs WHERE user_id = ?`,
+		`DELETE FROM transaction_revisions WHERE user_id = ?`,
+		`DELETE FROM extractions WHERE user_id = ?`,
+		`DELETE FROM claims WHERE user_id = ?`,
+		`DELETE FROM holding_valuations WHERE holding_id IN (SELECT id FROM holdings WHERE user_id = ?)`,
+		`DELETE FROM holdings WHERE user_id = ?`,
+		`DELETE FROM tax_categories WHERE user_id = ?`,
+		`DELETE FROM fx_rates WHERE user_id = ?`,
+		`DELETE FROM users WHERE id = ?`,
+	} {
+		if _, err := dbTx.Exec(query, id); err != nil {
+			return err
+		}
+	}
+	return dbTx.Commit()
+}
 
-	id, err := result.LastInsertId()
+// CreateTransaction creates a new transaction from a slip image.
+// duplicateOf is the ID of an earlier transaction with a near-identical slip, or 0.
+func (r *Repository) CreateTransaction(userID int64, slipImagePath, slipHash string, duplicateOf int64) (*models.Transaction, error) {
+	id, err := r.insertTransaction(userID, nil, models.ActorUser,
+		`INSERT INTO transactions (user_id, slip_image_path, slip_hash, duplicate_of, direction, currency, status, processing_state)
+		 VALUES (?, ?, ?, ?, 'expense', 'THB', 'pending', 'queued')`,
+		userID, slipImagePath, nullString(slipHash), nullInt(duplicateOf),
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -103,29 +176,32 @@ func (r *Repository) CreateTransaction(userID int64, slipImagePath string) (*mod
 	return r.GetTransaction(userID, id)
 }
 
-// CreateTransactionFromChat creates a transaction from chat input (no image required)
+// CreateTransactionFromChat creates a transaction from chat input (no image
+// required), tagged with tags. actor is the parser that produced the values.
 func (r *Repository) CreateTransactionFromChat(
 	userID int64,
 	txnDate string,
-	amount float64,
+	amount models.NullMoney,
 	currency string,
 	direction string,
 	channel string,
 	accountLabel string,
 	category string,
 	description string,
+	tags []string,
 	chatMessage string,
 	slipImagePath string,
 	rawOCRText string,
 	llmConfidence float64,
 	status string,
+	actor string,
 ) (*models.Transaction, error) {
-	result, err := r.db.Exec(`
+	id, err := r.insertTransaction(userID, tags, actor, `
 		INSERT INTO transactions (
-			user_id, txn_date, amount, currency, direction, channel, account_label,
+			user_id, txn_date, amount_minor, currency, direction, channel, account_label,
 			category, description, chat_message, slip_image_path, raw_ocr_text, llm_confidence, status
 		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
-		userID, nullString(txnDate), nullFloat(amount), currency, direction,
+		userID, nullString(txnDate), amount, currency, direction,
 		nullString(channel), nullString(accountLabel), nullString(category),
 		nullString(description), nullString(chatMessage), nullString(slipImagePath), nullString(rawOCRText),
 		nullFloat(llmConfidence), status,
@@ -134,35 +210,41 @@ func (r *Repository) CreateTransactionFromChat(
 		return nil, err
 	}
 
-	id, err := result.LastInsertId()
-	if err != nil {
-		return nil, err
-	}
-
 	return r.GetTransaction(userID, id)
 }
 
+// GetTransaction returns one of the user's transactions. Trashed
+// transactions are not found.
 func (r *Repository) GetTransaction(userID, id int64) (*models.Transaction, error) {
-	tx := &models.Transaction{}
-	err := r.db.QueryRow(`
-		SELECT id, user_id, txn_date, amount, currency, direction, channel, account_label,
-		       category, description, chat_message, slip_image_path, raw_ocr_text, llm_confidence,
-		       status, created_at, updated_at
-		FROM transactions WHERE id = ? AND user_id = ?
-	`, id, userID).Scan(
-		&tx.ID, &tx.UserID, &tx.TxnDate, &tx.Amount, &tx.Currency, &tx.Direction,
-		&tx.Channel, &tx.AccountLabel, &tx.Category, &tx.Description, &tx.ChatMessage,
-		&tx.SlipImagePath, &tx.RawOCRText, &tx.LLMConfidence,
-		&tx.Status, &tx.CreatedAt, &tx.UpdatedAt,
-	)
-	if err != nil {
-		return nil, err
-	}
-	return tx, nil
+	return scanTransaction(r.db.QueryRow(`
+		SELECT `+transactionColumns+`
+		FROM transactions WHERE id = ? AND user_id = ? AND deleted_at IS NULL
+	`, id, userID))
 }
 
+// DeleteTransaction moves a transaction to the trash. It stays there, slip
+// included, until RestoreTransaction or PurgeTrash.
 func (r *Repository) DeleteTransaction(userID, id int64) error {
-	result, err := r.db.Exec(`DELETE FROM transactions WHERE id = ? AND user_id = ?`, id, userID)
+	return r.revise(id, models.RevisionDelete, models.ActorUser, func(dbTx *sql.Tx) error {
+		return trashTransaction(dbTx, userID, id)
+	})
+}
+
+// trashTransaction moves a transaction to the trash. Its queued jobs would
+// only touch the deleted row, so they are paused until it is restored. It
+// returns ErrClaimNotDraft for transactions a submitted or paid claim counts.
+func trashTransaction(dbTx *sql.Tx, userID, id int64) error {
+	var locked bool
+	if err := dbTx.QueryRow(`SELECT `+claimLocked+` FROM transactions WHERE id = ?`, id).Scan(&locked); err != nil {
+		return err
+	}
+	if locked {
+		return ErrClaimNotDraft
+	}
+	result, err := dbTx.Exec(`
+		UPDATE transactions SET deleted_at = datetime('now')
+		WHERE id = ? AND user_id = ? AND deleted_at IS NULL
+	`, id, userID)
 	if err != nil {
 		return err
 	}
@@ -173,212 +255,210 @@ func (r *Repository) DeleteTransaction(userID, id int64) error {
 	if affected == 0 {
 		return sql.ErrNoRows
 	}
-	return nil
+	_, err = dbTx.Exec(`
+		UPDATE jobs SET status = ?, updated_at = datetime('now')
+		WHERE transaction_id = ? AND status = ?
+	`, models.JobPaused, id, models.JobQueued)
+	return err
 }
 
-// UpdateOCRResult updates a transaction with OCR/LLM parsed data
+// UpdateOCRResult updates a transaction with OCR/LLM parsed data. actor is
+// the parser that produced the values. Fields the user has changed by hand
+// keep their values.
 func (r *Repository) UpdateOCRResult(
 	id int64,
 	rawText string,
-	amount float64,
+	amount models.NullMoney,
+	currency string,
 	txnDate string,
 	channel string,
 	category string,
 	description string,
 	llmConfidence float64,
+	actor string,
 ) error {
-	_, err := r.db.Exec(`
-		UPDATE transactions
-		SET raw_ocr_text = ?, amount = ?, txn_date = ?, channel = ?,
-		    category = ?, description = ?, llm_confidence = ?,
-		    updated_at = datetime('now')
-		WHERE id = ?
-	`, rawText, nullFloat(amount), nullString(txnDate), nullString(channel),
-		nullString(category), nullString(description), nullFloat(llmConfidence), id)
+	return r.revise(id, models.RevisionUpdate, actor, func(dbTx *sql.Tx) error {
+		tx, err := scanTransaction(dbTx.QueryRow(`SELECT `+transactionColumns+` FROM transactions WHERE id = ?`, id))
+		if err != nil {
+			return err
+		}
+
+		sets := []string{"raw_ocr_text = ?", "llm_confidence = ?"}
+		args := []interface{}{rawText, nullFloat(llmConfidence)}
+		for _, f := range []struct {
+			field, column string
+			value         interface{}
+		}{
+			{"amount", "amount_minor = ?", amount},
+			{"currency", "currency = COALESCE(?, currency)", nullString(currency)},
+			{"txn_date", "txn_date = ?", nullString(txnDate)},
+			{"channel", "channel = ?", nullString(channel)},
+			{"category", "category = ?", nullString(category)},
+			{"description", "description = ?", nullString(description)},
+		} {
+			if tx.IsUserEdited(f.field) {
+				continue
+			}
+			sets = append(sets, f.column)
+			args = append(args, f.value)
+		}
+
+		_, err = dbTx.Exec(`UPDATE transactions SET `+strings.Join(sets, ", ")+`, updated_at = datetime('now') WHERE id = ?`,
+			append(args, id)...)
+		return err
+	})
+}
+
+// SetPayee stores who a slip was paid to
+func (r *Repository) SetPayee(id int64, payee string) error {
+	_, err := r.db.Exec(`UPDATE transactions SET payee = ? WHERE id = ?`, nullString(payee), id)
+	return err
+}
+
+// SetRawOCRText stores the text OCR read from a transaction's slip
+func (r *Repository) SetRawOCRText(id int64, text string) error {
+	_, err := r.db.Exec(`UPDATE transactions SET raw_ocr_text = ? WHERE id = ?`, nullString(text), id)
 	return err
 }
 
+// SetOCRBlocks stores the OCR blocks (JSON) found on a transaction's slip
+func (r *Repository) SetOCRBlocks(id int64, blocks string) error {
+	_, err := r.db.Exec(`UPDATE transactions SET ocr_blocks = ? WHERE id = ?`, nullString(blocks), id)
+	return err
+}
+
+// ConfirmTransaction saves the user's final values and remembers which fields
+// differ from what was extracted, so re-parsing leaves them alone.
 func (r *Repository) ConfirmTransaction(userID, id int64, req models.ConfirmRequest) error {
-	_, err := r.db.Exec(`
+	current, err := r.GetTransaction(userID, id)
+	if err != nil {
+		return err
+	}
+
+	return r.revise(id, models.RevisionConfirm, models.ActorUser, func(dbTx *sql.Tx) error {
+		return confirmTransaction(dbTx, userID, current, req)
+	})
+}
+
+// confirmTransaction saves req as current's final values
+func confirmTransaction(dbTx *sql.Tx, userID int64, current *models.Transaction, req models.ConfirmRequest) error {
+	_, err := dbTx.Exec(`
 		UPDATE transactions
-		SET amount = ?, txn_date = ?, direction = ?, channel = ?,
+		SET amount_minor = ?, currency = COALESCE(?, currency), txn_date = ?, direction = ?, channel = ?,
 		    account_label = ?, category = ?, description = ?,
-		    status = 'confirmed', updated_at = datetime('now')
+		    user_edited_fields = ?, status = 'confirmed', updated_at = datetime('now')
 		WHERE id = ? AND user_id = ?
-	`, req.Amount, nullString(req.TxnDate), nullString(req.Direction),
+	`, req.Amount, nullString(req.Currency), nullString(req.TxnDate), nullString(req.Direction),
 		nullString(req.Channel), nullString(req.AccountLabel),
-		nullString(req.Category), nullString(req.Description), id, userID)
+		nullString(req.Category), nullString(req.Description),
+		nullString(editedFields(current, req)), current.ID, userID)
 	return err
 }
 
-// UpdateTransactionFromChat updates an existing transaction with parsed chat data.
+// editedFields merges the fields changed by req into those already marked as
+// user-edited on tx, returning them comma-separated.
+func editedFields(tx *models.Transaction, req models.ConfirmRequest) string {
+	submitted := map[string]string{
+		"amount":        req.Amount.String(),
+		"currency":      req.Currency,
+		"txn_date":      req.TxnDate,
+		"direction":     req.Direction,
+		"channel":       req.Channel,
+		"account_label": req.AccountLabel,
+		"category":      req.Category,
+		"description":   req.Description,
+	}
+
+	fields := tx.EditedFields()
+	for _, field := range models.ReparseFields {
+		value, ok := submitted[field]
+		if !ok || tx.IsUserEdited(field) || value == tx.FieldValue(field) {
+			continue
+		}
+		if field == "currency" && value == "" {
+			// Not submitted: ConfirmTransaction keeps the stored currency
+			continue
+		}
+		fields = append(fields, field)
+	}
+	return strings.Join(fields, ",")
+}
+
+// UpdateTransactionFromChat updates an existing transaction with parsed chat
+// data and adds tags to the ones it carries. actor is the parser that
+// produced the values.
 func (r *Repository) UpdateTransactionFromChat(
 	userID, id int64,
 	txnDate string,
-	amount float64,
+	amount models.NullMoney,
 	currency string,
 	direction string,
 	channel string,
 	accountLabel string,
 	category string,
 	description string,
+	tags []string,
 	chatMessage string,
 	rawOCRText string,
 	llmConfidence float64,
 	status string,
+	actor string,
 ) error {
-	_, err := r.db.Exec(`
-		UPDATE transactions
-		SET txn_date = ?, amount = ?, currency = ?, direction = ?, channel = ?,
-		    account_label = ?, category = ?, description = ?, chat_message = ?,
-		    raw_ocr_text = ?, llm_confidence = ?, status = ?, updated_at = datetime('now')
-		WHERE id = ? AND user_id = ?
-	`, nullString(txnDate), nullFloat(amount), nullString(currency), nullString(direction),
-		nullString(channel), nullString(accountLabel), nullString(category), nullString(description),
-		nullString(chatMessage), nullString(rawOCRText), nullFloat(llmConfidence), nullString(status), id, userID)
-	return err
-}
-
-func (r *Repository) ListTransactions(userID int64, limit, offset int) ([]models.Transaction, error) {
-	rows, err := r.db.Query(`
-		SELECT id, user_id, txn_date, amount, currency, direction, channel, account_label,
-		       category, description, chat_message, slip_image_path, raw_ocr_text, llm_confidence,
-		       status, created_at, updated_at
-		FROM transactions
-		WHERE user_id = ?
-		ORDER BY created_at DESC
-		LIMIT ? OFFSET ?
-	`, userID, limit, offset)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var transactions []models.Transaction
-	for rows.Next() {
-		var tx models.Transaction
-		err := rows.Scan(
-			&tx.ID, &tx.UserID, &tx.TxnDate, &tx.Amount, &tx.Currency, &tx.Direction,
-			&tx.Channel, &tx.AccountLabel, &tx.Category, &tx.Description, &tx.ChatMessage,
-			&tx.SlipImagePath, &tx.RawOCRText, &tx.LLMConfidence,
-			&tx.Status, &tx.CreatedAt, &tx.UpdatedAt,
-		)
+	return r.revise(id, models.RevisionUpdate, actor, func(dbTx *sql.Tx) error {
+		result, err := dbTx.Exec(`
+			UPDATE transactions
+			SET txn_date = ?, amount_minor = ?, currency = ?, direction = ?, channel = ?,
+			    account_label = ?, category = ?, description = ?, chat_message = ?,
+			    raw_ocr_text = ?, llm_confidence = ?, status = ?, updated_at = datetime('now')
+			WHERE id = ? AND user_id = ?
+		`, nullString(txnDate), amount, nullString(currency), nullString(direction),
+			nullString(channel), nullString(accountLabel), nullString(category), nullString(description),
+			nullString(chatMessage), nullString(rawOCRText), nullFloat(llmConfidence), nullString(status), id, userID)
 		if err != nil {
-			return nil, err
+			return err
 		}
-		transactions = append(transactions, tx)
-	}
-	return transactions, rows.Err()
-}
-
-// ListTransactionsByRange returns transactions within a date range (txn_date or created_at).
-func (r *Repository) ListTransactionsByRange(userID int64, from, to string, limit int) ([]models.Transaction, error) {
-	if limit <= 0 || limit > 500 {
-		limit = 200
-	}
-	rows, err := r.db.Query(`
-		SELECT id, user_id, txn_date, amount, currency, direction, channel, account_label,
-		       category, description, chat_message, slip_image_path, raw_ocr_text, llm_confidence,
-		       status, created_at, updated_at
-		FROM transactions
-		WHERE user_id = ?
-		  AND date(COALESCE(txn_date, created_at)) BETWEEN date(?) AND date(?)
-		ORDER BY COALESCE(txn_date, created_at) DESC, created_at DESC
-		LIMIT ?
-	`, userID, from, to, limit)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var transactions []models.Transaction
-	for rows.Next() {
-		var tx models.Transaction
-		err := rows.Scan(
-			&tx.ID, &tx.UserID, &tx.TxnDate, &tx.Amount, &tx.Currency, &tx.Direction,
-			&tx.Channel, &tx.AccountLabel, &tx.Category, &tx.Description, &tx.ChatMessage,
-			&tx.SlipImagePath, &tx.RawOCRText, &tx.LLMConfidence,
-			&tx.Status, &tx.CreatedAt, &tx.UpdatedAt,
-		)
+		affected, err := result.RowsAffected()
 		if err != nil {
-			return nil, err
+			return err
 		}
-		transactions = append(transactions, tx)
-	}
-	return transactions, nil
-}
-
-// ListTransactionsByRangeFiltered returns transactions within a date range filtered by category/channel.
-func (r *Repository) ListTransactionsByRangeFiltered(userID int64, from, to, category, channel string, limit int) ([]models.Transaction, error) {
-	if limit <= 0 || limit > 500 {
-		limit = 200
-	}
-	query := `
-		SELECT id, user_id, txn_date, amount, currency, direction, channel, account_label,
-		       category, description, chat_message, slip_image_path, raw_ocr_text, llm_confidence,
-		       status, created_at, updated_at
-		FROM transactions
-		WHERE user_id = ?
-		  AND date(COALESCE(txn_date, created_at)) BETWEEN date(?) AND date(?)
-	`
-	args := []interface{}{userID, from, to}
-	if category != "" {
-		query += " AND category = ?"
-		args = append(args, category)
-	}
-	if channel != "" {
-		query += " AND channel = ?"
-		args = append(args, channel)
-	}
-	query += " ORDER BY COALESCE(txn_date, created_at) DESC, created_at DESC LIMIT ?"
-	args = append(args, limit)
-
-	rows, err := r.db.Query(query, args...)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var transactions []models.Transaction
-	for rows.Next() {
-		var tx models.Transaction
-		err := rows.Scan(
-			&tx.ID, &tx.UserID, &tx.TxnDate, &tx.Amount, &tx.Currency, &tx.Direction,
-			&tx.Channel, &tx.AccountLabel, &tx.Category, &tx.Description, &tx.ChatMessage,
-			&tx.SlipImagePath, &tx.RawOCRText, &tx.LLMConfidence,
-			&tx.Status, &tx.CreatedAt, &tx.UpdatedAt,
-		)
-		if err != nil {
-			return nil, err
+		if affected == 0 {
+			// Not the user's transaction, so there is nothing to record
+			return sql.ErrNoRows
 		}
-		transactions = append(transactions, tx)
-	}
-	return transactions, nil
+		return addTags(dbTx, userID, id, tags)
+	})
 }
 
-// GetDashboardSummary returns aggregated data for the dashboard
-func (r *Repository) GetDashboardSummary(userID int64, from, to string) (*models.DashboardSummary, error) {
+// GetDashboardSummary returns aggregated data for the dashboard. Amounts are
+// converted to the user's base currency at the rate for each transaction's
+// date; transactions without any rate are left out and counted in
+// Unconverted. A non-empty tag limits the summary to transactions carrying
+// it.
+func (r *Repository) GetDashboardSummary(userID int64, from, to, tag string) (*models.DashboardSummary, error) {
 	summary := &models.DashboardSummary{
 		Period: models.Period{From: from, To: to},
 	}
 
 	// Get totals - use COALESCE to fallback to created_at date if txn_date is empty
+	tagCond, tagArgs := tagFilter(tag)
 	err := r.db.QueryRow(`
 		SELECT
-			COALESCE(SUM(CASE WHEN direction = 'expense' THEN amount ELSE 0 END), 0) as total_expense,
-			COALESCE(SUM(CASE WHEN direction = 'income' THEN amount ELSE 0 END), 0) as total_income
+			COALESCE(SUM(CASE WHEN direction = 'expense' THEN `+amountInBase+` ELSE 0 END), 0) as total_expense,
+			COALESCE(SUM(CASE WHEN direction = 'income' THEN `+amountInBase+` ELSE 0 END), 0) as total_income,
+			COUNT(CASE WHEN `+fxRate+` IS NULL THEN 1 END) as unconverted,
+			COALESCE((SELECT base_currency FROM users WHERE id = ?), 'THB') as currency
 		FROM transactions
-		WHERE status = 'confirmed'
+		WHERE status = 'confirmed' AND deleted_at IS NULL AND `+personal+`
 		  AND user_id = ?
-		  AND COALESCE(NULLIF(txn_date, ''), date(created_at)) >= ?
-		  AND COALESCE(NULLIF(txn_date, ''), date(created_at)) <= ?
-	`, userID, from, to).Scan(&summary.TotalExpense, &summary.TotalIncome)
+		  AND `+txnDay+` >= ?
+		  AND `+txnDay+` <= ?`+tagCond,
+		append([]interface{}{userID, userID, from, to}, tagArgs...)...).Scan(&summary.TotalExpense, &summary.TotalIncome, &summary.Unconverted, &summary.Currency)
 	if err != nil {
 		return nil, err
 	}
 
 	// Get by category
-	summary.ByCategory, err = r.GetExpenseByCategory(userID, from, to)
+	summary.ByCategory, err = r.GetExpenseByCategory(userID, from, to, tag)
 	if err != nil {
 		return nil, err
 	}
@@ -386,12 +466,12 @@ func (r *Repository) GetDashboardSummary(userID int64, from, to string) (*models
 	// Calculate percentages
 	for i := range summary.ByCategory {
 		if summary.TotalExpense > 0 {
-			summary.ByCategory[i].PercentOfExpense = (summary.ByCategory[i].Amount / summary.TotalExpense) * 100
+			summary.ByCategory[i].PercentOfExpense = float64(summary.ByCategory[i].Amount) / float64(summary.TotalExpense) * 100
 		}
 	}
 
 	// Get by channel
-	summary.ByChannel, err = r.GetExpenseByChannel(userID, from, to)
+	summary.ByChannel, err = r.GetExpenseByChannel(userID, from, to, tag)
 	if err != nil {
 		return nil, err
 	}
@@ -399,19 +479,21 @@ func (r *Repository) GetDashboardSummary(userID int64, from, to string) (*models
 	return summary, nil
 }
 
-// GetExpenseByCategory returns expense breakdown by category
-func (r *Repository) GetExpenseByCategory(userID int64, from, to string) ([]models.CategoryAmount, error) {
+// GetExpenseByCategory returns expense breakdown by category in the user's
+// base currency, limited to transactions carrying tag unless it is empty
+func (r *Repository) GetExpenseByCategory(userID int64, from, to, tag string) ([]models.CategoryAmount, error) {
+	tagCond, tagArgs := tagFilter(tag)
 	rows, err := r.db.Query(`
-		SELECT COALESCE(NULLIF(category, ''), 'uncategorized') as category, SUM(amount) as amount
+		SELECT COALESCE(NULLIF(category, ''), 'uncategorized') as category, COALESCE(SUM(`+amountInBase+`), 0) as amount
 		FROM transactions
-		WHERE status = 'confirmed'
+		WHERE status = 'confirmed' AND deleted_at IS NULL AND `+personal+`
 		  AND user_id = ?
 		  AND direction = 'expense'
-		  AND COALESCE(NULLIF(txn_date, ''), date(created_at)) >= ?
-		  AND COALESCE(NULLIF(txn_date, ''), date(created_at)) <= ?
+		  AND `+txnDay+` >= ?
+		  AND `+txnDay+` <= ?`+tagCond+`
 		GROUP BY category
 		ORDER BY amount DESC
-	`, userID, from, to)
+	`, append([]interface{}{userID, from, to}, tagArgs...)...)
 	if err != nil {
 		return nil, err
 	}
@@ -428,19 +510,21 @@ func (r *Repository) GetExpenseByCategory(userID int64, from, to string) ([]mode
 	return result, rows.Err()
 }
 
-// GetExpenseByChannel returns expense breakdown by channel
-func (r *Repository) GetExpenseByChannel(userID int64, from, to string) ([]models.ChannelAmount, error) {
+// GetExpenseByChannel returns expense breakdown by channel in the user's base
+// currency, limited to transactions carrying tag unless it is empty
+func (r *Repository) GetExpenseByChannel(userID int64, from, to, tag string) ([]models.ChannelAmount, error) {
+	tagCond, tagArgs := tagFilter(tag)
 	rows, err := r.db.Query(`
-		SELECT COALESCE(NULLIF(channel, ''), 'unknown') as channel, SUM(amount) as amount
+		SELECT COALESCE(NULLIF(channel, ''), 'unknown') as channel, COALESCE(SUM(`+amountInBase+`), 0) as amount
 		FROM transactions
-		WHERE status = 'confirmed'
+		WHERE status = 'confirmed' AND deleted_at IS NULL AND `+personal+`
 		  AND user_id = ?
 		  AND direction = 'expense'
-		  AND COALESCE(NULLIF(txn_date, ''), date(created_at)) >= ?
-		  AND COALESCE(NULLIF(txn_date, ''), date(created_at)) <= ?
+		  AND `+txnDay+` >= ?
+		  AND `+txnDay+` <= ?`+tagCond+`
 		GROUP BY channel
 		ORDER BY amount DESC
-	`, userID, from, to)
+	`, append([]interface{}{userID, from, to}, tagArgs...)...)
 	if err != nil {
 		return nil, err
 	}
@@ -457,8 +541,41 @@ func (r *Repository) GetExpenseByChannel(userID int64, from, to string) ([]model
 	return result, rows.Err()
 }
 
-// QuerySummary returns summary data based on query filters (for chat)
-func (r *Repository) QuerySummary(userID int64, direction string, from, to string, category, channel string) (*models.DashboardSummary, error) {
+// GetExpenseByTag returns expense per tag in the user's base currency,
+// largest first. Untagged transactions are left out.
+func (r *Repository) GetExpenseByTag(userID int64, from, to string) ([]models.TagAmount, error) {
+	rows, err := r.db.Query(`
+		SELECT tags.name, COALESCE(SUM(`+amountInBase+`), 0) AS amount, COUNT(*)
+		FROM transactions
+		JOIN transaction_tags ON transaction_tags.transaction_id = transactions.id
+		JOIN tags ON tags.id = transaction_tags.tag_id
+		WHERE status = 'confirmed' AND deleted_at IS NULL AND `+personal+`
+		  AND transactions.user_id = ?
+		  AND direction = 'expense'
+		  AND `+txnDay+` >= ?
+		  AND `+txnDay+` <= ?
+		GROUP BY tags.name
+		ORDER BY amount DESC, tags.name ASC
+	`, userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []models.TagAmount
+	for rows.Next() {
+		var ta models.TagAmount
+		if err := rows.Scan(&ta.Tag, &ta.Amount, &ta.Count); err != nil {
+			return nil, err
+		}
+		result = append(result, ta)
+	}
+	return result, rows.Err()
+}
+
+// QuerySummary returns summary data based on query filters (for chat), in
+// the user's base currency
+func (r *Repository) QuerySummary(userID int64, direction string, from, to string, category, channel, tag string) (*models.DashboardSummary, error) {
 	summary := &models.DashboardSummary{
 		Period: models.Period{From: from, To: to},
 	}
@@ -466,11 +583,11 @@ func (r *Repository) QuerySummary(userID int64, direction string, from, to strin
 	// Build query based on filters - use created_at as fallback for txn_date
 	baseQuery := `
 		FROM transactions
-		WHERE status = 'confirmed'
+		WHERE status = 'confirmed' AND deleted_at IS NULL AND ` + personal + `
 		  AND user_id = ?
-		  AND COALESCE(NULLIF(txn_date, ''), date(created_at)) >= ?
-		  AND COALESCE(NULLIF(txn_date, ''), date(created_at)) <= ?`
-	args := []interface{}{userID, from, to}
+		  AND ` + txnDay + ` >= ?
+		  AND ` + txnDay + ` <= ?`
+	args := []interface{}{userID, userID, from, to}
 
 	if category != "" {
 		baseQuery += ` AND category = ?`
@@ -480,23 +597,24 @@ func (r *Repository) QuerySummary(userID int64, direction string, from, to strin
 		baseQuery += ` AND channel = ?`
 		args = append(args, channel)
 	}
-
-	var err error
+	if tag != "" {
+		baseQuery += ` AND ` + taggedWith
+		args = append(args, tag)
+	}
 	switch direction {
-	case "expense":
-		query := `SELECT COALESCE(SUM(amount), 0) ` + baseQuery + ` AND direction = 'expense'`
-		err = r.db.QueryRow(query, args...).Scan(&summary.TotalExpense)
-	case "income":
-		query := `SELECT COALESCE(SUM(amount), 0) ` + baseQuery + ` AND direction = 'income'`
-		err = r.db.QueryRow(query, args...).Scan(&summary.TotalIncome)
-	default:
-		query := `
-			SELECT
-				COALESCE(SUM(CASE WHEN direction = 'expense' THEN amount ELSE 0 END), 0),
-				COALESCE(SUM(CASE WHEN direction = 'income' THEN amount ELSE 0 END), 0)
-			` + baseQuery
-		err = r.db.QueryRow(query, args...).Scan(&summary.TotalExpense, &summary.TotalIncome)
+	case "expense", "income":
+		baseQuery += ` AND direction = ?`
+		args = append(args, direction)
 	}
+
+	query := `
+		SELECT
+			COALESCE(SUM(CASE WHEN direction = 'expense' THEN ` + amountInBase + ` ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN direction = 'income' THEN ` + amountInBase + ` ELSE 0 END), 0),
+			COUNT(CASE WHEN ` + fxRate + ` IS NULL THEN 1 END),
+			COALESCE((SELECT base_currency FROM users WHERE id = ?), 'THB')
+		` + baseQuery
+	err := r.db.QueryRow(query, args...).Scan(&summary.TotalExpense, &summary.TotalIncome, &summary.Unconverted, &summary.Currency)
 	if err != nil {
 		return nil, err
 	}
@@ -518,3 +636,10 @@ func nullFloat(f float64) interface{} {
 	}
 	return f
 }
+
+func nullInt(i int64) interface{} {
+	if i == 0 {
+		return nil
+	}
+	return i
+}
@@ -0,0 +1,242 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"cash-track/internal/models"
+)
+
+// revisionState returns the RevisionFields of a transaction, trashed or not
+func revisionState(dbTx *sql.Tx, id int64) (map[string]string, error) {
+	tx, err := scanTransaction(dbTx.QueryRow(`SELECT `+transactionColumns+` FROM transactions WHERE id = ?`, id))
+	if err != nil {
+		return nil, err
+	}
+	state := map[string]string{}
+	for _, field := range models.RevisionFields {
+		state[field] = tx.FieldValue(field)
+	}
+	return state, nil
+}
+
+// recordRevision stores the change from before to the transaction's current
+// state. before is nil for a create. Updates that leave every RevisionField
+// as it was are not recorded.
+func recordRevision(dbTx *sql.Tx, id int64, action, actor string, before map[string]string) error {
+	after, err := revisionState(dbTx, id)
+	if err != nil {
+		return err
+	}
+	if action == models.RevisionUpdate && len(changedFields(before, after)) == 0 {
+		return nil
+	}
+
+	var beforeJSON interface{}
+	if before != nil {
+		data, err := json.Marshal(before)
+		if err != nil {
+			return err
+		}
+		beforeJSON = string(data)
+	}
+	afterJSON, err := json.Marshal(after)
+	if err != nil {
+		return err
+	}
+	_, err = dbTx.Exec(`
+		INSERT INTO transaction_revisions (transaction_id, user_id, action, actor, before, after)
+		SELECT id, user_id, ?, ?, ?, ? FROM transactions WHERE id = ?
+	`, action, actor, beforeJSON, string(afterJSON), id)
+	return err
+}
+
+// revise runs change in a database transaction and records what it did to
+// transaction id. It returns sql.ErrNoRows when the transaction does not exist.
+func (r *Repository) revise(id int64, action, actor string, change func(dbTx *sql.Tx) error) error {
+	dbTx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer dbTx.Rollback()
+
+	before, err := revisionState(dbTx, id)
+	if err != nil {
+		return err
+	}
+	if err := change(dbTx); err != nil {
+		return err
+	}
+	if err := recordRevision(dbTx, id, action, actor, before); err != nil {
+		return err
+	}
+	return dbTx.Commit()
+}
+
+// insertTransaction runs an INSERT into transactions for the user, tags the
+// new transaction and records its create revision, returning its ID
+func (r *Repository) insertTransaction(userID int64, tags []string, actor, query string, args ...interface{}) (int64, error) {
+	dbTx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer dbTx.Rollback()
+
+	result, err := dbTx.Exec(query, args...)
+	if err != nil {
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	if err := addTags(dbTx, userID, id, tags); err != nil {
+		return 0, err
+	}
+	if err := recordRevision(dbTx, id, models.RevisionCreate, actor, nil); err != nil {
+		return 0, err
+	}
+	return id, dbTx.Commit()
+}
+
+// changedFields lists the RevisionFields that differ between two states. A
+// nil before counts every field with a value as changed.
+func changedFields(before, after map[string]string) []string {
+	changed := []string{}
+	for _, field := range models.RevisionFields {
+		if before[field] != after[field] {
+			changed = append(changed, field)
+		}
+	}
+	return changed
+}
+
+func scanRevision(row rowScanner) (*models.TransactionRevision, error) {
+	rev := &models.TransactionRevision{}
+	var before sql.NullString
+	var after string
+	err := row.Scan(&rev.ID, &rev.TransactionID, &rev.Action, &rev.Actor, &before, &after, &rev.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if before.Valid {
+		if err := json.Unmarshal([]byte(before.String), &rev.Before); err != nil {
+			return nil, fmt.Errorf("revision %d: %w", rev.ID, err)
+		}
+	}
+	if err := json.Unmarshal([]byte(after), &rev.After); err != nil {
+		return nil, fmt.Errorf("revision %d: %w", rev.ID, err)
+	}
+	rev.Changed = changedFields(rev.Before, rev.After)
+	return rev, nil
+}
+
+const revisionColumns = `id, transaction_id, action, actor, before, after, created_at`
+
+// ListRevisions returns the recorded changes to one of the user's
+// transactions, newest first. Trashed transactions keep their history.
+func (r *Repository) ListRevisions(userID, txID int64) ([]models.TransactionRevision, error) {
+	rows, err := r.db.Query(`
+		SELECT `+revisionColumns+` FROM transaction_revisions
+		WHERE transaction_id = ? AND user_id = ?
+		ORDER BY id DESC
+	`, txID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := []models.TransactionRevision{}
+	for rows.Next() {
+		rev, err := scanRevision(rows)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, *rev)
+	}
+	return revisions, rows.Err()
+}
+
+// RevertTransaction puts a transaction's fields back to how they were after
+// revision revID and records that as a revert. Fields that change become
+// user-edited, so re-parsing leaves them alone; claims are left as they are.
+// It returns sql.ErrNoRows when the revision is not one of the transaction's
+// or the transaction is in the trash.
+func (r *Repository) RevertTransaction(userID, txID, revID int64) error {
+	rev, err := scanRevision(r.db.QueryRow(`
+		SELECT `+revisionColumns+` FROM transaction_revisions
+		WHERE id = ? AND transaction_id = ? AND user_id = ?
+	`, revID, txID, userID))
+	if err != nil {
+		return err
+	}
+
+	return r.revise(txID, models.RevisionRevert, models.ActorUser, func(dbTx *sql.Tx) error {
+		tx, err := scanTransaction(dbTx.QueryRow(`
+			SELECT `+transactionColumns+` FROM transactions
+			WHERE id = ? AND user_id = ? AND deleted_at IS NULL
+		`, txID, userID))
+		if err != nil {
+			return err
+		}
+
+		sets := []string{"updated_at = datetime('now')"}
+		var args []interface{}
+		edited := tx.EditedFields()
+		for _, field := range models.RevisionFields {
+			value, ok := rev.After[field]
+			if !ok || value == tx.FieldValue(field) {
+				continue
+			}
+			switch field {
+			case "tags":
+				continue
+			case "reimbursable", "claim_id":
+				// Expenses join and leave claims through the claim, which
+				// may have been submitted since
+				continue
+			case "status":
+				sets = append(sets, "status = ?")
+				args = append(args, value)
+				continue
+			}
+			column, ok := reparseColumns[field]
+			if !ok {
+				continue
+			}
+			v, err := reparseValue(field, value)
+			if err != nil {
+				return err
+			}
+			sets = append(sets, column)
+			args = append(args, v)
+			// Only fields the parser reads from the slip become user-edited
+			if field != "llm_confidence" && field != "tax_item" && !tx.IsUserEdited(field) {
+				edited = append(edited, field)
+			}
+		}
+		sets = append(sets, "user_edited_fields = ?")
+		args = append(args, nullString(strings.Join(edited, ",")), txID)
+		if _, err := dbTx.Exec(`UPDATE transactions SET `+strings.Join(sets, ", ")+` WHERE id = ?`, args...); err != nil {
+			return err
+		}
+
+		if tags, ok := rev.After["tags"]; ok {
+			return setTags(dbTx, userID, txID, tags)
+		}
+		return nil
+	})
+}
+
+// setTags replaces a transaction's tags with the comma-separated names
+func setTags(dbTx *sql.Tx, userID, txID int64, names string) error {
+	if _, err := dbTx.Exec(`DELETE FROM transaction_tags WHERE transaction_id = ?`, txID); err != nil {
+		return err
+	}
+	if names == "" {
+		return nil
+	}
+	return addTags(dbTx, userID, txID, strings.Split(names, ","))
+}
@@ -0,0 +1,160 @@
+package database
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+
+	"cash-track/internal/models"
+)
+
+// addChatTransaction stores a pending lunch parsed from chat and returns its
+// ID
+func addChatTransaction(t *testing.T, r *Repository) int64 {
+	t.Helper()
+	tx, err := r.CreateTransactionFromChat(1, "2026-10-01", models.SomeMoney(5000), "THB", "expense", "", "",
+		"food", "lunch", []string{"work"}, "ข้าว 50", "", "", 0.9, "pending", models.ActorLLM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tx.ID
+}
+
+func TestRevisionHistory(t *testing.T) {
+	r := newTestRepository(t)
+	id := addChatTransaction(t, r)
+	if err := r.UpdateOCRResult(id, "TAXI 60.00", models.SomeMoney(6000), "THB", "2026-10-02", "", "travel", "taxi", 0.8, models.ActorRegex); err != nil {
+		t.Fatal(err)
+	}
+	err := r.ConfirmTransaction(1, id, models.ConfirmRequest{
+		Amount: 6500, Currency: "THB", TxnDate: "2026-10-02", Direction: "expense", Category: "transport", Description: "taxi",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.DeleteTransaction(1, id); err != nil {
+		t.Fatal(err)
+	}
+
+	revisions, err := r.ListRevisions(1, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Newest first; before and after of the fields that changed
+	want := []struct {
+		action, actor string
+		changed       map[string][2]string
+	}{
+		{models.RevisionDelete, models.ActorUser, map[string][2]string{}},
+		{models.RevisionConfirm, models.ActorUser, map[string][2]string{
+			"amount":   {"60.00", "65.00"},
+			"category": {"travel", "transport"},
+			"status":   {"pending", "confirmed"},
+		}},
+		{models.RevisionUpdate, models.ActorRegex, map[string][2]string{
+			"txn_date":       {"2026-10-01", "2026-10-02"},
+			"amount":         {"50.00", "60.00"},
+			"category":       {"food", "travel"},
+			"description":    {"lunch", "taxi"},
+			"llm_confidence": {"0.90", "0.80"},
+		}},
+		{models.RevisionCreate, models.ActorLLM, map[string][2]string{
+			"txn_date":       {"", "2026-10-01"},
+			"amount":         {"", "50.00"},
+			"currency":       {"", "THB"},
+			"direction":      {"", "expense"},
+			"category":       {"", "food"},
+			"description":    {"", "lunch"},
+			"llm_confidence": {"", "0.90"},
+			"status":         {"", "pending"},
+			"tags":           {"", "work"},
+			"reimbursable":   {"", "false"},
+		}},
+	}
+	if len(revisions) != len(want) {
+		t.Fatalf("%d revisions, want %d", len(revisions), len(want))
+	}
+	for i, w := range want {
+		rev := revisions[i]
+		changed := map[string][2]string{}
+		for _, field := range rev.Changed {
+			changed[field] = [2]string{rev.Before[field], rev.After[field]}
+		}
+		if rev.Action != w.action || rev.Actor != w.actor || !reflect.DeepEqual(changed, w.changed) {
+			t.Errorf("revision %d: %s by %s changed %v, want %s by %s changed %v", i, rev.Action, rev.Actor, changed, w.action, w.actor, w.changed)
+		}
+	}
+	if revisions[len(revisions)-1].Before != nil {
+		t.Errorf("create revision has a before state: %v", revisions[len(revisions)-1].Before)
+	}
+}
+
+func TestRevertTransaction(t *testing.T) {
+	r := newTestRepository(t)
+	id := addChatTransaction(t, r)
+	other := addChatTransaction(t, r)
+	if err := r.UpdateOCRResult(id, "TAXI 60.00", models.SomeMoney(6000), "THB", "2026-10-02", "", "travel", "taxi", 0.8, models.ActorRegex); err != nil {
+		t.Fatal(err)
+	}
+	revisions, err := r.ListRevisions(1, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	created := revisions[len(revisions)-1]
+	otherRevisions, _ := r.ListRevisions(1, other)
+
+	if err := r.RevertTransaction(1, id, otherRevisions[0].ID); err != sql.ErrNoRows {
+		t.Errorf("revert to another transaction's revision: err = %v, want sql.ErrNoRows", err)
+	}
+	if err := r.RevertTransaction(1, id, created.ID); err != nil {
+		t.Fatalf("revert: %v", err)
+	}
+
+	tx, err := r.GetTransaction(1, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, field := range []string{"amount", "category", "txn_date", "description", "llm_confidence"} {
+		if got := tx.FieldValue(field); got != created.After[field] {
+			t.Errorf("%s = %q, want %q", field, got, created.After[field])
+		}
+	}
+	if edited := tx.EditedFields(); !reflect.DeepEqual(edited, []string{"txn_date", "amount", "category", "description"}) {
+		t.Errorf("user-edited fields = %v", edited)
+	}
+	if revisions, _ = r.ListRevisions(1, id); revisions[0].Action != models.RevisionRevert || revisions[0].Actor != models.ActorUser {
+		t.Errorf("revert recorded as %s by %s", revisions[0].Action, revisions[0].Actor)
+	}
+
+	if err := r.DeleteTransaction(1, id); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.RevertTransaction(1, id, created.ID); err != sql.ErrNoRows {
+		t.Errorf("revert in the trash: err = %v, want sql.ErrNoRows", err)
+	}
+}
+
+func TestRevertTaxItem(t *testing.T) {
+	r := newTestRepository(t)
+	id := addTestTransaction(t, r, testTransaction{day: "2026-10-01", amount: 5000, category: "health"})
+	for _, item := range []string{"health_insurance", models.TaxItemNone} {
+		if _, err := r.ApplyBulk(1, models.BulkRequest{Action: models.BulkSetTaxItem, IDs: []int64{id}, Value: item}, models.SearchQuery{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	revisions, err := r.ListRevisions(1, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revisions) != 2 || revisions[0].After["tax_item"] != models.TaxItemNone || revisions[1].After["tax_item"] != "health_insurance" {
+		t.Fatalf("revisions = %+v", revisions)
+	}
+	if err := r.RevertTransaction(1, id, revisions[1].ID); err != nil {
+		t.Fatal(err)
+	}
+	tx, _ := r.GetTransaction(1, id)
+	if tx.TaxItem.String != "health_insurance" || tx.IsUserEdited("tax_item") {
+		t.Errorf("after revert tax item = %q, edited %v", tx.TaxItem.String, tx.EditedFields())
+	}
+}
@@ -0,0 +1,141 @@
+package database
+
+import (
+	"database/sql"
+	"html"
+	"strings"
+	"unicode/utf8"
+
+	"cash-track/internal/models"
+)
+
+// Markers around matches in FTS snippets, replaced with <mark> once the
+// snippet has been HTML-escaped
+const (
+	snippetOpen  = "\x02"
+	snippetClose = "\x03"
+)
+
+// searchableColumns are the columns indexed by transactions_fts
+var searchableColumns = []string{"description", "chat_message", "raw_ocr_text", "payee"}
+
+// querier runs queries on the database or inside a transaction
+type querier interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// SearchTransactions returns the user's transactions matching q, newest first.
+// Terms of three or more characters use the full-text index; shorter ones,
+// which the trigram index cannot match, fall back to LIKE.
+func (r *Repository) SearchTransactions(userID int64, q models.SearchQuery, limit int) ([]models.SearchResult, error) {
+	return searchTransactions(r.db, userID, q, limit)
+}
+
+func searchTransactions(db querier, userID int64, q models.SearchQuery, limit int) ([]models.SearchResult, error) {
+	var ftsTerms, shortTerms []string
+	for _, term := range q.Terms {
+		if utf8.RuneCountInString(term) >= 3 {
+			ftsTerms = append(ftsTerms, `"`+strings.ReplaceAll(term, `"`, `""`)+`"`)
+		} else {
+			shortTerms = append(shortTerms, term)
+		}
+	}
+
+	var args []interface{}
+	query := `SELECT ` + transactionColumns + `, `
+	if len(ftsTerms) > 0 {
+		query += `hits.snippet FROM transactions
+			JOIN (
+				SELECT rowid, snippet(transactions_fts, -1, '` + snippetOpen + `', '` + snippetClose + `', '…', 24) AS snippet
+				FROM transactions_fts WHERE transactions_fts MATCH ?
+			) AS hits ON hits.rowid = transactions.id`
+		args = append(args, strings.Join(ftsTerms, " AND "))
+	} else {
+		query += `'' FROM transactions`
+	}
+
+	query += ` WHERE user_id = ? AND deleted_at IS NULL`
+	args = append(args, userID)
+
+	for _, term := range shortTerms {
+		var likes []string
+		for _, column := range searchableColumns {
+			likes = append(likes, column+` LIKE ?`)
+			args = append(args, "%"+term+"%")
+		}
+		query += ` AND (` + strings.Join(likes, " OR ") + `)`
+	}
+	if q.Status != "" {
+		query += ` AND status = ?`
+		args = append(args, q.Status)
+	}
+	if q.Direction != "" {
+		query += ` AND direction = ?`
+		args = append(args, q.Direction)
+	}
+	if q.Category != "" {
+		query += ` AND category = ?`
+		args = append(args, q.Category)
+	}
+	if q.Channel != "" {
+		query += ` AND channel = ?`
+		args = append(args, q.Channel)
+	}
+	if q.Tag != "" {
+		query += ` AND ` + taggedWith
+		args = append(args, q.Tag)
+	}
+	if q.SellerTaxID != "" {
+		query += ` AND transactions.id IN (SELECT transaction_id FROM receipt_details WHERE seller_tax_id = ?)`
+		args = append(args, q.SellerTaxID)
+	}
+	if q.InvoiceNumber != "" {
+		query += ` AND transactions.id IN (SELECT transaction_id FROM receipt_details WHERE invoice_number LIKE ?)`
+		args = append(args, "%"+q.InvoiceNumber+"%")
+	}
+	if q.From != "" {
+		query += ` AND ` + txnDay + ` >= ?`
+		args = append(args, q.From)
+	}
+	if q.To != "" {
+		query += ` AND ` + txnDay + ` <= ?`
+		args = append(args, q.To)
+	}
+	if q.MinAmount.Valid {
+		query += ` AND amount_minor >= ?`
+		args = append(args, q.MinAmount.Money)
+	}
+	if q.MaxAmount.Valid {
+		query += ` AND amount_minor <= ?`
+		args = append(args, q.MaxAmount.Money)
+	}
+	query += ` ORDER BY ` + txnDay + ` DESC, transactions.id DESC LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []models.SearchResult
+	for rows.Next() {
+		var snippet string
+		tx, err := scanTransaction(rows, &snippet)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, models.SearchResult{
+			TransactionView: tx.ToView(),
+			Snippet:         highlight(snippet),
+		})
+	}
+	return results, rows.Err()
+}
+
+// highlight escapes a snippet and turns its match markers into <mark> tags
+func highlight(snippet string) string {
+	escaped := html.EscapeString(snippet)
+	escaped = strings.ReplaceAll(escaped, snippetOpen, "<mark>")
+	return strings.ReplaceAll(escaped, snippetClose, "</mark>")
+}
@@ -0,0 +1,105 @@
+package database
+
+import (
+	"database/sql"
+
+	"cash-track/internal/models"
+	"cash-track/internal/storage"
+)
+
+// FindSimilarSlip returns the ID of the user's transaction whose slip hash is
+// closest to hash, if it is within maxDistance bits. It returns 0 when no slip
+// is close enough.
+func (r *Repository) FindSimilarSlip(userID int64, hash uint64, maxDistance int) (int64, error) {
+	rows, err := r.db.Query(`
+		SELECT id, slip_hash FROM transactions
+		WHERE user_id = ? AND slip_hash IS NOT NULL AND deleted_at IS NULL
+		ORDER BY id ASC
+	`, userID)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	var bestID int64
+	bestDistance := maxDistance + 1
+	for rows.Next() {
+		var id int64
+		var stored string
+		if err := rows.Scan(&id, &stored); err != nil {
+			return 0, err
+		}
+		other, err := storage.ParseHash(stored)
+		if err != nil {
+			continue
+		}
+		if d := storage.HammingDistance(hash, other); d < bestDistance {
+			bestID, bestDistance = id, d
+		}
+	}
+	return bestID, rows.Err()
+}
+
+// SetSlipReference stores the bank reference number read from a slip. If
+// another transaction of the same user already carries that reference, the
+// transaction is flagged as its duplicate and the earlier ID is returned.
+func (r *Repository) SetSlipReference(userID, id int64, ref string) (int64, error) {
+	if ref == "" {
+		return 0, nil
+	}
+
+	var dupID int64
+	err := r.db.QueryRow(`
+		SELECT id FROM transactions
+		WHERE user_id = ? AND slip_ref = ? AND id <> ? AND deleted_at IS NULL
+		ORDER BY id ASC LIMIT 1
+	`, userID, ref, id).Scan(&dupID)
+	if err != nil && err != sql.ErrNoRows {
+		return 0, err
+	}
+
+	_, err = r.db.Exec(`
+		UPDATE transactions
+		SET slip_ref = ?, duplicate_of = COALESCE(duplicate_of, ?)
+		WHERE id = ? AND user_id = ?
+	`, ref, nullInt(dupID), id, userID)
+	if err != nil {
+		return 0, err
+	}
+	return dupID, nil
+}
+
+// ListUnhashedSlips returns every transaction that has a slip image but no hash yet.
+func (r *Repository) ListUnhashedSlips() ([]models.Transaction, error) {
+	rows, err := r.db.Query(`
+		SELECT ` + transactionColumns + `
+		FROM transactions
+		WHERE slip_image_path IS NOT NULL AND slip_image_path <> '' AND slip_hash IS NULL
+		ORDER BY id ASC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var transactions []models.Transaction
+	for rows.Next() {
+		tx, err := scanTransaction(rows)
+		if err != nil {
+			return nil, err
+		}
+		transactions = append(transactions, *tx)
+	}
+	return transactions, rows.Err()
+}
+
+// UpdateSlipHash stores the perceptual hash of a transaction's slip and,
+// when duplicateOf is non-zero, flags it as a duplicate of that transaction.
+func (r *Repository) UpdateSlipHash(id int64, slipHash string, duplicateOf int64) error {
+	_, err := r.db.Exec(`
+		UPDATE transactions
+		SET slip_hash = ?, duplicate_of = COALESCE(duplicate_of, ?)
+		WHERE id = ?
+	`, slipHash, nullInt(duplicateOf), id)
+	return err
+}
@@ -0,0 +1,52 @@
+package database
+
+import (
+	"database/sql"
+
+	"cash-track/internal/models"
+)
+
+// ListTags returns the user's tags with the number of transactions carrying
+// each, most used first. Tags left on trashed transactions only are kept
+// with a count of zero.
+func (r *Repository) ListTags(userID int64) ([]models.Tag, error) {
+	rows, err := r.db.Query(`
+		SELECT tags.id, tags.name, COUNT(transactions.id) AS uses
+		FROM tags
+		LEFT JOIN transaction_tags ON transaction_tags.tag_id = tags.id
+		LEFT JOIN transactions ON transactions.id = transaction_tags.transaction_id AND transactions.deleted_at IS NULL
+		WHERE tags.user_id = ?
+		GROUP BY tags.id
+		ORDER BY uses DESC, tags.name ASC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tags []models.Tag
+	for rows.Next() {
+		var t models.Tag
+		if err := rows.Scan(&t.ID, &t.Name, &t.Count); err != nil {
+			return nil, err
+		}
+		tags = append(tags, t)
+	}
+	return tags, rows.Err()
+}
+
+// addTags tags a transaction with the named tags, creating any the user does
+// not have yet. Tags it already carries are kept.
+func addTags(dbTx *sql.Tx, userID, txID int64, names []string) error {
+	tagIDs, err := ensureTags(dbTx, userID, names)
+	if err != nil {
+		return err
+	}
+	for _, tagID := range tagIDs {
+		_, err := dbTx.Exec(`INSERT OR IGNORE INTO transaction_tags (transaction_id, tag_id) VALUES (?, ?)`, txID, tagID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
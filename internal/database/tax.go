@@ -0,0 +1,93 @@
+package database
+
+import (
+	"database/sql"
+
+	"cash-track/internal/models"
+)
+
+// taxItem is the tax deduction a transaction counts towards: its own, or
+// else its category's. It is NULL or models.TaxItemNone when there is none.
+const taxItem = `COALESCE(NULLIF(transactions.tax_item, ''),
+	(SELECT tax_categories.item FROM tax_categories
+	 WHERE tax_categories.user_id = transactions.user_id AND tax_categories.category = transactions.category))`
+
+// ListTaxCategories returns the user's categories that count towards a tax
+// deduction
+func (r *Repository) ListTaxCategories(userID int64) ([]models.TaxCategory, error) {
+	rows, err := r.db.Query(`SELECT category, item FROM tax_categories WHERE user_id = ? ORDER BY category ASC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var categories []models.TaxCategory
+	for rows.Next() {
+		var c models.TaxCategory
+		if err := rows.Scan(&c.Category, &c.Item); err != nil {
+			return nil, err
+		}
+		categories = append(categories, c)
+	}
+	return categories, rows.Err()
+}
+
+// SetTaxCategory makes the user's transactions in a category count towards
+// a tax item
+func (r *Repository) SetTaxCategory(userID int64, c models.TaxCategory) error {
+	_, err := r.db.Exec(`
+		INSERT INTO tax_categories (user_id, category, item) VALUES (?, ?, ?)
+		ON CONFLICT (user_id, category) DO UPDATE SET item = excluded.item
+	`, userID, c.Category, c.Item)
+	return err
+}
+
+// DeleteTaxCategory stops a category counting towards a tax item. It
+// returns sql.ErrNoRows when the category had none.
+func (r *Repository) DeleteTaxCategory(userID int64, category string) error {
+	result, err := r.db.Exec(`DELETE FROM tax_categories WHERE user_id = ? AND category = ?`, userID, category)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// GetTaxTransactions returns the user's confirmed expenses between from and
+// to that count towards a tax deduction, oldest first, limited to item
+// unless it is empty. Expenses netted out by a paid claim are left out.
+func (r *Repository) GetTaxTransactions(userID int64, from, to, item string) ([]models.TaxTransaction, error) {
+	query := `
+		SELECT ` + transactionColumns + `, ` + taxItem + `, ` + txnDay + `, ` + amountInBase + `
+		FROM transactions
+		WHERE status = 'confirmed' AND deleted_at IS NULL AND ` + personal + `
+		  AND user_id = ?
+		  AND direction = 'expense'
+		  AND ` + txnDay + ` >= ?
+		  AND ` + txnDay + ` <= ?
+		  AND COALESCE(` + taxItem + `, ?) <> ?`
+	args := []interface{}{userID, from, to, models.TaxItemNone, models.TaxItemNone}
+	if item != "" {
+		query += ` AND ` + taxItem + ` = ?`
+		args = append(args, item)
+	}
+	rows, err := r.db.Query(query+` ORDER BY `+txnDay+` ASC, id ASC`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var transactions []models.TaxTransaction
+	for rows.Next() {
+		var t models.TaxTransaction
+		tx, err := scanTransaction(rows, &t.Item, &t.Day, &t.Base)
+		if err != nil {
+			return nil, err
+		}
+		t.Transaction = *tx
+		transactions = append(transactions, t)
+	}
+	return transactions, rows.Err()
+}
@@ -0,0 +1,99 @@
+package database
+
+import (
+	"sort"
+
+	"cash-track/internal/models"
+)
+
+// GetTimeSeries sums confirmed expense and income into buckets, which must
+// be sorted, contiguous date ranges. Buckets without transactions stay zero.
+// With byCategory the expense of every bucket is split by category. A
+// non-empty tag limits the series to transactions carrying it.
+func (r *Repository) GetTimeSeries(userID int64, interval string, buckets []models.Period, byCategory bool, tag string) (*models.TimeSeries, error) {
+	series := &models.TimeSeries{
+		Interval: interval,
+		Buckets:  make([]models.TimeBucket, len(buckets)),
+	}
+	for i, b := range buckets {
+		series.Buckets[i].Period = b
+	}
+	if err := r.db.QueryRow(`SELECT COALESCE((SELECT base_currency FROM users WHERE id = ?), 'THB')`, userID).Scan(&series.Currency); err != nil {
+		return nil, err
+	}
+	if len(buckets) == 0 {
+		return series, nil
+	}
+	series.Period = models.Period{From: buckets[0].From, To: buckets[len(buckets)-1].To}
+
+	tagCond, tagArgs := tagFilter(tag)
+	rows, err := r.db.Query(`
+		SELECT `+txnDay+` AS day, direction, COALESCE(NULLIF(category, ''), 'uncategorized') AS category,
+		       COALESCE(SUM(`+amountInBase+`), 0)
+		FROM transactions
+		WHERE status = 'confirmed' AND deleted_at IS NULL AND `+personal+`
+		  AND user_id = ?
+		  AND direction IN ('expense', 'income')
+		  AND `+txnDay+` >= ?
+		  AND `+txnDay+` <= ?`+tagCond+`
+		GROUP BY day, direction, category
+	`, append([]interface{}{userID, series.Period.From, series.Period.To}, tagArgs...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	totals := map[string]models.Money{}
+	for rows.Next() {
+		var day, direction, category string
+		var amount models.Money
+		if err := rows.Scan(&day, &direction, &category, &amount); err != nil {
+			return nil, err
+		}
+		i := sort.Search(len(buckets), func(i int) bool { return buckets[i].To >= day })
+		if i == len(buckets) {
+			continue
+		}
+		bucket := &series.Buckets[i]
+		if direction == "income" {
+			bucket.Income += amount
+			continue
+		}
+		bucket.Expense += amount
+		if byCategory {
+			if bucket.ByCategory == nil {
+				bucket.ByCategory = map[string]models.Money{}
+			}
+			bucket.ByCategory[category] += amount
+			totals[category] += amount
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if byCategory {
+		for category := range totals {
+			series.Categories = append(series.Categories, category)
+		}
+		sort.Slice(series.Categories, func(i, j int) bool {
+			a, b := series.Categories[i], series.Categories[j]
+			if totals[a] != totals[b] {
+				return totals[a] > totals[b]
+			}
+			return a < b
+		})
+		// Zero-fill the split as well, so every bucket has every category
+		for i := range series.Buckets {
+			if series.Buckets[i].ByCategory == nil {
+				series.Buckets[i].ByCategory = map[string]models.Money{}
+			}
+			for _, category := range series.Categories {
+				if _, ok := series.Buckets[i].ByCategory[category]; !ok {
+					series.Buckets[i].ByCategory[category] = 0
+				}
+			}
+		}
+	}
+	return series, nil
+}
@@ -0,0 +1,133 @@
+package database
+
+import (
+	"database/sql"
+	"time"
+
+	"cash-track/internal/models"
+)
+
+// ListTrash returns the user's trashed transactions, most recently deleted
+// first
+func (r *Repository) ListTrash(userID int64, limit int) ([]models.Transaction, error) {
+	rows, err := r.db.Query(`
+		SELECT `+transactionColumns+`
+		FROM transactions
+		WHERE user_id = ? AND deleted_at IS NOT NULL
+		ORDER BY deleted_at DESC, id DESC
+		LIMIT ?
+	`, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var transactions []models.Transaction
+	for rows.Next() {
+		tx, err := scanTransaction(rows)
+		if err != nil {
+			return nil, err
+		}
+		transactions = append(transactions, *tx)
+	}
+	return transactions, rows.Err()
+}
+
+// RestoreTransaction takes a transaction back out of the trash. It returns
+// sql.ErrNoRows when the transaction is not in the user's trash.
+func (r *Repository) RestoreTransaction(userID, id int64) error {
+	return r.revise(id, models.RevisionRestore, models.ActorUser, func(dbTx *sql.Tx) error {
+		return untrashTransaction(dbTx, userID, id)
+	})
+}
+
+// untrashTransaction takes a transaction out of the trash and queues the
+// jobs trashTransaction paused again
+func untrashTransaction(dbTx *sql.Tx, userID, id int64) error {
+	result, err := dbTx.Exec(`
+		UPDATE transactions SET deleted_at = NULL, updated_at = datetime('now')
+		WHERE id = ? AND user_id = ? AND deleted_at IS NOT NULL
+	`, id, userID)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	_, err = dbTx.Exec(`
+		UPDATE jobs SET status = ?, run_after = datetime('now'), updated_at = datetime('now')
+		WHERE transaction_id = ? AND status = ?
+	`, models.JobQueued, id, models.JobPaused)
+	return err
+}
+
+// PurgeTrash permanently deletes transactions trashed before cutoff. It
+// returns how many were deleted and the slip files no remaining transaction
+// uses, which the caller should remove from storage. Transactions a
+// submitted or paid claim still refers to stay in the trash.
+func (r *Repository) PurgeTrash(cutoff time.Time) (int, []string, error) {
+	dbTx, err := r.db.Begin()
+	if err != nil {
+		return 0, nil, err
+	}
+	defer dbTx.Rollback()
+
+	before := cutoff.UTC().Format("2006-01-02 15:04:05")
+	ids, err := queryIDs(dbTx, `
+		SELECT id FROM transactions
+		WHERE deleted_at IS NOT NULL AND deleted_at < ?
+		  AND NOT `+claimLocked+`
+	`, before)
+	if err != nil || len(ids) == 0 {
+		return 0, nil, err
+	}
+	placeholders, args := idList(ids)
+
+	rows, err := dbTx.Query(`
+		SELECT DISTINCT slip_image_path FROM transactions
+		WHERE id IN (`+placeholders+`) AND COALESCE(slip_image_path, '') <> ''
+	`, args...)
+	if err != nil {
+		return 0, nil, err
+	}
+	var slips []string
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			rows.Close()
+			return 0, nil, err
+		}
+		slips = append(slips, path)
+	}
+	rows.Close()
+
+	for _, query := range []string{
+		`DELETE FROM transaction_tags WHERE transaction_id IN (` + placeholders + `)`,
+		`DELETE FROM transaction_revisions WHERE transaction_id IN (` + placeholders + `)`,
+		`DELETE FROM extractions WHERE transaction_id IN (` + placeholders + `)`,
+		`DELETE FROM jobs WHERE transaction_id IN (` + placeholders + `)`,
+		`DELETE FROM receipt_details WHERE transaction_id IN (` + placeholders + `)`,
+		`DELETE FROM transactions WHERE id IN (` + placeholders + `)`,
+	} {
+		if _, err := dbTx.Exec(query, args...); err != nil {
+			return 0, nil, err
+		}
+	}
+
+	// A slip can be shared with a transaction that is not being purged
+	var unused []string
+	for _, path := range slips {
+		var inUse int
+		if err := dbTx.QueryRow(`SELECT COUNT(*) FROM transactions WHERE slip_image_path = ?`, path).Scan(&inUse); err != nil {
+			return 0, nil, err
+		}
+		if inUse == 0 {
+			unused = append(unused, path)
+		}
+	}
+	return len(ids), unused, dbTx.Commit()
+}
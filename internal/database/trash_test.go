@@ -0,0 +1,207 @@
+package database
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+	"time"
+
+	"cash-track/internal/models"
+)
+
+// trash moves a transaction to the trash as if it was deleted ago
+func trash(t *testing.T, r *Repository, id int64, ago time.Duration) {
+	t.Helper()
+	deletedAt := time.Now().Add(-ago).UTC().Format("2006-01-02 15:04:05")
+	if _, err := r.db.Exec(`UPDATE transactions SET deleted_at = ? WHERE id = ?`, deletedAt, id); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestListTrash(t *testing.T) {
+	r := newTestRepository(t)
+	var trashed []int64
+	for _, ago := range []time.Duration{3 * time.Hour, time.Hour, 2 * time.Hour} {
+		id := addTestTransaction(t, r, testTransaction{day: "2026-10-01", amount: 5000})
+		trash(t, r, id, ago)
+		trashed = append(trashed, id)
+	}
+	addTestTransaction(t, r, testTransaction{day: "2026-10-01", amount: 5000})
+
+	tests := []struct {
+		userID int64
+		limit  int
+		want   []int64
+	}{
+		{1, 10, []int64{trashed[1], trashed[2], trashed[0]}},
+		{1, 2, []int64{trashed[1], trashed[2]}},
+		{2, 10, nil},
+	}
+	for _, tt := range tests {
+		transactions, err := r.ListTrash(tt.userID, tt.limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []int64
+		for _, tx := range transactions {
+			got = append(got, tx.ID)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("user %d, limit %d: trash = %v, want %v", tt.userID, tt.limit, got, tt.want)
+		}
+	}
+}
+
+func TestRestoreTransaction(t *testing.T) {
+	r := newTestRepository(t)
+	var ids []int64
+	for _, day := range []string{"2026-10-01", "2026-10-02", "2026-10-03"} {
+		ids = append(ids, addTestTransaction(t, r, testTransaction{day: day, amount: 5000}))
+	}
+	if _, err := r.ApplyBulk(1, models.BulkRequest{Action: models.BulkDelete, IDs: ids[:2]}, models.SearchQuery{}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		userID int64
+		id     int64
+		want   error
+	}{
+		{"other user", 2, ids[0], sql.ErrNoRows},
+		{"trashed", 1, ids[0], nil},
+		{"restored", 1, ids[0], sql.ErrNoRows},
+		{"never deleted", 1, ids[2], sql.ErrNoRows},
+	}
+	for _, tt := range tests {
+		if err := r.RestoreTransaction(tt.userID, tt.id); err != tt.want {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+
+	if _, err := r.GetTransaction(1, ids[0]); err != nil {
+		t.Errorf("restored transaction: %v", err)
+	}
+	if _, err := r.GetTransaction(1, ids[1]); err != sql.ErrNoRows {
+		t.Errorf("transaction left in the trash: err = %v, want sql.ErrNoRows", err)
+	}
+	revisions, err := r.ListRevisions(1, ids[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revisions) == 0 || revisions[0].Action != models.RevisionRestore {
+		t.Errorf("restore was not recorded: %+v", revisions)
+	}
+}
+
+func TestPurgeTrash(t *testing.T) {
+	r := newTestRepository(t)
+	add := func(slip string, ago time.Duration) int64 {
+		t.Helper()
+		id := addTestTransaction(t, r, testTransaction{day: "2026-10-01", amount: 5000})
+		r.db.Exec(`UPDATE transactions SET slip_image_path = ? WHERE id = ?`, nullString(slip), id)
+		if ago > 0 {
+			trash(t, r, id, ago)
+		}
+		return id
+	}
+	const week = 7 * 24 * time.Hour
+	old := add("old.jpg", 2*week)
+	shared := add("shared.jpg", 2*week)
+	kept := add("shared.jpg", 0)
+	recent := add("recent.jpg", time.Hour)
+
+	// A paid claim still refers to its expense and to the income that paid it
+	expense := add("", 0)
+	income := add("", 0)
+	r.db.Exec(`UPDATE transactions SET direction = 'income' WHERE id = ?`, income)
+	claim, err := r.CreateClaim(models.Claim{UserID: 1, Title: "Client lunch"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.AddClaimTransactions(1, claim.ID, []int64{expense}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.SubmitClaim(1, claim.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.MarkClaimPaid(1, claim.ID, income); err != nil {
+		t.Fatal(err)
+	}
+	trash(t, r, expense, 2*week)
+	trash(t, r, income, 2*week)
+
+	purged, unused, err := r.PurgeTrash(time.Now().Add(-week))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if purged != 2 || !reflect.DeepEqual(unused, []string{"old.jpg"}) {
+		t.Errorf("purged %d, unused slips %v, want 2 and [old.jpg]", purged, unused)
+	}
+	for id, want := range map[int64]bool{old: false, shared: false, kept: true, recent: true, expense: true, income: true} {
+		var n int
+		r.db.QueryRow(`SELECT COUNT(*) FROM transactions WHERE id = ?`, id).Scan(&n)
+		if (n == 1) != want {
+			t.Errorf("transaction %d: kept = %v, want %v", id, n == 1, want)
+		}
+	}
+	if got, _ := r.GetClaim(1, claim.ID); got.ReimbursementID != income {
+		t.Errorf("claim reimbursement = %d, want %d", got.ReimbursementID, income)
+	}
+}
+
+func TestTrashPausesJobs(t *testing.T) {
+	var undoToken string
+	tests := []struct {
+		name            string
+		delete, restore func(r *Repository, id int64) error
+	}{
+		{
+			"single",
+			func(r *Repository, id int64) error { return r.DeleteTransaction(1, id) },
+			func(r *Repository, id int64) error { return r.RestoreTransaction(1, id) },
+		},
+		{
+			"bulk",
+			func(r *Repository, id int64) error {
+				result, err := r.ApplyBulk(1, models.BulkRequest{Action: models.BulkDelete, IDs: []int64{id}}, models.SearchQuery{})
+				if err == nil {
+					undoToken = result.UndoToken
+				}
+				return err
+			},
+			func(r *Repository, id int64) error {
+				_, err := r.UndoBulk(1, undoToken)
+				return err
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRepository(t)
+			id := addTestTransaction(t, r, testTransaction{day: "2026-10-01", amount: 5000})
+			queued, err := r.EnqueueJob("slip_ocr", 1, id, `{"filename": "slip.jpg"}`, 3)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := tt.delete(r, id); err != nil {
+				t.Fatalf("delete: %v", err)
+			}
+			if job, err := r.ClaimJob(); job != nil || err != nil {
+				t.Fatalf("job of a trashed transaction ran: %v, %v", job, err)
+			}
+			if err := tt.restore(r, id); err != nil {
+				t.Fatalf("restore: %v", err)
+			}
+			job, err := r.ClaimJob()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if job == nil || job.ID != queued.ID {
+				t.Errorf("after restoring, claimed job %v, want %d", job, queued.ID)
+			}
+		})
+	}
+}
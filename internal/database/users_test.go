@@ -0,0 +1,115 @@
+package database
+
+import "testing"
+
+// userRows stores one row in each table holding a user's data, for the user
+// numbered ?1, and counts them back. Rows linked through another table count
+// as the user's unless they belong to someone else, so orphans are caught.
+var userRows = []struct {
+	table  string
+	insert string
+	count  string
+}{
+	{
+		"transactions",
+		`INSERT INTO transactions (user_id, txn_date, amount_minor, direction, status) VALUES (?1, '2026-10-01', 100, 'expense', 'confirmed')`,
+		`SELECT COUNT(*) FROM transactions WHERE user_id = ?1`,
+	},
+	{
+		"tags",
+		`INSERT INTO tags (user_id, name) VALUES (?1, 'trip')`,
+		`SELECT COUNT(*) FROM tags WHERE user_id = ?1`,
+	},
+	{
+		"transaction_tags",
+		`INSERT INTO transaction_tags (transaction_id, tag_id)
+		 SELECT (SELECT MAX(id) FROM transactions WHERE user_id = ?1), (SELECT MAX(id) FROM tags WHERE user_id = ?1)`,
+		`SELECT COUNT(*) FROM transaction_tags WHERE tag_id NOT IN (SELECT id FROM tags WHERE user_id <> ?1)`,
+	},
+	{
+		"jobs",
+		`INSERT INTO jobs (kind, user_id, payload, max_attempts) VALUES ('slip_ocr', ?1, '{}', 3)`,
+		`SELECT COUNT(*) FROM jobs WHERE user_id = ?1`,
+	},
+	{
+		"claims",
+		`INSERT INTO claims (user_id, title) VALUES (?1, 'Client lunch')`,
+		`SELECT COUNT(*) FROM claims WHERE user_id = ?1`,
+	},
+	{
+		"holdings",
+		`INSERT INTO holdings (user_id, name, kind, class) VALUES (?1, 'Savings', 'account', 'cash')`,
+		`SELECT COUNT(*) FROM holdings WHERE user_id = ?1`,
+	},
+	{
+		"holding_valuations",
+		`INSERT INTO holding_valuations (holding_id, valued_on, value_minor)
+		 SELECT MAX(id), '2026-10-01', 100000 FROM holdings WHERE user_id = ?1`,
+		`SELECT COUNT(*) FROM holding_valuations WHERE holding_id NOT IN (SELECT id FROM holdings WHERE user_id <> ?1)`,
+	},
+	{
+		"tax_categories",
+		`INSERT INTO tax_categories (user_id, category, item) VALUES (?1, 'health', 'health_insurance')`,
+		`SELECT COUNT(*) FROM tax_categories WHERE user_id = ?1`,
+	},
+	{
+		"receipt_details",
+		`INSERT INTO receipt_details (transaction_id, invoice_number)
+		 SELECT MAX(id), 'INV-1' FROM transactions WHERE user_id = ?1`,
+		`SELECT COUNT(*) FROM receipt_details WHERE transaction_id NOT IN (SELECT id FROM transactions WHERE user_id <> ?1)`,
+	},
+	{
+		"reparse_runs",
+		`INSERT INTO reparse_runs (user_id, mode) VALUES (?1, 'llm')`,
+		`SELECT COUNT(*) FROM reparse_runs WHERE user_id = ?1`,
+	},
+	{
+		"reparse_changes",
+		`INSERT INTO reparse_changes (run_id, transaction_id, field)
+		 SELECT (SELECT MAX(id) FROM reparse_runs WHERE user_id = ?1), MAX(id), 'category' FROM transactions WHERE user_id = ?1`,
+		`SELECT COUNT(*) FROM reparse_changes WHERE transaction_id NOT IN (SELECT id FROM transactions WHERE user_id <> ?1)`,
+	},
+	{
+		"bulk_operations",
+		`INSERT INTO bulk_operations (user_id, token, action) VALUES (?1, 'token' || ?1, 'confirm')`,
+		`SELECT COUNT(*) FROM bulk_operations WHERE user_id = ?1`,
+	},
+	{
+		"fx_rates",
+		`INSERT INTO fx_rates (user_id, rate_date, from_currency, to_currency, rate) VALUES (?1, '2026-10-01', 'JPY', 'THB', 0.25)`,
+		`SELECT COUNT(*) FROM fx_rates WHERE user_id = ?1`,
+	},
+}
+
+func TestDeleteUser(t *testing.T) {
+	r := newTestRepository(t)
+	other, err := r.CreateUser("other")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, userID := range []int64{1, other.ID} {
+		for _, row := range userRows {
+			if _, err := r.db.Exec(row.insert, userID); err != nil {
+				t.Fatalf("%s: %v", row.table, err)
+			}
+		}
+	}
+
+	if err := r.DeleteUser(other.ID); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	for _, row := range userRows {
+		for userID, want := range map[int64]int{1: 1, other.ID: 0} {
+			var n int
+			if err := r.db.QueryRow(row.count, userID).Scan(&n); err != nil {
+				t.Fatalf("%s: %v", row.table, err)
+			}
+			if n != want {
+				t.Errorf("%s: user %d has %d rows, want %d", row.table, userID, n, want)
+			}
+		}
+	}
+	if _, err := r.GetUser(other.ID); err == nil {
+		t.Error("user was not deleted")
+	}
+}
@@ -0,0 +1,88 @@
+package fx
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"cash-track/internal/models"
+)
+
+// ParseRatesCSV reads exchange rates with the columns
+//
+//	date,currency,rate[,base]
+//
+// where rate is the number of base units per one unit of currency, e.g.
+// "2026-03-01,JPY,0.2391,THB". base defaults to defaultBase. A header row is
+// skipped if present.
+func ParseRatesCSV(r io.Reader, defaultBase string) ([]models.FXRate, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	var rates []models.FXRate
+	line := 0
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		line++
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if len(record) == 1 && strings.TrimSpace(record[0]) == "" {
+			continue
+		}
+		if line == 1 && strings.EqualFold(strings.TrimSpace(record[0]), "date") {
+			continue
+		}
+		if len(record) < 3 {
+			return nil, fmt.Errorf("line %d: expected date,currency,rate[,base]", line)
+		}
+
+		base := defaultBase
+		if len(record) > 3 && strings.TrimSpace(record[3]) != "" {
+			base = record[3]
+		}
+		rate, err := NewRate(record[0], record[1], base, record[2])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		rate.Source = models.FXSourceCSV
+		rates = append(rates, *rate)
+	}
+	return rates, nil
+}
+
+// NewRate validates and builds a manually entered rate
+func NewRate(date, currency, base, rate string) (*models.FXRate, error) {
+	date = strings.TrimSpace(date)
+	if _, err := time.Parse("2006-01-02", date); err != nil {
+		return nil, fmt.Errorf("invalid date %q (want YYYY-MM-DD)", date)
+	}
+	if !Valid(currency) {
+		return nil, fmt.Errorf("invalid currency %q", currency)
+	}
+	if base != "" && !Valid(base) {
+		return nil, fmt.Errorf("invalid base currency %q", base)
+	}
+	value, err := strconv.ParseFloat(strings.TrimSpace(rate), 64)
+	if err != nil || value <= 0 {
+		return nil, fmt.Errorf("invalid rate %q", rate)
+	}
+	if Normalize(currency) == Normalize(base) {
+		return nil, fmt.Errorf("currency and base are both %s", Normalize(base))
+	}
+
+	return &models.FXRate{
+		RateDate:     date,
+		FromCurrency: Normalize(currency),
+		ToCurrency:   Normalize(base),
+		Rate:         value,
+		Source:       models.FXSourceManual,
+	}, nil
+}
@@ -0,0 +1,37 @@
+package fx
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseRatesCSV(t *testing.T) {
+	input := "date,currency,rate,base\n2026-03-01,jpy,0.2391\n2026-03-01,USD,35.1,THB\n2026-03-02,THB,0.0285,usd\n"
+
+	rates, err := ParseRatesCSV(strings.NewReader(input), "THB")
+	if err != nil {
+		t.Fatalf("ParseRatesCSV: %v", err)
+	}
+	if len(rates) != 3 {
+		t.Fatalf("got %d rates, want 3", len(rates))
+	}
+	if rates[0].FromCurrency != "JPY" || rates[0].ToCurrency != "THB" || rates[0].Rate != 0.2391 {
+		t.Fatalf("rates[0] = %+v", rates[0])
+	}
+	if rates[2].FromCurrency != "THB" || rates[2].ToCurrency != "USD" {
+		t.Fatalf("rates[2] = %+v, want THB -> USD", rates[2])
+	}
+}
+
+func TestParseRatesCSVRejectsBadRows(t *testing.T) {
+	for _, input := range []string{
+		"2026-03-01,JPY,abc\n",
+		"03/01/2026,JPY,0.24\n",
+		"2026-03-01,THB,1\n",
+		"2026-03-01,JPY\n",
+	} {
+		if _, err := ParseRatesCSV(strings.NewReader(input), "THB"); err == nil {
+			t.Fatalf("ParseRatesCSV(%q) succeeded, want error", input)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+// Package fx knows about the currencies Cash Track handles and reads
+// exchange rates from CSV.
+package fx
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"cash-track/internal/models"
+)
+
+// DefaultCurrency is used when a transaction or user does not name one
+const DefaultCurrency = "THB"
+
+// Currency describes how amounts in a currency are written
+type Currency struct {
+	Code     string
+	Decimals int
+	ThaiName string
+}
+
+var currencies = map[string]Currency{
+	"THB": {Code: "THB", Decimals: 2, ThaiName: "บาท"},
+	"USD": {Code: "USD", Decimals: 2, ThaiName: "ดอลลาร์"},
+	"JPY": {Code: "JPY", Decimals: 0, ThaiName: "เยน"},
+	"LAK": {Code: "LAK", Decimals: 2, ThaiName: "กีบ"},
+	"EUR": {Code: "EUR", Decimals: 2, ThaiName: "ยูโร"},
+}
+
+// Codes returns the known currency codes, DefaultCurrency first
+func Codes() []string {
+	codes := []string{DefaultCurrency}
+	for code := range currencies {
+		if code != DefaultCurrency {
+			codes = append(codes, code)
+		}
+	}
+	sort.Strings(codes[1:])
+	return codes
+}
+
+// Lookup returns the currency for an ISO 4217 code. Unknown but well-formed
+// codes get two decimals and no Thai name.
+func Lookup(code string) Currency {
+	code = Normalize(code)
+	if c, ok := currencies[code]; ok {
+		return c
+	}
+	return Currency{Code: code, Decimals: 2}
+}
+
+// Normalize upper-cases a currency code and falls back to DefaultCurrency
+// for empty or malformed codes
+func Normalize(code string) string {
+	if !Valid(code) {
+		return DefaultCurrency
+	}
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// Valid reports whether code looks like an ISO 4217 currency code
+func Valid(code string) bool {
+	code = strings.TrimSpace(code)
+	if len(code) != 3 {
+		return false
+	}
+	for _, r := range code {
+		if (r < 'A' || r > 'Z') && (r < 'a' || r > 'z') {
+			return false
+		}
+	}
+	return true
+}
+
+// Format writes amount with the currency's decimals and its name in the
+// reply language, e.g. "50.00 บาท" or "1500 JPY".
+func Format(amount models.Money, code, lang string) string {
+	c := Lookup(code)
+	number := fmt.Sprintf("%.*f", c.Decimals, amount.Float64())
+	if lang != "en" && c.ThaiName != "" {
+		return number + " " + c.ThaiName
+	}
+	return number + " " + c.Code
+}
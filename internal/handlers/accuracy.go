@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"cash-track/internal/llm"
+	"cash-track/internal/models"
+)
+
+// recordExtraction keeps what the parser originally made of a transaction so
+// it can be compared with the confirmed values later
+func (h *Handler) recordExtraction(txID int64, ext llm.Extraction, confidence float64, values map[string]string) {
+	err := h.repo.RecordExtraction(txID, models.Extraction{
+		Source:        ext.Source,
+		Model:         ext.Model,
+		PromptVersion: ext.PromptVersion,
+		LatencyMS:     ext.LatencyMS,
+		Confidence:    confidence,
+		Values:        values,
+		Raw:           ext.Raw,
+	})
+	if err != nil {
+		log.Printf("Failed to record extraction for transaction %d: %v", txID, err)
+	}
+}
+
+// ListExtractions handles GET /api/transactions/{id}/extractions
+func (h *Handler) ListExtractions(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid transaction ID", http.StatusBadRequest)
+		return
+	}
+
+	userID, _ := h.currentUserID(w, r)
+	extractions, err := h.repo.ListExtractions(userID, id)
+	if err != nil {
+		log.Printf("Failed to list extractions of transaction %d: %v", id, err)
+		http.Error(w, "Failed to load extractions", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(extractions)
+}
+
+// AccuracyReport handles GET /api/reports/accuracy?from=YYYY-MM-DD&to=YYYY-MM-DD
+func (h *Handler) AccuracyReport(w http.ResponseWriter, r *http.Request) {
+	userID, _ := h.currentUserID(w, r)
+	report, err := h.repo.AccuracyReport(userID, r.URL.Query().Get("from"), r.URL.Query().Get("to"))
+	if err != nil {
+		log.Printf("Failed to build accuracy report: %v", err)
+		http.Error(w, "Failed to build accuracy report", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"cash-track/internal/database"
+	"cash-track/internal/models"
+	"cash-track/internal/search"
+)
+
+// bulkUndoWindow is how long a bulk action can be undone
+const bulkUndoWindow = 24 * time.Hour
+
+// BulkTransactions handles POST /api/transactions/bulk
+func (h *Handler) BulkTransactions(w http.ResponseWriter, r *http.Request) {
+	var req models.BulkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	switch req.Action {
+	case models.BulkConfirm, models.BulkDelete:
+	case models.BulkCategorize, models.BulkSetChannel, models.BulkSetAccount:
+		req.Value = strings.TrimSpace(req.Value)
+		if req.Action == models.BulkCategorize {
+			req.Value = strings.ToLower(req.Value)
+			if req.Value == "" {
+				http.Error(w, "value is required", http.StatusBadRequest)
+				return
+			}
+		}
+	case models.BulkSetReimbursable:
+		reimbursable, err := strconv.ParseBool(strings.TrimSpace(req.Value))
+		if err != nil {
+			http.Error(w, "value must be true or false", http.StatusBadRequest)
+			return
+		}
+		req.Value = strconv.FormatBool(reimbursable)
+	case models.BulkSetTaxItem:
+		req.Value = strings.TrimSpace(req.Value)
+		if _, ok := models.LookupTaxItem(req.Value); !ok && req.Value != "" && req.Value != models.TaxItemNone {
+			http.Error(w, "value must be a tax item, none or empty", http.StatusBadRequest)
+			return
+		}
+	case models.BulkAddTags:
+		req.Tags = models.NormalizeTags(req.Tags)
+		if len(req.Tags) == 0 {
+			http.Error(w, "tags are required", http.StatusBadRequest)
+			return
+		}
+	default:
+		http.Error(w, "action must be confirm, delete, categorize, set_channel, set_account, set_reimbursable, set_tax_item or add_tags", http.StatusBadRequest)
+		return
+	}
+
+	var filter models.SearchQuery
+	if len(req.IDs) == 0 {
+		var err error
+		if filter, err = search.Parse(req.Filter); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		// An empty filter would select everything
+		if filter.IsEmpty() {
+			http.Error(w, "ids or filter is required", http.StatusBadRequest)
+			return
+		}
+	}
+
+	h.expireBulkUndo()
+
+	userID, _ := h.currentUserID(w, r)
+	result, err := h.repo.ApplyBulk(userID, req, filter)
+	if errors.Is(err, database.ErrBulkLimit) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Printf("Bulk %s failed: %v", req.Action, err)
+		http.Error(w, "Bulk action failed", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// UndoBulkTransactions handles POST /api/transactions/bulk/undo
+func (h *Handler) UndoBulkTransactions(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Token string `json:"undo_token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" {
+		http.Error(w, "undo_token is required", http.StatusBadRequest)
+		return
+	}
+
+	h.expireBulkUndo()
+
+	userID, _ := h.currentUserID(w, r)
+	result, err := h.repo.UndoBulk(userID, req.Token)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "Undo token not found, already used or expired", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Bulk undo failed: %v", err)
+		http.Error(w, "Failed to undo bulk action", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// expireBulkUndo ends the undo window of old bulk actions
+func (h *Handler) expireBulkUndo() {
+	if err := h.repo.ExpireBulkUndo(time.Now().Add(-bulkUndoWindow)); err != nil {
+		log.Printf("Failed to expire bulk undo data: %v", err)
+	}
+}
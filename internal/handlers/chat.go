@@ -9,7 +9,12 @@ import (
 	"strings"
 	"time"
 
+	"cash-track/internal/fx"
 	"cash-track/internal/llm"
+	"cash-track/internal/models"
+	"cash-track/internal/ocr"
+	"cash-track/internal/period"
+	"cash-track/internal/search"
 )
 
 // ChatRequest represents the incoming chat message
@@ -45,11 +50,11 @@ func (h *Handler) Chat(w http.ResponseWriter, r *http.Request) {
 		if !filepath.IsAbs(imagePath) && !strings.Contains(imagePath, ":\\") {
 			imagePath = h.storage.GetPath(imagePath)
 		}
-		text, err := h.ocrClient.ExtractText(imagePath)
+		result, err := h.ocrClient.Extract(r.Context(), imagePath)
 		if err != nil {
 			log.Printf("OCR failed: %v", err)
 		} else {
-			ocrText = &text
+			ocrText = &result.Text
 		}
 	}
 
@@ -70,6 +75,10 @@ func (h *Handler) Chat(w http.ResponseWriter, r *http.Request) {
 		h.handleAddTransaction(w, r, req.Message, llmResp, req.ImagePath, ocrText, lang, req.TxID)
 	case "query_summary":
 		h.handleQuerySummary(w, r, llmResp, lang)
+	case "search_transactions":
+		h.handleSearch(w, r, llmResp, lang)
+	case "forecast":
+		h.handleForecast(w, r, llmResp, lang)
 	default:
 		respondChat(w, chatText(lang, "error_unknown"), nil, llmResp)
 	}
@@ -82,8 +91,9 @@ func (h *Handler) handleAddTransaction(w http.ResponseWriter, r *http.Request, m
 	}
 
 	tx := resp.Transaction
+	extracted := tx.Values()
 
-	if tx.Amount == 0 {
+	if !tx.Amount.Valid || tx.Amount.Money == 0 {
 		if message != "" {
 			tx.Amount = llm.ExtractAmountFromText(message)
 		} else if ocrText != nil {
@@ -92,16 +102,17 @@ func (h *Handler) handleAddTransaction(w http.ResponseWriter, r *http.Request, m
 	}
 
 	// Set defaults
-	if tx.Currency == "" {
-		tx.Currency = "THB"
-	}
+	tx.Currency = fx.Normalize(tx.Currency)
 	if tx.Direction == "" {
 		tx.Direction = "expense"
 	}
+	// #tags written in the message are kept even when the model missed them
+	messageTags, _ := llm.ParseTags(message)
+	tx.Tags = models.NormalizeTags(append(tx.Tags, messageTags...))
 
 	// Determine status based on completeness - pending if missing important fields
 	status := "confirmed"
-	if tx.Amount == 0 || tx.Category == "" || tx.Channel == "" {
+	if !tx.Amount.Valid || tx.Amount.Money == 0 || tx.Category == "" || tx.Channel == "" {
 		status = "pending"
 	}
 
@@ -128,15 +139,21 @@ func (h *Handler) handleAddTransaction(w http.ResponseWriter, r *http.Request, m
 			tx.AccountLabel,
 			tx.Category,
 			tx.Description,
+			tx.Tags,
 			message,
 			rawOCR,
 			resp.Confidence,
 			status,
+			resp.Source,
 		); err != nil {
 			log.Printf("Failed to update transaction %d: %v", *txID, err)
 			respondChat(w, chatText(lang, "save_failed"), nil, resp)
 			return
 		}
+		h.recordExtraction(*txID, resp.Extraction, resp.Confidence, extracted)
+		if rawOCR != "" {
+			h.saveReceipt(*txID, ocr.ParseSlipText(rawOCR).Receipt, resp.Receipt)
+		}
 
 		reply := buildTransactionReply(tx, status, lang)
 		respondChat(w, reply, txID, resp)
@@ -153,18 +170,24 @@ func (h *Handler) handleAddTransaction(w http.ResponseWriter, r *http.Request, m
 		tx.AccountLabel,
 		tx.Category,
 		tx.Description,
+		tx.Tags,
 		message,
 		slipPath,
 		rawOCR,
 		resp.Confidence,
 		status,
+		resp.Source,
 	)
 	if err != nil {
 		log.Printf("Failed to create transaction: %v", err)
 		respondChat(w, chatText(lang, "save_failed"), nil, resp)
 		return
 	}
-	log.Printf("Transaction created id=%d status=%s amount=%.2f", created.ID, status, tx.Amount)
+	log.Printf("Transaction created id=%d status=%s amount=%s", created.ID, status, tx.Amount)
+	h.recordExtraction(created.ID, resp.Extraction, resp.Confidence, extracted)
+	if rawOCR != "" {
+		h.saveReceipt(created.ID, ocr.ParseSlipText(rawOCR).Receipt, resp.Receipt)
+	}
 
 	// Build reply
 	reply := buildTransactionReply(tx, status, lang)
@@ -178,17 +201,15 @@ func (h *Handler) handleQuerySummary(w http.ResponseWriter, r *http.Request, res
 	}
 
 	filters := resp.Filters
+	filters.Tag = models.NormalizeTag(filters.Tag)
 
 	// Calculate date range based on period
 	userID, _ := h.currentUserID(w, r)
-	cutoff := 1
-	if user, err := h.repo.GetUser(userID); err == nil && user.CutoffDay >= 1 {
-		cutoff = user.CutoffDay
-	}
+	cutoff := h.cutoffDay(userID)
 	from, to := calculatePeriod(filters.Period, cutoff)
 
 	// Query database
-	summary, err := h.repo.QuerySummary(userID, filters.Direction, from, to, filters.Category, filters.Channel)
+	summary, err := h.repo.QuerySummary(userID, filters.Direction, from, to, filters.Category, filters.Channel, filters.Tag)
 	if err != nil {
 		log.Printf("Query failed: %v", err)
 		respondChat(w, chatText(lang, "fetch_failed"), nil, resp)
@@ -196,10 +217,144 @@ func (h *Handler) handleQuerySummary(w http.ResponseWriter, r *http.Request, res
 	}
 
 	// Build reply
-	reply := buildSummaryReplyText(summary.TotalExpense, summary.TotalIncome, filters, from, to, lang)
+	reply := buildSummaryReplyText(summary, filters, from, to, lang)
+	if filters.Category == "" && filters.Channel == "" && filters.Direction != "income" {
+		// Call out what changed from earlier periods
+		if full, err := h.repo.GetDashboardSummary(userID, from, to, filters.Tag); err != nil {
+			log.Printf("Failed to get summary for comparison: %v", err)
+		} else {
+			h.compareSummary(userID, full, cutoff, filters.Tag)
+			for _, callout := range summaryCallouts(full, lang) {
+				reply += "\n" + callout
+			}
+		}
+	}
 	respondChat(w, reply, nil, resp)
 }
 
+// chatSearchLimit caps how many matches a chat search reply lists, and
+// chatSearchMax how many it counts and totals
+const (
+	chatSearchLimit = 5
+	chatSearchMax   = 100
+)
+
+func (h *Handler) handleSearch(w http.ResponseWriter, r *http.Request, resp *llm.ChatResponse, lang string) {
+	if resp.Search == nil {
+		respondChat(w, chatText(lang, "error_unknown"), nil, resp)
+		return
+	}
+
+	q, err := search.Parse(resp.Search.Query)
+	if err != nil {
+		respondChat(w, chatText(lang, "error_unknown"), nil, resp)
+		return
+	}
+
+	userID, _ := h.currentUserID(w, r)
+	cutoff := h.cutoffDay(userID)
+	if resp.Search.Period.Type != "" && resp.Search.Period.Type != "all" {
+		q.From, q.To = calculatePeriod(resp.Search.Period, cutoff)
+	}
+	if q.IsEmpty() {
+		respondChat(w, chatText(lang, "error_unknown"), nil, resp)
+		return
+	}
+
+	results, err := h.repo.SearchTransactions(userID, q, chatSearchMax+1)
+	if err != nil {
+		log.Printf("Search failed: %v", err)
+		respondChat(w, chatText(lang, "fetch_failed"), nil, resp)
+		return
+	}
+	more := len(results) > chatSearchMax
+	if more {
+		results = results[:chatSearchMax]
+	}
+
+	respondChat(w, buildSearchReplyText(results, more, resp.Search.Query, q, lang), nil, resp)
+}
+
+// buildSearchReplyText summarises search results: the count, the expense
+// and income totals per currency and the newest few matches. more means
+// results holds only the newest matches, which the totals then cover.
+func buildSearchReplyText(results []models.SearchResult, more bool, query string, q models.SearchQuery, lang string) string {
+	period := ""
+	if q.From != "" || q.To != "" {
+		period = fmt.Sprintf(" (%s - %s)", q.From, q.To)
+	}
+	if len(results) == 0 {
+		if lang == "en" {
+			return fmt.Sprintf("No transactions found for \"%s\"%s", query, period)
+		}
+		return fmt.Sprintf("ไม่พบรายการ \"%s\"%s", query, period)
+	}
+
+	// Expense and income are totalled apart, each per currency
+	totals := map[string]map[string]models.Money{}
+	currencies := map[string][]string{}
+	for _, result := range results {
+		direction := result.Direction
+		if direction != "income" {
+			direction = "expense"
+		}
+		currency := fx.Normalize(result.Currency)
+		if totals[direction] == nil {
+			totals[direction] = map[string]models.Money{}
+		}
+		if _, ok := totals[direction][currency]; !ok {
+			currencies[direction] = append(currencies[direction], currency)
+		}
+		totals[direction][currency] += result.Amount
+	}
+	labels := map[string]string{"expense": "รายจ่าย", "income": "รายรับ"}
+	if lang == "en" {
+		labels = map[string]string{"expense": "expense", "income": "income"}
+	}
+	var totalParts []string
+	for _, direction := range []string{"expense", "income"} {
+		var amounts []string
+		for _, currency := range currencies[direction] {
+			amounts = append(amounts, fx.Format(totals[direction][currency], currency, lang))
+		}
+		if len(amounts) > 0 {
+			totalParts = append(totalParts, labels[direction]+" "+strings.Join(amounts, " + "))
+		}
+	}
+
+	var sb strings.Builder
+	switch {
+	case lang == "en" && more:
+		fmt.Fprintf(&sb, "Found more than %d transactions for \"%s\"%s; the newest %d: %s", len(results), query, period, len(results), strings.Join(totalParts, ", "))
+	case lang == "en":
+		fmt.Fprintf(&sb, "Found %d transactions for \"%s\"%s: %s", len(results), query, period, strings.Join(totalParts, ", "))
+	case more:
+		fmt.Fprintf(&sb, "พบมากกว่า %d รายการ \"%s\"%s %d รายการล่าสุด %s", len(results), query, period, len(results), strings.Join(totalParts, ", "))
+	default:
+		fmt.Fprintf(&sb, "พบ %d รายการ \"%s\"%s %s", len(results), query, period, strings.Join(totalParts, ", "))
+	}
+	for i, result := range results {
+		if i == chatSearchLimit {
+			if lang == "en" {
+				fmt.Fprintf(&sb, "\n…and %d more", len(results)-chatSearchLimit)
+			} else {
+				fmt.Fprintf(&sb, "\n…และอีก %d รายการ", len(results)-chatSearchLimit)
+			}
+			break
+		}
+		label := result.Description
+		if label == "" {
+			label = categoryLabel(result.Category, lang)
+		}
+		date := result.TxnDate
+		if date == "" {
+			date = result.CreatedAt
+		}
+		fmt.Fprintf(&sb, "\n• %s %s %s", date, label, fx.Format(result.Amount, result.Currency, lang))
+	}
+	return sb.String()
+}
+
 func respondChat(w http.ResponseWriter, text string, txID *int64, debug interface{}) {
 	resp := ChatResponse{
 		ReplyText:     text,
@@ -215,9 +370,9 @@ func buildTransactionReply(tx *llm.ParsedTransaction, status string, lang string
 
 	if status == "confirmed" {
 		if lang == "en" {
-			reply = fmt.Sprintf("Saved: %.2f THB", tx.Amount)
+			reply = fmt.Sprintf("Saved: %s", fx.Format(tx.Amount.Money, tx.Currency, lang))
 		} else {
-			reply = fmt.Sprintf("บันทึกแล้ว: %.2f บาท", tx.Amount)
+			reply = fmt.Sprintf("บันทึกแล้ว: %s", fx.Format(tx.Amount.Money, tx.Currency, lang))
 		}
 		if tx.Category != "" {
 			if lang == "en" {
@@ -232,12 +387,14 @@ func buildTransactionReply(tx *llm.ParsedTransaction, status string, lang string
 		if tx.Description != "" {
 			reply += fmt.Sprintf(" - %s", tx.Description)
 		}
+		reply += tagList(tx.Tags)
 	} else {
 		if lang == "en" {
-			reply = fmt.Sprintf("Saved %.2f THB - pending", tx.Amount)
+			reply = fmt.Sprintf("Saved %s - pending", fx.Format(tx.Amount.Money, tx.Currency, lang))
 		} else {
-			reply = fmt.Sprintf("บันทึก %.2f บาท - รอยืนยัน", tx.Amount)
+			reply = fmt.Sprintf("บันทึก %s - รอยืนยัน", fx.Format(tx.Amount.Money, tx.Currency, lang))
 		}
+		reply += tagList(tx.Tags)
 		var missing []string
 		if tx.Category == "" {
 			if lang == "en" {
@@ -265,26 +422,38 @@ func buildTransactionReply(tx *llm.ParsedTransaction, status string, lang string
 	return reply
 }
 
-func buildSummaryReplyText(totalExpense, totalIncome float64, filters *llm.QueryFilters, from, to string, lang string) string {
+// tagList formats tags as " #a #b", or "" when there are none
+func tagList(tags []string) string {
+	if len(tags) == 0 {
+		return ""
+	}
+	return " #" + strings.Join(tags, " #")
+}
+
+// buildSummaryReplyText describes summary, whose totals are in the user's
+// base currency
+func buildSummaryReplyText(summary *models.DashboardSummary, filters *llm.QueryFilters, from, to string, lang string) string {
 	var reply string
+	totalExpense := fx.Format(summary.TotalExpense, summary.Currency, lang)
+	totalIncome := fx.Format(summary.TotalIncome, summary.Currency, lang)
 
 	if filters.Direction == "expense" || filters.Direction == "" {
 		if lang == "en" {
-			reply = fmt.Sprintf("Spent %.2f THB", totalExpense)
+			reply = fmt.Sprintf("Spent %s", totalExpense)
 		} else {
-			reply = fmt.Sprintf("ใช้ไป %.2f บาท", totalExpense)
+			reply = fmt.Sprintf("ใช้ไป %s", totalExpense)
 		}
 	} else if filters.Direction == "income" {
 		if lang == "en" {
-			reply = fmt.Sprintf("Income %.2f THB", totalIncome)
+			reply = fmt.Sprintf("Income %s", totalIncome)
 		} else {
-			reply = fmt.Sprintf("รายรับ %.2f บาท", totalIncome)
+			reply = fmt.Sprintf("รายรับ %s", totalIncome)
 		}
 	} else {
 		if lang == "en" {
-			reply = fmt.Sprintf("Expense %.2f THB, Income %.2f THB", totalExpense, totalIncome)
+			reply = fmt.Sprintf("Expense %s, Income %s", totalExpense, totalIncome)
 		} else {
-			reply = fmt.Sprintf("รายจ่าย %.2f บาท, รายรับ %.2f บาท", totalExpense, totalIncome)
+			reply = fmt.Sprintf("รายจ่าย %s, รายรับ %s", totalExpense, totalIncome)
 		}
 	}
 
@@ -298,6 +467,9 @@ func buildSummaryReplyText(totalExpense, totalIncome float64, filters *llm.Query
 	if filters.Channel != "" {
 		reply += fmt.Sprintf(" (%s)", filters.Channel)
 	}
+	if filters.Tag != "" {
+		reply += fmt.Sprintf(" (#%s)", filters.Tag)
+	}
 	if from != "" && to != "" {
 		if lang == "en" {
 			reply += fmt.Sprintf(" from %s to %s", from, to)
@@ -305,66 +477,43 @@ func buildSummaryReplyText(totalExpense, totalIncome float64, filters *llm.Query
 			reply += fmt.Sprintf(" ช่วง %s ถึง %s", from, to)
 		}
 	}
+	if summary.Unconverted > 0 {
+		if lang == "en" {
+			reply += fmt.Sprintf(" (%d transactions skipped: no exchange rate)", summary.Unconverted)
+		} else {
+			reply += fmt.Sprintf(" (ไม่รวม %d รายการที่ไม่มีอัตราแลกเปลี่ยน)", summary.Unconverted)
+		}
+	}
 
 	return reply
 }
 
-func calculatePeriod(period llm.PeriodFilter, cutoffDay int) (string, string) {
+// calculatePeriod turns the period of a chat message into dates. "month"
+// is the user's current cycle, "last_month" the one before and "year" runs
+// from 1 January to today.
+func calculatePeriod(p llm.PeriodFilter, cutoffDay int) (string, string) {
 	now := time.Now()
 
-	switch period.Type {
+	name := period.CurrentCycle
+	switch p.Type {
 	case "month":
-		if period.From != "" && period.To != "" {
-			return period.From, period.To
+		if p.From != "" && p.To != "" {
+			return p.From, p.To
 		}
-		return cutoffRange(now, cutoffDay, 0)
+	case "last_month":
+		name = period.PreviousCycle
 	case "year":
-		firstDay := time.Date(now.Year(), 1, 1, 0, 0, 0, 0, now.Location())
-		lastDay := time.Date(now.Year(), 12, 31, 0, 0, 0, 0, now.Location())
-		return firstDay.Format("2006-01-02"), lastDay.Format("2006-01-02")
+		name = period.YearToDate
 	case "day":
 		today := now.Format("2006-01-02")
 		return today, today
 	case "range":
-		return period.From, period.To
+		return p.From, p.To
 	case "all":
 		return "1970-01-01", now.Format("2006-01-02")
-	default:
-		return cutoffRange(now, cutoffDay, 0)
-	}
-}
-
-func cutoffRange(now time.Time, cutoffDay int, offsetMonths int) (string, string) {
-	if cutoffDay < 1 || cutoffDay > 30 {
-		cutoffDay = 1
-	}
-	year, month := now.Year(), now.Month()
-	if offsetMonths != 0 {
-		date := time.Date(year, month, 1, 0, 0, 0, 0, now.Location()).AddDate(0, offsetMonths, 0)
-		year, month = date.Year(), date.Month()
-	}
-
-	var from, to time.Time
-	if now.Day() >= cutoffDay {
-		from = time.Date(year, month, clampDay(year, month, cutoffDay), 0, 0, 0, 0, now.Location())
-		to = time.Date(year, month+1, clampDay(year, month+1, cutoffDay-1), 0, 0, 0, 0, now.Location())
-	} else {
-		from = time.Date(year, month-1, clampDay(year, month-1, cutoffDay), 0, 0, 0, 0, now.Location())
-		to = time.Date(year, month, clampDay(year, month, cutoffDay-1), 0, 0, 0, 0, now.Location())
-	}
-
-	return from.Format("2006-01-02"), to.Format("2006-01-02")
-}
-
-func clampDay(year int, month time.Month, day int) int {
-	if day < 1 {
-		return 1
-	}
-	last := time.Date(year, month+1, 0, 0, 0, 0, 0, time.Local).Day()
-	if day > last {
-		return last
 	}
-	return day
+	r, _ := period.Named(name, now, cutoffDay)
+	return r.Dates()
 }
 
 func categoryLabel(category string, lang string) string {
@@ -418,6 +567,12 @@ func chatText(lang string, key string) string {
 			return "Unable to save the transaction."
 		case "fetch_failed":
 			return "Unable to fetch data."
+		case "spending":
+			return "Spending"
+		case "vs_previous":
+			return "vs last period"
+		case "vs_average":
+			return "vs its average over the last %d periods"
 		}
 	}
 
@@ -432,6 +587,12 @@ func chatText(lang string, key string) string {
 		return "ไม่สามารถบันทึกรายการได้"
 	case "fetch_failed":
 		return "ไม่สามารถดึงข้อมูลได้"
+	case "spending":
+		return "รายจ่าย"
+	case "vs_previous":
+		return "จากรอบก่อน"
+	case "vs_average":
+		return "จากค่าเฉลี่ย %d รอบก่อนหน้า"
 	}
 	return ""
 }
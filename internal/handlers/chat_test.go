@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"strings"
+	"testing"
+
+	"cash-track/internal/models"
+)
+
+func TestBuildSearchReplyText(t *testing.T) {
+	result := func(direction string, amount models.Money, currency string) models.SearchResult {
+		return models.SearchResult{TransactionView: models.TransactionView{
+			TxnDate: "2026-10-01", Description: "grab", Direction: direction, Amount: amount, Currency: currency,
+		}}
+	}
+	results := []models.SearchResult{
+		result("expense", 12000, "THB"),
+		result("income", 5000, "THB"),
+		result("expense", 30000, "JPY"),
+		result("expense", 8000, "THB"),
+	}
+
+	cases := []struct {
+		more bool
+		lang string
+		want string
+	}{
+		{false, "en", `Found 4 transactions for "grab": expense 200.00 THB + 300 JPY, income 50.00 THB`},
+		{true, "en", `Found more than 4 transactions for "grab"; the newest 4: expense 200.00 THB + 300 JPY, income 50.00 THB`},
+		{false, "th", `พบ 4 รายการ "grab" รายจ่าย 200.00 บาท + 300 เยน, รายรับ 50.00 บาท`},
+	}
+	for _, c := range cases {
+		got := buildSearchReplyText(results, c.more, "grab", models.SearchQuery{}, c.lang)
+		if first, _, _ := strings.Cut(got, "\n"); first != c.want {
+			t.Errorf("more=%v %s: %q, want %q", c.more, c.lang, first, c.want)
+		}
+	}
+}
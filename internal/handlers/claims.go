@@ -0,0 +1,352 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"cash-track/internal/database"
+	"cash-track/internal/models"
+)
+
+type claimRequest struct {
+	Title string `json:"title"`
+	Note  string `json:"note"`
+	// IDs are expenses to put on the new claim
+	IDs []int64 `json:"ids"`
+}
+
+type claimTransactionsRequest struct {
+	IDs []int64 `json:"ids"`
+}
+
+type claimPaidRequest struct {
+	// TransactionID is the income that paid the claim, if it was recorded
+	TransactionID int64 `json:"transaction_id"`
+}
+
+// claimID reads the claim ID from the URL, answering 400 when it is invalid
+func claimID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid claim ID", http.StatusBadRequest)
+		return 0, false
+	}
+	return id, true
+}
+
+// claimError answers with the status for err from a claim update
+func claimError(w http.ResponseWriter, err error, action string, id int64) {
+	switch {
+	case err == sql.ErrNoRows:
+		http.Error(w, "Claim not found", http.StatusNotFound)
+	case errors.Is(err, database.ErrClaimNotDraft), errors.Is(err, database.ErrClaimStatus):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, database.ErrInvalidReimbursement):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		log.Printf("Failed to %s claim %d: %v", action, id, err)
+		http.Error(w, "Failed to "+action+" claim", http.StatusInternalServerError)
+	}
+}
+
+// ListClaims handles GET /api/claims, optionally filtered by status
+func (h *Handler) ListClaims(w http.ResponseWriter, r *http.Request) {
+	userID, _ := h.currentUserID(w, r)
+
+	claims, err := h.repo.ListClaims(userID, r.URL.Query().Get("status"))
+	if err != nil {
+		log.Printf("Failed to list claims: %v", err)
+		http.Error(w, "Failed to load claims", http.StatusInternalServerError)
+		return
+	}
+	if claims == nil {
+		claims = []models.Claim{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"claims": claims,
+	})
+}
+
+// CreateClaim handles POST /api/claims. The expenses in ids, if any, go on
+// the new draft claim.
+func (h *Handler) CreateClaim(w http.ResponseWriter, r *http.Request) {
+	userID, _ := h.currentUserID(w, r)
+
+	var req claimRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.Title = strings.TrimSpace(req.Title)
+	if req.Title == "" {
+		http.Error(w, "Title is required", http.StatusBadRequest)
+		return
+	}
+
+	claim, err := h.repo.CreateClaim(models.Claim{
+		UserID: userID,
+		Title:  req.Title,
+		Note:   strings.TrimSpace(req.Note),
+	}, req.IDs)
+	if err != nil {
+		log.Printf("Failed to create claim: %v", err)
+		http.Error(w, "Failed to create claim", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(claim)
+}
+
+// GetClaim handles GET /api/claims/{id}, returning the claim and its
+// expenses
+func (h *Handler) GetClaim(w http.ResponseWriter, r *http.Request) {
+	userID, _ := h.currentUserID(w, r)
+	id, ok := claimID(w, r)
+	if !ok {
+		return
+	}
+
+	claim, views, err := h.loadClaim(userID, id)
+	if err != nil {
+		claimError(w, err, "load", id)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"claim":        claim,
+		"transactions": views,
+	})
+}
+
+// loadClaim returns one of the user's claims and its expenses
+func (h *Handler) loadClaim(userID, id int64) (*models.Claim, []models.TransactionView, error) {
+	claim, err := h.repo.GetClaim(userID, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	transactions, err := h.repo.ClaimTransactions(userID, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	views := []models.TransactionView{}
+	for _, tx := range transactions {
+		views = append(views, tx.ToView())
+	}
+	return claim, views, nil
+}
+
+// DeleteClaim handles DELETE /api/claims/{id}. Only drafts can be deleted;
+// their expenses stay marked reimbursable.
+func (h *Handler) DeleteClaim(w http.ResponseWriter, r *http.Request) {
+	userID, _ := h.currentUserID(w, r)
+	id, ok := claimID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.repo.DeleteClaim(userID, id); err != nil {
+		claimError(w, err, "delete", id)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+	})
+}
+
+// AddClaimTransactions handles POST /api/claims/{id}/transactions. Only
+// draft claims take expenses; ids that are not expenses or are on another
+// claim are skipped.
+func (h *Handler) AddClaimTransactions(w http.ResponseWriter, r *http.Request) {
+	userID, _ := h.currentUserID(w, r)
+	id, ok := claimID(w, r)
+	if !ok {
+		return
+	}
+
+	var req claimTransactionsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if len(req.IDs) == 0 {
+		http.Error(w, "ids are required", http.StatusBadRequest)
+		return
+	}
+
+	added, err := h.repo.AddClaimTransactions(userID, id, req.IDs)
+	if err != nil {
+		claimError(w, err, "update", id)
+		return
+	}
+	claim, err := h.repo.GetClaim(userID, id)
+	if err != nil {
+		claimError(w, err, "load", id)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"claim": claim,
+		"added": added,
+	})
+}
+
+// RemoveClaimTransaction handles DELETE /api/claims/{id}/transactions/{txID}
+func (h *Handler) RemoveClaimTransaction(w http.ResponseWriter, r *http.Request) {
+	userID, _ := h.currentUserID(w, r)
+	id, ok := claimID(w, r)
+	if !ok {
+		return
+	}
+	txID, err := strconv.ParseInt(chi.URLParam(r, "txID"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid transaction ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.repo.RemoveClaimTransaction(userID, id, txID); err != nil {
+		claimError(w, err, "update", id)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+	})
+}
+
+// SubmitClaim handles POST /api/claims/{id}/submit
+func (h *Handler) SubmitClaim(w http.ResponseWriter, r *http.Request) {
+	userID, _ := h.currentUserID(w, r)
+	id, ok := claimID(w, r)
+	if !ok {
+		return
+	}
+
+	claim, err := h.repo.SubmitClaim(userID, id)
+	if err != nil {
+		claimError(w, err, "submit", id)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(claim)
+}
+
+// PayClaim handles POST /api/claims/{id}/paid. transaction_id links the
+// income that paid the claim, after which the claim's expenses and that
+// income no longer count as personal spending and income.
+func (h *Handler) PayClaim(w http.ResponseWriter, r *http.Request) {
+	userID, _ := h.currentUserID(w, r)
+	id, ok := claimID(w, r)
+	if !ok {
+		return
+	}
+
+	var req claimPaidRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+
+	claim, err := h.repo.MarkClaimPaid(userID, id, req.TransactionID)
+	if err != nil {
+		claimError(w, err, "pay", id)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(claim)
+}
+
+// claimSlip is a slip image embedded in a claim report
+type claimSlip struct {
+	Transaction models.TransactionView
+	Image       template.URL
+}
+
+// ClaimReport handles GET /api/claims/{id}/report: a standalone printable
+// HTML page listing the claim's expenses with their slip images embedded,
+// so it can be saved, mailed or printed to PDF from the browser as is.
+// download=1 serves it as an attachment.
+func (h *Handler) ClaimReport(w http.ResponseWriter, r *http.Request) {
+	userID, _ := h.currentUserID(w, r)
+	id, ok := claimID(w, r)
+	if !ok {
+		return
+	}
+
+	claim, views, err := h.loadClaim(userID, id)
+	if err != nil {
+		claimError(w, err, "load", id)
+		return
+	}
+	user, _ := h.repo.GetUser(userID)
+
+	var slips []claimSlip
+	for _, view := range views {
+		if view.SlipImagePath == "" {
+			continue
+		}
+		image, err := h.slipDataURL(view.SlipImagePath)
+		if err != nil {
+			log.Printf("Failed to read slip of transaction %d for claim %d: %v", view.ID, id, err)
+			continue
+		}
+		slips = append(slips, claimSlip{Transaction: view, Image: image})
+	}
+
+	tmpl, err := template.ParseFiles(filepath.Join(h.templateDir, "claim_report.html"))
+	if err != nil {
+		log.Printf("Failed to parse claim report template: %v", err)
+		http.Error(w, "Template error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if r.URL.Query().Get("download") == "1" {
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="claim-%d.html"`, claim.ID))
+	}
+	err = tmpl.ExecuteTemplate(w, "claim_report", map[string]interface{}{
+		"Claim":        claim,
+		"User":         user,
+		"Transactions": views,
+		"Slips":        slips,
+	})
+	if err != nil {
+		log.Printf("Failed to render claim report %d: %v", id, err)
+	}
+}
+
+// slipDataURL reads a stored slip image as a data URL
+func (h *Handler) slipDataURL(filename string) (template.URL, error) {
+	data, err := os.ReadFile(h.storage.GetPath(filename))
+	if err != nil {
+		return "", err
+	}
+	mediaType := http.DetectContentType(data)
+	if !strings.HasPrefix(mediaType, "image/") {
+		return "", fmt.Errorf("slip is %s, not an image", mediaType)
+	}
+	return template.URL("data:" + mediaType + ";base64," + base64.StdEncoding.EncodeToString(data)), nil
+}
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"math"
+
+	"cash-track/internal/models"
+	"cash-track/internal/period"
+)
+
+// anomalyPeriods is how many earlier periods the trailing average covers
+const anomalyPeriods = 6
+
+// calloutMinPercent is the smallest category change worth a chat callout
+const calloutMinPercent = 20
+
+// previousPeriod returns the period of the same kind just before p, or a
+// zero Period when p is not a valid range
+func previousPeriod(p models.Period, cutoffDay int) models.Period {
+	r, err := period.Parse(p.From, p.To)
+	if err != nil {
+		return models.Period{}
+	}
+	return toPeriod(period.Previous(r, cutoffDay))
+}
+
+// yearBefore returns the period equivalent to p a year earlier
+func yearBefore(p models.Period, cutoffDay int) models.Period {
+	r, err := period.Parse(p.From, p.To)
+	if err != nil {
+		return models.Period{}
+	}
+	return toPeriod(period.YearBefore(r, cutoffDay))
+}
+
+func toPeriod(r period.Range) models.Period {
+	from, to := r.Dates()
+	return models.Period{From: from, To: to}
+}
+
+// compareSummary fills in the comparison with the previous period and the
+// same period last year, and flags categories far from their trailing
+// average. Earlier periods are limited to the same tag as the summary.
+// Failures are logged and leave the comparison out.
+func (h *Handler) compareSummary(userID int64, summary *models.DashboardSummary, cutoffDay int, tag string) {
+	prev := previousPeriod(summary.Period, cutoffDay)
+	if prev.From == "" {
+		return
+	}
+	previous, err := h.repo.GetDashboardSummary(userID, prev.From, prev.To, tag)
+	if err != nil {
+		log.Printf("Failed to get summary of %s..%s: %v", prev.From, prev.To, err)
+		return
+	}
+	summary.PreviousPeriod = models.Compare(summary, previous)
+
+	year := yearBefore(summary.Period, cutoffDay)
+	if lastYear, err := h.repo.GetDashboardSummary(userID, year.From, year.To, tag); err != nil {
+		log.Printf("Failed to get summary of %s..%s: %v", year.From, year.To, err)
+	} else {
+		summary.PreviousYear = models.Compare(summary, lastYear)
+	}
+
+	history := [][]models.CategoryAmount{previous.ByCategory}
+	for p := prev; len(history) < anomalyPeriods; {
+		p = previousPeriod(p, cutoffDay)
+		categories, err := h.repo.GetExpenseByCategory(userID, p.From, p.To, tag)
+		if err != nil {
+			log.Printf("Failed to get categories of %s..%s: %v", p.From, p.To, err)
+			return
+		}
+		history = append(history, categories)
+	}
+	summary.Anomalies = models.FindAnomalies(summary.ByCategory, history)
+}
+
+// summaryCallouts describes the notable changes of a compared summary for
+// a chat reply: the total against the previous period, then the categories
+// far from their average or, failing that, the largest category change
+func summaryCallouts(summary *models.DashboardSummary, lang string) []string {
+	var callouts []string
+	if cmp := summary.PreviousPeriod; cmp != nil && cmp.TotalExpense.Percent != nil {
+		callouts = append(callouts, changeText(chatText(lang, "spending"), *cmp.TotalExpense.Percent, chatText(lang, "vs_previous"), lang))
+	}
+
+	for i, a := range summary.Anomalies {
+		if i == 2 {
+			break
+		}
+		average := fmt.Sprintf(chatText(lang, "vs_average"), a.Periods)
+		callouts = append(callouts, changeText(categorySubject(a.Category, lang), a.Percent, average, lang))
+	}
+	if len(summary.Anomalies) > 0 || summary.PreviousPeriod == nil {
+		return callouts
+	}
+	for _, c := range summary.PreviousPeriod.ByCategory {
+		if c.Percent != nil && math.Abs(*c.Percent) >= calloutMinPercent {
+			callouts = append(callouts, changeText(categorySubject(c.Key, lang), *c.Percent, chatText(lang, "vs_previous"), lang))
+			break
+		}
+	}
+	return callouts
+}
+
+func categorySubject(category string, lang string) string {
+	if lang == "en" {
+		return categoryLabel(category, lang)
+	}
+	return "หมวด" + categoryLabel(category, lang)
+}
+
+// changeText reads e.g. "food is up 42% vs last period"
+func changeText(subject string, percent float64, against string, lang string) string {
+	if lang == "en" {
+		direction := "up"
+		if percent < 0 {
+			direction = "down"
+		}
+		return fmt.Sprintf("%s is %s %.0f%% %s", subject, direction, math.Abs(percent), against)
+	}
+	direction := "เพิ่มขึ้น"
+	if percent < 0 {
+		direction = "ลดลง"
+	}
+	return fmt.Sprintf("%s%s %.0f%% %s", subject, direction, math.Abs(percent), against)
+}
@@ -2,23 +2,38 @@ package handlers
 
 import (
 	"encoding/json"
+	"fmt"
+	"log"
 	"net/http"
 	"strconv"
 	"time"
 
 	"cash-track/internal/models"
+	"cash-track/internal/period"
 )
 
+// dashboardTag returns the tag the dashboard is limited to by ?tag=, or ""
+func dashboardTag(r *http.Request) string {
+	return models.NormalizeTag(r.URL.Query().Get("tag"))
+}
+
 // DashboardSummary handles GET /api/dashboard/summary
 func (h *Handler) DashboardSummary(w http.ResponseWriter, r *http.Request) {
-	from, to := getDateRange(r)
 	userID, _ := h.currentUserID(w, r)
+	from, to, ok := h.dateRange(w, r, userID)
+	if !ok {
+		return
+	}
+	tag := dashboardTag(r)
 
-	summary, err := h.repo.GetDashboardSummary(userID, from, to)
+	summary, err := h.repo.GetDashboardSummary(userID, from, to, tag)
 	if err != nil {
 		http.Error(w, "Failed to get summary", http.StatusInternalServerError)
 		return
 	}
+	if r.URL.Query().Get("compare") != "false" {
+		h.compareSummary(userID, summary, h.cutoffDay(userID), tag)
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(summary)
@@ -26,10 +41,13 @@ func (h *Handler) DashboardSummary(w http.ResponseWriter, r *http.Request) {
 
 // DashboardByCategory handles GET /api/dashboard/by-category
 func (h *Handler) DashboardByCategory(w http.ResponseWriter, r *http.Request) {
-	from, to := getDateRange(r)
 	userID, _ := h.currentUserID(w, r)
+	from, to, ok := h.dateRange(w, r, userID)
+	if !ok {
+		return
+	}
 
-	categories, err := h.repo.GetExpenseByCategory(userID, from, to)
+	categories, err := h.repo.GetExpenseByCategory(userID, from, to, dashboardTag(r))
 	if err != nil {
 		http.Error(w, "Failed to get category data", http.StatusInternalServerError)
 		return
@@ -41,10 +59,13 @@ func (h *Handler) DashboardByCategory(w http.ResponseWriter, r *http.Request) {
 
 // DashboardByChannel handles GET /api/dashboard/by-channel
 func (h *Handler) DashboardByChannel(w http.ResponseWriter, r *http.Request) {
-	from, to := getDateRange(r)
 	userID, _ := h.currentUserID(w, r)
+	from, to, ok := h.dateRange(w, r, userID)
+	if !ok {
+		return
+	}
 
-	channels, err := h.repo.GetExpenseByChannel(userID, from, to)
+	channels, err := h.repo.GetExpenseByChannel(userID, from, to, dashboardTag(r))
 	if err != nil {
 		http.Error(w, "Failed to get channel data", http.StatusInternalServerError)
 		return
@@ -54,10 +75,48 @@ func (h *Handler) DashboardByChannel(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(channels)
 }
 
+// DashboardByTag handles GET /api/dashboard/by-tag. A transaction with
+// several tags counts towards each, so the shares can add up to more than
+// 100%.
+func (h *Handler) DashboardByTag(w http.ResponseWriter, r *http.Request) {
+	userID, _ := h.currentUserID(w, r)
+	from, to, ok := h.dateRange(w, r, userID)
+	if !ok {
+		return
+	}
+
+	tags, err := h.repo.GetExpenseByTag(userID, from, to)
+	if err != nil {
+		log.Printf("Failed to get tag data: %v", err)
+		http.Error(w, "Failed to get tag data", http.StatusInternalServerError)
+		return
+	}
+	if tags == nil {
+		tags = []models.TagAmount{}
+	}
+	summary, err := h.repo.GetDashboardSummary(userID, from, to, "")
+	if err != nil {
+		log.Printf("Failed to get summary: %v", err)
+		http.Error(w, "Failed to get tag data", http.StatusInternalServerError)
+		return
+	}
+	for i := range tags {
+		if summary.TotalExpense > 0 {
+			tags[i].PercentOfExpense = float64(tags[i].Amount) / float64(summary.TotalExpense) * 100
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tags)
+}
+
 // DashboardTransactions handles GET /api/dashboard/transactions
 func (h *Handler) DashboardTransactions(w http.ResponseWriter, r *http.Request) {
-	from, to := getDateRange(r)
 	userID, _ := h.currentUserID(w, r)
+	from, to, ok := h.dateRange(w, r, userID)
+	if !ok {
+		return
+	}
 
 	limit := 200
 	if v := r.URL.Query().Get("limit"); v != "" {
@@ -68,18 +127,17 @@ func (h *Handler) DashboardTransactions(w http.ResponseWriter, r *http.Request)
 			limit = parsed
 		}
 	}
-	category := r.URL.Query().Get("category")
-	channel := r.URL.Query().Get("channel")
 
-	var (
-		transactions []models.Transaction
-		err          error
-	)
-	if category != "" || channel != "" {
-		transactions, err = h.repo.ListTransactionsByRangeFiltered(userID, from, to, category, channel, limit)
-	} else {
-		transactions, err = h.repo.ListTransactionsByRange(userID, from, to, limit)
-	}
+	transactions, _, err := h.repo.QueryTransactions(models.TransactionQuery{
+		UserID:   userID,
+		From:     from,
+		To:       to,
+		Category: r.URL.Query().Get("category"),
+		Channel:  r.URL.Query().Get("channel"),
+		Tag:      dashboardTag(r),
+		Sort:     models.SortTxnDate,
+		Limit:    limit,
+	})
 	if err != nil {
 		http.Error(w, "Failed to get transactions", http.StatusInternalServerError)
 		return
@@ -94,24 +152,116 @@ func (h *Handler) DashboardTransactions(w http.ResponseWriter, r *http.Request)
 	json.NewEncoder(w).Encode(views)
 }
 
-// DashboardPage renders the dashboard UI
-func (h *Handler) DashboardPage(w http.ResponseWriter, r *http.Request) {
-	h.renderTemplate(w, "dashboard.html", h.withUserContext(w, r, nil))
+// maxTimeBuckets bounds the size of a time series
+const maxTimeBuckets = 1000
+
+// DashboardTimeSeries handles GET /api/dashboard/timeseries
+// ?interval=day|week|month|cutoff-period&split=category&tag=
+func (h *Handler) DashboardTimeSeries(w http.ResponseWriter, r *http.Request) {
+	userID, _ := h.currentUserID(w, r)
+	from, to, ok := h.dateRange(w, r, userID)
+	if !ok {
+		return
+	}
+
+	interval := r.URL.Query().Get("interval")
+	if interval == "" {
+		interval = models.IntervalDay
+	}
+	cutoff := h.cutoffDay(userID)
+	buckets, err := timeBuckets(from, to, interval, cutoff)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	series, err := h.repo.GetTimeSeries(userID, interval, buckets, r.URL.Query().Get("split") == "category", dashboardTag(r))
+	if err != nil {
+		log.Printf("Failed to get time series: %v", err)
+		http.Error(w, "Failed to get time series", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(series)
+}
+
+// timeBuckets splits from..to into the date ranges of interval. The first
+// and last ranges are cut to from and to.
+func timeBuckets(from, to, interval string, cutoffDay int) ([]models.Period, error) {
+	start, err := time.Parse("2006-01-02", from)
+	if err != nil {
+		return nil, fmt.Errorf("invalid from date %q", from)
+	}
+	end, err := time.Parse("2006-01-02", to)
+	if err != nil {
+		return nil, fmt.Errorf("invalid to date %q", to)
+	}
+	if end.Before(start) {
+		return nil, fmt.Errorf("from date is after to date")
+	}
+
+	// next returns the first day after the bucket holding day
+	var next func(day time.Time) time.Time
+	switch interval {
+	case models.IntervalDay:
+		next = func(day time.Time) time.Time { return day.AddDate(0, 0, 1) }
+	case models.IntervalWeek:
+		next = func(day time.Time) time.Time {
+			return day.AddDate(0, 0, 7-(int(day.Weekday())+6)%7)
+		}
+	case models.IntervalMonth:
+		next = func(day time.Time) time.Time {
+			return time.Date(day.Year(), day.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+		}
+	case models.IntervalCutoffPeriod:
+		next = func(day time.Time) time.Time {
+			return period.Cycle(day, cutoffDay).To.AddDate(0, 0, 1)
+		}
+	default:
+		return nil, fmt.Errorf("unknown interval %q", interval)
+	}
+
+	var buckets []models.Period
+	for day := start; !day.After(end); day = next(day) {
+		if len(buckets) == maxTimeBuckets {
+			return nil, fmt.Errorf("range is too long for interval %q", interval)
+		}
+		last := next(day).AddDate(0, 0, -1)
+		if last.After(end) {
+			last = end
+		}
+		buckets = append(buckets, models.Period{From: day.Format("2006-01-02"), To: last.Format("2006-01-02")})
+	}
+	return buckets, nil
 }
 
-// getDateRange extracts from/to dates from query params, defaults to current month
-func getDateRange(r *http.Request) (string, string) {
-	from := r.URL.Query().Get("from")
-	to := r.URL.Query().Get("to")
+// DashboardPage renders the dashboard UI
+func (h *Handler) DashboardPage(w http.ResponseWriter, r *http.Request) {
+	userID, _ := h.currentUserID(w, r)
+	cutoff := h.cutoffDay(userID)
 
-	if from == "" || to == "" {
-		// Default to current month
-		now := time.Now()
-		firstDay := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
-		lastDay := firstDay.AddDate(0, 1, -1)
-		from = firstDay.Format("2006-01-02")
-		to = lastDay.Format("2006-01-02")
+	// The range buttons carry their dates so the page needs no date math
+	periods := map[string]models.Period{}
+	for _, name := range period.Names {
+		p, _ := period.Named(name, time.Now(), cutoff)
+		periods[name] = toPeriod(p)
 	}
+	h.renderTemplate(w, "dashboard.html", h.withUserContext(w, r, map[string]interface{}{
+		"Periods": periods,
+	}))
+}
 
-	return from, to
+// dateRange resolves the ?period= name, or ?from=&to=, against the user's
+// cutoff day. Without either it is the current cycle. Bad input is answered
+// with 400 and ok is false.
+func (h *Handler) dateRange(w http.ResponseWriter, r *http.Request, userID int64) (from, to string, ok bool) {
+	q := r.URL.Query()
+	p, err := period.Resolve(q.Get("period"), q.Get("from"), q.Get("to"), time.Now(), h.cutoffDay(userID))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return "", "", false
+	}
+	from, to = p.Dates()
+	return from, to, true
 }
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"testing"
+
+	"cash-track/internal/models"
+)
+
+func TestTimeBuckets(t *testing.T) {
+	cases := []struct {
+		interval string
+		from, to string
+		cutoff   int
+		want     []models.Period
+	}{
+		{models.IntervalDay, "2026-02-27", "2026-03-01", 1, []models.Period{
+			{From: "2026-02-27", To: "2026-02-27"},
+			{From: "2026-02-28", To: "2026-02-28"},
+			{From: "2026-03-01", To: "2026-03-01"},
+		}},
+		// 2026-10-14 is a Wednesday; weeks run Monday to Sunday
+		{models.IntervalWeek, "2026-10-14", "2026-10-27", 1, []models.Period{
+			{From: "2026-10-14", To: "2026-10-18"},
+			{From: "2026-10-19", To: "2026-10-25"},
+			{From: "2026-10-26", To: "2026-10-27"},
+		}},
+		{models.IntervalMonth, "2026-01-15", "2026-03-10", 1, []models.Period{
+			{From: "2026-01-15", To: "2026-01-31"},
+			{From: "2026-02-01", To: "2026-02-28"},
+			{From: "2026-03-01", To: "2026-03-10"},
+		}},
+		{models.IntervalCutoffPeriod, "2026-01-01", "2026-03-31", 25, []models.Period{
+			{From: "2026-01-01", To: "2026-01-24"},
+			{From: "2026-01-25", To: "2026-02-24"},
+			{From: "2026-02-25", To: "2026-03-24"},
+			{From: "2026-03-25", To: "2026-03-31"},
+		}},
+	}
+
+	for _, tc := range cases {
+		got, err := timeBuckets(tc.from, tc.to, tc.interval, tc.cutoff)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.interval, err)
+		}
+		if len(got) != len(tc.want) {
+			t.Fatalf("%s: got %v, want %v", tc.interval, got, tc.want)
+		}
+		for i := range got {
+			if got[i] != tc.want[i] {
+				t.Errorf("%s: bucket %d = %v, want %v", tc.interval, i, got[i], tc.want[i])
+			}
+		}
+	}
+}
+
+func TestTimeBucketsRejectsBadInput(t *testing.T) {
+	if _, err := timeBuckets("2026-01-01", "2026-01-31", "hour", 1); err == nil {
+		t.Error("unknown interval accepted")
+	}
+	if _, err := timeBuckets("2026-02-01", "2026-01-01", models.IntervalDay, 1); err == nil {
+		t.Error("reversed range accepted")
+	}
+	if _, err := timeBuckets("2000-01-01", "2026-01-01", models.IntervalDay, 1); err == nil {
+		t.Error("26 years of days accepted")
+	}
+}
+
+func TestComparisonPeriods(t *testing.T) {
+	cases := []struct {
+		from, to     string
+		cutoff       int
+		prev, lastYr models.Period
+	}{
+		// A cutoff cycle
+		{"2026-09-25", "2026-10-24", 25,
+			models.Period{From: "2026-08-25", To: "2026-09-24"},
+			models.Period{From: "2025-09-25", To: "2025-10-24"}},
+		// A calendar month and a calendar year
+		{"2026-03-01", "2026-03-31", 25,
+			models.Period{From: "2026-02-01", To: "2026-02-28"},
+			models.Period{From: "2025-03-01", To: "2025-03-31"}},
+		{"2026-01-01", "2026-12-31", 1,
+			models.Period{From: "2025-01-01", To: "2025-12-31"},
+			models.Period{From: "2025-01-01", To: "2025-12-31"}},
+		// Any other range moves back by its length
+		{"2026-10-10", "2026-10-16", 1,
+			models.Period{From: "2026-10-03", To: "2026-10-09"},
+			models.Period{From: "2025-10-10", To: "2025-10-16"}},
+	}
+
+	for _, tc := range cases {
+		p := models.Period{From: tc.from, To: tc.to}
+		if got := previousPeriod(p, tc.cutoff); got != tc.prev {
+			t.Errorf("previousPeriod(%v) = %v, want %v", p, got, tc.prev)
+		}
+		if got := yearBefore(p, tc.cutoff); got != tc.lastYr {
+			t.Errorf("yearBefore(%v) = %v, want %v", p, got, tc.lastYr)
+		}
+	}
+}
@@ -0,0 +1,373 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"cash-track/internal/database"
+	"cash-track/internal/httpfixture"
+	"cash-track/internal/jobs"
+	"cash-track/internal/llm"
+	"cash-track/internal/models"
+	"cash-track/internal/ocr"
+	"cash-track/internal/storage"
+)
+
+// newTestServer wires the handlers to a temporary SQLite database and to
+// replays of the named Ollama and OCR fixtures, the way cmd/server does
+func newTestServer(t *testing.T, ollamaFixture, ocrFixture string) *httptest.Server {
+	t.Helper()
+	dir := t.TempDir()
+
+	db, err := database.New(filepath.Join(dir, "cash-track.db"))
+	if err != nil {
+		t.Fatalf("database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	repo := database.NewRepository(db)
+
+	store, err := storage.NewLocalStorage(filepath.Join(dir, "uploads"))
+	if err != nil {
+		t.Fatalf("storage: %v", err)
+	}
+
+	ollama := httpfixture.Server(t, "testdata/"+ollamaFixture, os.Getenv("OLLAMA_URL"))
+	ocrURL := "http://127.0.0.1:0" // unused unless the test uploads slips
+	if ocrFixture != "" {
+		ocrURL = httpfixture.Server(t, "testdata/"+ocrFixture, os.Getenv("OCR_ENDPOINT")).URL
+	}
+
+	queue := jobs.NewQueue(repo, 1, 1)
+	h, err := New(repo, store, ocr.NewClient(ocrURL, 10*time.Second, 0), llm.NewClient(ollama.URL, "llama3.2"), queue, "../../web/templates", Options{})
+	if err != nil {
+		t.Fatalf("handlers: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := queue.Start(ctx); err != nil {
+		t.Fatalf("queue: %v", err)
+	}
+	// Cleanups run last-in first-out: stop the workers before the fixture
+	// servers and the database go away
+	t.Cleanup(func() {
+		cancel()
+		queue.Wait()
+	})
+
+	r := chi.NewRouter()
+	r.Post("/api/chat", h.Chat)
+	r.Post("/api/transactions/slip", h.UploadSlip)
+	r.Get("/api/transactions/{id}", h.GetTransaction)
+	r.Get("/api/transactions/{id}/status", h.TransactionStatus)
+	r.Get("/api/transactions/{id}/extractions", h.ListExtractions)
+	r.Get("/api/transactions/{id}/receipt", h.GetReceipt)
+	r.Get("/api/transactions/search", h.SearchTransactions)
+	r.Get("/api/receipts/export", h.ExportReceipts)
+	r.Get("/api/dashboard/summary", h.DashboardSummary)
+	r.Get("/api/dashboard/by-category", h.DashboardByCategory)
+	r.Get("/api/dashboard/by-channel", h.DashboardByChannel)
+	r.Get("/api/dashboard/by-tag", h.DashboardByTag)
+	r.Get("/api/dashboard/transactions", h.DashboardTransactions)
+	r.Get("/api/dashboard/timeseries", h.DashboardTimeSeries)
+	r.Post("/api/claims", h.CreateClaim)
+	r.Post("/api/claims/{id}/submit", h.SubmitClaim)
+	r.Post("/api/claims/{id}/paid", h.PayClaim)
+	r.Get("/api/claims/{id}/report", h.ClaimReport)
+
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// getJSON fetches path and decodes the JSON answer into v
+func getJSON(t *testing.T, srv *httptest.Server, path string, v interface{}) {
+	t.Helper()
+	resp, err := http.Get(srv.URL + path)
+	if err != nil {
+		t.Fatalf("GET %s: %v", path, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET %s: status %d", path, resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		t.Fatalf("GET %s: %v", path, err)
+	}
+}
+
+// postJSON posts body as JSON to path and decodes the JSON answer into v
+func postJSON(t *testing.T, srv *httptest.Server, path string, body, v interface{}) {
+	t.Helper()
+	data, _ := json.Marshal(body)
+	resp, err := http.Post(srv.URL+path, "application/json", bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("POST %s: %v", path, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("POST %s: status %d", path, resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		t.Fatalf("POST %s: %v", path, err)
+	}
+}
+
+func chat(t *testing.T, srv *httptest.Server, message string) ChatResponse {
+	t.Helper()
+	body, _ := json.Marshal(ChatRequest{Message: message, Lang: "th"})
+	resp, err := http.Post(srv.URL+"/api/chat", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("chat %q: %v", message, err)
+	}
+	defer resp.Body.Close()
+	var reply ChatResponse
+	if err := json.NewDecoder(resp.Body).Decode(&reply); err != nil {
+		t.Fatalf("chat %q: %v", message, err)
+	}
+	return reply
+}
+
+func TestChatAndDashboard(t *testing.T) {
+	srv := newTestServer(t, "ollama_chat.json", "")
+
+	food := chat(t, srv, "ข้าวมันไก่ 60 บาท เงินสด #Lunch")
+	if food.TransactionID == nil {
+		t.Fatalf("reply = %+v, want a transaction", food)
+	}
+	taxi := chat(t, srv, "ค่าแท็กซี่ 120")
+	if taxi.TransactionID == nil {
+		t.Fatalf("reply = %+v, want a transaction", taxi)
+	}
+
+	var view models.TransactionView
+	getJSON(t, srv, "/api/transactions/"+strconv.FormatInt(*food.TransactionID, 10), &view)
+	if view.Status != "confirmed" || view.Amount.String() != "60.00" || view.Category != "food" || view.Channel != "cash" {
+		t.Errorf("food = %+v", view)
+	}
+	// The model left the tag out, but it was written in the message
+	if len(view.Tags) != 1 || view.Tags[0] != "lunch" {
+		t.Errorf("food tags = %q, want [lunch]", view.Tags)
+	}
+	getJSON(t, srv, "/api/transactions/"+strconv.FormatInt(*taxi.TransactionID, 10), &view)
+	if view.Status != "pending" {
+		t.Errorf("taxi status = %q, want pending without a channel", view.Status)
+	}
+
+	var extractions []models.Extraction
+	getJSON(t, srv, "/api/transactions/"+strconv.FormatInt(*food.TransactionID, 10)+"/extractions", &extractions)
+	if len(extractions) != 1 || extractions[0].Source != models.ActorLLM || extractions[0].Model != "llama3.2" {
+		t.Errorf("extractions = %+v", extractions)
+	}
+
+	// Only confirmed transactions count towards the dashboard
+	const period = "?from=2026-10-01&to=2026-10-31"
+	var summary models.DashboardSummary
+	getJSON(t, srv, "/api/dashboard/summary"+period, &summary)
+	if summary.TotalExpense.String() != "60.00" || summary.TotalIncome != 0 {
+		t.Errorf("summary = %+v", summary)
+	}
+
+	var categories []models.CategoryAmount
+	getJSON(t, srv, "/api/dashboard/by-category"+period, &categories)
+	if len(categories) != 1 || categories[0].Category != "food" {
+		t.Errorf("by category = %+v", categories)
+	}
+
+	var channels []models.ChannelAmount
+	getJSON(t, srv, "/api/dashboard/by-channel"+period, &channels)
+	if len(channels) != 1 || channels[0].Channel != "cash" {
+		t.Errorf("by channel = %+v", channels)
+	}
+
+	var series models.TimeSeries
+	getJSON(t, srv, "/api/dashboard/timeseries"+period+"&interval=day&split=category", &series)
+	if len(series.Buckets) != 31 {
+		t.Fatalf("got %d daily buckets, want 31", len(series.Buckets))
+	}
+	for _, b := range series.Buckets {
+		want := models.Money(0)
+		if b.From == "2026-10-15" {
+			want = models.NewMoney(60)
+		}
+		if b.Expense != want || b.ByCategory["food"] != want {
+			t.Errorf("bucket %s = %+v, want %s of food", b.From, b, want)
+		}
+	}
+
+	var listed []models.TransactionView
+	getJSON(t, srv, "/api/dashboard/transactions"+period+"&category=food", &listed)
+	if len(listed) != 1 || listed[0].ID != *food.TransactionID {
+		t.Errorf("transactions = %+v", listed)
+	}
+
+	var tags []models.TagAmount
+	getJSON(t, srv, "/api/dashboard/by-tag"+period, &tags)
+	if len(tags) != 1 || tags[0].Tag != "lunch" || tags[0].Amount.String() != "60.00" || tags[0].PercentOfExpense != 100 {
+		t.Errorf("by tag = %+v", tags)
+	}
+	getJSON(t, srv, "/api/dashboard/summary"+period+"&tag=dinner", &summary)
+	if summary.TotalExpense != 0 || len(summary.ByCategory) != 0 {
+		t.Errorf("summary of an unused tag = %+v", summary)
+	}
+	getJSON(t, srv, "/api/dashboard/transactions"+period+"&tag=%23lunch", &listed)
+	if len(listed) != 1 || listed[0].ID != *food.TransactionID {
+		t.Errorf("transactions tagged lunch = %+v", listed)
+	}
+
+	// A paid claim's expenses are no longer personal spending
+	var claim models.Claim
+	postJSON(t, srv, "/api/claims", map[string]interface{}{"title": "Client lunch", "ids": []int64{*food.TransactionID}}, &claim)
+	if claim.Status != models.ClaimDraft || claim.Count != 1 || claim.Total.String() != "60.00" {
+		t.Fatalf("claim = %+v", claim)
+	}
+	claimPath := "/api/claims/" + strconv.FormatInt(claim.ID, 10)
+	postJSON(t, srv, claimPath+"/submit", nil, &claim)
+	getJSON(t, srv, "/api/dashboard/summary"+period, &summary)
+	if summary.TotalExpense.String() != "60.00" {
+		t.Errorf("expense with the claim submitted = %s, want 60.00", summary.TotalExpense)
+	}
+	postJSON(t, srv, claimPath+"/paid", nil, &claim)
+	if claim.Status != models.ClaimPaid || claim.Outstanding != 0 {
+		t.Errorf("paid claim = %+v", claim)
+	}
+	getJSON(t, srv, "/api/dashboard/summary"+period, &summary)
+	if summary.TotalExpense != 0 || len(summary.ByCategory) != 0 {
+		t.Errorf("summary with the claim paid = %+v", summary)
+	}
+}
+
+// slipPNG encodes a small image so the upload can be hashed
+func slipPNG(t *testing.T) []byte {
+	t.Helper()
+	img := image.NewGray(image.Rect(0, 0, 64, 96))
+	for y := 0; y < 96; y++ {
+		for x := 0; x < 64; x++ {
+			img.SetGray(x, y, color.Gray{Y: uint8((x * y) % 256)})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestUploadSlip(t *testing.T) {
+	srv := newTestServer(t, "slip_ollama.json", "slip_ocr.json")
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("slip", "slip.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(slipPNG(t))
+	form.Close()
+
+	resp, err := http.Post(srv.URL+"/api/transactions/slip", form.FormDataContentType(), &body)
+	if err != nil {
+		t.Fatalf("upload: %v", err)
+	}
+	var uploaded struct {
+		ID int64 `json:"id"`
+	}
+	json.NewDecoder(resp.Body).Decode(&uploaded)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || uploaded.ID == 0 {
+		t.Fatalf("upload: status %d, id %d", resp.StatusCode, uploaded.ID)
+	}
+
+	// The slip is read and parsed by the job queue
+	path := "/api/transactions/" + strconv.FormatInt(uploaded.ID, 10)
+	var view models.TransactionView
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		getJSON(t, srv, path, &view)
+		if !view.IsProcessing() {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("slip still processing: %s", view.ProcessingState)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	if view.ProcessingError != "" {
+		t.Fatalf("processing failed: %s", view.ProcessingError)
+	}
+	if view.Amount.String() != "1234.50" || view.Channel != "kbank" || view.Category != "bill" {
+		t.Errorf("slip = %+v", view)
+	}
+	if view.SlipRef != "016291094152ATF07634" {
+		t.Errorf("slip reference = %q", view.SlipRef)
+	}
+	if len(view.OCRBlocks) == 0 {
+		t.Error("OCR blocks were not stored")
+	}
+
+	// The model misread the tax ID, so the OCR parser's is kept; the rest
+	// is the model's, with the pre-VAT amount it missed filled from OCR
+	var receipt models.ReceiptDetails
+	getJSON(t, srv, path+"/receipt", &receipt)
+	want := models.ReceiptDetails{
+		TransactionID: uploaded.ID,
+		SellerTaxID:   "0994000165510",
+		Branch:        models.HeadOffice,
+		InvoiceNumber: "EA-6910-0042",
+		VAT:           models.SomeMoney(8076),
+		PreVAT:        models.SomeMoney(115374),
+	}
+	receipt.UpdatedAt = ""
+	if receipt != want {
+		t.Errorf("receipt = %+v, want %+v", receipt, want)
+	}
+
+	var found struct {
+		Count int `json:"count"`
+	}
+	getJSON(t, srv, "/api/transactions/search?q=taxid:0994000165510+invoice:6910", &found)
+	if found.Count != 1 {
+		t.Errorf("search by receipt found %d transactions, want 1", found.Count)
+	}
+
+	resp, err = http.Get(srv.URL + "/api/receipts/export?from=2026-10-01&to=2026-10-31")
+	if err != nil {
+		t.Fatalf("export: %v", err)
+	}
+	export, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !bytes.Contains(export, []byte(",2026-10-18,0994000165510,00000,EA-6910-0042,1153.74,80.76,1234.50,THB,")) {
+		t.Errorf("export: status %d\n%s", resp.StatusCode, export)
+	}
+
+	// The claim report embeds the slip
+	var claim models.Claim
+	postJSON(t, srv, "/api/claims", map[string]interface{}{"title": "Utilities", "ids": []int64{uploaded.ID}}, &claim)
+	resp, err = http.Get(srv.URL + "/api/claims/" + strconv.FormatInt(claim.ID, 10) + "/report")
+	if err != nil {
+		t.Fatalf("report: %v", err)
+	}
+	report, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !bytes.Contains(report, []byte(`src="data:image/png;base64,`)) {
+		t.Errorf("report: status %d, slip not embedded", resp.StatusCode)
+	}
+	if !bytes.Contains(report, []byte("1,234.50 THB")) {
+		t.Errorf("report does not list the slip amount:\n%s", report[:min(len(report), 2000)])
+	}
+}
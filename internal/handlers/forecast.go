@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"cash-track/internal/fx"
+	"cash-track/internal/llm"
+	"cash-track/internal/models"
+	"cash-track/internal/period"
+)
+
+// forecastCycles is how many earlier cycles the forecast learns from
+const forecastCycles = 6
+
+// forecast projects the user's current cycle as of now
+func (h *Handler) forecast(userID int64, now time.Time) (*models.Forecast, error) {
+	cutoff := h.cutoffDay(userID)
+	current := period.Cycle(now, cutoff)
+	today := period.Day(now).Format("2006-01-02")
+
+	history := make([]models.Period, 0, forecastCycles)
+	for p := current; len(history) < forecastCycles; {
+		p = period.Previous(p, cutoff)
+		history = append(history, toPeriod(p))
+	}
+
+	items, err := h.repo.GetFlowItems(userID, history[len(history)-1].From, today)
+	if err != nil {
+		return nil, err
+	}
+	user, err := h.repo.GetUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	f := models.BuildForecast(toPeriod(current), history, today, items)
+	f.Currency = user.BaseCurrency
+	return f, nil
+}
+
+// Forecast handles GET /api/forecast
+func (h *Handler) Forecast(w http.ResponseWriter, r *http.Request) {
+	userID, _ := h.currentUserID(w, r)
+
+	f, err := h.forecast(userID, time.Now())
+	if err != nil {
+		log.Printf("Failed to forecast: %v", err)
+		http.Error(w, "Failed to get forecast", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(f)
+}
+
+func (h *Handler) handleForecast(w http.ResponseWriter, r *http.Request, resp *llm.ChatResponse, lang string) {
+	userID, _ := h.currentUserID(w, r)
+
+	f, err := h.forecast(userID, time.Now())
+	if err != nil {
+		log.Printf("Failed to forecast: %v", err)
+		respondChat(w, chatText(lang, "fetch_failed"), nil, resp)
+		return
+	}
+	respondChat(w, buildForecastReplyText(f, lang), nil, resp)
+}
+
+// buildForecastReplyText reads e.g. "By 2026-10-24 you should have 3200.00
+// THB left (1800.00 THB to 4600.00 THB)", then the spending and the bills due
+func buildForecastReplyText(f *models.Forecast, lang string) string {
+	money := func(m models.Money) string { return fx.Format(m, f.Currency, lang) }
+	end := f.End
+
+	var lines []string
+	if lang == "en" {
+		lines = append(lines,
+			fmt.Sprintf("By %s you should have %s left (%s to %s)", f.Period.To, money(end.Balance), money(end.Low), money(end.High)),
+			fmt.Sprintf("Spending for the cycle: about %s, about %s a day", money(end.Spent), money(f.DailySpend)))
+	} else {
+		lines = append(lines,
+			fmt.Sprintf("คาดว่าสิ้นรอบ %s จะเหลือ %s (ระหว่าง %s ถึง %s)", f.Period.To, money(end.Balance), money(end.Low), money(end.High)),
+			fmt.Sprintf("ทั้งรอบจะใช้ไปประมาณ %s เฉลี่ยวันละ %s", money(end.Spent), money(f.DailySpend)))
+	}
+
+	var due []string
+	for _, item := range f.Recurring {
+		if item.Paid || item.Direction != "expense" {
+			continue
+		}
+		due = append(due, fmt.Sprintf("%s %s (%s)", categoryLabel(item.Category, lang), money(item.Amount), item.Day))
+	}
+	if len(due) > 0 {
+		if lang == "en" {
+			lines = append(lines, "Bills still due: "+strings.Join(due, ", "))
+		} else {
+			lines = append(lines, "รายการประจำที่ยังไม่จ่าย: "+strings.Join(due, ", "))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"cash-track/internal/fx"
+	"cash-track/internal/models"
+)
+
+type fxRateRequest struct {
+	Date     string  `json:"date"`
+	Currency string  `json:"currency"`
+	Base     string  `json:"base"`
+	Rate     float64 `json:"rate"`
+}
+
+// ListFXRates handles GET /api/fx-rates?currency=JPY&limit=100: the current
+// user's rates and the shared ones
+func (h *Handler) ListFXRates(w http.ResponseWriter, r *http.Request) {
+	userID, _ := h.currentUserID(w, r)
+	currency := r.URL.Query().Get("currency")
+	if currency != "" {
+		if !fx.Valid(currency) {
+			http.Error(w, "Invalid currency", http.StatusBadRequest)
+			return
+		}
+		currency = fx.Normalize(currency)
+	}
+	limit := 100
+	if v, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && v > 0 {
+		limit = v
+	}
+
+	rates, err := h.repo.ListFXRates(userID, currency, limit)
+	if err != nil {
+		log.Printf("Failed to list exchange rates: %v", err)
+		http.Error(w, "Failed to load exchange rates", http.StatusInternalServerError)
+		return
+	}
+	if rates == nil {
+		rates = []models.FXRate{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"rates": rates,
+	})
+}
+
+// CreateFXRate handles POST /api/fx-rates, storing a rate for the current
+// user's transactions. base defaults to their base currency.
+func (h *Handler) CreateFXRate(w http.ResponseWriter, r *http.Request) {
+	userID, _ := h.currentUserID(w, r)
+	var req fxRateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Base == "" {
+		req.Base = h.currentBaseCurrency(w, r)
+	}
+
+	rate, err := fx.NewRate(req.Date, req.Currency, req.Base, strconv.FormatFloat(req.Rate, 'f', -1, 64))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	saved, err := h.repo.UpsertFXRate(userID, *rate)
+	if err != nil {
+		log.Printf("Failed to save exchange rate: %v", err)
+		http.Error(w, "Failed to save exchange rate", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(saved)
+}
+
+// ImportFXRates handles POST /api/fx-rates/import, storing rates for the
+// current user's transactions. The body (or the "file" form field) is CSV in
+// the format read by fx.ParseRatesCSV; rows without a base column use the
+// user's base currency. Nothing is stored if any row is invalid.
+func (h *Handler) ImportFXRates(w http.ResponseWriter, r *http.Request) {
+	userID, _ := h.currentUserID(w, r)
+	var body io.Reader = r.Body
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, "CSV file is required", http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+		body = file
+	}
+
+	rates, err := fx.ParseRatesCSV(io.LimitReader(body, 5<<20), h.currentBaseCurrency(w, r))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid CSV: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	imported, err := h.repo.ImportFXRates(userID, rates)
+	if err != nil {
+		log.Printf("Failed to import exchange rates: %v", err)
+		http.Error(w, "Failed to import exchange rates", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"imported": imported,
+	})
+}
+
+// DeleteFXRate handles DELETE /api/fx-rates/{id}. Only the current user's
+// own rates can be deleted.
+func (h *Handler) DeleteFXRate(w http.ResponseWriter, r *http.Request) {
+	userID, _ := h.currentUserID(w, r)
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid rate ID", http.StatusBadRequest)
+		return
+	}
+
+	err = h.repo.DeleteFXRate(userID, id)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Exchange rate not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Printf("Failed to delete exchange rate %d: %v", id, err)
+		http.Error(w, "Failed to delete exchange rate", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+	})
+}
+
+// currentBaseCurrency returns the base currency of the current user
+func (h *Handler) currentBaseCurrency(w http.ResponseWriter, r *http.Request) string {
+	userID, _ := h.currentUserID(w, r)
+	if user, err := h.repo.GetUser(userID); err == nil {
+		return fx.Normalize(user.BaseCurrency)
+	}
+	return fx.DefaultCurrency
+}
@@ -5,35 +5,66 @@ import (
 	"net/http"
 	"path/filepath"
 	"strconv"
+	"time"
 
 	"cash-track/internal/database"
+	"cash-track/internal/jobs"
 	"cash-track/internal/llm"
 	"cash-track/internal/ocr"
+	"cash-track/internal/period"
+	"cash-track/internal/reparse"
 	"cash-track/internal/storage"
 )
 
 type Handler struct {
 	repo        *database.Repository
 	storage     *storage.LocalStorage
-	ocrClient   *ocr.Client
+	ocrClient   ocr.OCREngine
 	llmClient   *llm.Client
+	queue       *jobs.Queue
+	reparser    *reparse.Runner
 	templateDir string
 	defaultUser int64
+
+	slipHashDistance int
+	ocrLowConfidence float64
+	trashRetention   time.Duration
+}
+
+// Options holds tuning values for the handlers
+type Options struct {
+	// SlipHashDistance is the maximum perceptual-hash distance at which an
+	// upload is treated as a duplicate slip.
+	SlipHashDistance int
+	// OCRLowConfidence is the block confidence below which OCR text is
+	// highlighted on the confirm page.
+	OCRLowConfidence float64
+	// TrashRetention is how long deleted transactions stay in the trash.
+	TrashRetention time.Duration
 }
 
-func New(repo *database.Repository, storage *storage.LocalStorage, ocrClient *ocr.Client, llmClient *llm.Client, templateDir string) (*Handler, error) {
+func New(repo *database.Repository, storage *storage.LocalStorage, ocrClient ocr.OCREngine, llmClient *llm.Client, queue *jobs.Queue, templateDir string, opts Options) (*Handler, error) {
 	defaultUser, err := repo.EnsureDefaultUser()
 	if err != nil {
 		return nil, err
 	}
-	return &Handler{
+	h := &Handler{
 		repo:        repo,
 		storage:     storage,
 		ocrClient:   ocrClient,
 		llmClient:   llmClient,
+		queue:       queue,
+		reparser:    reparse.NewRunner(repo, storage, ocrClient, llmClient),
 		templateDir: templateDir,
 		defaultUser: defaultUser,
-	}, nil
+
+		slipHashDistance: opts.SlipHashDistance,
+		ocrLowConfidence: opts.OCRLowConfidence,
+		trashRetention:   opts.TrashRetention,
+	}
+	queue.Register(jobs.KindSlipOCR, h.processSlipJob)
+	queue.Register(jobs.KindReparse, h.processReparseJob)
+	return h, nil
 }
 
 func (h *Handler) renderTemplate(w http.ResponseWriter, page string, data interface{}) {
@@ -79,6 +110,15 @@ func (h *Handler) currentUserID(w http.ResponseWriter, r *http.Request) (int64,
 	return h.defaultUser, nil
 }
 
+// cutoffDay returns the day of the month on which the user's spending
+// cycle starts, or period.LastBusinessDay
+func (h *Handler) cutoffDay(userID int64) int {
+	if user, err := h.repo.GetUser(userID); err == nil && period.ValidCutoff(user.CutoffDay) {
+		return user.CutoffDay
+	}
+	return 1
+}
+
 func (h *Handler) setCurrentUserCookie(w http.ResponseWriter, userID int64) {
 	http.SetCookie(w, &http.Cookie{
 		Name:     "ct_user_id",
@@ -92,24 +132,6 @@ func (h *Handler) Index(w http.ResponseWriter, r *http.Request) {
 	http.Redirect(w, r, "/chat", http.StatusFound)
 }
 
-func (h *Handler) History(w http.ResponseWriter, r *http.Request) {
-	userID, _ := h.currentUserID(w, r)
-	transactions, err := h.repo.ListTransactions(userID, 50, 0)
-	if err != nil {
-		http.Error(w, "Failed to load transactions", http.StatusInternalServerError)
-		return
-	}
-
-	var views []interface{}
-	for _, tx := range transactions {
-		views = append(views, tx.ToView())
-	}
-
-	h.renderTemplate(w, "history.html", h.withUserContext(w, r, map[string]interface{}{
-		"Transactions": views,
-	}))
-}
-
 func (h *Handler) UsersPage(w http.ResponseWriter, r *http.Request) {
 	h.renderTemplate(w, "users.html", h.withUserContext(w, r, nil))
 }
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+const healthCheckTimeout = 3 * time.Second
+
+// Health handles GET /api/health. It reports 503 only when the database is
+// unreachable; an unavailable OCR service marks the app as degraded since
+// chat entry still works without it.
+func (h *Handler) Health(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), healthCheckTimeout)
+	defer cancel()
+
+	status := "ok"
+	code := http.StatusOK
+
+	database := map[string]interface{}{"status": "ok"}
+	if err := h.repo.Ping(ctx); err != nil {
+		database = map[string]interface{}{"status": "error", "error": err.Error()}
+		status = "error"
+		code = http.StatusServiceUnavailable
+	}
+
+	ocrStatus := map[string]interface{}{"status": "ok"}
+	if health, err := h.ocrClient.Health(ctx); err != nil {
+		ocrStatus = map[string]interface{}{"status": "unavailable", "error": err.Error()}
+	} else if !health.Ready() {
+		ocrStatus = map[string]interface{}{"status": "starting", "models_loaded": health.ModelsLoaded}
+	}
+	if ocrStatus["status"] != "ok" && status == "ok" {
+		status = "degraded"
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":   status,
+		"database": database,
+		"ocr":      ocrStatus,
+	})
+}
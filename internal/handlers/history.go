@@ -0,0 +1,306 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"cash-track/internal/database"
+	"cash-track/internal/models"
+)
+
+// historyPageSize is how many transactions the history page loads at a time
+const historyPageSize = 30
+
+// parseTransactionQuery reads list filters, sort and cursor from the URL:
+// from, to, status, direction, category, channel, account, tag, tax_item,
+// min_amount, max_amount, has_slip, reimbursable, claimed, min_confidence,
+// max_confidence, sort, order, cursor and limit.
+func parseTransactionQuery(values url.Values, userID int64) (models.TransactionQuery, error) {
+	q := models.TransactionQuery{
+		UserID:       userID,
+		From:         values.Get("from"),
+		To:           values.Get("to"),
+		Status:       values.Get("status"),
+		Direction:    values.Get("direction"),
+		Category:     values.Get("category"),
+		Channel:      values.Get("channel"),
+		AccountLabel: values.Get("account"),
+		Tag:          models.NormalizeTag(values.Get("tag")),
+		TaxItem:      values.Get("tax_item"),
+		Sort:         values.Get("sort"),
+		Cursor:       values.Get("cursor"),
+	}
+
+	for _, amount := range []struct {
+		param string
+		dest  *models.NullMoney
+	}{{"min_amount", &q.MinAmount}, {"max_amount", &q.MaxAmount}} {
+		if v := values.Get(amount.param); v != "" {
+			parsed, err := models.ParseMoney(v)
+			if err != nil {
+				return q, fmt.Errorf("invalid %s", amount.param)
+			}
+			*amount.dest = models.SomeMoney(parsed)
+		}
+	}
+	for _, confidence := range []struct {
+		param string
+		dest  *float64
+	}{{"min_confidence", &q.MinConfidence}, {"max_confidence", &q.MaxConfidence}} {
+		if v := values.Get(confidence.param); v != "" {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil || parsed < 0 || parsed > 1 {
+				return q, fmt.Errorf("invalid %s (want 0 to 1)", confidence.param)
+			}
+			*confidence.dest = parsed
+		}
+	}
+	if v := values.Get("has_slip"); v != "" {
+		parsed, err := strconv.ParseBool(v)
+		if err != nil {
+			return q, fmt.Errorf("invalid has_slip")
+		}
+		q.HasSlip = &parsed
+	}
+	if v := values.Get("reimbursable"); v != "" {
+		parsed, err := strconv.ParseBool(v)
+		if err != nil {
+			return q, fmt.Errorf("invalid reimbursable")
+		}
+		q.Reimbursable = &parsed
+	}
+	if v := values.Get("claimed"); v != "" {
+		parsed, err := strconv.ParseBool(v)
+		if err != nil {
+			return q, fmt.Errorf("invalid claimed")
+		}
+		q.Claimed = &parsed
+	}
+
+	switch q.Sort {
+	case "", models.SortTxnDate, models.SortAmount, models.SortCreatedAt:
+	default:
+		return q, fmt.Errorf("invalid sort (want txn_date, amount or created_at)")
+	}
+	switch values.Get("order") {
+	case "", "desc":
+	case "asc":
+		q.Ascending = true
+	default:
+		return q, fmt.Errorf("invalid order (want asc or desc)")
+	}
+	if v := values.Get("limit"); v != "" {
+		if parsed, err := strconv.Atoi(v); err == nil && parsed > 0 {
+			q.Limit = parsed
+		}
+	}
+	return q, nil
+}
+
+// queryTransactionViews runs q, turning a bad cursor into a client error
+func (h *Handler) queryTransactionViews(w http.ResponseWriter, q models.TransactionQuery) ([]models.TransactionView, string, bool) {
+	transactions, next, err := h.repo.QueryTransactions(q)
+	if errors.Is(err, database.ErrInvalidCursor) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, "", false
+	}
+	if err != nil {
+		log.Printf("Failed to list transactions: %v", err)
+		http.Error(w, "Failed to load transactions", http.StatusInternalServerError)
+		return nil, "", false
+	}
+
+	views := []models.TransactionView{}
+	for _, tx := range transactions {
+		views = append(views, tx.ToView())
+	}
+	return views, next, true
+}
+
+// ListTransactions handles GET /api/transactions
+func (h *Handler) ListTransactions(w http.ResponseWriter, r *http.Request) {
+	userID, _ := h.currentUserID(w, r)
+	q, err := parseTransactionQuery(r.URL.Query(), userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	views, next, ok := h.queryTransactionViews(w, q)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.TransactionPage{Transactions: views, NextCursor: next})
+}
+
+// filterChip is a link on the history page that adds or removes a filter
+type filterChip struct {
+	Label string // i18n key, or the text itself when Text is set
+	Text  bool
+	Href  string
+	On    bool
+}
+
+// presetChips are the one-tap filters offered above the history list
+var presetChips = []struct {
+	label string
+	param string
+	value string
+}{
+	{"history.chip_pending", "status", "pending"},
+	{"history.chip_confirmed", "status", "confirmed"},
+	{"history.chip_expense", "direction", "expense"},
+	{"history.chip_income", "direction", "income"},
+	{"history.chip_slip", "has_slip", "true"},
+	{"history.chip_reimbursable", "reimbursable", "true"},
+	{"history.chip_low_confidence", "max_confidence", "0.6"},
+}
+
+// sortChips switch the history sort order
+var sortChips = []struct {
+	label string
+	value string
+}{
+	{"history.sort_txn_date", models.SortTxnDate},
+	{"history.sort_amount", models.SortAmount},
+	{"history.sort_created_at", models.SortCreatedAt},
+}
+
+// historyLink returns the history URL for values, without paging state
+func historyLink(values url.Values) string {
+	values.Del("cursor")
+	values.Del("partial")
+	if len(values) == 0 {
+		return "/history"
+	}
+	return "/history?" + values.Encode()
+}
+
+// historyChips builds the preset, sort and active-filter chips for the
+// current history filters
+func historyChips(current url.Values) (presets, sorts, active []filterChip) {
+	preset := map[string]bool{}
+	for _, chip := range presetChips {
+		preset[chip.param] = true
+		values := cloneValues(current)
+		on := current.Get(chip.param) == chip.value
+		if on {
+			values.Del(chip.param)
+		} else {
+			values.Set(chip.param, chip.value)
+		}
+		presets = append(presets, filterChip{Label: chip.label, Href: historyLink(values), On: on})
+	}
+
+	sort := current.Get("sort")
+	if sort == "" {
+		sort = models.SortTxnDate
+	}
+	for _, chip := range sortChips {
+		values := cloneValues(current)
+		values.Set("sort", chip.value)
+		sorts = append(sorts, filterChip{Label: chip.label, Href: historyLink(values), On: sort == chip.value})
+	}
+
+	// Filters set some other way (links, typed URLs) can be removed too
+	for _, param := range []string{"category", "channel", "account", "tag", "tax_item", "claimed", "from", "to", "min_amount", "max_amount", "min_confidence"} {
+		value := current.Get(param)
+		if value == "" || preset[param] {
+			continue
+		}
+		values := cloneValues(current)
+		values.Del(param)
+		active = append(active, filterChip{Label: param + ": " + value, Text: true, Href: historyLink(values), On: true})
+	}
+	return presets, sorts, active
+}
+
+func cloneValues(values url.Values) url.Values {
+	clone := url.Values{}
+	for key, list := range values {
+		clone[key] = append([]string(nil), list...)
+	}
+	return clone
+}
+
+// History renders the transaction history. With q it shows search results;
+// otherwise it pages through the filtered list, and partial=1 returns just
+// the next page of cards for infinite scroll with the following cursor in
+// the X-Next-Cursor header.
+func (h *Handler) History(w http.ResponseWriter, r *http.Request) {
+	userID, _ := h.currentUserID(w, r)
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query != "" {
+		views, snippets, err := h.searchHistory(userID, query)
+		searchError := ""
+		if err != nil {
+			searchError = err.Error()
+		}
+		h.renderTemplate(w, "history.html", h.withUserContext(w, r, map[string]interface{}{
+			"Transactions": views,
+			"Snippets":     snippets,
+			"Query":        query,
+			"SearchError":  searchError,
+		}))
+		return
+	}
+
+	values := r.URL.Query()
+	q, err := parseTransactionQuery(values, userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	q.Limit = historyPageSize
+
+	views, next, ok := h.queryTransactionViews(w, q)
+	if !ok {
+		return
+	}
+	data := map[string]interface{}{
+		"Transactions": views,
+		"Snippets":     map[int64]template.HTML{},
+		"NextCursor":   next,
+	}
+
+	if values.Get("partial") == "1" {
+		w.Header().Set("X-Next-Cursor", next)
+		h.renderPartial(w, "history.html", "transaction_cards", data)
+		return
+	}
+
+	presets, sorts, active := historyChips(values)
+	data["PresetChips"] = presets
+	data["SortChips"] = sorts
+	data["ActiveChips"] = active
+	filtered := len(active) > 0
+	for _, chip := range presets {
+		filtered = filtered || chip.On
+	}
+	data["Filtered"] = filtered
+	h.renderTemplate(w, "history.html", h.withUserContext(w, r, data))
+}
+
+// renderPartial renders one named template from page without the layout
+func (h *Handler) renderPartial(w http.ResponseWriter, page, name string, data interface{}) {
+	tmpl, err := template.ParseFiles(
+		filepath.Join(h.templateDir, "layout.html"),
+		filepath.Join(h.templateDir, page),
+	)
+	if err != nil {
+		http.Error(w, "Template error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	tmpl.ExecuteTemplate(w, name, data)
+}
@@ -0,0 +1,281 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"cash-track/internal/fx"
+	"cash-track/internal/models"
+	"cash-track/internal/period"
+)
+
+type holdingRequest struct {
+	Name         string `json:"name"`
+	Kind         string `json:"kind"`
+	Currency     string `json:"currency"`
+	AccountLabel string `json:"account_label"`
+}
+
+type valuationRequest struct {
+	Date  string       `json:"date"`
+	Value models.Money `json:"value"`
+	Note  string       `json:"note"`
+}
+
+// ListHoldings handles GET /api/holdings
+func (h *Handler) ListHoldings(w http.ResponseWriter, r *http.Request) {
+	userID, _ := h.currentUserID(w, r)
+
+	holdings, err := h.repo.ListHoldings(userID)
+	if err != nil {
+		log.Printf("Failed to list holdings: %v", err)
+		http.Error(w, "Failed to load holdings", http.StatusInternalServerError)
+		return
+	}
+	if holdings == nil {
+		holdings = []models.Holding{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"holdings": holdings,
+	})
+}
+
+// CreateHolding handles POST /api/holdings. currency defaults to the
+// current user's base currency.
+func (h *Handler) CreateHolding(w http.ResponseWriter, r *http.Request) {
+	userID, _ := h.currentUserID(w, r)
+
+	var req holdingRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		http.Error(w, "Name is required", http.StatusBadRequest)
+		return
+	}
+	class, ok := models.HoldingKinds[req.Kind]
+	if !ok {
+		http.Error(w, "Invalid kind", http.StatusBadRequest)
+		return
+	}
+	if req.Currency == "" {
+		req.Currency = h.currentBaseCurrency(w, r)
+	}
+	if !fx.Valid(req.Currency) {
+		http.Error(w, "Invalid currency", http.StatusBadRequest)
+		return
+	}
+
+	holding, err := h.repo.CreateHolding(models.Holding{
+		UserID:       userID,
+		Name:         req.Name,
+		Kind:         req.Kind,
+		Class:        class,
+		Currency:     fx.Normalize(req.Currency),
+		AccountLabel: strings.TrimSpace(req.AccountLabel),
+	})
+	if err != nil {
+		log.Printf("Failed to create holding: %v", err)
+		http.Error(w, "Failed to create holding", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(holding)
+}
+
+// DeleteHolding handles DELETE /api/holdings/{id}
+func (h *Handler) DeleteHolding(w http.ResponseWriter, r *http.Request) {
+	userID, _ := h.currentUserID(w, r)
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid holding ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.repo.DeleteHolding(userID, id); err == sql.ErrNoRows {
+		http.Error(w, "Holding not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Printf("Failed to delete holding %d: %v", id, err)
+		http.Error(w, "Failed to delete holding", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+	})
+}
+
+// ListValuations handles GET /api/holdings/{id}/valuations
+func (h *Handler) ListValuations(w http.ResponseWriter, r *http.Request) {
+	userID, _ := h.currentUserID(w, r)
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid holding ID", http.StatusBadRequest)
+		return
+	}
+
+	valuations, err := h.repo.ListValuations(userID, id)
+	if err != nil {
+		log.Printf("Failed to list valuations of holding %d: %v", id, err)
+		http.Error(w, "Failed to load valuations", http.StatusInternalServerError)
+		return
+	}
+	if valuations == nil {
+		valuations = []models.Valuation{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"valuations": valuations,
+	})
+}
+
+// CreateValuation handles POST /api/holdings/{id}/valuations. date defaults
+// to today; a valuation of the same day is replaced.
+func (h *Handler) CreateValuation(w http.ResponseWriter, r *http.Request) {
+	userID, _ := h.currentUserID(w, r)
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid holding ID", http.StatusBadRequest)
+		return
+	}
+
+	var req valuationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Date == "" {
+		req.Date = time.Now().Format("2006-01-02")
+	}
+	if _, err := time.Parse("2006-01-02", req.Date); err != nil {
+		http.Error(w, "Invalid date", http.StatusBadRequest)
+		return
+	}
+	if req.Value < 0 {
+		http.Error(w, "Value must not be negative", http.StatusBadRequest)
+		return
+	}
+
+	valuation, err := h.repo.UpsertValuation(userID, models.Valuation{
+		HoldingID: id,
+		ValuedOn:  req.Date,
+		Value:     req.Value,
+		Note:      strings.TrimSpace(req.Note),
+	})
+	if err == sql.ErrNoRows {
+		http.Error(w, "Holding not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Printf("Failed to save valuation of holding %d: %v", id, err)
+		http.Error(w, "Failed to save valuation", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(valuation)
+}
+
+// DeleteValuation handles DELETE /api/holdings/{id}/valuations/{valuationID}
+func (h *Handler) DeleteValuation(w http.ResponseWriter, r *http.Request) {
+	userID, _ := h.currentUserID(w, r)
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid holding ID", http.StatusBadRequest)
+		return
+	}
+	valuationID, err := strconv.ParseInt(chi.URLParam(r, "valuationID"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid valuation ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.repo.DeleteValuation(userID, id, valuationID); err == sql.ErrNoRows {
+		http.Error(w, "Valuation not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Printf("Failed to delete valuation %d: %v", valuationID, err)
+		http.Error(w, "Failed to delete valuation", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+	})
+}
+
+// NetWorth handles GET /api/networth?period=&interval=. It covers the last
+// 12 cycles by cutoff cycle unless told otherwise, with a point at the end
+// of every interval up to today.
+func (h *Handler) NetWorth(w http.ResponseWriter, r *http.Request) {
+	userID, _ := h.currentUserID(w, r)
+	cutoff := h.cutoffDay(userID)
+	q := r.URL.Query()
+
+	name := q.Get("period")
+	if name == "" && q.Get("from") == "" && q.Get("to") == "" {
+		name = period.Last12Cycles
+	}
+	now := time.Now()
+	p, err := period.Resolve(name, q.Get("from"), q.Get("to"), now, cutoff)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	interval := q.Get("interval")
+	if interval == "" {
+		interval = models.IntervalCutoffPeriod
+	}
+	from, to := p.Dates()
+	buckets, err := timeBuckets(from, to, interval, cutoff)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	today := period.Day(now).Format("2006-01-02")
+	var days []string
+	for _, b := range buckets {
+		if b.From > today {
+			break
+		}
+		if b.To > today {
+			b.To = today
+		}
+		days = append(days, b.To)
+	}
+
+	series := &models.NetWorthSeries{
+		Interval: interval,
+		Period:   models.Period{From: from, To: to},
+		Currency: h.currentBaseCurrency(w, r),
+		Points:   []models.NetWorthPoint{},
+	}
+	if len(days) > 0 {
+		holdings, valuations, flows, err := h.repo.GetNetWorthData(userID, days[len(days)-1])
+		if err != nil {
+			log.Printf("Failed to get net worth: %v", err)
+			http.Error(w, "Failed to get net worth", http.StatusInternalServerError)
+			return
+		}
+		series.Points = models.NetWorthAt(holdings, valuations, flows, days)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(series)
+}
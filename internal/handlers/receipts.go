@@ -0,0 +1,214 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"cash-track/internal/llm"
+	"cash-track/internal/models"
+)
+
+type receiptRequest struct {
+	SellerTaxID   string           `json:"seller_tax_id"`
+	Branch        string           `json:"branch"`
+	InvoiceNumber string           `json:"invoice_number"`
+	VAT           models.NullMoney `json:"vat"`
+	PreVAT        models.NullMoney `json:"pre_vat_amount"`
+}
+
+// receiptFromModel normalizes the receipt fields the model read, dropping
+// tax IDs and branches that are not valid and negative amounts
+func receiptFromModel(p *llm.ParsedReceipt) models.ReceiptDetails {
+	if p == nil {
+		return models.ReceiptDetails{}
+	}
+	d := models.ReceiptDetails{
+		SellerTaxID:   models.NormalizeTaxID(p.SellerTaxID),
+		Branch:        models.NormalizeBranch(p.Branch),
+		InvoiceNumber: strings.ToUpper(strings.TrimSpace(p.InvoiceNumber)),
+	}
+	if p.VAT.Valid && p.VAT.Money >= 0 {
+		d.VAT = p.VAT
+	}
+	if p.PreVAT.Valid && p.PreVAT.Money >= 0 {
+		d.PreVAT = p.PreVAT
+	}
+	return d
+}
+
+// saveReceipt stores the receipt details read from a slip: the model's
+// where it found them, else the OCR parser's. Slips that are not receipts
+// have none and store nothing, and details the transaction already has are
+// kept.
+func (h *Handler) saveReceipt(txID int64, slip models.ReceiptDetails, parsed *llm.ParsedReceipt) {
+	d := receiptFromModel(parsed)
+	d.Fill(slip)
+	if d.IsEmpty() {
+		return
+	}
+	d.TransactionID = txID
+	if err := h.repo.FillReceiptDetails(d); err != nil {
+		log.Printf("Failed to store receipt details for transaction %d: %v", txID, err)
+	}
+}
+
+// GetReceipt handles GET /api/transactions/{id}/receipt
+func (h *Handler) GetReceipt(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid transaction ID", http.StatusBadRequest)
+		return
+	}
+
+	userID, _ := h.currentUserID(w, r)
+	d, err := h.repo.GetReceiptDetails(userID, id)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Receipt details not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Printf("Failed to load receipt details of transaction %d: %v", id, err)
+		http.Error(w, "Failed to load receipt details", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(d)
+}
+
+// SetReceipt handles PUT /api/transactions/{id}/receipt, correcting what was
+// read from the slip or adding details for a receipt that was not uploaded.
+// Leaving every field empty removes the details.
+func (h *Handler) SetReceipt(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid transaction ID", http.StatusBadRequest)
+		return
+	}
+
+	userID, _ := h.currentUserID(w, r)
+	if _, err := h.repo.GetTransaction(userID, id); err != nil {
+		http.Error(w, "Transaction not found", http.StatusNotFound)
+		return
+	}
+
+	var req receiptRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	d := models.ReceiptDetails{
+		TransactionID: id,
+		InvoiceNumber: strings.ToUpper(strings.TrimSpace(req.InvoiceNumber)),
+		VAT:           req.VAT,
+		PreVAT:        req.PreVAT,
+	}
+	if strings.TrimSpace(req.SellerTaxID) != "" {
+		if d.SellerTaxID = models.NormalizeTaxID(req.SellerTaxID); d.SellerTaxID == "" {
+			http.Error(w, "Invalid seller tax ID", http.StatusBadRequest)
+			return
+		}
+	}
+	if strings.TrimSpace(req.Branch) != "" {
+		if d.Branch = models.NormalizeBranch(req.Branch); d.Branch == "" {
+			http.Error(w, "Invalid branch", http.StatusBadRequest)
+			return
+		}
+	}
+	if (d.VAT.Valid && d.VAT.Money < 0) || (d.PreVAT.Valid && d.PreVAT.Money < 0) {
+		http.Error(w, "Amounts must not be negative", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.repo.SetReceiptDetails(d); err != nil {
+		log.Printf("Failed to store receipt details for transaction %d: %v", id, err)
+		http.Error(w, "Failed to save receipt details", http.StatusInternalServerError)
+		return
+	}
+	if d.IsEmpty() {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(d)
+		return
+	}
+	saved, err := h.repo.GetReceiptDetails(userID, id)
+	if err != nil {
+		log.Printf("Failed to load receipt details of transaction %d: %v", id, err)
+		http.Error(w, "Failed to load receipt details", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(saved)
+}
+
+// ListReceipts handles GET /api/receipts?period=...|from=&to=: the receipt
+// details of transactions in the range, oldest first
+func (h *Handler) ListReceipts(w http.ResponseWriter, r *http.Request) {
+	userID, _ := h.currentUserID(w, r)
+	from, to, ok := h.dateRange(w, r, userID)
+	if !ok {
+		return
+	}
+
+	receipts, err := h.repo.ListReceipts(userID, from, to)
+	if err != nil {
+		log.Printf("Failed to list receipts: %v", err)
+		http.Error(w, "Failed to load receipts", http.StatusInternalServerError)
+		return
+	}
+	if receipts == nil {
+		receipts = []models.Receipt{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"from":     from,
+		"to":       to,
+		"receipts": receipts,
+	})
+}
+
+// ExportReceipts handles GET /api/receipts/export?period=...|from=&to=: the
+// same receipts as a CSV file
+func (h *Handler) ExportReceipts(w http.ResponseWriter, r *http.Request) {
+	userID, _ := h.currentUserID(w, r)
+	from, to, ok := h.dateRange(w, r, userID)
+	if !ok {
+		return
+	}
+
+	receipts, err := h.repo.ListReceipts(userID, from, to)
+	if err != nil {
+		log.Printf("Failed to list receipts: %v", err)
+		http.Error(w, "Failed to export receipts", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="receipts-%s-%s.csv"`, from, to))
+	rows := newExcelCSV(w)
+	rows.Write([]string{"id", "date", "seller_tax_id", "branch", "invoice_number", "pre_vat_amount", "vat", "amount", "currency", "payee", "description"})
+	for _, rc := range receipts {
+		rows.Write([]string{strconv.FormatInt(rc.TransactionID, 10), rc.Day, rc.SellerTaxID, rc.Branch, rc.InvoiceNumber,
+			rc.PreVAT.String(), rc.VAT.String(), rc.Amount.String(), rc.Currency, rc.Payee, rc.Description})
+	}
+	rows.Flush()
+	if err := rows.Error(); err != nil {
+		log.Printf("Failed to export receipts: %v", err)
+	}
+}
+
+// newExcelCSV starts a CSV file on w that spreadsheets open correctly: a
+// byte order mark makes them read the Thai text as UTF-8
+func newExcelCSV(w io.Writer) *csv.Writer {
+	w.Write([]byte("\ufeff"))
+	return csv.NewWriter(w)
+}
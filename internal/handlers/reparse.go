@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"cash-track/internal/jobs"
+	"cash-track/internal/models"
+	"cash-track/internal/reparse"
+)
+
+type reparseRequest struct {
+	Mode          string  `json:"mode"`
+	From          string  `json:"from"`
+	To            string  `json:"to"`
+	Status        string  `json:"status"`
+	MaxConfidence float64 `json:"max_confidence"`
+	Limit         int     `json:"limit"`
+}
+
+// reparseJobPayload is the payload of a jobs.KindReparse job
+type reparseJobPayload struct {
+	RunID int64 `json:"run_id"`
+}
+
+func (h *Handler) processReparseJob(ctx context.Context, job *models.Job) error {
+	var payload reparseJobPayload
+	if err := json.Unmarshal([]byte(job.Payload), &payload); err != nil {
+		return jobs.Permanent(fmt.Errorf("invalid payload: %w", err))
+	}
+	return h.reparser.Run(ctx, payload.RunID)
+}
+
+// StartReparse handles POST /api/admin/reparse. It queues a run over the
+// current user's transactions; the proposed changes are reviewed with
+// GetReparse and written with ApplyReparse.
+func (h *Handler) StartReparse(w http.ResponseWriter, r *http.Request) {
+	var req reparseRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Mode == "" {
+		req.Mode = models.ReparseLLM
+	}
+	if !reparse.ValidMode(req.Mode) {
+		http.Error(w, "Mode must be ocr, llm or both", http.StatusBadRequest)
+		return
+	}
+
+	userID, _ := h.currentUserID(w, r)
+	run, err := h.reparser.Create(req.Mode, models.ReparseFilter{
+		UserID:        userID,
+		From:          req.From,
+		To:            req.To,
+		Status:        req.Status,
+		MaxConfidence: req.MaxConfidence,
+		Limit:         req.Limit,
+	})
+	if err != nil {
+		log.Printf("Failed to create re-parse run: %v", err)
+		http.Error(w, "Failed to create re-parse run", http.StatusInternalServerError)
+		return
+	}
+
+	if _, err := h.queue.Enqueue(jobs.KindReparse, userID, 0, reparseJobPayload{RunID: run.ID}); err != nil {
+		log.Printf("Failed to queue re-parse run %d: %v", run.ID, err)
+		h.repo.FinishReparseRun(run.ID, models.ReparseFailed, "failed to queue run")
+		http.Error(w, "Failed to queue re-parse run", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(run)
+}
+
+// ListReparses handles GET /api/admin/reparse
+func (h *Handler) ListReparses(w http.ResponseWriter, r *http.Request) {
+	userID, _ := h.currentUserID(w, r)
+	runs, err := h.repo.ListReparseRuns(userID, 20)
+	if err != nil {
+		http.Error(w, "Failed to load re-parse runs", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(runs)
+}
+
+// GetReparse handles GET /api/admin/reparse/{id} and returns the run with
+// its diff report.
+func (h *Handler) GetReparse(w http.ResponseWriter, r *http.Request) {
+	run, ok := h.loadReparseRun(w, r)
+	if !ok {
+		return
+	}
+
+	changes, err := h.repo.ListReparseChanges(run.ID)
+	if err != nil {
+		http.Error(w, "Failed to load changes", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"run":     run,
+		"changes": changes,
+	})
+}
+
+// ApplyReparse handles POST /api/admin/reparse/{id}/apply. The body selects
+// changes by change ID, transaction ID and/or field; an empty body applies
+// every unprotected change.
+func (h *Handler) ApplyReparse(w http.ResponseWriter, r *http.Request) {
+	run, ok := h.loadReparseRun(w, r)
+	if !ok {
+		return
+	}
+	if run.Status != models.ReparseDone {
+		http.Error(w, "Re-parse run has not finished", http.StatusConflict)
+		return
+	}
+
+	var sel models.ReparseSelection
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&sel); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+
+	applied, skipped, err := h.repo.ApplyReparseChanges(run.ID, sel)
+	if err != nil {
+		log.Printf("Failed to apply re-parse run %d: %v", run.ID, err)
+		http.Error(w, "Failed to apply changes", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"applied": applied,
+		"skipped": skipped,
+	})
+}
+
+func (h *Handler) loadReparseRun(w http.ResponseWriter, r *http.Request) (*models.ReparseRun, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid run ID", http.StatusBadRequest)
+		return nil, false
+	}
+
+	userID, _ := h.currentUserID(w, r)
+	run, err := h.repo.GetReparseRun(userID, id)
+	if err != nil {
+		http.Error(w, "Re-parse run not found", http.StatusNotFound)
+		return nil, false
+	}
+	return run, true
+}
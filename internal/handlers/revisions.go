@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// ListRevisions handles GET /api/transactions/{id}/revisions
+func (h *Handler) ListRevisions(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid transaction ID", http.StatusBadRequest)
+		return
+	}
+
+	userID, _ := h.currentUserID(w, r)
+	revisions, err := h.repo.ListRevisions(userID, id)
+	if err != nil {
+		log.Printf("Failed to list revisions of transaction %d: %v", id, err)
+		http.Error(w, "Failed to load history", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(revisions)
+}
+
+// RevertTransaction handles POST /api/transactions/{id}/revisions/{rev}/revert
+func (h *Handler) RevertTransaction(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid transaction ID", http.StatusBadRequest)
+		return
+	}
+	revID, err := strconv.ParseInt(chi.URLParam(r, "rev"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid revision ID", http.StatusBadRequest)
+		return
+	}
+
+	userID, _ := h.currentUserID(w, r)
+	err = h.repo.RevertTransaction(userID, id, revID)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "Revision not found or transaction in trash", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to revert transaction %d to revision %d: %v", id, revID, err)
+		http.Error(w, "Failed to revert transaction", http.StatusInternalServerError)
+		return
+	}
+
+	tx, err := h.repo.GetTransaction(userID, id)
+	if err != nil {
+		http.Error(w, "Transaction not found", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tx.ToView())
+}
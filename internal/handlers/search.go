@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"strconv"
+
+	"cash-track/internal/models"
+	"cash-track/internal/search"
+)
+
+// SearchTransactions handles GET /api/transactions/search?q=...
+func (h *Handler) SearchTransactions(w http.ResponseWriter, r *http.Request) {
+	input := r.URL.Query().Get("q")
+	q, err := search.Parse(input)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if q.IsEmpty() {
+		http.Error(w, "q is required", http.StatusBadRequest)
+		return
+	}
+
+	limit := 50
+	if v := r.URL.Query().Get("limit"); v != "" {
+		if parsed, err := strconv.Atoi(v); err == nil && parsed > 0 {
+			if parsed > 200 {
+				parsed = 200
+			}
+			limit = parsed
+		}
+	}
+
+	userID, _ := h.currentUserID(w, r)
+	results, err := h.repo.SearchTransactions(userID, q, limit)
+	if err != nil {
+		http.Error(w, "Failed to search transactions", http.StatusInternalServerError)
+		return
+	}
+	if results == nil {
+		results = []models.SearchResult{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"query":   q,
+		"results": results,
+		"count":   len(results),
+	})
+}
+
+// searchHistory runs the history page search, returning the matching
+// transactions and their highlighted snippets by transaction ID
+func (h *Handler) searchHistory(userID int64, input string) ([]interface{}, map[int64]template.HTML, error) {
+	q, err := search.Parse(input)
+	if err != nil {
+		return nil, nil, err
+	}
+	results, err := h.repo.SearchTransactions(userID, q, 100)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var views []interface{}
+	snippets := map[int64]template.HTML{}
+	for _, result := range results {
+		views = append(views, result.TransactionView)
+		if result.Snippet != "" {
+			snippets[result.ID] = result.SnippetHTML()
+		}
+	}
+	return views, snippets, nil
+}
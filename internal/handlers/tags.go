@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"cash-track/internal/models"
+)
+
+// ListTags handles GET /api/tags
+func (h *Handler) ListTags(w http.ResponseWriter, r *http.Request) {
+	userID, _ := h.currentUserID(w, r)
+
+	tags, err := h.repo.ListTags(userID)
+	if err != nil {
+		log.Printf("Failed to list tags: %v", err)
+		http.Error(w, "Failed to load tags", http.StatusInternalServerError)
+		return
+	}
+	if tags == nil {
+		tags = []models.Tag{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"tags": tags,
+	})
+}
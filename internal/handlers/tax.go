@@ -0,0 +1,261 @@
+package handlers
+
+import (
+	"archive/zip"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"cash-track/internal/models"
+)
+
+type taxCategoryRequest struct {
+	Item string `json:"item"`
+}
+
+// taxYear reads the tax year from the year parameter, defaulting to the
+// current year
+func taxYear(r *http.Request) (int, error) {
+	v := r.URL.Query().Get("year")
+	if v == "" {
+		return time.Now().Year(), nil
+	}
+	year, err := strconv.Atoi(v)
+	if err != nil || year < 2000 || year > 2100 {
+		return 0, fmt.Errorf("invalid year")
+	}
+	return year, nil
+}
+
+// ListTaxItems handles GET /api/tax/items: the deductible items with their
+// statutory caps and the combined caps of their groups
+func (h *Handler) ListTaxItems(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"items":  models.TaxItems,
+		"groups": models.TaxGroupCaps,
+	})
+}
+
+// ListTaxCategories handles GET /api/tax/categories
+func (h *Handler) ListTaxCategories(w http.ResponseWriter, r *http.Request) {
+	userID, _ := h.currentUserID(w, r)
+
+	categories, err := h.repo.ListTaxCategories(userID)
+	if err != nil {
+		log.Printf("Failed to list tax categories: %v", err)
+		http.Error(w, "Failed to load tax categories", http.StatusInternalServerError)
+		return
+	}
+	if categories == nil {
+		categories = []models.TaxCategory{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"categories": categories,
+	})
+}
+
+// SetTaxCategory handles PUT /api/tax/categories/{category}: every
+// transaction in the category counts towards item unless it has its own
+func (h *Handler) SetTaxCategory(w http.ResponseWriter, r *http.Request) {
+	userID, _ := h.currentUserID(w, r)
+	category := strings.ToLower(strings.TrimSpace(chi.URLParam(r, "category")))
+	if category == "" {
+		http.Error(w, "Category is required", http.StatusBadRequest)
+		return
+	}
+
+	var req taxCategoryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if _, ok := models.LookupTaxItem(req.Item); !ok {
+		http.Error(w, "Invalid tax item", http.StatusBadRequest)
+		return
+	}
+
+	c := models.TaxCategory{Category: category, Item: req.Item}
+	if err := h.repo.SetTaxCategory(userID, c); err != nil {
+		log.Printf("Failed to set tax item of category %s: %v", category, err)
+		http.Error(w, "Failed to save tax category", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(c)
+}
+
+// DeleteTaxCategory handles DELETE /api/tax/categories/{category}
+func (h *Handler) DeleteTaxCategory(w http.ResponseWriter, r *http.Request) {
+	userID, _ := h.currentUserID(w, r)
+	category := strings.ToLower(strings.TrimSpace(chi.URLParam(r, "category")))
+
+	if err := h.repo.DeleteTaxCategory(userID, category); err == sql.ErrNoRows {
+		http.Error(w, "Tax category not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Printf("Failed to delete tax category %s: %v", category, err)
+		http.Error(w, "Failed to delete tax category", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+	})
+}
+
+// TaxReport handles GET /api/tax/report?year=...: the year's deductible
+// spending per item against the statutory caps. Caps that depend on income
+// use income when given, or else the year's confirmed income.
+func (h *Handler) TaxReport(w http.ResponseWriter, r *http.Request) {
+	userID, _ := h.currentUserID(w, r)
+	year, err := taxYear(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	period := models.TaxYear(year)
+
+	summary, err := h.repo.QuerySummary(userID, "income", period.From, period.To, "", "", "")
+	if err != nil {
+		log.Printf("Failed to load income for tax year %d: %v", year, err)
+		http.Error(w, "Failed to load tax report", http.StatusInternalServerError)
+		return
+	}
+	income := summary.TotalIncome
+	if v := r.URL.Query().Get("income"); v != "" {
+		if income, err = models.ParseMoney(v); err != nil || income < 0 {
+			http.Error(w, "Invalid income", http.StatusBadRequest)
+			return
+		}
+	}
+
+	transactions, err := h.repo.GetTaxTransactions(userID, period.From, period.To, "")
+	if err != nil {
+		log.Printf("Failed to load tax transactions for %d: %v", year, err)
+		http.Error(w, "Failed to load tax report", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.BuildTaxReport(year, summary.Currency, income, transactions))
+}
+
+// TaxSlips handles GET /api/tax/slips?year=...: a zip of the slips behind
+// the year's deductible spending, one folder per tax item, with index.csv
+// listing every transaction, its receipt details and the text read from its
+// slip. item limits it to one tax item.
+func (h *Handler) TaxSlips(w http.ResponseWriter, r *http.Request) {
+	userID, _ := h.currentUserID(w, r)
+	year, err := taxYear(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	item := r.URL.Query().Get("item")
+	if _, ok := models.LookupTaxItem(item); item != "" && !ok {
+		http.Error(w, "Invalid tax item", http.StatusBadRequest)
+		return
+	}
+	period := models.TaxYear(year)
+
+	transactions, err := h.repo.GetTaxTransactions(userID, period.From, period.To, item)
+	if err != nil {
+		log.Printf("Failed to load tax transactions for %d: %v", year, err)
+		http.Error(w, "Failed to export tax slips", http.StatusInternalServerError)
+		return
+	}
+
+	receipts, err := h.repo.ListReceipts(userID, period.From, period.To)
+	if err != nil {
+		log.Printf("Failed to load receipts for %d: %v", year, err)
+		http.Error(w, "Failed to export tax slips", http.StatusInternalServerError)
+		return
+	}
+	details := map[int64]models.ReceiptDetails{}
+	for _, rc := range receipts {
+		details[rc.TransactionID] = rc.ReceiptDetails
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="tax-%d-slips.zip"`, year))
+	archive := zip.NewWriter(w)
+	if err := h.writeTaxSlips(archive, transactions, details); err != nil {
+		// Headers are sent; the client sees a truncated archive
+		log.Printf("Failed to export tax slips for %d: %v", year, err)
+		return
+	}
+	if err := archive.Close(); err != nil {
+		log.Printf("Failed to export tax slips for %d: %v", year, err)
+	}
+}
+
+// writeTaxSlips adds the slips of transactions and their index to archive,
+// listing the receipt details of each transaction that has them
+func (h *Handler) writeTaxSlips(archive *zip.Writer, transactions []models.TaxTransaction, receipts map[int64]models.ReceiptDetails) error {
+	index, err := archive.Create("index.csv")
+	if err != nil {
+		return err
+	}
+	rows := newExcelCSV(index)
+	rows.Write([]string{"item", "id", "date", "amount", "currency", "amount_base", "description", "payee",
+		"seller_tax_id", "branch", "invoice_number", "pre_vat_amount", "vat", "slip", "ocr_text"})
+
+	var slips []models.TaxTransaction
+	for _, tx := range transactions {
+		view := tx.ToView()
+		base := ""
+		if tx.Base.Valid {
+			base = tx.Base.Money.String()
+		}
+		slip := ""
+		if view.SlipImagePath != "" {
+			slip = taxSlipName(tx)
+			slips = append(slips, tx)
+		}
+		receipt := receipts[tx.ID]
+		rows.Write([]string{tx.Item, strconv.FormatInt(view.ID, 10), tx.Day, view.Amount.String(), view.Currency, base,
+			view.Description, view.Payee, receipt.SellerTaxID, receipt.Branch, receipt.InvoiceNumber,
+			receipt.PreVAT.String(), receipt.VAT.String(), slip, view.RawOCRText})
+	}
+	rows.Flush()
+	if err := rows.Error(); err != nil {
+		return err
+	}
+
+	for _, tx := range slips {
+		file, err := os.Open(h.storage.GetPath(tx.SlipImagePath.String))
+		if err != nil {
+			log.Printf("Failed to read slip of transaction %d: %v", tx.ID, err)
+			continue
+		}
+		dest, err := archive.Create(taxSlipName(tx))
+		if err == nil {
+			_, err = io.Copy(dest, file)
+		}
+		file.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// taxSlipName is where a transaction's slip goes in the export
+func taxSlipName(tx models.TaxTransaction) string {
+	return fmt.Sprintf("%s/%s-%d%s", tx.Item, tx.Day, tx.ID, filepath.Ext(tx.SlipImagePath.String))
+}
@@ -1,14 +1,28 @@
 package handlers
 
 import (
+	"bytes"
+	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 
+	"cash-track/internal/database"
+	"cash-track/internal/fx"
+	"cash-track/internal/jobs"
 	"cash-track/internal/models"
+	"cash-track/internal/ocr"
+	"cash-track/internal/storage"
 )
 
 func (h *Handler) UploadSlip(w http.ResponseWriter, r *http.Request) {
@@ -24,65 +38,152 @@ func (h *Handler) UploadSlip(w http.ResponseWriter, r *http.Request) {
 	}
 	defer file.Close()
 
-	filename, err := h.storage.Save(header.Filename, file)
+	data, err := io.ReadAll(file)
+	if err != nil {
+		http.Error(w, "Failed to read file", http.StatusBadRequest)
+		return
+	}
+
+	userID, _ := h.currentUserID(w, r)
+
+	// Look for a near-identical slip that was uploaded before
+	var slipHash string
+	var duplicateOf int64
+	if hash, err := storage.DHash(bytes.NewReader(data)); err != nil {
+		log.Printf("Failed to hash slip %q: %v", header.Filename, err)
+	} else {
+		slipHash = storage.FormatHash(hash)
+		duplicateOf, err = h.repo.FindSimilarSlip(userID, hash, h.slipHashDistance)
+		if err != nil {
+			log.Printf("Failed to look up similar slips: %v", err)
+			duplicateOf = 0
+		}
+	}
+
+	if duplicateOf != 0 && r.FormValue("allow_duplicate") != "true" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error":        "duplicate_slip",
+			"duplicate_of": duplicateOf,
+			"redirect":     "/transactions/" + strconv.FormatInt(duplicateOf, 10) + "/confirm",
+		})
+		return
+	}
+
+	filename, err := h.storage.Save(header.Filename, bytes.NewReader(data))
 	if err != nil {
 		log.Printf("Failed to save file: %v", err)
 		http.Error(w, "Failed to save file", http.StatusInternalServerError)
 		return
 	}
 
-	userID, _ := h.currentUserID(w, r)
-	tx, err := h.repo.CreateTransaction(userID, filename)
+	tx, err := h.repo.CreateTransaction(userID, filename, slipHash, duplicateOf)
 	if err != nil {
 		log.Printf("Failed to create transaction: %v", err)
 		http.Error(w, "Failed to create transaction", http.StatusInternalServerError)
 		return
 	}
 
-	go h.processOCR(tx.ID, filename)
+	if _, err := h.queue.Enqueue(jobs.KindSlipOCR, userID, tx.ID, slipJobPayload{Filename: filename}); err != nil {
+		log.Printf("Failed to queue OCR for transaction %d: %v", tx.ID, err)
+		h.repo.SetProcessingState(tx.ID, models.ProcessingFailed, "failed to queue slip processing")
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"id":         tx.ID,
-		"image_path": filename,
-		"redirect":   "/transactions/" + strconv.FormatInt(tx.ID, 10) + "/confirm",
+		"id":           tx.ID,
+		"image_path":   filename,
+		"duplicate_of": duplicateOf,
+		"redirect":     "/transactions/" + strconv.FormatInt(tx.ID, 10) + "/confirm",
 	})
 }
 
-func (h *Handler) processOCR(txID int64, filename string) {
-	imagePath := h.storage.GetPath(filename)
+// slipJobPayload is the payload of a jobs.KindSlipOCR job
+type slipJobPayload struct {
+	Filename string `json:"filename"`
+}
+
+// processSlipJob runs OCR and LLM parsing for an uploaded slip
+func (h *Handler) processSlipJob(ctx context.Context, job *models.Job) error {
+	var payload slipJobPayload
+	if err := json.Unmarshal([]byte(job.Payload), &payload); err != nil {
+		return jobs.Permanent(fmt.Errorf("invalid payload: %w", err))
+	}
+	if !job.TransactionID.Valid {
+		return jobs.Permanent(fmt.Errorf("job has no transaction"))
+	}
+
+	userID, txID := job.UserID, job.TransactionID.Int64
+	imagePath := h.storage.GetPath(payload.Filename)
+	if _, err := os.Stat(imagePath); err != nil {
+		return jobs.Permanent(fmt.Errorf("slip image missing: %w", err))
+	}
 
 	// Step 1: Extract text with EasyOCR
-	rawText, err := h.ocrClient.ExtractText(imagePath)
+	h.repo.SetProcessingState(txID, models.ProcessingOCR, "")
+	result, err := h.ocrClient.Extract(ctx, imagePath)
 	if err != nil {
-		log.Printf("OCR failed for transaction %d: %v", txID, err)
-		return
+		return fmt.Errorf("OCR failed: %w", err)
 	}
+	rawText := result.Text
 
 	log.Printf("OCR text for transaction %d: %s", txID, rawText)
 
+	if blocks, err := json.Marshal(result.Blocks); err == nil && len(result.Blocks) > 0 {
+		if err := h.repo.SetOCRBlocks(txID, string(blocks)); err != nil {
+			log.Printf("Failed to store OCR blocks for transaction %d: %v", txID, err)
+		}
+	}
+
+	slip := ocr.ParseSlipText(rawText)
+	if slip.ToAccount != "" {
+		if err := h.repo.SetPayee(txID, slip.ToAccount); err != nil {
+			log.Printf("Failed to store payee for transaction %d: %v", txID, err)
+		}
+	}
+
+	// The bank reference number catches re-uploads that the image hash missed
+	if ref := slip.Reference; ref != "" {
+		dupID, err := h.repo.SetSlipReference(userID, txID, ref)
+		if err != nil {
+			log.Printf("Failed to store slip reference for transaction %d: %v", txID, err)
+		} else if dupID != 0 {
+			log.Printf("Transaction %d has the same slip reference as transaction %d", txID, dupID)
+		}
+	}
+
 	// Step 2: Parse with LLM (Ollama)
+	h.repo.SetProcessingState(txID, models.ProcessingLLM, "")
 	parsed, err := h.llmClient.ParseSlipText(rawText)
 	if err != nil {
-		log.Printf("LLM parsing failed for transaction %d: %v", txID, err)
-		// Still save the raw OCR text
-		h.repo.UpdateOCRResult(txID, rawText, 0, "", "", "", "", 0)
-		return
+		h.saveReceipt(txID, slip.Receipt, nil)
+		// Keep the OCR text but not the parsed values, which a retry may
+		// already have stored. The queue retries and records the failure.
+		if err := h.repo.SetRawOCRText(txID, rawText); err != nil {
+			log.Printf("Failed to store OCR text for transaction %d: %v", txID, err)
+		}
+		return fmt.Errorf("LLM parsing failed: %w", err)
 	}
 
 	err = h.repo.UpdateOCRResult(
 		txID,
 		rawText,
 		parsed.Amount,
+		fx.Normalize(parsed.Currency),
 		parsed.TxnDate,
 		parsed.Channel,
 		parsed.Category,
 		parsed.Description,
 		parsed.Confidence,
+		parsed.Source,
 	)
 	if err != nil {
-		log.Printf("Failed to update OCR result for transaction %d: %v", txID, err)
+		return fmt.Errorf("failed to save OCR result: %w", err)
 	}
+	h.recordExtraction(txID, parsed.Extraction, parsed.Confidence, parsed.Values())
+	h.saveReceipt(txID, slip.Receipt, parsed.Receipt)
+	return nil
 }
 
 func (h *Handler) ConfirmPage(w http.ResponseWriter, r *http.Request) {
@@ -100,8 +201,18 @@ func (h *Handler) ConfirmPage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	var result ocr.Result
+	if tx.OCRBlocks.Valid {
+		if err := json.Unmarshal([]byte(tx.OCRBlocks.String), &result.Blocks); err != nil {
+			log.Printf("Invalid OCR blocks on transaction %d: %v", id, err)
+		}
+	}
+
 	h.renderTemplate(w, "confirm.html", h.withUserContext(w, r, map[string]interface{}{
-		"Transaction": tx.ToView(),
+		"Transaction":      tx.ToView(),
+		"LowConfidenceOCR": result.LowConfidence(h.ocrLowConfidence),
+		"OCRLowConfidence": h.ocrLowConfidence,
+		"Currencies":       currencyOptions(tx.Currency),
 	}))
 }
 
@@ -118,6 +229,13 @@ func (h *Handler) ConfirmTransaction(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
+	if req.Currency != "" {
+		if !fx.Valid(req.Currency) {
+			http.Error(w, "Invalid currency", http.StatusBadRequest)
+			return
+		}
+		req.Currency = fx.Normalize(req.Currency)
+	}
 
 	userID, _ := h.currentUserID(w, r)
 	if err := h.repo.ConfirmTransaction(userID, id, req); err != nil {
@@ -152,7 +270,25 @@ func (h *Handler) GetTransaction(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(tx.ToView())
 }
 
-func (h *Handler) DeleteTransaction(w http.ResponseWriter, r *http.Request) {
+const (
+	statusPollInterval  = 500 * time.Millisecond
+	statusStreamTimeout = 2 * time.Minute
+)
+
+func transactionStatus(view models.TransactionView) map[string]interface{} {
+	return map[string]interface{}{
+		"id":               view.ID,
+		"processing_state": view.ProcessingState,
+		"processing_error": view.ProcessingError,
+		"status":           view.Status,
+		"transaction":      view,
+	}
+}
+
+// TransactionStatus handles GET /api/transactions/{id}/status. Clients that
+// accept text/event-stream receive a "status" event whenever processing
+// progresses, until it is done or failed; other clients get the current status.
+func (h *Handler) TransactionStatus(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
 	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil {
@@ -167,16 +303,131 @@ func (h *Handler) DeleteTransaction(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.repo.DeleteTransaction(userID, id); err != nil {
-		log.Printf("Failed to delete transaction %d: %v", id, err)
-		http.Error(w, "Failed to delete transaction", http.StatusInternalServerError)
+	if !strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(transactionStatus(tx.ToView()))
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
 		return
 	}
 
-	if tx.SlipImagePath.Valid && tx.SlipImagePath.String != "" {
-		if err := h.storage.Delete(tx.SlipImagePath.String); err != nil {
-			log.Printf("Failed to delete slip for transaction %d: %v", id, err)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	ticker := time.NewTicker(statusPollInterval)
+	defer ticker.Stop()
+	timeout := time.After(statusStreamTimeout)
+
+	var last string
+	for {
+		view := tx.ToView()
+		data, err := json.Marshal(transactionStatus(view))
+		if err != nil {
+			return
+		}
+		if string(data) != last {
+			fmt.Fprintf(w, "event: status\ndata: %s\n\n", data)
+			flusher.Flush()
+			last = string(data)
+		}
+		if !view.IsProcessing() {
+			return
+		}
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-timeout:
+			return
+		case <-ticker.C:
 		}
+
+		if tx, err = h.repo.GetTransaction(userID, id); err != nil {
+			return
+		}
+	}
+}
+
+// RetryProcessing handles POST /api/transactions/{id}/retry and queues the
+// slip for another round of OCR and LLM parsing. Confirmed transactions
+// are not parsed again.
+func (h *Handler) RetryProcessing(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid transaction ID", http.StatusBadRequest)
+		return
+	}
+
+	userID, _ := h.currentUserID(w, r)
+	tx, err := h.repo.GetTransaction(userID, id)
+	if err != nil {
+		http.Error(w, "Transaction not found", http.StatusNotFound)
+		return
+	}
+
+	view := tx.ToView()
+	if view.SlipImagePath == "" {
+		http.Error(w, "Transaction has no slip to parse", http.StatusBadRequest)
+		return
+	}
+	if view.IsProcessing() {
+		http.Error(w, "Transaction is already being processed", http.StatusConflict)
+		return
+	}
+	// Parsing again would overwrite the values the user confirmed
+	if view.Status == "confirmed" {
+		http.Error(w, "Transaction is already confirmed", http.StatusConflict)
+		return
+	}
+
+	if err := h.repo.SetProcessingState(id, models.ProcessingQueued, ""); err != nil {
+		log.Printf("Failed to reset processing state for transaction %d: %v", id, err)
+		http.Error(w, "Failed to queue transaction", http.StatusInternalServerError)
+		return
+	}
+	if _, err := h.queue.Enqueue(jobs.KindSlipOCR, userID, id, slipJobPayload{Filename: view.SlipImagePath}); err != nil {
+		log.Printf("Failed to queue OCR for transaction %d: %v", id, err)
+		h.repo.SetProcessingState(id, models.ProcessingFailed, "failed to queue slip processing")
+		http.Error(w, "Failed to queue transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":          true,
+		"processing_state": models.ProcessingQueued,
+	})
+}
+
+func (h *Handler) DeleteTransaction(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid transaction ID", http.StatusBadRequest)
+		return
+	}
+
+	// The transaction and its slip stay in the trash until purged
+	userID, _ := h.currentUserID(w, r)
+	err = h.repo.DeleteTransaction(userID, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "Transaction not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, database.ErrClaimNotDraft) {
+		http.Error(w, "Transaction is on a submitted or paid claim", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to delete transaction %d: %v", id, err)
+		http.Error(w, "Failed to delete transaction", http.StatusInternalServerError)
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -186,10 +437,11 @@ func (h *Handler) DeleteTransaction(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// GetRecentTransactions handles GET /api/transactions/recent, the newest
+// transactions by creation time. Use GET /api/transactions to page further.
 func (h *Handler) GetRecentTransactions(w http.ResponseWriter, r *http.Request) {
 	userID, _ := h.currentUserID(w, r)
 	limit := 20
-	offset := 0
 	if v := r.URL.Query().Get("limit"); v != "" {
 		if parsed, err := strconv.Atoi(v); err == nil && parsed > 0 {
 			if parsed > 100 {
@@ -198,13 +450,12 @@ func (h *Handler) GetRecentTransactions(w http.ResponseWriter, r *http.Request)
 			limit = parsed
 		}
 	}
-	if v := r.URL.Query().Get("offset"); v != "" {
-		if parsed, err := strconv.Atoi(v); err == nil && parsed >= 0 {
-			offset = parsed
-		}
-	}
 
-	transactions, err := h.repo.ListTransactions(userID, limit, offset)
+	transactions, _, err := h.repo.QueryTransactions(models.TransactionQuery{
+		UserID: userID,
+		Sort:   models.SortCreatedAt,
+		Limit:  limit,
+	})
 	if err != nil {
 		http.Error(w, "Failed to load transactions", http.StatusInternalServerError)
 		return
@@ -218,3 +469,18 @@ func (h *Handler) GetRecentTransactions(w http.ResponseWriter, r *http.Request)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(views)
 }
+
+// currencyOptions lists the currencies a transaction can be confirmed in,
+// including its current one when that is not a known currency
+func currencyOptions(current string) []string {
+	codes := fx.Codes()
+	for _, code := range codes {
+		if code == current {
+			return codes
+		}
+	}
+	if current != "" {
+		codes = append(codes, current)
+	}
+	return codes
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"cash-track/internal/models"
+)
+
+// TrashPage renders the user's trashed transactions
+func (h *Handler) TrashPage(w http.ResponseWriter, r *http.Request) {
+	userID, _ := h.currentUserID(w, r)
+	transactions, err := h.repo.ListTrash(userID, 200)
+	if err != nil {
+		http.Error(w, "Failed to load trash", http.StatusInternalServerError)
+		return
+	}
+
+	var views []models.TransactionView
+	for _, tx := range transactions {
+		views = append(views, tx.ToView())
+	}
+
+	h.renderTemplate(w, "trash.html", h.withUserContext(w, r, map[string]interface{}{
+		"Transactions":  views,
+		"RetentionDays": int(h.trashRetention.Hours() / 24),
+	}))
+}
+
+// ListTrash handles GET /api/transactions/trash
+func (h *Handler) ListTrash(w http.ResponseWriter, r *http.Request) {
+	userID, _ := h.currentUserID(w, r)
+	transactions, err := h.repo.ListTrash(userID, 200)
+	if err != nil {
+		http.Error(w, "Failed to load trash", http.StatusInternalServerError)
+		return
+	}
+
+	views := []models.TransactionView{}
+	for _, tx := range transactions {
+		views = append(views, tx.ToView())
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(views)
+}
+
+// RestoreTransaction handles POST /api/transactions/{id}/restore
+func (h *Handler) RestoreTransaction(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid transaction ID", http.StatusBadRequest)
+		return
+	}
+
+	userID, _ := h.currentUserID(w, r)
+	err = h.repo.RestoreTransaction(userID, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "Transaction not in trash", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to restore transaction %d: %v", id, err)
+		http.Error(w, "Failed to restore transaction", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"success": true})
+}
+
+// RunTrashPurge permanently deletes transactions that have been in the trash
+// longer than the retention period, now and then every interval, until ctx
+// is cancelled.
+func (h *Handler) RunTrashPurge(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		h.purgeTrash()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (h *Handler) purgeTrash() {
+	purged, slips, err := h.repo.PurgeTrash(time.Now().Add(-h.trashRetention))
+	if err != nil {
+		log.Printf("Failed to purge trash: %v", err)
+		return
+	}
+	for _, path := range slips {
+		if err := h.storage.Delete(path); err != nil {
+			log.Printf("Failed to delete slip %s: %v", path, err)
+		}
+	}
+	if purged > 0 {
+		log.Printf("Purged %d transactions from the trash", purged)
+	}
+}
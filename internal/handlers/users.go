@@ -7,6 +7,9 @@ import (
 	"strings"
 
 	"github.com/go-chi/chi/v5"
+
+	"cash-track/internal/fx"
+	"cash-track/internal/period"
 )
 
 type createUserRequest struct {
@@ -17,8 +20,10 @@ type selectUserRequest struct {
 	UserID int64 `json:"user_id"`
 }
 
-type updateCutoffRequest struct {
-	CutoffDay int `json:"cutoff_day"`
+// updateUserRequest changes the fields that are set and leaves the rest
+type updateUserRequest struct {
+	CutoffDay    *int    `json:"cutoff_day"`
+	BaseCurrency *string `json:"base_currency"`
 }
 
 func (h *Handler) ListUsers(w http.ResponseWriter, r *http.Request) {
@@ -87,7 +92,7 @@ func (h *Handler) SelectUser(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-func (h *Handler) UpdateCutoff(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) UpdateUser(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
 	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil {
@@ -95,22 +100,38 @@ func (h *Handler) UpdateCutoff(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var req updateCutoffRequest
+	var req updateUserRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
-	if req.CutoffDay < 1 || req.CutoffDay > 30 {
-		http.Error(w, "Cutoff day must be between 1 and 30", http.StatusBadRequest)
+	if req.CutoffDay != nil && !period.ValidCutoff(*req.CutoffDay) {
+		http.Error(w, "Cutoff day must be between 1 and 31, or -1 for the last business day", http.StatusBadRequest)
+		return
+	}
+	if req.BaseCurrency != nil && !fx.Valid(*req.BaseCurrency) {
+		http.Error(w, "Invalid base currency", http.StatusBadRequest)
 		return
 	}
 
-	user, err := h.repo.UpdateUserCutoff(id, req.CutoffDay)
+	user, err := h.repo.GetUser(id)
 	if err != nil {
-		http.Error(w, "Failed to update cutoff", http.StatusInternalServerError)
+		http.Error(w, "User not found", http.StatusNotFound)
 		return
 	}
+	if req.CutoffDay != nil {
+		if user, err = h.repo.UpdateUserCutoff(id, *req.CutoffDay); err != nil {
+			http.Error(w, "Failed to update cutoff", http.StatusInternalServerError)
+			return
+		}
+	}
+	if req.BaseCurrency != nil {
+		if user, err = h.repo.UpdateUserBaseCurrency(id, fx.Normalize(*req.BaseCurrency)); err != nil {
+			http.Error(w, "Failed to update base currency", http.StatusInternalServerError)
+			return
+		}
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(user)
@@ -0,0 +1,210 @@
+// Package httpfixture records HTTP exchanges with the Ollama and OCR services
+// and replays them in tests.
+//
+// A test asks for a server backed by a fixture file:
+//
+//	srv := httpfixture.Server(t, "testdata/ollama_chat.json", os.Getenv("OLLAMA_URL"))
+//	client := llm.NewClient(srv.URL, "llama3.2")
+//
+// Normally the server answers from the file. With RECORD_FIXTURES=1 it
+// forwards every request to the upstream URL instead and rewrites the file
+// with what was exchanged when the test ends.
+//
+// Requests are matched on method and path, in the order they were recorded.
+package httpfixture
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// RecordEnv switches Server from replaying to recording when set
+const RecordEnv = "RECORD_FIXTURES"
+
+// Exchange is one recorded request and its response
+type Exchange struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Request is the part of a request used for matching. Bodies are not kept:
+// prompts embed today's date and OCR requests carry the image.
+type Request struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+}
+
+// Response is a recorded response
+type Response struct {
+	Status      int             `json:"status"`
+	ContentType string          `json:"content_type,omitempty"`
+	Body        json.RawMessage `json:"body,omitempty"`
+	Text        string          `json:"text,omitempty"` // a body that is not JSON
+}
+
+// Recording reports whether tests should record instead of replay
+func Recording() bool {
+	return os.Getenv(RecordEnv) != ""
+}
+
+// Load reads the exchanges stored in a fixture file
+func Load(path string) ([]Exchange, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var exchanges []Exchange
+	if err := json.Unmarshal(data, &exchanges); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return exchanges, nil
+}
+
+// Save writes exchanges to a fixture file
+func Save(path string, exchanges []Exchange) error {
+	data, err := json.MarshalIndent(exchanges, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// Server starts a test server backed by the fixture at path. It replays the
+// fixture, or records against upstream when RECORD_FIXTURES is set.
+func Server(t testing.TB, path, upstream string) *httptest.Server {
+	t.Helper()
+	var handler http.Handler
+	if Recording() {
+		if upstream == "" {
+			t.Fatalf("recording %s needs the service URL", path)
+		}
+		rec := &recorder{upstream: strings.TrimRight(upstream, "/")}
+		t.Cleanup(func() {
+			if err := Save(path, rec.exchanges); err != nil {
+				t.Errorf("save %s: %v", path, err)
+			}
+		})
+		handler = rec
+	} else {
+		exchanges, err := Load(path)
+		if err != nil {
+			t.Fatalf("load fixture: %v (record it with %s=1)", err, RecordEnv)
+		}
+		handler = &player{t: t, path: path, exchanges: exchanges}
+	}
+
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// recorder forwards requests upstream and keeps each exchange
+type recorder struct {
+	upstream string
+
+	mu        sync.Mutex
+	exchanges []Exchange
+}
+
+func (rec *recorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Read the body fully so it is not forwarded chunked
+	reqBody, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	req, err := http.NewRequestWithContext(r.Context(), r.Method, rec.upstream+r.URL.RequestURI(), bytes.NewReader(reqBody))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	req.Header.Set("Content-Type", r.Header.Get("Content-Type"))
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	ex := Exchange{
+		Request:  Request{Method: r.Method, Path: r.URL.Path},
+		Response: Response{Status: resp.StatusCode, ContentType: resp.Header.Get("Content-Type")},
+	}
+	if json.Valid(body) {
+		ex.Response.Body = body
+	} else {
+		ex.Response.Text = string(body)
+	}
+	rec.mu.Lock()
+	rec.exchanges = append(rec.exchanges, ex)
+	rec.mu.Unlock()
+
+	if ex.Response.ContentType != "" {
+		w.Header().Set("Content-Type", ex.Response.ContentType)
+	}
+	w.WriteHeader(resp.StatusCode)
+	w.Write(body)
+}
+
+// player answers each request with the next unused exchange for its method
+// and path
+type player struct {
+	t    testing.TB
+	path string
+
+	mu        sync.Mutex
+	exchanges []Exchange
+	used      []bool
+}
+
+func (p *player) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	io.Copy(io.Discard, r.Body)
+
+	p.mu.Lock()
+	if p.used == nil {
+		p.used = make([]bool, len(p.exchanges))
+	}
+	var ex *Exchange
+	for i := range p.exchanges {
+		if !p.used[i] && p.exchanges[i].Request.Method == r.Method && p.exchanges[i].Request.Path == r.URL.Path {
+			p.used[i] = true
+			ex = &p.exchanges[i]
+			break
+		}
+	}
+	p.mu.Unlock()
+
+	if ex == nil {
+		p.t.Errorf("%s: no recorded exchange left for %s %s", p.path, r.Method, r.URL.Path)
+		http.Error(w, "no recorded exchange", http.StatusNotImplemented)
+		return
+	}
+
+	if ex.Response.ContentType != "" {
+		w.Header().Set("Content-Type", ex.Response.ContentType)
+	}
+	w.WriteHeader(ex.Response.Status)
+	if len(ex.Response.Body) > 0 {
+		w.Write(ex.Response.Body)
+	} else {
+		io.WriteString(w, ex.Response.Text)
+	}
+}
@@ -0,0 +1,209 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"cash-track/internal/database"
+	"cash-track/internal/models"
+)
+
+// Job kinds
+const (
+	KindSlipOCR = "slip_ocr"
+	KindReparse = "reparse"
+)
+
+const (
+	pollInterval = 2 * time.Second
+	baseDelay    = 5 * time.Second
+	maxDelay     = 10 * time.Minute
+)
+
+// HandlerFunc processes a single job. Returning an error schedules a retry
+// with backoff unless the error is wrapped with Permanent.
+type HandlerFunc func(ctx context.Context, job *models.Job) error
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks an error as not worth retrying.
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+// Queue runs jobs stored in SQLite on a fixed pool of workers. Jobs survive
+// restarts: anything still queued or interrupted mid-run is picked up again
+// when the queue starts.
+type Queue struct {
+	repo        *database.Repository
+	workers     int
+	maxAttempts int
+	handlers    map[string]HandlerFunc
+	wake        chan struct{}
+	wg          sync.WaitGroup
+}
+
+func NewQueue(repo *database.Repository, workers, maxAttempts int) *Queue {
+	if workers < 1 {
+		workers = 1
+	}
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	return &Queue{
+		repo:        repo,
+		workers:     workers,
+		maxAttempts: maxAttempts,
+		handlers:    map[string]HandlerFunc{},
+		wake:        make(chan struct{}, 1),
+	}
+}
+
+// Register sets the handler for a job kind. It must be called before Start.
+func (q *Queue) Register(kind string, fn HandlerFunc) {
+	q.handlers[kind] = fn
+}
+
+// Enqueue stores a job and wakes an idle worker. payload is encoded as JSON.
+func (q *Queue) Enqueue(kind string, userID, transactionID int64, payload interface{}) (*models.Job, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode job payload: %w", err)
+	}
+
+	job, err := q.repo.EnqueueJob(kind, userID, transactionID, string(data), q.maxAttempts)
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+	return job, nil
+}
+
+// Start requeues interrupted jobs and launches the worker pool. Workers stop
+// when ctx is cancelled; use Wait to block until they have exited.
+func (q *Queue) Start(ctx context.Context) error {
+	resumed, err := q.repo.RequeueRunningJobs()
+	if err != nil {
+		return fmt.Errorf("failed to requeue interrupted jobs: %w", err)
+	}
+	if resumed > 0 {
+		log.Printf("Resuming %d interrupted jobs", resumed)
+	}
+
+	for i := 0; i < q.workers; i++ {
+		q.wg.Add(1)
+		go q.worker(ctx)
+	}
+	return nil
+}
+
+// Wait blocks until all workers have stopped.
+func (q *Queue) Wait() {
+	q.wg.Wait()
+}
+
+func (q *Queue) worker(ctx context.Context) {
+	defer q.wg.Done()
+
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+
+		job, err := q.repo.ClaimJob()
+		if err != nil {
+			log.Printf("Failed to claim job: %v", err)
+		}
+		if job == nil {
+			select {
+			case <-ctx.Done():
+				return
+			case <-q.wake:
+			case <-time.After(pollInterval):
+			}
+			continue
+		}
+
+		q.run(ctx, job)
+	}
+}
+
+func (q *Queue) run(ctx context.Context, job *models.Job) {
+	err := q.call(ctx, job)
+	if err == nil {
+		if err := q.repo.CompleteJob(job.ID); err != nil {
+			log.Printf("Failed to complete job %d: %v", job.ID, err)
+		}
+		if job.TransactionID.Valid {
+			q.repo.SetProcessingState(job.TransactionID.Int64, models.ProcessingDone, "")
+		}
+		return
+	}
+
+	var permanent *permanentError
+	retry := !errors.As(err, &permanent) && job.Attempts < job.MaxAttempts
+
+	msg := err.Error()
+	state := models.ProcessingFailed
+	if retry {
+		state = models.ProcessingQueued
+		delay := backoff(job.Attempts)
+		log.Printf("Job %d (%s) attempt %d/%d failed, retrying in %s: %v", job.ID, job.Kind, job.Attempts, job.MaxAttempts, delay, err)
+		if err := q.repo.RetryJob(job.ID, msg, delay); err != nil {
+			log.Printf("Failed to reschedule job %d: %v", job.ID, err)
+		}
+		msg = fmt.Sprintf("attempt %d/%d failed, retrying: %s", job.Attempts, job.MaxAttempts, msg)
+	} else {
+		log.Printf("Job %d (%s) failed: %v", job.ID, job.Kind, err)
+		if err := q.repo.FailJob(job.ID, msg); err != nil {
+			log.Printf("Failed to mark job %d as failed: %v", job.ID, err)
+		}
+	}
+
+	if job.TransactionID.Valid {
+		if err := q.repo.SetProcessingState(job.TransactionID.Int64, state, msg); err != nil {
+			log.Printf("Failed to record error on transaction %d: %v", job.TransactionID.Int64, err)
+		}
+	}
+}
+
+func (q *Queue) call(ctx context.Context, job *models.Job) (err error) {
+	fn, ok := q.handlers[job.Kind]
+	if !ok {
+		return Permanent(fmt.Errorf("no handler registered for job kind %q", job.Kind))
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+	return fn(ctx, job)
+}
+
+// backoff returns the delay before retry number attempt (1-based), doubling
+// each time up to maxDelay.
+func backoff(attempt int) time.Duration {
+	delay := baseDelay
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if delay >= maxDelay {
+			return maxDelay
+		}
+	}
+	return delay
+}
@@ -0,0 +1,88 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"cash-track/internal/database"
+	"cash-track/internal/models"
+)
+
+func newTestQueue(t *testing.T, maxAttempts int) (*Queue, *database.Repository, *sql.DB) {
+	t.Helper()
+	db, err := database.New(fmt.Sprintf("file:%s?mode=memory&cache=shared&_pragma=busy_timeout(5000)", t.Name()))
+	if err != nil {
+		t.Fatalf("database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	repo := database.NewRepository(db)
+	return NewQueue(repo, 1, maxAttempts), repo, db
+}
+
+func TestQueueRun(t *testing.T) {
+	tests := []struct {
+		name        string
+		handler     HandlerFunc
+		maxAttempts int
+		want        string
+	}{
+		{"success", func(context.Context, *models.Job) error { return nil }, 3, "done"},
+		{"error", func(context.Context, *models.Job) error { return errors.New("timeout") }, 3, "queued"},
+		{"last attempt", func(context.Context, *models.Job) error { return errors.New("timeout") }, 1, "failed"},
+		{"permanent", func(context.Context, *models.Job) error { return Permanent(errors.New("bad slip")) }, 3, "failed"},
+		{"panic", func(context.Context, *models.Job) error { panic("boom") }, 3, "queued"},
+		{"no handler", nil, 3, "failed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, repo, db := newTestQueue(t, tt.maxAttempts)
+			if tt.handler != nil {
+				q.Register(KindSlipOCR, tt.handler)
+			}
+			if _, err := q.Enqueue(KindSlipOCR, 1, 0, map[string]string{"path": "slip.jpg"}); err != nil {
+				t.Fatal(err)
+			}
+			job, err := repo.ClaimJob()
+			if err != nil || job == nil {
+				t.Fatalf("claim = %+v, %v", job, err)
+			}
+			if job.Payload != `{"path":"slip.jpg"}` {
+				t.Errorf("payload = %s", job.Payload)
+			}
+
+			q.run(context.Background(), job)
+			var status string
+			var lastError sql.NullString
+			if err := db.QueryRow(`SELECT status, last_error FROM jobs WHERE id = ?`, job.ID).Scan(&status, &lastError); err != nil {
+				t.Fatal(err)
+			}
+			if status != tt.want || lastError.Valid != (tt.want != "done") {
+				t.Errorf("status = %s, last error %q, want %s", status, lastError.String, tt.want)
+			}
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{1, 5 * time.Second},
+		{2, 10 * time.Second},
+		{3, 20 * time.Second},
+		{7, 320 * time.Second},
+		{8, maxDelay},
+		{100, maxDelay},
+	}
+	for _, tt := range tests {
+		if got := backoff(tt.attempt); got != tt.want {
+			t.Errorf("backoff(%d) = %s, want %s", tt.attempt, got, tt.want)
+		}
+	}
+}
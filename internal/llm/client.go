@@ -8,6 +8,8 @@ import (
 	"net/http"
 	"strings"
 	"time"
+
+	"cash-track/internal/models"
 )
 
 type Client struct {
@@ -29,23 +31,48 @@ type GenerateResponse struct {
 
 // ChatResponse represents the parsed LLM response for chat messages
 type ChatResponse struct {
-	Intent      string             `json:"intent"` // add_transaction | bill_payment | query_summary | unknown
+	Intent      string             `json:"intent"` // add_transaction | bill_payment | query_summary | search_transactions | forecast | unknown
 	Transaction *ParsedTransaction `json:"transaction,omitempty"`
 	Filters     *QueryFilters      `json:"filters,omitempty"`
+	Search      *SearchFilters     `json:"search,omitempty"`
+	Receipt     *ParsedReceipt     `json:"receipt,omitempty"`
 	Confidence  float64            `json:"confidence,omitempty"`
+	Extraction
+}
+
+// Extraction describes how a response was produced
+type Extraction struct {
+	Source        string `json:"source,omitempty"` // models.ActorLLM or models.ActorRegex
+	Model         string `json:"model,omitempty"`
+	PromptVersion string `json:"prompt_version,omitempty"`
+	LatencyMS     int64  `json:"latency_ms"`
+	Raw           string `json:"-"` // the model's JSON, kept even when it was unusable
 }
 
 // ParsedTransaction represents a transaction extracted by LLM
 type ParsedTransaction struct {
-	TxnDate      string  `json:"txn_date"`
-	Amount       float64 `json:"amount"`
-	Currency     string  `json:"currency"`
-	Direction    string  `json:"direction"`
-	Channel      string  `json:"channel"`
-	AccountLabel string  `json:"account_label"`
-	Category     string  `json:"category"`
-	Description  string  `json:"description"`
-	Confidence   float64 `json:"confidence"`
+	TxnDate      string           `json:"txn_date"`
+	Amount       models.NullMoney `json:"amount"`
+	Currency     string           `json:"currency"`
+	Direction    string           `json:"direction"`
+	Channel      string           `json:"channel"`
+	AccountLabel string           `json:"account_label"`
+	Category     string           `json:"category"`
+	Description  string           `json:"description"`
+	Tags         []string         `json:"tags"`
+	Confidence   float64          `json:"confidence"`
+	Receipt      *ParsedReceipt   `json:"-"`
+	Extraction   `json:"-"`
+}
+
+// ParsedReceipt holds the tax invoice fields of a receipt, when the slip is
+// one. The handler validates them before they are stored.
+type ParsedReceipt struct {
+	SellerTaxID   string           `json:"seller_tax_id"`
+	Branch        string           `json:"branch"`
+	InvoiceNumber string           `json:"invoice_number"`
+	VAT           models.NullMoney `json:"vat"`
+	PreVAT        models.NullMoney `json:"pre_vat_amount"`
 }
 
 // QueryFilters represents filters for summary queries
@@ -54,11 +81,19 @@ type QueryFilters struct {
 	Period    PeriodFilter `json:"period"`
 	Category  string       `json:"category"`
 	Channel   string       `json:"channel"`
+	Tag       string       `json:"tag"`
+}
+
+// SearchFilters represents a request to find transactions. Query holds the
+// words to look for and may use the search syntax (amount:, category:, ...).
+type SearchFilters struct {
+	Query  string       `json:"query"`
+	Period PeriodFilter `json:"period"`
 }
 
 // PeriodFilter represents a time period for queries
 type PeriodFilter struct {
-	Type string `json:"type"` // month | day | range | year | all
+	Type string `json:"type"` // month | last_month | day | range | year | all
 	From string `json:"from"`
 	To   string `json:"to"`
 }
@@ -75,6 +110,15 @@ func NewClient(endpoint, model string) *Client {
 
 // ParseChatMessage parses a chat message (with optional OCR text) and returns structured data
 func (c *Client) ParseChatMessage(message string, ocrText *string, lang string) (*ChatResponse, error) {
+	start := time.Now()
+	resp := c.parseChatMessage(message, ocrText, lang)
+	resp.LatencyMS = time.Since(start).Milliseconds()
+	return resp, nil
+}
+
+// parseChatMessage asks the model and falls back to regex parsing when it is
+// unreachable or its answer is unusable
+func (c *Client) parseChatMessage(message string, ocrText *string, lang string) *ChatResponse {
 	var prompt string
 	today := time.Now().Format("2006-01-02")
 
@@ -88,28 +132,38 @@ func (c *Client) ParseChatMessage(message string, ocrText *string, lang string)
 
 	response, err := c.generate(prompt)
 	if err != nil {
-		return parseWithRegex(message, ocrText), nil
+		return parseWithRegex(message, ocrText)
 	}
 
 	// Extract JSON from response
 	jsonStr := extractJSON(response)
+	fallback := func() *ChatResponse {
+		resp := parseWithRegex(message, ocrText)
+		resp.Raw = response
+		return resp
+	}
 	if jsonStr == "" {
-		return parseWithRegex(message, ocrText), nil
+		return fallback()
 	}
 
 	var chatResp ChatResponse
 	if err := json.Unmarshal([]byte(jsonStr), &chatResp); err != nil {
-		return parseWithRegex(message, ocrText), nil
+		return fallback()
+	}
+	chatResp.Extraction = Extraction{
+		Source:        models.ActorLLM,
+		Model:         c.model,
+		PromptVersion: PromptVersion,
+		Raw:           jsonStr,
 	}
 
 	if chatResp.Intent == "" || chatResp.Intent == "unknown" {
-		fallback := parseWithRegex(message, ocrText)
-		if fallback != nil && fallback.Intent != "" && fallback.Intent != "unknown" {
-			return fallback, nil
+		if resp := fallback(); resp.Intent != "" && resp.Intent != "unknown" {
+			return resp
 		}
 	}
 
-	return &chatResp, nil
+	return &chatResp
 }
 
 // ParseSlipText parses OCR text from a slip and returns transaction data
@@ -124,8 +178,10 @@ func (c *Client) ParseSlipText(ocrText string) (*ParsedTransaction, error) {
 		return nil, fmt.Errorf("no transaction data in LLM response")
 	}
 
-	// Copy confidence from response to transaction
+	// Copy confidence, receipt and extraction details from response to transaction
 	resp.Transaction.Confidence = resp.Confidence
+	resp.Transaction.Receipt = resp.Receipt
+	resp.Transaction.Extraction = resp.Extraction
 
 	return resp.Transaction, nil
 }
@@ -165,6 +221,25 @@ func (c *Client) generate(prompt string) (string, error) {
 	return genResp.Response, nil
 }
 
+// Values returns the extracted fields formatted like Transaction.FieldValue.
+// Fields the parser left empty are "".
+func (p *ParsedTransaction) Values() map[string]string {
+	values := map[string]string{
+		"txn_date":      p.TxnDate,
+		"amount":        "",
+		"currency":      strings.ToUpper(strings.TrimSpace(p.Currency)),
+		"direction":     p.Direction,
+		"channel":       p.Channel,
+		"account_label": p.AccountLabel,
+		"category":      p.Category,
+		"description":   p.Description,
+	}
+	if p.Amount.Valid {
+		values["amount"] = p.Amount.String()
+	}
+	return values
+}
+
 func extractJSON(text string) string {
 	start := strings.Index(text, "{")
 	end := strings.LastIndex(text, "}")
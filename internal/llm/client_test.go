@@ -0,0 +1,103 @@
+package llm
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"cash-track/internal/httpfixture"
+	"cash-track/internal/models"
+)
+
+// fixtureClient returns a client talking to a replay of the named fixture,
+// or to Ollama when recording
+func fixtureClient(t *testing.T, name string) *Client {
+	t.Helper()
+	srv := httpfixture.Server(t, "testdata/"+name, os.Getenv("OLLAMA_URL"))
+	return NewClient(srv.URL, "llama3.2")
+}
+
+func TestGenerate(t *testing.T) {
+	client := fixtureClient(t, "ollama_chat.json")
+
+	response, err := client.generate("ข้าวมันไก่ 60 บาท")
+	if err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	if extractJSON(response) == "" {
+		t.Fatalf("response has no JSON: %q", response)
+	}
+}
+
+func TestGenerateError(t *testing.T) {
+	client := fixtureClient(t, "ollama_missing_model.json")
+
+	_, err := client.generate("ข้าวมันไก่ 60 บาท")
+	if err == nil {
+		t.Fatal("generate succeeded, want an error")
+	}
+	if !strings.Contains(err.Error(), "status 404") {
+		t.Errorf("error = %v, want the status", err)
+	}
+}
+
+func TestParseChatMessage(t *testing.T) {
+	client := fixtureClient(t, "ollama_chat.json")
+
+	resp, err := client.ParseChatMessage("ข้าวมันไก่ 60 บาท", nil, "th")
+	if err != nil {
+		t.Fatalf("ParseChatMessage: %v", err)
+	}
+	if resp.Intent != "add_transaction" {
+		t.Fatalf("intent = %q, want add_transaction", resp.Intent)
+	}
+	if resp.Source != models.ActorLLM || resp.Model != "llama3.2" || resp.PromptVersion != PromptVersion {
+		t.Errorf("extraction = %+v", resp.Extraction)
+	}
+	values := resp.Transaction.Values()
+	if values["amount"] != "60.00" || values["category"] != "food" || values["currency"] != "THB" {
+		t.Errorf("values = %v", values)
+	}
+}
+
+func TestParseChatMessageFallsBackToRegex(t *testing.T) {
+	cases := []struct {
+		fixture string
+		raw     bool // the unusable answer is kept
+	}{
+		{"ollama_unusable.json", true},
+		{"ollama_missing_model.json", false},
+	}
+
+	for _, tc := range cases {
+		client := fixtureClient(t, tc.fixture)
+		resp, err := client.ParseChatMessage("ข้าวมันไก่ 60 บาท", nil, "th")
+		if err != nil {
+			t.Fatalf("%s: ParseChatMessage: %v", tc.fixture, err)
+		}
+		if resp.Source != models.ActorRegex {
+			t.Errorf("%s: source = %q, want regex", tc.fixture, resp.Source)
+		}
+		if resp.Transaction == nil || resp.Transaction.Amount.String() != "60.00" {
+			t.Errorf("%s: transaction = %+v, want amount 60", tc.fixture, resp.Transaction)
+		}
+		if got := resp.Raw != ""; got != tc.raw {
+			t.Errorf("%s: raw = %q", tc.fixture, resp.Raw)
+		}
+	}
+}
+
+func TestParseSlipText(t *testing.T) {
+	client := fixtureClient(t, "ollama_slip.json")
+
+	tx, err := client.ParseSlipText("ชำระเงินสำเร็จ\nการไฟฟ้านครหลวง\n1,234.50 บาท")
+	if err != nil {
+		t.Fatalf("ParseSlipText: %v", err)
+	}
+	if tx.Confidence != 0.88 || tx.Source != models.ActorLLM {
+		t.Errorf("confidence = %v, source = %q", tx.Confidence, tx.Source)
+	}
+	if tx.Amount.String() != "1234.50" || tx.Channel != "kbank" || tx.TxnDate != "2026-10-18" {
+		t.Errorf("transaction = %v", tx.Values())
+	}
+}
@@ -1,5 +1,9 @@
 package llm
 
+// PromptVersion identifies the prompts below. Bump it whenever they change so
+// extractions and accuracy reports can be compared across versions.
+const PromptVersion = "2026-10-19.5"
+
 // TextPromptTemplate is used for parsing text-only chat messages
 const TextPromptTemplate = `You are a strict JSON parser for a single-user personal finance tracker.
 User writes informal Thai or English messages about expenses and incomes.
@@ -10,6 +14,10 @@ You MUST respond with ONLY raw JSON. No explanation. No markdown.
 Supported intents:
 - "add_transaction": user logs a new income/expense/transfer.
 - "query_summary": user asks for totals or breakdowns over some time period.
+- "search_transactions": user wants to find or list specific transactions (หา, ค้นหา, find, search).
+- "forecast": user asks how much will be left or spent by the end of the month or pay cycle
+  (สิ้นเดือนจะเหลือเท่าไหร่, how much will I have left at the end of the month). Respond with
+  {"intent": "forecast"} only.
 - "unknown": cannot confidently interpret the message.
 
 When intent = "add_transaction", use this JSON format:
@@ -19,15 +27,23 @@ When intent = "add_transaction", use this JSON format:
   "transaction": {
     "txn_date": "YYYY-MM-DD or current date time",
     "amount": number or null,
-    "currency": "THB",
+    "currency": "THB" | "USD" | "JPY" | "LAK",
     "direction": "income" | "expense" | "transfer",
     "channel": "cash" | "scb" | "kbank" | "tmw" | "unknown",
     "account_label": "string or null",
     "category": "food" | "rent" | "shopping" | "transport" | "bill" | "debt" | "other",
-    "description": "string or null"
+    "description": "string or null",
+    "tags": ["tag", ...]
   }
 }
 
+Words starting with # (#trip-chiangmai, #งานบริษัท) are tags. Put them in "tags" in lower case
+without the #, leave them out of the description, and use [] when there are none.
+
+Currency comes from the symbol or word next to the amount:
+บาท, ฿, baht -> "THB"; $, ดอลลาร์, dollar -> "USD"; ¥, 円, เยน, yen -> "JPY"; ₭, กีบ, kip -> "LAK".
+Use "THB" when no currency is written. Write amounts without thousands separators.
+
 When the user message is unclear or missing required information (like amount),
 set those fields to null. NEVER guess.
 
@@ -38,15 +54,37 @@ When intent = "query_summary", use this JSON format:
   "filters": {
     "direction": "income" | "expense" | "both",
     "period": {
-      "type": "month" | "day" | "range" | "year" | "all",
+      "type": "month" | "last_month" | "day" | "range" | "year" | "all",
       "from": "YYYY-MM-DD or null",
       "to": "YYYY-MM-DD or null"
     },
     "category": "string or null",
-    "channel": "string or null"
+    "channel": "string or null",
+    "tag": "tag without the # or null"
+  }
+}
+
+When intent = "search_transactions", use this JSON format:
+
+{
+  "intent": "search_transactions",
+  "search": {
+    "query": "words to look for, e.g. grab",
+    "period": {
+      "type": "month" | "last_month" | "day" | "range" | "year" | "all",
+      "from": "YYYY-MM-DD or null",
+      "to": "YYYY-MM-DD or null"
+    }
   }
 }
 
+Keep the words the user is looking for (shop, app or item names) and any #tags in "query"
+without verbs like หา/find or the time period. Use period type "all" when no period is given.
+
+Period type "month" is the user's current pay cycle (เดือนนี้, this month) and "last_month"
+the one before it (เดือนที่แล้ว, last month); leave from and to null for both, the app
+knows the cycle dates. "year" runs from 1 January to today.
+
 If you really cannot understand, respond with:
 
 {
@@ -72,13 +110,20 @@ Use this JSON format:
   "transaction": {
     "txn_date": "YYYY-MM-DD or null",
     "amount": number or null,
-    "currency": "THB",
+    "currency": "THB" | "USD" | "JPY" | "LAK",
     "direction": "expense",
     "channel": "tmw" | "scb" | "kbank" | "bbl" | "ktb" | "cash" | "unknown",
     "account_label": "string or null",
     "category": "food" | "bill" | "shopping" | "transport" | "other",
     "description": "string or null"
   },
+  "receipt": {
+    "seller_tax_id": "13 digits or null",
+    "branch": "5 digits, \"00000\" for head office, or null",
+    "invoice_number": "string or null",
+    "vat": number or null,
+    "pre_vat_amount": number or null
+  } or null,
   "confidence": 0.0 to 1.0
 }
 
@@ -90,12 +135,26 @@ Channel mapping:
 - Krungthai, กรุงไทย, KTB -> "ktb"
 - PromptPay can be any bank, try to identify from context
 
+Currency hints:
+- บาท, ฿, THB, or no currency shown -> "THB"
+- $, USD -> "USD"
+- ¥, 円, JPY -> "JPY"
+- ₭, กีบ, LAK -> "LAK"
+
 Category hints:
 - Food/restaurant names, ร้านอาหาร -> "food"
 - Electricity, water, internet, phone -> "bill"
 - Shopee, Lazada, online shopping -> "shopping"
 - Grab, Bolt, taxi, BTS, MRT -> "transport"
 
+Receipt hints:
+- Only fill "receipt" for a tax invoice or receipt from a shop (ใบกำกับภาษี, ใบเสร็จรับเงิน, TAX INVOICE); use null for bank transfer slips
+- seller_tax_id: the seller's เลขประจำตัวผู้เสียภาษี / Tax ID, digits only
+- branch: สาขาที่ / Branch No.; สำนักงานใหญ่ or Head Office -> "00000"
+- invoice_number: เลขที่ใบกำกับภาษี / เลขที่ใบเสร็จ / Invoice No. / Receipt No.
+- vat: ภาษีมูลค่าเพิ่ม / VAT amount, not the rate
+- pre_vat_amount: มูลค่าก่อนภาษี / amount before VAT
+
 Only fill fields when the information is clearly present or strongly implied.
 If unclear, use null. Set confidence based on how certain you are.
 
@@ -5,27 +5,66 @@ import (
 	"strconv"
 	"strings"
 	"time"
+
+	"cash-track/internal/models"
 )
 
+// number matches amounts with or without thousands separators, e.g.
+// "1,234.50" or "1234.50"
+const number = `\d{1,3}(?:,\d{3})+(?:\.\d+)?|\d+(?:\.\d+)?`
+
 var (
-	amountWithUnitRegex = regexp.MustCompile(`(?i)(\d+(?:[.,]\d+)?)\s*(บาท|฿|thb)`)
-	amountRegex         = regexp.MustCompile(`\d+(?:[.,]\d+)?`)
-	dateISORegex        = regexp.MustCompile(`\b(\d{4})-(\d{2})-(\d{2})\b`)
-	dateSlashRegex      = regexp.MustCompile(`\b(\d{1,2})[/-](\d{1,2})[/-](\d{4})\b`)
+	amountWithUnitRegex   = regexp.MustCompile(`(?i)(` + number + `)\s*(บาท|฿|thb|baht|เยน|¥|円|jpy|yen|ดอลลาร์|\$|usd|dollars?|กีบ|₭|lak|kip)`)
+	amountWithPrefixRegex = regexp.MustCompile(`(?i)(฿|¥|\$|₭|thb|jpy|usd|lak)\s*(` + number + `)`)
+	amountRegex           = regexp.MustCompile(number)
+	dateISORegex          = regexp.MustCompile(`\b(\d{4})-(\d{2})-(\d{2})\b`)
+	dateSlashRegex        = regexp.MustCompile(`\b(\d{1,2})[/-](\d{1,2})[/-](\d{4})\b`)
+	tagRegex              = regexp.MustCompile(`(?:^|\s)#([\p{L}\p{M}\p{N}_-]+)`)
 )
 
+// ParseRegex parses a message, or slip text when ocrText is set, with the
+// regex rules alone, as ParseChatMessage does when the model is unavailable
+func ParseRegex(message string, ocrText *string) *ChatResponse {
+	return parseWithRegex(message, ocrText)
+}
+
 func parseWithRegex(message string, ocrText *string) *ChatResponse {
+	var resp *ChatResponse
 	if ocrText != nil && *ocrText != "" {
-		return parseSlipRegex(*ocrText)
+		resp = parseSlipRegex(*ocrText)
+	} else {
+		resp = parseTextRegex(message)
 	}
-	return parseTextRegex(message)
+	resp.Source = models.ActorRegex
+	return resp
 }
 
 func parseTextRegex(message string) *ChatResponse {
 	lower := strings.ToLower(message)
 
+	// Searches keep their #tags, which the search syntax understands
+	if isSearchQuery(lower) {
+		search := parseSearchFilters(lower)
+		return &ChatResponse{
+			Intent: "search_transactions",
+			Search: &search,
+		}
+	}
+
+	// Tags are taken out before anything else is parsed, so "#trip2026" is
+	// not read as an amount
+	tags, message := ParseTags(message)
+	lower = strings.ToLower(message)
+
+	if isForecastQuery(lower) {
+		return &ChatResponse{Intent: "forecast"}
+	}
+
 	if isSummaryQuery(lower) {
 		filters := parseSummaryFilters(lower)
+		if len(tags) > 0 {
+			filters.Tag = tags[0]
+		}
 		return &ChatResponse{
 			Intent:  "query_summary",
 			Filters: &filters,
@@ -33,17 +72,18 @@ func parseTextRegex(message string) *ChatResponse {
 	}
 
 	tx := ParsedTransaction{
-		Currency: "THB",
+		Currency: parseCurrency(lower),
 	}
 
-	tx.Amount = parseAmount(lower)
+	tx.Amount = foundAmount(parseAmount(lower))
 	tx.TxnDate = parseDate(lower)
 	tx.Direction = parseDirection(lower, "expense")
 	tx.Channel = parseChannel(lower)
 	tx.Category = parseCategory(lower)
 	tx.Description = strings.TrimSpace(message)
+	tx.Tags = tags
 
-	if tx.Amount == 0 {
+	if !tx.Amount.Valid {
 		return &ChatResponse{Intent: "unknown"}
 	}
 
@@ -54,21 +94,35 @@ func parseTextRegex(message string) *ChatResponse {
 	}
 }
 
+// ParseTags returns the #tags in text, normalized, and the text without
+// them
+func ParseTags(text string) ([]string, string) {
+	var tags []string
+	for _, m := range tagRegex.FindAllStringSubmatch(text, -1) {
+		tags = append(tags, m[1])
+	}
+	if len(tags) == 0 {
+		return nil, text
+	}
+	rest := strings.Join(strings.Fields(tagRegex.ReplaceAllString(text, " ")), " ")
+	return models.NormalizeTags(tags), rest
+}
+
 func parseSlipRegex(ocrText string) *ChatResponse {
 	lower := strings.ToLower(ocrText)
 
 	tx := ParsedTransaction{
-		Currency:  "THB",
+		Currency:  parseCurrency(lower),
 		Direction: "expense",
 	}
 
-	tx.Amount = parseAmount(lower)
+	tx.Amount = foundAmount(parseAmount(lower))
 	tx.TxnDate = parseDate(lower)
 	tx.Channel = parseChannel(lower)
 	tx.Category = parseCategory(lower)
 	tx.Description = "slip payment"
 
-	if tx.Amount == 0 {
+	if !tx.Amount.Valid {
 		return &ChatResponse{Intent: "unknown"}
 	}
 
@@ -79,20 +133,43 @@ func parseSlipRegex(ocrText string) *ChatResponse {
 	}
 }
 
-func parseAmount(text string) float64 {
+func parseAmount(text string) models.Money {
 	if matches := amountWithUnitRegex.FindStringSubmatch(text); len(matches) > 1 {
 		return parseNumber(matches[1])
 	}
+	if matches := amountWithPrefixRegex.FindStringSubmatch(text); len(matches) > 2 {
+		return parseNumber(matches[2])
+	}
 	if matches := amountRegex.FindAllString(text, -1); len(matches) > 0 {
 		return parseNumber(matches[len(matches)-1])
 	}
 	return 0
 }
 
-func parseNumber(value string) float64 {
-	value = strings.ReplaceAll(value, ",", "")
-	num, _ := strconv.ParseFloat(value, 64)
-	return num
+// currencyUnits maps the symbols and words written next to amounts to their
+// currency codes
+var currencyUnits = map[string]string{
+	"บาท": "THB", "฿": "THB", "thb": "THB", "baht": "THB",
+	"เยน": "JPY", "¥": "JPY", "円": "JPY", "jpy": "JPY", "yen": "JPY",
+	"ดอลลาร์": "USD", "$": "USD", "usd": "USD", "dollar": "USD", "dollars": "USD",
+	"กีบ": "LAK", "₭": "LAK", "lak": "LAK", "kip": "LAK",
+}
+
+// parseCurrency returns the currency written next to the amount, defaulting
+// to THB when none is given
+func parseCurrency(text string) string {
+	if matches := amountWithUnitRegex.FindStringSubmatch(text); len(matches) > 2 {
+		return currencyUnits[strings.ToLower(matches[2])]
+	}
+	if matches := amountWithPrefixRegex.FindStringSubmatch(text); len(matches) > 1 {
+		return currencyUnits[strings.ToLower(matches[1])]
+	}
+	return "THB"
+}
+
+func parseNumber(value string) models.Money {
+	amount, _ := models.ParseMoney(value)
+	return amount
 }
 
 func parseDate(text string) string {
@@ -179,6 +256,78 @@ func parseCategory(text string) string {
 	}
 }
 
+// searchPrefixes start messages that ask to find transactions
+var searchPrefixes = []string{"ค้นหา", "หาค่า", "search for", "search", "find"}
+
+// bareSearchPrefix ("find") also starts everyday words like "หาหมอ" and
+// "หาดใหญ่", so it only makes a search of messages without an amount
+const bareSearchPrefix = "หา"
+
+func isSearchQuery(text string) bool {
+	text = strings.TrimSpace(text)
+	for _, prefix := range searchPrefixes {
+		if strings.HasPrefix(text, prefix) {
+			return true
+		}
+	}
+	// "หาร" (split a bill) also starts with "หา"
+	if !strings.HasPrefix(text, bareSearchPrefix) || strings.HasPrefix(text, "หาร") {
+		return false
+	}
+	_, rest := ParseTags(text)
+	return parseAmount(rest) == 0
+}
+
+// periodWords are removed from search messages once the period is parsed
+var periodWords = []string{
+	"เดือนที่แล้ว", "เดือนนี้", "ปีที่แล้ว", "ปีนี้", "เมื่อวาน", "วันนี้", "ทั้งหมด",
+	"last month", "this month", "last year", "this year", "yesterday", "today", "all time",
+}
+
+// parseSearchFilters extracts what to look for and the period from messages
+// like "หาค่า grab เดือนที่แล้ว". Searches cover all time unless a period is
+// named.
+func parseSearchFilters(text string) SearchFilters {
+	search := SearchFilters{Period: PeriodFilter{Type: "all"}}
+
+	query := strings.TrimSpace(text)
+	for _, prefix := range append(searchPrefixes, bareSearchPrefix) {
+		if strings.HasPrefix(query, prefix) {
+			query = strings.TrimPrefix(query, prefix)
+			break
+		}
+	}
+	// "ค่า" (cost of) prefixes the thing being looked for
+	query = strings.TrimPrefix(strings.TrimSpace(query), "ค่า")
+
+	for _, word := range periodWords {
+		if strings.Contains(query, word) {
+			search.Period = parseSummaryFilters(word).Period
+			query = strings.ReplaceAll(query, word, " ")
+			break
+		}
+	}
+
+	search.Query = strings.Join(strings.Fields(query), " ")
+	return search
+}
+
+// isForecastQuery matches questions about the end of the cycle, like
+// "สิ้นเดือนจะเหลือเท่าไหร่". Questions about a past cycle are summaries.
+func isForecastQuery(text string) bool {
+	if strings.Contains(text, "ที่แล้ว") || strings.Contains(text, "last") {
+		return false
+	}
+	if strings.Contains(text, "สิ้นเดือน") || strings.Contains(text, "สิ้นรอบ") {
+		return strings.Contains(text, "เหลือ") || strings.Contains(text, "เท่าไหร่")
+	}
+	return strings.Contains(text, "จะเหลือ") ||
+		strings.Contains(text, "forecast") ||
+		strings.Contains(text, "end of the month") ||
+		strings.Contains(text, "end of month") ||
+		strings.Contains(text, "will i have left")
+}
+
 func isSummaryQuery(text string) bool {
 	return strings.Contains(text, "เท่าไหร่") ||
 		strings.Contains(text, "สรุป") ||
@@ -217,21 +366,13 @@ func parseSummaryFilters(text string) QueryFilters {
 			Type: "all",
 		}
 	case strings.Contains(text, "เดือนที่แล้ว") || strings.Contains(text, "last month"):
-		previous := now.AddDate(0, -1, 0)
-		first := time.Date(previous.Year(), previous.Month(), 1, 0, 0, 0, 0, previous.Location())
-		last := first.AddDate(0, 1, -1)
+		// The previous cycle, resolved against the user's cutoff day
 		filters.Period = PeriodFilter{
-			Type: "range",
-			From: first.Format("2006-01-02"),
-			To:   last.Format("2006-01-02"),
+			Type: "last_month",
 		}
 	case strings.Contains(text, "ปีนี้") || strings.Contains(text, "this year"):
-		first := time.Date(now.Year(), 1, 1, 0, 0, 0, 0, now.Location())
-		last := time.Date(now.Year(), 12, 31, 0, 0, 0, 0, now.Location())
 		filters.Period = PeriodFilter{
-			Type: "range",
-			From: first.Format("2006-01-02"),
-			To:   last.Format("2006-01-02"),
+			Type: "year",
 		}
 	case strings.Contains(text, "ปีที่แล้ว") || strings.Contains(text, "last year"):
 		prevYear := now.Year() - 1
@@ -256,14 +397,6 @@ func parseSummaryFilters(text string) QueryFilters {
 			From: yesterday,
 			To:   yesterday,
 		}
-	default:
-		first := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
-		last := first.AddDate(0, 1, -1)
-		filters.Period = PeriodFilter{
-			Type: "range",
-			From: first.Format("2006-01-02"),
-			To:   last.Format("2006-01-02"),
-		}
 	}
 
 	filters.Category = parseCategory(text)
@@ -273,6 +406,12 @@ func parseSummaryFilters(text string) QueryFilters {
 }
 
 // ExtractAmountFromText exposes a minimal amount extractor for fallback usage.
-func ExtractAmountFromText(text string) float64 {
-	return parseAmount(strings.ToLower(text))
+// It returns an unset amount when the text has none.
+func ExtractAmountFromText(text string) models.NullMoney {
+	return foundAmount(parseAmount(strings.ToLower(text)))
+}
+
+// foundAmount treats a zero amount from the regex parser as "not found"
+func foundAmount(amount models.Money) models.NullMoney {
+	return models.NullMoney{Money: amount, Valid: amount != 0}
 }
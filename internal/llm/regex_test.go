@@ -16,7 +16,7 @@ func TestParseAmount(t *testing.T) {
 	}
 
 	for _, tc := range cases {
-		if got := parseAmount(tc.input); got != tc.expected {
+		if got := parseAmount(tc.input).Float64(); got != tc.expected {
 			t.Fatalf("parseAmount(%q) = %v, want %v", tc.input, got, tc.expected)
 		}
 	}
@@ -49,7 +49,7 @@ func TestParseTextRegexAddTransaction(t *testing.T) {
 	if resp.Transaction == nil {
 		t.Fatal("transaction is nil")
 	}
-	if resp.Transaction.Amount != 50 {
+	if resp.Transaction.Amount.Money.Float64() != 50 {
 		t.Fatalf("amount = %v, want 50", resp.Transaction.Amount)
 	}
 	if resp.Transaction.Channel != "cash" {
@@ -75,34 +75,29 @@ func TestParseTextRegexSummary(t *testing.T) {
 	if resp.Filters == nil {
 		t.Fatal("filters is nil")
 	}
-	if resp.Filters.Period.Type == "" {
-		t.Fatal("period type is empty")
-	}
-	if resp.Filters.Period.From == "" || resp.Filters.Period.To == "" {
-		t.Fatalf("period range is empty: %+v", resp.Filters.Period)
+	// "This month" is the user's current cycle, which the handler resolves
+	if resp.Filters.Period.Type != "month" {
+		t.Fatalf("period = %+v, want month", resp.Filters.Period)
 	}
 }
 
 func TestParseSummaryFiltersLastMonth(t *testing.T) {
-	now := time.Now()
-	prev := now.AddDate(0, -1, 0)
-	first := time.Date(prev.Year(), prev.Month(), 1, 0, 0, 0, 0, prev.Location())
-	last := first.AddDate(0, 1, -1)
-
 	filters := parseSummaryFilters("เดือนที่แล้วค่าอาหารเท่าไหร่")
 
-	if filters.Period.Type != "range" {
-		t.Fatalf("period type = %q, want range", filters.Period.Type)
-	}
-	if filters.Period.From != first.Format("2006-01-02") {
-		t.Fatalf("from = %q, want %q", filters.Period.From, first.Format("2006-01-02"))
-	}
-	if filters.Period.To != last.Format("2006-01-02") {
-		t.Fatalf("to = %q, want %q", filters.Period.To, last.Format("2006-01-02"))
+	// The handler resolves the previous cycle against the user's cutoff day
+	if filters.Period.Type != "last_month" || filters.Period.From != "" || filters.Period.To != "" {
+		t.Fatalf("period = %+v, want last_month without dates", filters.Period)
 	}
 	if filters.Category != "food" {
 		t.Fatalf("category = %q, want food", filters.Category)
 	}
+
+	if filters := parseSummaryFilters("ปีนี้ใช้ไปเท่าไหร่"); filters.Period.Type != "year" {
+		t.Errorf("this year = %+v, want year", filters.Period)
+	}
+	if filters := parseSummaryFilters("สรุป"); filters.Period.Type != "month" {
+		t.Errorf("default = %+v, want month", filters.Period)
+	}
 }
 
 func TestParseSlipRegex(t *testing.T) {
@@ -115,7 +110,7 @@ func TestParseSlipRegex(t *testing.T) {
 	if resp.Transaction == nil {
 		t.Fatal("transaction is nil")
 	}
-	if resp.Transaction.Amount != 96.00 {
+	if resp.Transaction.Amount.Money.Float64() != 96.00 {
 		t.Fatalf("amount = %v, want 96.00", resp.Transaction.Amount)
 	}
 	if resp.Transaction.TxnDate != "2026-01-30" {
@@ -128,3 +123,107 @@ func TestParseSlipRegex(t *testing.T) {
 		t.Fatalf("category = %q, want food", resp.Transaction.Category)
 	}
 }
+
+func TestParseCurrency(t *testing.T) {
+	cases := []struct {
+		input    string
+		amount   float64
+		currency string
+	}{
+		{"กินข้าว 50 บาท", 50, "THB"},
+		{"ramen ¥1,500", 1500, "JPY"},
+		{"ราเมง 1500 เยน", 1500, "JPY"},
+		{"coffee $4.50", 4.50, "USD"},
+		{"taxi 12 usd", 12, "USD"},
+		{"ข้าวเปียก 25,000 กีบ", 25000, "LAK"},
+		{"ค่าน้ำ 120", 120, "THB"},
+	}
+
+	for _, tc := range cases {
+		resp := parseTextRegex(tc.input)
+		if resp.Transaction == nil {
+			t.Fatalf("parseTextRegex(%q) returned no transaction", tc.input)
+		}
+		if resp.Transaction.Amount.Money.Float64() != tc.amount {
+			t.Fatalf("parseTextRegex(%q) amount = %v, want %v", tc.input, resp.Transaction.Amount, tc.amount)
+		}
+		if resp.Transaction.Currency != tc.currency {
+			t.Fatalf("parseTextRegex(%q) currency = %q, want %q", tc.input, resp.Transaction.Currency, tc.currency)
+		}
+	}
+}
+
+func TestParseTextRegexSearch(t *testing.T) {
+	resp := parseTextRegex("หาค่า grab เดือนที่แล้ว")
+	if resp.Intent != "search_transactions" {
+		t.Fatalf("intent = %q, want search_transactions", resp.Intent)
+	}
+	if resp.Search == nil {
+		t.Fatal("search is nil")
+	}
+	if resp.Search.Query != "grab" {
+		t.Fatalf("query = %q, want grab", resp.Search.Query)
+	}
+	if resp.Search.Period.Type != "last_month" {
+		t.Fatalf("period = %+v, want last_month", resp.Search.Period)
+	}
+
+	resp = parseTextRegex("find starbucks")
+	if resp.Search == nil || resp.Search.Query != "starbucks" || resp.Search.Period.Type != "all" {
+		t.Fatalf("search = %+v, want starbucks over all time", resp.Search)
+	}
+
+	// Words starting with "หา" are expenses when there is an amount
+	for _, msg := range []string{"หาหมอ 500 บาท", "หาของขวัญ 300 บาท", "หาดใหญ่ ค่ารถ 200"} {
+		if resp := parseTextRegex(msg); resp.Intent != "add_transaction" {
+			t.Errorf("%q: intent = %q, want add_transaction", msg, resp.Intent)
+		}
+	}
+}
+
+func TestParseTextRegexForecast(t *testing.T) {
+	for _, msg := range []string{"สิ้นเดือนจะเหลือเท่าไหร่", "สิ้นรอบเหลือเท่าไหร่", "How much will I have left at the end of the month?"} {
+		if resp := parseTextRegex(msg); resp.Intent != "forecast" {
+			t.Errorf("%q: intent = %q, want forecast", msg, resp.Intent)
+		}
+	}
+	// A total for the month, or the end of a past one, is still a summary
+	for _, msg := range []string{"เดือนนี้ใช้ไปเท่าไหร่", "สิ้นเดือนที่แล้วใช้ไปเท่าไหร่"} {
+		if resp := parseTextRegex(msg); resp.Intent != "query_summary" {
+			t.Errorf("%q: intent = %q, want query_summary", msg, resp.Intent)
+		}
+	}
+}
+
+func TestParseTextRegexTags(t *testing.T) {
+	resp := parseTextRegex("ค่าที่พัก 1,200 บาท #Trip-ChiangMai #trip2026 #trip-chiangmai")
+	if resp.Intent != "add_transaction" {
+		t.Fatalf("intent = %q, want add_transaction", resp.Intent)
+	}
+	tx := resp.Transaction
+	if tx.Amount.Money.Float64() != 1200 {
+		t.Errorf("amount = %v, want 1200", tx.Amount)
+	}
+	if len(tx.Tags) != 2 || tx.Tags[0] != "trip-chiangmai" || tx.Tags[1] != "trip2026" {
+		t.Errorf("tags = %q, want [trip-chiangmai trip2026]", tx.Tags)
+	}
+	if tx.Description != "ค่าที่พัก 1,200 บาท" {
+		t.Errorf("description = %q", tx.Description)
+	}
+
+	resp = parseTextRegex("#งานบริษัท เดือนนี้ใช้ไปเท่าไหร่")
+	if resp.Intent != "query_summary" || resp.Filters.Tag != "งานบริษัท" {
+		t.Errorf("summary = %q %+v, want query_summary for งานบริษัท", resp.Intent, resp.Filters)
+	}
+
+	// Searches keep the tag for the search syntax
+	resp = parseTextRegex("หา #trip")
+	if resp.Search == nil || resp.Search.Query != "#trip" {
+		t.Errorf("search = %+v, want #trip", resp.Search)
+	}
+
+	// A # inside a word is not a tag
+	if tags, _ := ParseTags("ห้อง#3 100 บาท"); len(tags) != 0 {
+		t.Errorf("tags = %q, want none", tags)
+	}
+}
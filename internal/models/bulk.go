@@ -0,0 +1,44 @@
+package models
+
+// Bulk actions
+const (
+	BulkConfirm    = "confirm"
+	BulkDelete     = "delete"
+	BulkCategorize = "categorize"
+	BulkSetChannel = "set_channel"
+	BulkSetAccount = "set_account"
+	BulkAddTags    = "add_tags"
+	// BulkSetReimbursable leaves transactions on a claim marked
+	BulkSetReimbursable = "set_reimbursable"
+	BulkSetTaxItem      = "set_tax_item"
+)
+
+// BulkRequest applies one action to the transactions listed in IDs or, when
+// IDs is empty, to those matching Filter (search syntax, e.g.
+// "status:pending date:2026-01 grab"). Value is the new category, channel or
+// account for the set actions, "true" or "false" for set_reimbursable, and
+// a tax item, "none" or "" (use the category's) for set_tax_item.
+// Preview reports what would change without changing it.
+type BulkRequest struct {
+	Action  string   `json:"action"`
+	IDs     []int64  `json:"ids"`
+	Filter  string   `json:"filter"`
+	Value   string   `json:"value"`
+	Tags    []string `json:"tags"`
+	Preview bool     `json:"preview"`
+}
+
+// BulkResult reports a bulk action. Matched counts the selected transactions
+// and Affected those the action changed; pending transactions without an
+// amount are not confirmed, for example. UndoToken reverts the action with
+// POST /api/transactions/bulk/undo. An undo reports in Skipped the
+// transactions it left alone because they were edited since or purged.
+type BulkResult struct {
+	Action    string  `json:"action"`
+	Matched   int     `json:"matched"`
+	Affected  int     `json:"affected"`
+	Skipped   int     `json:"skipped,omitempty"`
+	IDs       []int64 `json:"ids"`
+	Preview   bool    `json:"preview"`
+	UndoToken string  `json:"undo_token,omitempty"`
+}
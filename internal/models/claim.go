@@ -0,0 +1,49 @@
+package models
+
+// Claim statuses. A draft claim collects expenses; once submitted its
+// expenses are fixed, and once paid they no longer count as the user's own
+// spending.
+const (
+	ClaimDraft     = "draft"
+	ClaimSubmitted = "submitted"
+	ClaimPaid      = "paid"
+)
+
+// Claim groups reimbursable expenses that are paid back together, such as
+// one expense report to an employer
+type Claim struct {
+	ID          int64  `json:"id"`
+	UserID      int64  `json:"user_id"`
+	Title       string `json:"title"`
+	Note        string `json:"note,omitempty"`
+	Status      string `json:"status"`
+	SubmittedAt string `json:"submitted_at,omitempty"`
+	PaidAt      string `json:"paid_at,omitempty"`
+	// ReimbursementID is the income transaction that paid the claim
+	ReimbursementID int64  `json:"reimbursement_id,omitempty"`
+	CreatedAt       string `json:"created_at"`
+	UpdatedAt       string `json:"updated_at"`
+
+	// Count and Total cover the claim's expenses, and Reimbursed the income
+	// that paid it, in the user's base currency. Unconverted counts expenses
+	// left out of Total because no exchange rate is known. Outstanding is
+	// what Owed returns.
+	Currency    string `json:"currency"`
+	Count       int    `json:"count"`
+	Total       Money  `json:"total"`
+	Reimbursed  Money  `json:"reimbursed"`
+	Outstanding Money  `json:"outstanding"`
+	Unconverted int    `json:"unconverted"`
+}
+
+// Owed is what is still owed on the claim: everything until the claim is
+// paid, then whatever the linked reimbursement fell short by
+func (c Claim) Owed() Money {
+	if c.Status != ClaimPaid {
+		return c.Total
+	}
+	if c.ReimbursementID == 0 || c.Reimbursed >= c.Total {
+		return 0
+	}
+	return c.Total - c.Reimbursed
+}
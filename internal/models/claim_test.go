@@ -0,0 +1,23 @@
+package models
+
+import "testing"
+
+func TestClaimOwed(t *testing.T) {
+	tests := []struct {
+		name  string
+		claim Claim
+		want  Money
+	}{
+		{"draft", Claim{Status: ClaimDraft, Total: NewMoney(500)}, NewMoney(500)},
+		{"submitted", Claim{Status: ClaimSubmitted, Total: NewMoney(500)}, NewMoney(500)},
+		{"paid without a linked income", Claim{Status: ClaimPaid, Total: NewMoney(500)}, 0},
+		{"paid in full", Claim{Status: ClaimPaid, Total: NewMoney(500), ReimbursementID: 7, Reimbursed: NewMoney(500)}, 0},
+		{"paid short", Claim{Status: ClaimPaid, Total: NewMoney(500), ReimbursementID: 7, Reimbursed: NewMoney(450)}, NewMoney(50)},
+		{"paid more", Claim{Status: ClaimPaid, Total: NewMoney(500), ReimbursementID: 7, Reimbursed: NewMoney(600)}, 0},
+	}
+	for _, tt := range tests {
+		if got := tt.claim.Owed(); got != tt.want {
+			t.Errorf("%s: Owed() = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
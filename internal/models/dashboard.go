@@ -1,12 +1,26 @@
 package models
 
+import (
+	"math"
+	"sort"
+)
+
 // DashboardSummary represents the summary data for the dashboard
 type DashboardSummary struct {
-	Period       Period           `json:"period"`
-	TotalExpense float64          `json:"total_expense"`
-	TotalIncome  float64          `json:"total_income"`
-	ByCategory   []CategoryAmount `json:"by_category"`
-	ByChannel    []ChannelAmount  `json:"by_channel"`
+	Period       Period `json:"period"`
+	Currency     string `json:"currency"`
+	TotalExpense Money  `json:"total_expense"`
+	TotalIncome  Money  `json:"total_income"`
+	// Unconverted counts transactions left out of the totals because no
+	// exchange rate to the base currency is known
+	Unconverted int              `json:"unconverted"`
+	ByCategory  []CategoryAmount `json:"by_category"`
+	ByChannel   []ChannelAmount  `json:"by_channel"`
+	// PreviousPeriod and PreviousYear compare with the period just before
+	// and the same period a year earlier
+	PreviousPeriod *Comparison `json:"previous_period,omitempty"`
+	PreviousYear   *Comparison `json:"previous_year,omitempty"`
+	Anomalies      []Anomaly   `json:"anomalies,omitempty"`
 }
 
 // Period represents a date range
@@ -18,12 +32,196 @@ type Period struct {
 // CategoryAmount represents spending by category
 type CategoryAmount struct {
 	Category         string  `json:"category"`
-	Amount           float64 `json:"amount"`
+	Amount           Money   `json:"amount"`
 	PercentOfExpense float64 `json:"percent_of_expense"`
 }
 
 // ChannelAmount represents spending by channel
 type ChannelAmount struct {
-	Channel string  `json:"channel"`
-	Amount  float64 `json:"amount"`
+	Channel string `json:"channel"`
+	Amount  Money  `json:"amount"`
+}
+
+// Time-series intervals
+const (
+	IntervalDay          = "day"
+	IntervalWeek         = "week" // Monday to Sunday
+	IntervalMonth        = "month"
+	IntervalCutoffPeriod = "cutoff-period" // the user's cycle starting on cutoff_day
+)
+
+// TimeSeries is expense and income per interval over a date range
+type TimeSeries struct {
+	Interval string `json:"interval"`
+	Period   Period `json:"period"`
+	Currency string `json:"currency"`
+	// Categories lists the expense categories of a split series, largest first
+	Categories []string     `json:"categories,omitempty"`
+	Buckets    []TimeBucket `json:"buckets"`
+}
+
+// TimeBucket is one interval of a time series. The first and last buckets
+// are cut to the requested range. ByCategory splits Expense and is only set
+// when a split was asked for.
+type TimeBucket struct {
+	Period
+	Expense    Money            `json:"expense"`
+	Income     Money            `json:"income"`
+	ByCategory map[string]Money `json:"by_category,omitempty"`
+}
+
+// Change is an amount next to the same amount in an earlier period
+type Change struct {
+	Key      string `json:"key,omitempty"`
+	Amount   Money  `json:"amount"`
+	Previous Money  `json:"previous"`
+	Delta    Money  `json:"delta"`
+	// Percent is the change relative to Previous; nil when Previous is zero
+	Percent *float64 `json:"percent"`
+}
+
+// NewChange compares amount with previous
+func NewChange(key string, amount, previous Money) Change {
+	c := Change{Key: key, Amount: amount, Previous: previous, Delta: amount - previous}
+	if previous != 0 {
+		p := float64(amount-previous) / float64(previous) * 100
+		c.Percent = &p
+	}
+	return c
+}
+
+// Comparison is an earlier period's expense and income with the change
+// from it, per category and channel
+type Comparison struct {
+	Period       Period   `json:"period"`
+	TotalExpense Change   `json:"total_expense"`
+	TotalIncome  Change   `json:"total_income"`
+	ByCategory   []Change `json:"by_category"`
+	ByChannel    []Change `json:"by_channel"`
+}
+
+// Compare returns how current differs from previous. Categories and
+// channels are listed by the size of their change, largest first.
+func Compare(current, previous *DashboardSummary) *Comparison {
+	c := &Comparison{
+		Period:       previous.Period,
+		TotalExpense: NewChange("", current.TotalExpense, previous.TotalExpense),
+		TotalIncome:  NewChange("", current.TotalIncome, previous.TotalIncome),
+	}
+
+	now, before := map[string]Money{}, map[string]Money{}
+	for _, ca := range current.ByCategory {
+		now[ca.Category] = ca.Amount
+	}
+	for _, ca := range previous.ByCategory {
+		before[ca.Category] = ca.Amount
+	}
+	c.ByCategory = changes(now, before)
+
+	now, before = map[string]Money{}, map[string]Money{}
+	for _, ca := range current.ByChannel {
+		now[ca.Channel] = ca.Amount
+	}
+	for _, ca := range previous.ByChannel {
+		before[ca.Channel] = ca.Amount
+	}
+	c.ByChannel = changes(now, before)
+	return c
+}
+
+func changes(now, before map[string]Money) []Change {
+	result := []Change{}
+	for key, amount := range now {
+		result = append(result, NewChange(key, amount, before[key]))
+	}
+	for key, amount := range before {
+		if _, ok := now[key]; !ok {
+			result = append(result, NewChange(key, 0, amount))
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		a, b := result[i].Delta.Abs(), result[j].Delta.Abs()
+		if a != b {
+			return a > b
+		}
+		return result[i].Key < result[j].Key
+	})
+	return result
+}
+
+// Anomaly thresholds: a category is flagged when it is at least
+// AnomalyRatio times its trailing average or at most 1/AnomalyRatio of it,
+// averaged over periods with any spending in which it appeared at least
+// AnomalyMinPeriods times.
+const (
+	AnomalyRatio      = 1.5
+	AnomalyMinPeriods = 2
+)
+
+// Anomaly is a category whose spending is far from its trailing average
+type Anomaly struct {
+	Category string  `json:"category"`
+	Amount   Money   `json:"amount"`
+	Average  Money   `json:"average"`
+	Percent  float64 `json:"percent"` // change from the average
+	Periods  int     `json:"periods"` // earlier periods averaged
+}
+
+// FindAnomalies compares each category of current with its average over
+// history, the category breakdowns of earlier periods. Periods without
+// any spending, such as those before the user started, are left out.
+func FindAnomalies(current []CategoryAmount, history [][]CategoryAmount) []Anomaly {
+	totals := map[string]Money{}
+	seen := map[string]int{}
+	periods := 0
+	for _, period := range history {
+		if len(period) == 0 {
+			continue
+		}
+		periods++
+		for _, ca := range period {
+			totals[ca.Category] += ca.Amount
+			seen[ca.Category]++
+		}
+	}
+
+	now := map[string]Money{}
+	for _, ca := range current {
+		now[ca.Category] = ca.Amount
+	}
+	for category := range totals {
+		if _, ok := now[category]; !ok {
+			now[category] = 0
+		}
+	}
+
+	anomalies := []Anomaly{}
+	for category, amount := range now {
+		if seen[category] < AnomalyMinPeriods || totals[category] <= 0 {
+			continue
+		}
+		average := Money(int64(totals[category]) / int64(periods))
+		if average == 0 {
+			continue
+		}
+		ratio := float64(amount) / float64(average)
+		if ratio < AnomalyRatio && ratio > 1/AnomalyRatio {
+			continue
+		}
+		anomalies = append(anomalies, Anomaly{
+			Category: category,
+			Amount:   amount,
+			Average:  average,
+			Percent:  (ratio - 1) * 100,
+			Periods:  periods,
+		})
+	}
+	sort.Slice(anomalies, func(i, j int) bool {
+		a, b := math.Abs(anomalies[i].Percent), math.Abs(anomalies[j].Percent)
+		if a != b {
+			return a > b
+		}
+		return anomalies[i].Category < anomalies[j].Category
+	})
+	return anomalies
 }
@@ -0,0 +1,69 @@
+package models
+
+import "testing"
+
+func TestCompare(t *testing.T) {
+	current := &DashboardSummary{
+		TotalExpense: NewMoney(1420),
+		ByCategory: []CategoryAmount{
+			{Category: "food", Amount: NewMoney(1420)},
+		},
+		ByChannel: []ChannelAmount{{Channel: "cash", Amount: NewMoney(1420)}},
+	}
+	previous := &DashboardSummary{
+		Period:       Period{From: "2026-09-01", To: "2026-09-30"},
+		TotalExpense: NewMoney(1300),
+		ByCategory: []CategoryAmount{
+			{Category: "food", Amount: NewMoney(1000)},
+			{Category: "transport", Amount: NewMoney(300)},
+		},
+	}
+
+	c := Compare(current, previous)
+	if c.Period != previous.Period || c.TotalExpense.Delta != NewMoney(120) {
+		t.Errorf("comparison = %+v", c)
+	}
+	if c.TotalIncome.Percent != nil {
+		t.Errorf("income percent = %v, want nil without earlier income", *c.TotalIncome.Percent)
+	}
+	if len(c.ByCategory) != 2 {
+		t.Fatalf("by category = %+v", c.ByCategory)
+	}
+	food, transport := c.ByCategory[0], c.ByCategory[1]
+	if food.Key != "food" || food.Percent == nil || *food.Percent != 42 {
+		t.Errorf("food = %+v, want up 42%%", food)
+	}
+	if transport.Amount != 0 || transport.Delta != NewMoney(-300) || *transport.Percent != -100 {
+		t.Errorf("transport = %+v, want gone", transport)
+	}
+	if len(c.ByChannel) != 1 || c.ByChannel[0].Percent != nil {
+		t.Errorf("by channel = %+v", c.ByChannel)
+	}
+}
+
+func TestFindAnomalies(t *testing.T) {
+	current := []CategoryAmount{
+		{Category: "food", Amount: NewMoney(3000)},
+		{Category: "transport", Amount: NewMoney(500)},
+		{Category: "shopping", Amount: NewMoney(900)},
+	}
+	history := [][]CategoryAmount{
+		{{Category: "food", Amount: NewMoney(2000)}, {Category: "transport", Amount: NewMoney(500)}, {Category: "bill", Amount: NewMoney(800)}},
+		{{Category: "food", Amount: NewMoney(1800)}, {Category: "transport", Amount: NewMoney(450)}, {Category: "bill", Amount: NewMoney(800)}},
+		{{Category: "food", Amount: NewMoney(2200)}, {Category: "transport", Amount: NewMoney(550)}},
+		{}, // before the user started
+	}
+
+	anomalies := FindAnomalies(current, history)
+	// bill averages 533.33 over three periods and is missing now; food is
+	// 1.5 times its average; transport is normal and shopping is new
+	if len(anomalies) != 2 {
+		t.Fatalf("anomalies = %+v", anomalies)
+	}
+	if a := anomalies[0]; a.Category != "bill" || a.Amount != 0 || a.Percent != -100 || a.Periods != 3 {
+		t.Errorf("first = %+v, want bill down 100%%", a)
+	}
+	if a := anomalies[1]; a.Category != "food" || a.Average != NewMoney(2000) || a.Percent != 50 {
+		t.Errorf("second = %+v, want food up 50%%", a)
+	}
+}
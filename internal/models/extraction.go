@@ -0,0 +1,55 @@
+package models
+
+// Extraction is what a parser originally made of a transaction, kept after
+// the user confirms or edits it. Values holds AccuracyFields formatted like
+// Transaction.FieldValue; Raw is the model's JSON answer.
+type Extraction struct {
+	ID            int64             `json:"id"`
+	TransactionID int64             `json:"transaction_id"`
+	Source        string            `json:"source"`
+	Model         string            `json:"model"`
+	PromptVersion string            `json:"prompt_version"`
+	LatencyMS     int64             `json:"latency_ms"`
+	Confidence    float64           `json:"confidence"`
+	Values        map[string]string `json:"values"`
+	Raw           string            `json:"raw"`
+	CreatedAt     string            `json:"created_at"`
+}
+
+// AccuracyFields lists the fields compared by the accuracy report
+var AccuracyFields = []string{
+	"txn_date", "amount", "currency", "direction", "channel", "account_label",
+	"category", "description",
+}
+
+// FieldAccuracy counts how often a parser got one field right. Only
+// transactions where the field has a confirmed value count; Missing are those
+// where the parser found nothing.
+type FieldAccuracy struct {
+	Field    string  `json:"field"`
+	Total    int     `json:"total"`
+	Correct  int     `json:"correct"`
+	Missing  int     `json:"missing"`
+	Accuracy float64 `json:"accuracy"`
+}
+
+// AccuracyGroup is the accuracy of the transactions sharing a channel or
+// model. Accuracy is over all of the group's fields.
+type AccuracyGroup struct {
+	Key          string          `json:"key"`
+	Transactions int             `json:"transactions"`
+	AvgLatencyMS int64           `json:"avg_latency_ms"`
+	Accuracy     float64         `json:"accuracy"`
+	Fields       []FieldAccuracy `json:"fields"`
+}
+
+// AccuracyReport compares the first extraction of each reviewed transaction
+// with the values it was confirmed with. A transaction is reviewed once the
+// user has confirmed, edited or reverted it.
+type AccuracyReport struct {
+	From      string          `json:"from,omitempty"`
+	To        string          `json:"to,omitempty"`
+	Overall   AccuracyGroup   `json:"overall"`
+	ByChannel []AccuracyGroup `json:"by_channel"`
+	ByModel   []AccuracyGroup `json:"by_model"`
+}
@@ -0,0 +1,301 @@
+package models
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Recurring items appear in at least RecurringMinCycles of the earlier
+// cycles, about once a cycle, with per-cycle amounts within
+// RecurringMaxSpread of each other
+const (
+	RecurringMinCycles = 3
+	RecurringMaxSpread = 1.5
+)
+
+// ForecastBandZ scales the spread of daily spending into the confidence
+// band; 1.28 standard deviations on either side cover about 80%
+const ForecastBandZ = 1.28
+
+// FlowItem is a confirmed expense or income as the forecast sees it
+type FlowItem struct {
+	Day         string
+	Direction   string
+	Category    string
+	Channel     string
+	Description string
+	Amount      Money
+}
+
+// Key identifies the same bill or income from cycle to cycle: its category
+// and description without numbers, or its channel when there is no
+// description
+func (f FlowItem) Key() string {
+	words := strings.FieldsFunc(strings.ToLower(f.Description), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsMark(r)
+	})
+	if len(words) == 0 {
+		return f.Category + ":" + f.Channel
+	}
+	return f.Category + ":" + strings.Join(words, " ")
+}
+
+// Forecast projects the current cycle from what was spent and received so
+// far, the recurring items still due and the average daily spend
+type Forecast struct {
+	Period   Period `json:"period"`
+	Currency string `json:"currency"`
+	Today    string `json:"today"`
+	// HistoryCycles is how many earlier cycles with transactions the
+	// averages and recurring items come from
+	HistoryCycles int `json:"history_cycles"`
+	// DailySpend is the average spend a day outside recurring items
+	DailySpend Money           `json:"daily_spend"`
+	Recurring  []RecurringItem `json:"recurring"`
+	// Days runs over the whole cycle; days up to today are actual
+	Days []ForecastDay `json:"days"`
+	End  ForecastDay   `json:"end"`
+}
+
+// RecurringItem is a bill or income expected once a cycle
+type RecurringItem struct {
+	Key       string `json:"key"`
+	Direction string `json:"direction"`
+	Category  string `json:"category"`
+	Amount    Money  `json:"amount"`
+	// Day is when it is expected in the current cycle
+	Day    string `json:"day"`
+	Paid   bool   `json:"paid"`
+	Cycles int    `json:"cycles"`
+}
+
+// ForecastDay is the running total of a cycle at the end of a day. Balance
+// is income less expense since the start of the cycle; Low and High bound
+// the projected balance.
+type ForecastDay struct {
+	Date    string `json:"date"`
+	Actual  bool   `json:"actual"`
+	Spent   Money  `json:"spent"`
+	Income  Money  `json:"income"`
+	Balance Money  `json:"balance"`
+	Low     Money  `json:"low"`
+	High    Money  `json:"high"`
+}
+
+const dateLayout = "2006-01-02"
+
+func daysBetween(from, to string) int {
+	f, _ := time.Parse(dateLayout, from)
+	t, _ := time.Parse(dateLayout, to)
+	return int(math.Round(t.Sub(f).Hours() / 24))
+}
+
+func addDays(day string, n int) string {
+	d, _ := time.Parse(dateLayout, day)
+	return d.AddDate(0, 0, n).Format(dateLayout)
+}
+
+// recurringStats collects one key's per-cycle totals
+type recurringStats struct {
+	direction, category string
+	totals              map[int]Money
+	offsets             map[int]int // days from the start of the cycle
+	count               int
+}
+
+// BuildForecast projects current as of today from items, which cover the
+// history cycles and the current cycle so far
+func BuildForecast(current Period, history []Period, today string, items []FlowItem) *Forecast {
+	f := &Forecast{Period: current, Today: today, Recurring: []RecurringItem{}}
+	if today < current.From {
+		today = current.From
+	}
+	if today > current.To {
+		today = current.To
+	}
+
+	// Cycles before the first transaction would only dilute the averages
+	first := today
+	for _, item := range items {
+		if item.Day < first {
+			first = item.Day
+		}
+	}
+	var cycles []Period
+	for _, p := range history {
+		if p.To < first || p.From >= current.From {
+			continue
+		}
+		if p.From < first {
+			p.From = first
+		}
+		cycles = append(cycles, p)
+	}
+	sort.Slice(cycles, func(i, j int) bool { return cycles[i].From < cycles[j].From })
+	f.HistoryCycles = len(cycles)
+	cycleOf := func(day string) int {
+		for i, p := range cycles {
+			if day >= p.From && day <= p.To {
+				return i
+			}
+		}
+		return -1
+	}
+
+	stats := map[string]*recurringStats{}
+	for _, item := range items {
+		i := cycleOf(item.Day)
+		if i < 0 {
+			continue
+		}
+		key := item.Direction + "/" + item.Key()
+		s := stats[key]
+		if s == nil {
+			s = &recurringStats{direction: item.Direction, category: item.Category, totals: map[int]Money{}, offsets: map[int]int{}}
+			stats[key] = s
+		}
+		if _, ok := s.offsets[i]; !ok {
+			s.offsets[i] = daysBetween(cycles[i].From, item.Day)
+		}
+		s.totals[i] += item.Amount
+		s.count++
+	}
+
+	recurring := map[string]bool{}
+	minCycles := RecurringMinCycles
+	if half := (len(cycles) + 1) / 2; half > minCycles {
+		minCycles = half
+	}
+	for key, s := range stats {
+		n := len(s.totals)
+		if n < minCycles || float64(s.count) > 1.5*float64(n) {
+			continue
+		}
+		var amounts []Money
+		var offsets []int
+		for i, total := range s.totals {
+			amounts = append(amounts, total)
+			offsets = append(offsets, s.offsets[i])
+		}
+		sort.Slice(amounts, func(i, j int) bool { return amounts[i] < amounts[j] })
+		sort.Ints(offsets)
+		if amounts[0] <= 0 || float64(amounts[n-1]) > RecurringMaxSpread*float64(amounts[0]) {
+			continue
+		}
+		recurring[key] = true
+		day := addDays(current.From, offsets[n/2])
+		if day > current.To {
+			day = current.To
+		}
+		f.Recurring = append(f.Recurring, RecurringItem{
+			Key:       key[len(s.direction)+1:],
+			Direction: s.direction,
+			Category:  s.category,
+			Amount:    amounts[n/2],
+			Day:       day,
+			Cycles:    n,
+		})
+	}
+
+	// Daily spend outside recurring items over the history days
+	var historyDays int
+	for _, p := range cycles {
+		historyDays += daysBetween(p.From, p.To) + 1
+	}
+	daily := map[string]float64{}
+	actualSpent := map[string]Money{}
+	actualIncome := map[string]Money{}
+	paid := map[string]bool{}
+	for _, item := range items {
+		key := item.Direction + "/" + item.Key()
+		if item.Day >= current.From && item.Day <= today {
+			if item.Direction == "income" {
+				actualIncome[item.Day] += item.Amount
+			} else {
+				actualSpent[item.Day] += item.Amount
+			}
+			paid[key] = true
+			continue
+		}
+		if item.Direction == "expense" && !recurring[key] && cycleOf(item.Day) >= 0 {
+			daily[item.Day] += float64(item.Amount)
+		}
+	}
+	if historyDays == 0 {
+		// No earlier cycle to learn from: average the current one so far,
+		// counting recurring items alike
+		historyDays = daysBetween(current.From, today) + 1
+		for day, amount := range actualSpent {
+			daily[day] = float64(amount)
+		}
+	}
+	var mean, variance float64
+	for _, amount := range daily {
+		mean += amount
+	}
+	mean /= float64(historyDays)
+	for _, amount := range daily {
+		variance += (amount - mean) * (amount - mean)
+	}
+	variance += float64(historyDays-len(daily)) * mean * mean // the days without spending
+	stddev := math.Sqrt(variance / float64(historyDays))
+	f.DailySpend = Money(math.Round(mean))
+
+	// Recurring items not seen yet this cycle are still due, at the
+	// earliest tomorrow
+	due := map[string]Money{}
+	for i := range f.Recurring {
+		item := &f.Recurring[i]
+		if paid[item.Direction+"/"+item.Key] {
+			item.Paid = true
+			continue
+		}
+		if item.Day <= today {
+			item.Day = addDays(today, 1)
+		}
+		if item.Day > current.To {
+			continue
+		}
+		amount := item.Amount
+		if item.Direction == "expense" {
+			amount = -amount
+		}
+		due[item.Day] += amount
+	}
+	sort.Slice(f.Recurring, func(i, j int) bool {
+		a, b := f.Recurring[i], f.Recurring[j]
+		if a.Day != b.Day {
+			return a.Day < b.Day
+		}
+		return a.Key < b.Key
+	})
+
+	var spent, income Money
+	var ahead int
+	for day := current.From; day <= current.To; day = addDays(day, 1) {
+		d := ForecastDay{Date: day, Actual: day <= today}
+		if d.Actual {
+			spent += actualSpent[day]
+			income += actualIncome[day]
+		} else {
+			ahead++
+			spent += Money(math.Round(mean))
+			if amount := due[day]; amount < 0 {
+				spent -= amount
+			} else {
+				income += amount
+			}
+		}
+		d.Spent, d.Income, d.Balance = spent, income, income-spent
+		width := Money(math.Round(ForecastBandZ * stddev * math.Sqrt(float64(ahead))))
+		d.Low, d.High = d.Balance-width, d.Balance+width
+		f.Days = append(f.Days, d)
+	}
+	if len(f.Days) > 0 {
+		f.End = f.Days[len(f.Days)-1]
+	}
+	return f
+}
@@ -0,0 +1,113 @@
+package models
+
+import (
+	"fmt"
+	"testing"
+)
+
+// forecastItems spends food of amount(day) on every day of the cycles and
+// pays rent on the 5th and salary on the 25th of the earlier ones
+func forecastItems(cycles []Period, amount func(i int) float64) []FlowItem {
+	var items []FlowItem
+	for _, p := range cycles {
+		for i, day := 0, p.From; day <= p.To; i, day = i+1, addDays(day, 1) {
+			items = append(items, FlowItem{Day: day, Direction: "expense", Category: "food", Description: fmt.Sprintf("ข้าว %d บาท", i), Amount: NewMoney(amount(i))})
+		}
+		items = append(items,
+			FlowItem{Day: addDays(p.From, 4), Direction: "expense", Category: "rent", Channel: "kbank", Description: "ค่าเช่า", Amount: NewMoney(5000)},
+			FlowItem{Day: addDays(p.From, 24), Direction: "income", Category: "other", Description: "เงินเดือน", Amount: NewMoney(30000)},
+		)
+	}
+	return items
+}
+
+func TestBuildForecast(t *testing.T) {
+	history := []Period{
+		{From: "2026-09-01", To: "2026-09-30"},
+		{From: "2026-08-01", To: "2026-08-31"},
+		{From: "2026-07-01", To: "2026-07-31"},
+	}
+	current := Period{From: "2026-10-01", To: "2026-10-31"}
+	items := forecastItems(history, func(int) float64 { return 100 })
+	items = append(items, forecastItems([]Period{{From: "2026-10-01", To: "2026-10-10"}}, func(int) float64 { return 100 })[:10]...)
+
+	f := BuildForecast(current, history, "2026-10-10", items)
+	if f.HistoryCycles != 3 || f.DailySpend != NewMoney(100) {
+		t.Fatalf("history = %d cycles, daily spend %s", f.HistoryCycles, f.DailySpend)
+	}
+	if len(f.Recurring) != 2 {
+		t.Fatalf("recurring = %+v, want rent and salary", f.Recurring)
+	}
+	// Rent was due on the 5th and is still unpaid, so it is expected tomorrow
+	if rent := f.Recurring[0]; rent.Category != "rent" || rent.Paid || rent.Day != "2026-10-11" || rent.Amount != NewMoney(5000) {
+		t.Errorf("rent = %+v", rent)
+	}
+	if salary := f.Recurring[1]; salary.Direction != "income" || salary.Day != "2026-10-25" || salary.Amount != NewMoney(30000) {
+		t.Errorf("salary = %+v", salary)
+	}
+
+	if len(f.Days) != 31 {
+		t.Fatalf("got %d days, want 31", len(f.Days))
+	}
+	if today := f.Days[9]; !today.Actual || today.Spent != NewMoney(1000) || today.Balance != NewMoney(-1000) {
+		t.Errorf("today = %+v", today)
+	}
+	if f.Days[10].Actual {
+		t.Error("tomorrow is actual")
+	}
+	// 1,000 so far, 21 more days of 100 and the rent; the salary comes in
+	if end := f.End; end.Spent != NewMoney(8100) || end.Income != NewMoney(30000) || end.Balance != NewMoney(21900) || end.Low != end.Balance {
+		t.Errorf("end = %+v", end)
+	}
+}
+
+func TestBuildForecastBand(t *testing.T) {
+	history := []Period{
+		{From: "2026-09-01", To: "2026-09-30"},
+		{From: "2026-08-01", To: "2026-08-31"},
+		{From: "2026-07-01", To: "2026-07-31"},
+	}
+	current := Period{From: "2026-10-01", To: "2026-10-31"}
+	items := forecastItems(history, func(i int) float64 { return float64(i%2) * 200 })
+
+	f := BuildForecast(current, history, "2026-10-10", items)
+	today, tomorrow, end := f.Days[9], f.Days[10], f.End
+	if today.Low != today.Balance || today.High != today.Balance {
+		t.Errorf("actual day has a band: %+v", today)
+	}
+	if !(end.Low < end.Balance && end.Balance < end.High) {
+		t.Errorf("end = %+v, want a band around the balance", end)
+	}
+	if end.High-end.Low <= tomorrow.High-tomorrow.Low {
+		t.Errorf("band does not widen: tomorrow %+v, end %+v", tomorrow, end)
+	}
+}
+
+func TestBuildForecastWithoutHistory(t *testing.T) {
+	current := Period{From: "2026-10-01", To: "2026-10-31"}
+	items := []FlowItem{
+		{Day: "2026-10-02", Direction: "expense", Category: "food", Amount: NewMoney(300)},
+		{Day: "2026-10-03", Direction: "expense", Category: "food", Amount: NewMoney(300)},
+	}
+
+	// Earlier cycles before the first transaction are ignored, so the
+	// average comes from the 10 days of this cycle
+	f := BuildForecast(current, []Period{{From: "2026-09-01", To: "2026-09-30"}}, "2026-10-10", items)
+	if f.HistoryCycles != 0 || f.DailySpend != NewMoney(60) || len(f.Recurring) != 0 {
+		t.Errorf("forecast = %+v", f)
+	}
+	if f.End.Spent != NewMoney(600+21*60) {
+		t.Errorf("end spent = %s", f.End.Spent)
+	}
+}
+
+func TestFlowItemKey(t *testing.T) {
+	a := FlowItem{Category: "bill", Description: "ค่าไฟ 1,234 บาท"}
+	b := FlowItem{Category: "bill", Description: "ค่าไฟ 987 บาท"}
+	if a.Key() != b.Key() {
+		t.Errorf("keys differ: %q, %q", a.Key(), b.Key())
+	}
+	if c := (FlowItem{Category: "bill", Channel: "kbank"}); c.Key() != "bill:kbank" {
+		t.Errorf("key without description = %q", c.Key())
+	}
+}
@@ -0,0 +1,21 @@
+package models
+
+// Exchange rate sources
+const (
+	FXSourceManual = "manual"
+	FXSourceCSV    = "csv"
+)
+
+// FXRate says how many ToCurrency units one FromCurrency unit was worth on
+// RateDate. Rates belong to the user who added them; Shared rates, imported
+// with the admin command, apply to every user.
+type FXRate struct {
+	ID           int64   `json:"id"`
+	RateDate     string  `json:"rate_date"`
+	FromCurrency string  `json:"from_currency"`
+	ToCurrency   string  `json:"to_currency"`
+	Rate         float64 `json:"rate"`
+	Source       string  `json:"source"`
+	Shared       bool    `json:"shared,omitempty"`
+	CreatedAt    string  `json:"created_at"`
+}
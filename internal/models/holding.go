@@ -0,0 +1,126 @@
+package models
+
+import "sort"
+
+// Holding classes
+const (
+	HoldingAsset     = "asset"
+	HoldingLiability = "liability"
+)
+
+// HoldingKinds maps every kind of holding to its class
+var HoldingKinds = map[string]string{
+	"savings":     HoldingAsset,
+	"fund":        HoldingAsset,
+	"gold":        HoldingAsset,
+	"crypto":      HoldingAsset,
+	"property":    HoldingAsset,
+	"other":       HoldingAsset,
+	"loan":        HoldingLiability,
+	"credit_card": HoldingLiability,
+	"other_debt":  HoldingLiability,
+}
+
+// Holding is something owned or owed whose value is entered by hand from
+// time to time. A holding with an AccountLabel is also moved by the
+// confirmed transactions of that account between valuations.
+type Holding struct {
+	ID           int64  `json:"id"`
+	UserID       int64  `json:"user_id"`
+	Name         string `json:"name"`
+	Kind         string `json:"kind"`
+	Class        string `json:"class"`
+	Currency     string `json:"currency"`
+	AccountLabel string `json:"account_label,omitempty"`
+	CreatedAt    string `json:"created_at"`
+	// Latest is the most recent valuation, if any
+	Latest *Valuation `json:"latest,omitempty"`
+}
+
+// Valuation is the value of a holding on a day, in the holding's currency.
+// Liabilities are valued at what is owed, as a positive amount.
+type Valuation struct {
+	ID        int64  `json:"id"`
+	HoldingID int64  `json:"holding_id"`
+	ValuedOn  string `json:"valued_on"`
+	Value     Money  `json:"value"`
+	Note      string `json:"note,omitempty"`
+	CreatedAt string `json:"created_at"`
+}
+
+// AccountFlow is the net of the confirmed transactions of an account on a
+// day in the base currency, income less expense
+type AccountFlow struct {
+	AccountLabel string
+	Day          string
+	Net          Money
+}
+
+// NetWorthSeries is the value of the user's holdings at the end of every
+// interval over a date range
+type NetWorthSeries struct {
+	Interval string          `json:"interval"`
+	Period   Period          `json:"period"`
+	Currency string          `json:"currency"`
+	Points   []NetWorthPoint `json:"points"`
+}
+
+// NetWorthPoint is the value of the holdings on Date. Holdings are counted
+// from their first valuation.
+type NetWorthPoint struct {
+	Date        string `json:"date"`
+	Assets      Money  `json:"assets"`
+	Liabilities Money  `json:"liabilities"`
+	NetWorth    Money  `json:"net_worth"`
+}
+
+// HoldingValueAt is the value of h on day in the base currency: its last
+// valuation on or before day, moved by its account's flows since. valuations
+// must be h's, in the base currency and sorted by day. ok is false before
+// the first valuation.
+func HoldingValueAt(h Holding, valuations []Valuation, flows []AccountFlow, day string) (Money, bool) {
+	i := sort.Search(len(valuations), func(i int) bool { return valuations[i].ValuedOn > day }) - 1
+	if i < 0 {
+		return 0, false
+	}
+	value := valuations[i].Value
+	if h.AccountLabel == "" {
+		return value, true
+	}
+	for _, f := range flows {
+		if f.AccountLabel != h.AccountLabel || f.Day <= valuations[i].ValuedOn || f.Day > day {
+			continue
+		}
+		// Spending from an account lowers what it holds and raises what
+		// a card or loan is owed
+		if h.Class == HoldingLiability {
+			value -= f.Net
+		} else {
+			value += f.Net
+		}
+	}
+	return value, true
+}
+
+// NetWorthAt sums the holdings on each of days. valuations maps holding IDs
+// to their valuations in the base currency, sorted by day.
+func NetWorthAt(holdings []Holding, valuations map[int64][]Valuation, flows []AccountFlow, days []string) []NetWorthPoint {
+	points := make([]NetWorthPoint, len(days))
+	for i, day := range days {
+		p := NetWorthPoint{Date: day}
+		for _, h := range holdings {
+			value, ok := HoldingValueAt(h, valuations[h.ID], flows, day)
+			if !ok {
+				continue
+			}
+			if h.Class == HoldingLiability {
+				p.Liabilities += value
+			} else {
+				p.Assets += value
+			}
+		}
+		p.NetWorth = p.Assets - p.Liabilities
+		points[i] = p
+	}
+	return points
+}
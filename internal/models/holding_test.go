@@ -0,0 +1,37 @@
+package models
+
+import "testing"
+
+func TestNetWorthAt(t *testing.T) {
+	holdings := []Holding{
+		{ID: 1, Name: "Gold", Kind: "gold", Class: HoldingAsset},
+		{ID: 2, Name: "Savings", Kind: "savings", Class: HoldingAsset, AccountLabel: "kbank-savings"},
+		{ID: 3, Name: "Card", Kind: "credit_card", Class: HoldingLiability, AccountLabel: "ktc"},
+	}
+	valuations := map[int64][]Valuation{
+		1: {{ValuedOn: "2026-08-01", Value: NewMoney(50000)}, {ValuedOn: "2026-10-01", Value: NewMoney(56000)}},
+		2: {{ValuedOn: "2026-09-01", Value: NewMoney(100000)}},
+		3: {{ValuedOn: "2026-09-15", Value: NewMoney(3000)}},
+	}
+	flows := []AccountFlow{
+		// Before the valuation, already counted in it
+		{AccountLabel: "kbank-savings", Day: "2026-08-25", Net: NewMoney(40000)},
+		{AccountLabel: "kbank-savings", Day: "2026-09-25", Net: NewMoney(40000)},
+		{AccountLabel: "kbank-savings", Day: "2026-10-05", Net: NewMoney(-8000)},
+		// Spending on the card adds to what is owed
+		{AccountLabel: "ktc", Day: "2026-10-10", Net: NewMoney(-1500)},
+	}
+
+	points := NetWorthAt(holdings, valuations, flows, []string{"2026-07-31", "2026-08-31", "2026-09-30", "2026-10-19"})
+	want := []NetWorthPoint{
+		{Date: "2026-07-31"},
+		{Date: "2026-08-31", Assets: NewMoney(50000), NetWorth: NewMoney(50000)},
+		{Date: "2026-09-30", Assets: NewMoney(190000), Liabilities: NewMoney(3000), NetWorth: NewMoney(187000)},
+		{Date: "2026-10-19", Assets: NewMoney(188000), Liabilities: NewMoney(4500), NetWorth: NewMoney(183500)},
+	}
+	for i := range want {
+		if points[i] != want[i] {
+			t.Errorf("point %d = %+v, want %+v", i, points[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package models
+
+import "database/sql"
+
+// Job status values
+const (
+	JobQueued  = "queued"
+	JobRunning = "running"
+	JobDone    = "done"
+	JobFailed  = "failed"
+	// JobPaused jobs belong to a trashed transaction and are queued again
+	// when it is restored
+	JobPaused = "paused"
+)
+
+// Job is a unit of background work persisted in the jobs table
+type Job struct {
+	ID            int64          `json:"id"`
+	Kind          string         `json:"kind"`
+	UserID        int64          `json:"user_id"`
+	TransactionID sql.NullInt64  `json:"transaction_id"`
+	Payload       string         `json:"payload"`
+	Status        string         `json:"status"`
+	Attempts      int            `json:"attempts"`
+	MaxAttempts   int            `json:"max_attempts"`
+	LastError     sql.NullString `json:"last_error"`
+	RunAfter      string         `json:"run_after"`
+	CreatedAt     string         `json:"created_at"`
+	UpdatedAt     string         `json:"updated_at"`
+}
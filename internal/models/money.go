@@ -0,0 +1,249 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Money is an amount in minor units: hundredths of the transaction's
+// currency (satang, cents). Every currency is kept with two minor digits, so
+// 1,500 JPY is Money(150000). Sums of Money are exact.
+type Money int64
+
+// NewMoney converts a floating-point amount in major units, rounding to the
+// nearest minor unit. Use ParseMoney for amounts that arrive as text.
+func NewMoney(major float64) Money {
+	return Money(math.Round(major * 100))
+}
+
+// ParseMoney reads an amount written in major units such as "1,234.50",
+// "-12" or "96.5" without going through floating point. Thousands
+// separators are ignored; more than two decimals are rounded half away from
+// zero.
+func ParseMoney(s string) (Money, error) {
+	text := strings.ReplaceAll(strings.TrimSpace(s), ",", "")
+	if text == "" {
+		return 0, fmt.Errorf("empty amount")
+	}
+
+	negative := false
+	switch text[0] {
+	case '-':
+		negative = true
+		text = text[1:]
+	case '+':
+		text = text[1:]
+	}
+
+	whole, frac, _ := strings.Cut(text, ".")
+	if whole == "" && frac == "" {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+	if !digitsOnly(whole) || !digitsOnly(frac) {
+		// Exponents and the like: fall back to float parsing
+		f, err := strconv.ParseFloat(strings.ReplaceAll(strings.TrimSpace(s), ",", ""), 64)
+		if err != nil || math.IsInf(f, 0) || math.IsNaN(f) {
+			return 0, fmt.Errorf("invalid amount %q", s)
+		}
+		return NewMoney(f), nil
+	}
+
+	var units int64
+	if whole != "" {
+		n, err := strconv.ParseInt(whole, 10, 64)
+		if err != nil || n > math.MaxInt64/100 {
+			return 0, fmt.Errorf("amount %q is too large", s)
+		}
+		units = n * 100
+	}
+	digits := (frac + "000")[:3]
+	cents, _ := strconv.ParseInt(digits[:2], 10, 64)
+	units += cents
+	if digits[2] >= '5' {
+		units++
+	}
+
+	if negative {
+		units = -units
+	}
+	return Money(units), nil
+}
+
+func digitsOnly(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Float64 returns the amount in major units, for display and charts only
+func (m Money) Float64() float64 {
+	return float64(m) / 100
+}
+
+// Abs returns the amount without its sign
+func (m Money) Abs() Money {
+	if m < 0 {
+		return -m
+	}
+	return m
+}
+
+// String formats the amount in major units with two decimals, e.g. "1234.50"
+func (m Money) String() string {
+	sign := ""
+	units := int64(m)
+	if units < 0 {
+		sign = "-"
+		units = -units
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, units/100, units%100)
+}
+
+// Format writes the amount with thousands separators, e.g. "1,234.50"
+func (m Money) Format() string {
+	text := m.String()
+	sign := ""
+	if strings.HasPrefix(text, "-") {
+		sign, text = "-", text[1:]
+	}
+	whole, frac, _ := strings.Cut(text, ".")
+
+	var b strings.Builder
+	for i, r := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(r)
+	}
+	return sign + b.String() + "." + frac
+}
+
+// MarshalJSON encodes the amount as a JSON number in major units
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalJSON accepts a number, a string such as "1,234.50", or null (zero)
+func (m *Money) UnmarshalJSON(data []byte) error {
+	text := strings.TrimSpace(string(data))
+	if text == "null" {
+		*m = 0
+		return nil
+	}
+	if strings.HasPrefix(text, `"`) {
+		if err := json.Unmarshal(data, &text); err != nil {
+			return err
+		}
+		if strings.TrimSpace(text) == "" {
+			*m = 0
+			return nil
+		}
+	}
+	parsed, err := ParseMoney(text)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// Value stores the amount as an integer number of minor units
+func (m Money) Value() (driver.Value, error) {
+	return int64(m), nil
+}
+
+// Scan reads minor units. REAL values, which only appear in aggregates over
+// legacy rows, are rounded.
+func (m *Money) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*m = 0
+	case int64:
+		*m = Money(v)
+	case float64:
+		*m = Money(math.Round(v))
+	case []byte:
+		n, err := strconv.ParseInt(string(v), 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid money value %q", v)
+		}
+		*m = Money(n)
+	case string:
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid money value %q", v)
+		}
+		*m = Money(n)
+	default:
+		return fmt.Errorf("cannot scan %T into Money", src)
+	}
+	return nil
+}
+
+// NullMoney is a Money that may be unset, like sql.NullInt64. An unset
+// amount is stored as NULL and encoded as JSON null, so a real zero is kept
+// apart from "no amount found".
+type NullMoney struct {
+	Money Money
+	Valid bool
+}
+
+// SomeMoney returns a set NullMoney
+func SomeMoney(m Money) NullMoney {
+	return NullMoney{Money: m, Valid: true}
+}
+
+// String formats the amount like Money.String, or "" when unset
+func (n NullMoney) String() string {
+	if !n.Valid {
+		return ""
+	}
+	return n.Money.String()
+}
+
+// MarshalJSON encodes the amount as a number, or null when unset
+func (n NullMoney) MarshalJSON() ([]byte, error) {
+	if !n.Valid {
+		return []byte("null"), nil
+	}
+	return n.Money.MarshalJSON()
+}
+
+// UnmarshalJSON accepts what Money does; null and "" leave the amount unset
+func (n *NullMoney) UnmarshalJSON(data []byte) error {
+	text := strings.TrimSpace(string(data))
+	if text == "null" || text == `""` {
+		*n = NullMoney{}
+		return nil
+	}
+	if err := n.Money.UnmarshalJSON(data); err != nil {
+		return err
+	}
+	n.Valid = true
+	return nil
+}
+
+// Value stores NULL when unset
+func (n NullMoney) Value() (driver.Value, error) {
+	if !n.Valid {
+		return nil, nil
+	}
+	return int64(n.Money), nil
+}
+
+// Scan reads minor units, leaving the amount unset for NULL
+func (n *NullMoney) Scan(src interface{}) error {
+	if src == nil {
+		*n = NullMoney{}
+		return nil
+	}
+	n.Valid = true
+	return n.Money.Scan(src)
+}
@@ -0,0 +1,94 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	cases := []struct {
+		input    string
+		expected Money
+	}{
+		{"1,234.50", 123450},
+		{"96", 9600},
+		{"96.5", 9650},
+		{"0.1", 10},
+		{"-12.34", -1234},
+		{"0.005", 1},
+		{"1e3", 100000},
+	}
+
+	for _, tc := range cases {
+		got, err := ParseMoney(tc.input)
+		if err != nil {
+			t.Fatalf("ParseMoney(%q): %v", tc.input, err)
+		}
+		if got != tc.expected {
+			t.Fatalf("ParseMoney(%q) = %d, want %d", tc.input, got, tc.expected)
+		}
+	}
+
+	for _, input := range []string{"", "abc", ".", "1.2.3"} {
+		if _, err := ParseMoney(input); err == nil {
+			t.Fatalf("ParseMoney(%q) succeeded, want error", input)
+		}
+	}
+}
+
+func TestMoneySumIsExact(t *testing.T) {
+	a, _ := ParseMoney("0.1")
+	b, _ := ParseMoney("0.2")
+	if got := (a + b).String(); got != "0.30" {
+		t.Fatalf("0.1 + 0.2 = %s, want 0.30", got)
+	}
+}
+
+func TestMoneyFormat(t *testing.T) {
+	cases := map[Money]string{
+		0:         "0.00",
+		5:         "0.05",
+		123450:    "1,234.50",
+		-12345678: "-123,456.78",
+	}
+	for m, expected := range cases {
+		if got := m.Format(); got != expected {
+			t.Fatalf("Money(%d).Format() = %q, want %q", int64(m), got, expected)
+		}
+	}
+}
+
+func TestMoneyJSON(t *testing.T) {
+	var v struct {
+		Amount  Money     `json:"amount"`
+		Text    Money     `json:"text"`
+		Missing NullMoney `json:"missing"`
+		Zero    NullMoney `json:"zero"`
+		Quoted  NullMoney `json:"quoted"`
+	}
+	data := `{"amount": 1234.5, "text": "1,234.50", "missing": null, "zero": 0, "quoted": "96.00"}`
+	if err := json.Unmarshal([]byte(data), &v); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if v.Amount != 123450 || v.Text != 123450 {
+		t.Fatalf("amount = %d, text = %d, want 123450", v.Amount, v.Text)
+	}
+	if v.Missing.Valid {
+		t.Fatal("null amount should be unset")
+	}
+	if !v.Zero.Valid || v.Zero.Money != 0 {
+		t.Fatalf("zero = %+v, want a set zero", v.Zero)
+	}
+	if v.Quoted.Money != 9600 {
+		t.Fatalf("quoted = %d, want 9600", v.Quoted.Money)
+	}
+
+	out, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	expected := `{"amount":1234.50,"text":1234.50,"missing":null,"zero":0.00,"quoted":96.00}`
+	if string(out) != expected {
+		t.Fatalf("Marshal = %s, want %s", out, expected)
+	}
+}
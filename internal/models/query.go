@@ -0,0 +1,48 @@
+package models
+
+// Sort orders for TransactionQuery
+const (
+	SortTxnDate   = "txn_date"
+	SortAmount    = "amount"
+	SortCreatedAt = "created_at"
+)
+
+// TransactionQuery selects a page of a user's transactions. Zero values mean
+// "no restriction"; From and To compare against the transaction date, falling
+// back to the day it was created. Claimed selects transactions that are or
+// are not on a claim, and TaxItem matches the transaction's own tax item or
+// else its category's.
+type TransactionQuery struct {
+	UserID        int64     `json:"user_id,omitempty"`
+	From          string    `json:"from,omitempty"`
+	To            string    `json:"to,omitempty"`
+	Status        string    `json:"status,omitempty"`
+	Direction     string    `json:"direction,omitempty"`
+	Category      string    `json:"category,omitempty"`
+	Channel       string    `json:"channel,omitempty"`
+	AccountLabel  string    `json:"account_label,omitempty"`
+	Tag           string    `json:"tag,omitempty"`
+	TaxItem       string    `json:"tax_item,omitempty"`
+	MinAmount     NullMoney `json:"min_amount"`
+	MaxAmount     NullMoney `json:"max_amount"`
+	HasSlip       *bool     `json:"has_slip,omitempty"`
+	Reimbursable  *bool     `json:"reimbursable,omitempty"`
+	Claimed       *bool     `json:"claimed,omitempty"`
+	MinConfidence float64   `json:"min_confidence,omitempty"`
+	MaxConfidence float64   `json:"max_confidence,omitempty"`
+
+	// Sort is one of the Sort constants, newest or largest first unless
+	// Ascending is set. Ties are broken by ID.
+	Sort      string `json:"sort,omitempty"`
+	Ascending bool   `json:"ascending,omitempty"`
+	// Cursor is the NextCursor of the previous page
+	Cursor string `json:"cursor,omitempty"`
+	Limit  int    `json:"limit,omitempty"`
+}
+
+// TransactionPage is one page of a TransactionQuery. NextCursor is empty on
+// the last page.
+type TransactionPage struct {
+	Transactions []TransactionView `json:"transactions"`
+	NextCursor   string            `json:"next_cursor,omitempty"`
+}
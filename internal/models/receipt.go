@@ -0,0 +1,102 @@
+package models
+
+import (
+	"strings"
+	"unicode"
+)
+
+// HeadOffice is the branch number of a seller's head office
+// (สำนักงานใหญ่) on Thai tax invoices
+const HeadOffice = "00000"
+
+// ReceiptDetails are the tax invoice fields read from a receipt, which
+// Easy E-Receipt and other deductions need as proof of purchase. Every
+// field is optional; SellerTaxID is only kept when its check digit is right.
+type ReceiptDetails struct {
+	TransactionID int64     `json:"transaction_id"`
+	SellerTaxID   string    `json:"seller_tax_id,omitempty"`
+	Branch        string    `json:"branch,omitempty"`
+	InvoiceNumber string    `json:"invoice_number,omitempty"`
+	VAT           NullMoney `json:"vat"`
+	PreVAT        NullMoney `json:"pre_vat_amount"`
+	UpdatedAt     string    `json:"updated_at,omitempty"`
+}
+
+// IsEmpty reports whether none of the receipt fields are known
+func (d ReceiptDetails) IsEmpty() bool {
+	return d.SellerTaxID == "" && d.Branch == "" && d.InvoiceNumber == "" && !d.VAT.Valid && !d.PreVAT.Valid
+}
+
+// Fill sets the fields of d that are unknown from other
+func (d *ReceiptDetails) Fill(other ReceiptDetails) {
+	if d.SellerTaxID == "" {
+		d.SellerTaxID = other.SellerTaxID
+	}
+	if d.Branch == "" {
+		d.Branch = other.Branch
+	}
+	if d.InvoiceNumber == "" {
+		d.InvoiceNumber = other.InvoiceNumber
+	}
+	if !d.VAT.Valid {
+		d.VAT = other.VAT
+	}
+	if !d.PreVAT.Valid {
+		d.PreVAT = other.PreVAT
+	}
+}
+
+// Receipt is a transaction's receipt details with what the export lists
+// alongside them
+type Receipt struct {
+	ReceiptDetails
+	Day         string `json:"date"`
+	Amount      Money  `json:"amount"`
+	Currency    string `json:"currency"`
+	Payee       string `json:"payee,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// ValidTaxID reports whether id is a 13-digit Thai tax identification
+// number with the right check digit: the first twelve digits weighted 13
+// down to 2, summed, and taken from 11 modulo 11, keeping the last digit.
+func ValidTaxID(id string) bool {
+	if len(id) != 13 || !digitsOnly(id) {
+		return false
+	}
+	sum := 0
+	for i := 0; i < 12; i++ {
+		sum += int(id[i]-'0') * (13 - i)
+	}
+	return (11-sum%11)%10 == int(id[12]-'0')
+}
+
+// NormalizeTaxID strips the dashes and spaces tax IDs are printed with. It
+// returns "" unless what is left is a valid tax ID.
+func NormalizeTaxID(s string) string {
+	id := strings.Map(func(r rune) rune {
+		if r == '-' || unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, s)
+	if !ValidTaxID(id) {
+		return ""
+	}
+	return id
+}
+
+// NormalizeBranch returns the five-digit branch number printed on tax
+// invoices, reading "head office" and สำนักงานใหญ่ as HeadOffice. It returns
+// "" for anything else.
+func NormalizeBranch(s string) string {
+	s = strings.TrimSpace(s)
+	switch strings.ToLower(s) {
+	case "สำนักงานใหญ่", "head office", "hq":
+		return HeadOffice
+	}
+	if s == "" || len(s) > len(HeadOffice) || !digitsOnly(s) {
+		return ""
+	}
+	return strings.Repeat("0", len(HeadOffice)-len(s)) + s
+}
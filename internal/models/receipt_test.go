@@ -0,0 +1,67 @@
+package models
+
+import "testing"
+
+func TestNormalizeTaxID(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"0105536092641", "0105536092641"},
+		{"0-1055-36092-64-1", "0105536092641"},
+		{"0994 0001 6551 0", "0994000165510"},
+		// Wrong check digit
+		{"0105536092642", ""},
+		{"010553609264", ""},
+		{"01055360926411", ""},
+		{"O105536092641", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := NormalizeTaxID(tt.input); got != tt.want {
+			t.Errorf("NormalizeTaxID(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeBranch(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"สำนักงานใหญ่", HeadOffice},
+		{"Head Office", HeadOffice},
+		{"00000", HeadOffice},
+		{"12", "00012"},
+		{"00345", "00345"},
+		{"123456", ""},
+		{"สาขาสยาม", ""},
+	}
+	for _, tt := range tests {
+		if got := NormalizeBranch(tt.input); got != tt.want {
+			t.Errorf("NormalizeBranch(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestReceiptDetailsFill(t *testing.T) {
+	d := ReceiptDetails{InvoiceNumber: "INV-1", VAT: SomeMoney(700)}
+	d.Fill(ReceiptDetails{
+		SellerTaxID:   "0105536092641",
+		InvoiceNumber: "INV-2",
+		VAT:           SomeMoney(600),
+		PreVAT:        SomeMoney(10000),
+	})
+	want := ReceiptDetails{
+		SellerTaxID:   "0105536092641",
+		InvoiceNumber: "INV-1",
+		VAT:           SomeMoney(700),
+		PreVAT:        SomeMoney(10000),
+	}
+	if d != want {
+		t.Errorf("Fill = %+v, want %+v", d, want)
+	}
+	if d.IsEmpty() || !(ReceiptDetails{TransactionID: 1}).IsEmpty() {
+		t.Error("IsEmpty is wrong")
+	}
+}
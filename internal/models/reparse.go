@@ -0,0 +1,118 @@
+package models
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Re-parse modes
+const (
+	ReparseOCR  = "ocr"  // re-run OCR only
+	ReparseLLM  = "llm"  // re-parse the stored OCR text or chat message
+	ReparseBoth = "both" // re-run OCR, then parse the new text
+)
+
+// Re-parse run status values
+const (
+	ReparseQueued  = "queued"
+	ReparseRunning = "running"
+	ReparseDone    = "done"
+	ReparseFailed  = "failed"
+)
+
+// ReparseFilter selects the transactions a re-parse run looks at. Zero values
+// mean "no restriction".
+type ReparseFilter struct {
+	UserID        int64   `json:"user_id,omitempty"`
+	From          string  `json:"from,omitempty"`
+	To            string  `json:"to,omitempty"`
+	Status        string  `json:"status,omitempty"`
+	MaxConfidence float64 `json:"max_confidence,omitempty"`
+	Limit         int     `json:"limit,omitempty"`
+}
+
+// ReparseRun is one pass of re-running OCR and/or LLM parsing. Its proposed
+// field changes are stored as ReparseChange rows until they are applied.
+type ReparseRun struct {
+	ID         int64  `json:"id"`
+	UserID     int64  `json:"user_id"`
+	Mode       string `json:"mode"`
+	Filter     string `json:"filter"`
+	Status     string `json:"status"`
+	Processed  int    `json:"processed"`
+	Changed    int    `json:"changed"`
+	Error      string `json:"error"`
+	CreatedAt  string `json:"created_at"`
+	FinishedAt string `json:"finished_at"`
+}
+
+// ReparseChange is a single field that a re-parse run would change.
+// Protected changes touch a field the user edited and are never applied.
+type ReparseChange struct {
+	ID            int64  `json:"id"`
+	RunID         int64  `json:"run_id"`
+	TransactionID int64  `json:"transaction_id"`
+	Field         string `json:"field"`
+	OldValue      string `json:"old_value"`
+	NewValue      string `json:"new_value"`
+	Protected     bool   `json:"protected"`
+	Applied       bool   `json:"applied"`
+}
+
+// ReparseSelection picks which changes of a run to apply. Empty lists match
+// everything, so the zero value applies every unprotected change.
+type ReparseSelection struct {
+	ChangeIDs      []int64  `json:"change_ids,omitempty"`
+	TransactionIDs []int64  `json:"transaction_ids,omitempty"`
+	Fields         []string `json:"fields,omitempty"`
+}
+
+// ReparseFields lists the transaction columns a re-parse may propose changes for.
+var ReparseFields = []string{
+	"raw_ocr_text", "txn_date", "amount", "currency", "direction", "channel",
+	"account_label", "category", "description", "llm_confidence",
+}
+
+// FieldValue returns a transaction field formatted the way re-parse diffs and
+// edit tracking compare it. Unknown fields return "".
+func (t *Transaction) FieldValue(field string) string {
+	switch field {
+	case "raw_ocr_text":
+		return t.RawOCRText.String
+	case "txn_date":
+		return t.TxnDate.String
+	case "amount":
+		return t.Amount.String()
+	case "currency":
+		return t.Currency
+	case "direction":
+		return t.Direction
+	case "channel":
+		return t.Channel.String
+	case "account_label":
+		return t.AccountLabel.String
+	case "category":
+		return t.Category.String
+	case "description":
+		return t.Description.String
+	case "llm_confidence":
+		if !t.LLMConfidence.Valid {
+			return ""
+		}
+		return strconv.FormatFloat(t.LLMConfidence.Float64, 'f', 2, 64)
+	case "status":
+		return t.Status
+	case "tags":
+		return strings.Join(t.Tags, ",")
+	case "reimbursable":
+		return strconv.FormatBool(t.Reimbursable)
+	case "claim_id":
+		if !t.ClaimID.Valid {
+			return ""
+		}
+		return strconv.FormatInt(t.ClaimID.Int64, 10)
+	case "tax_item":
+		return t.TaxItem.String
+	}
+	return ""
+}
@@ -0,0 +1,42 @@
+package models
+
+// Revision actions
+const (
+	RevisionCreate  = "create"
+	RevisionUpdate  = "update"
+	RevisionConfirm = "confirm"
+	RevisionDelete  = "delete"
+	RevisionRestore = "restore"
+	RevisionRevert  = "revert"
+)
+
+// Revision actors: who or what made a change
+const (
+	ActorUser   = "user"
+	ActorLLM    = "llm"
+	ActorRegex  = "regex"
+	ActorRule   = "rule"
+	ActorImport = "import"
+)
+
+// RevisionFields lists the transaction fields recorded in each revision.
+// "tags" holds the transaction's tag names, comma-separated and sorted.
+var RevisionFields = []string{
+	"txn_date", "amount", "currency", "direction", "channel", "account_label",
+	"category", "description", "llm_confidence", "status", "tags",
+	"reimbursable", "claim_id", "tax_item",
+}
+
+// TransactionRevision is one recorded change to a transaction. Before and
+// After hold RevisionFields as formatted by Transaction.FieldValue; Before is
+// nil for a create. Changed lists the fields that differ.
+type TransactionRevision struct {
+	ID            int64             `json:"id"`
+	TransactionID int64             `json:"transaction_id"`
+	Action        string            `json:"action"`
+	Actor         string            `json:"actor"`
+	Before        map[string]string `json:"before"`
+	After         map[string]string `json:"after"`
+	Changed       []string          `json:"changed"`
+	CreatedAt     string            `json:"created_at"`
+}
@@ -0,0 +1,40 @@
+package models
+
+import "html/template"
+
+// SearchQuery is a parsed transaction search. Terms are matched against the
+// description, chat message, OCR text and payee; the other fields filter.
+// SellerTaxID and InvoiceNumber match the receipt details.
+type SearchQuery struct {
+	Terms         []string  `json:"terms"`
+	Status        string    `json:"status,omitempty"`
+	Direction     string    `json:"direction,omitempty"`
+	Category      string    `json:"category,omitempty"`
+	Channel       string    `json:"channel,omitempty"`
+	Tag           string    `json:"tag,omitempty"`
+	SellerTaxID   string    `json:"seller_tax_id,omitempty"`
+	InvoiceNumber string    `json:"invoice_number,omitempty"`
+	From          string    `json:"from,omitempty"`
+	To            string    `json:"to,omitempty"`
+	MinAmount     NullMoney `json:"min_amount"`
+	MaxAmount     NullMoney `json:"max_amount"`
+}
+
+// IsEmpty reports whether the query has neither terms nor filters
+func (q SearchQuery) IsEmpty() bool {
+	return len(q.Terms) == 0 && q.Status == "" && q.Direction == "" && q.Category == "" && q.Channel == "" && q.Tag == "" &&
+		q.SellerTaxID == "" && q.InvoiceNumber == "" && q.From == "" && q.To == "" && !q.MinAmount.Valid && !q.MaxAmount.Valid
+}
+
+// SearchResult is a matching transaction. Snippet is HTML-escaped text
+// around the match with the matched parts wrapped in <mark>; it is empty
+// when the query had no terms.
+type SearchResult struct {
+	TransactionView
+	Snippet string `json:"snippet"`
+}
+
+// SnippetHTML returns the snippet for use in templates
+func (r SearchResult) SnippetHTML() template.HTML {
+	return template.HTML(r.Snippet)
+}
@@ -0,0 +1,41 @@
+package models
+
+import "strings"
+
+// Tag is a free-form label on transactions. A transaction can carry any
+// number of tags, alongside its one category.
+type Tag struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+	// Count is the number of transactions carrying the tag
+	Count int `json:"count"`
+}
+
+// TagAmount is the spending on transactions carrying a tag. A transaction
+// with several tags counts towards each of them.
+type TagAmount struct {
+	Tag              string  `json:"tag"`
+	Amount           Money   `json:"amount"`
+	Count            int     `json:"count"`
+	PercentOfExpense float64 `json:"percent_of_expense"`
+}
+
+// NormalizeTag lower-cases a tag name and drops a leading '#'
+func NormalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(tag), "#"))
+}
+
+// NormalizeTags normalizes tag names, removing blanks and duplicates
+func NormalizeTags(tags []string) []string {
+	seen := map[string]bool{}
+	var normalized []string
+	for _, tag := range tags {
+		tag = NormalizeTag(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	return normalized
+}
@@ -0,0 +1,217 @@
+package models
+
+import (
+	"fmt"
+	"math"
+)
+
+const (
+	// TaxItemNone marks a transaction as not deductible even though its
+	// category is
+	TaxItemNone = "none"
+	// TaxItemEasyEReceipt only counts purchases made during the campaign
+	TaxItemEasyEReceipt = "easy_e_receipt"
+)
+
+// TaxCap is the most an item deducts from the given year on
+type TaxCap struct {
+	Year   int   `json:"year"`
+	Amount Money `json:"amount"`
+}
+
+// TaxItem is a kind of expense that is deductible from Thai personal income
+// tax (ภ.ง.ด.90/91). Its cap is the amount for the year, further limited to
+// IncomeShare of the year's income when that is set. Items in the same
+// Group also share the group's cap.
+type TaxItem struct {
+	Key         string   `json:"key"`
+	Caps        []TaxCap `json:"caps,omitempty"`
+	IncomeShare float64  `json:"income_share,omitempty"`
+	Group       string   `json:"group,omitempty"`
+}
+
+// CapFor returns the cap of the item for a tax year, or zero when it is
+// only limited by income
+func (i TaxItem) CapFor(year int) Money {
+	var amount Money
+	for _, c := range i.Caps {
+		if c.Year <= year {
+			amount = c.Amount
+		}
+	}
+	return amount
+}
+
+// Tax deduction groups with a combined cap
+const (
+	TaxGroupLifeHealth = "life_health"
+	TaxGroupRetirement = "retirement"
+)
+
+// TaxGroupCaps are the combined caps of the tax deduction groups
+var TaxGroupCaps = map[string]Money{
+	TaxGroupLifeHealth: NewMoney(100000),
+	TaxGroupRetirement: NewMoney(500000),
+}
+
+// TaxItems are the deductible items in report order, with the statutory
+// caps. Donations are limited to 10% of income, which the law counts after
+// the other deductions; the report uses the income as given.
+var TaxItems = []TaxItem{
+	{Key: "life_insurance", Caps: []TaxCap{{0, NewMoney(100000)}}, Group: TaxGroupLifeHealth},
+	{Key: "health_insurance", Caps: []TaxCap{{0, NewMoney(25000)}}, Group: TaxGroupLifeHealth},
+	{Key: "parents_health_insurance", Caps: []TaxCap{{0, NewMoney(15000)}}},
+	{Key: "pension_insurance", Caps: []TaxCap{{0, NewMoney(200000)}}, IncomeShare: 0.15, Group: TaxGroupRetirement},
+	{Key: "rmf", Caps: []TaxCap{{0, NewMoney(500000)}}, IncomeShare: 0.30, Group: TaxGroupRetirement},
+	{Key: "ssf", Caps: []TaxCap{{0, NewMoney(200000)}}, IncomeShare: 0.30, Group: TaxGroupRetirement},
+	{Key: "thai_esg", Caps: []TaxCap{{0, NewMoney(100000)}, {2025, NewMoney(300000)}}, IncomeShare: 0.30},
+	{Key: "home_loan_interest", Caps: []TaxCap{{0, NewMoney(100000)}}},
+	{Key: TaxItemEasyEReceipt, Caps: []TaxCap{{0, NewMoney(50000)}}},
+	{Key: "donation", IncomeShare: 0.10},
+}
+
+// EasyEReceiptWindows are the campaign periods of Easy E-Receipt by year.
+// Purchases outside them do not count; in years not listed every purchase
+// tagged easy_e_receipt is counted.
+var EasyEReceiptWindows = map[int]Period{
+	2024: {From: "2024-01-01", To: "2024-02-15"},
+	2025: {From: "2025-01-16", To: "2025-02-28"},
+}
+
+// LookupTaxItem returns the deductible item with the given key
+func LookupTaxItem(key string) (TaxItem, bool) {
+	for _, item := range TaxItems {
+		if item.Key == key {
+			return item, true
+		}
+	}
+	return TaxItem{}, false
+}
+
+// TaxCategory makes every transaction in a category count towards a tax
+// item unless the transaction says otherwise
+type TaxCategory struct {
+	Category string `json:"category"`
+	Item     string `json:"item"`
+}
+
+// TaxTransaction is an expense counting towards a tax item on Day. Base is
+// its amount in the user's base currency, unset when no exchange rate is
+// known.
+type TaxTransaction struct {
+	Transaction
+	Item string
+	Day  string
+	Base NullMoney
+}
+
+// TaxItemTotal is the spending on one tax item over a year and how much of
+// it can be deducted. Cap is the item's own limit for the year and income,
+// before the cap of its group. Excluded counts Easy E-Receipt purchases made
+// outside the campaign and Unconverted transactions without an exchange
+// rate; neither is in Amount.
+type TaxItemTotal struct {
+	Item        string `json:"item"`
+	Group       string `json:"group,omitempty"`
+	Amount      Money  `json:"amount"`
+	Count       int    `json:"count"`
+	Cap         Money  `json:"cap"`
+	Deductible  Money  `json:"deductible"`
+	Excluded    int    `json:"excluded,omitempty"`
+	Unconverted int    `json:"unconverted,omitempty"`
+}
+
+// TaxGroupTotal is what the items of a group deduct together
+type TaxGroupTotal struct {
+	Group      string `json:"group"`
+	Cap        Money  `json:"cap"`
+	Deductible Money  `json:"deductible"`
+}
+
+// TaxReport sums the deductible spending of a tax year (1 January to 31
+// December) against the statutory caps, in the user's base currency
+type TaxReport struct {
+	Year            int             `json:"year"`
+	Period          Period          `json:"period"`
+	Currency        string          `json:"currency"`
+	Income          Money           `json:"income"`
+	Items           []TaxItemTotal  `json:"items"`
+	Groups          []TaxGroupTotal `json:"groups"`
+	TotalDeductible Money           `json:"total_deductible"`
+}
+
+// BuildTaxReport totals transactions by tax item and caps them: first by
+// each item's own cap and share of income, then by the combined cap of the
+// item's group, filling items in TaxItems order.
+func BuildTaxReport(year int, currency string, income Money, transactions []TaxTransaction) TaxReport {
+	report := TaxReport{
+		Year:     year,
+		Period:   TaxYear(year),
+		Currency: currency,
+		Income:   income,
+		Items:    []TaxItemTotal{},
+		Groups:   []TaxGroupTotal{},
+	}
+
+	totals := map[string]*TaxItemTotal{}
+	for _, item := range TaxItems {
+		limit := item.CapFor(year)
+		if item.IncomeShare > 0 {
+			share := Money(math.Round(float64(income) * item.IncomeShare))
+			if limit == 0 || share < limit {
+				limit = share
+			}
+		}
+		report.Items = append(report.Items, TaxItemTotal{Item: item.Key, Group: item.Group, Cap: limit})
+	}
+	for i := range report.Items {
+		totals[report.Items[i].Item] = &report.Items[i]
+	}
+
+	window, hasWindow := EasyEReceiptWindows[year]
+	for _, tx := range transactions {
+		total, ok := totals[tx.Item]
+		if !ok {
+			continue
+		}
+		if tx.Item == TaxItemEasyEReceipt && hasWindow && (tx.Day < window.From || tx.Day > window.To) {
+			total.Excluded++
+			continue
+		}
+		if !tx.Base.Valid {
+			total.Unconverted++
+			continue
+		}
+		total.Amount += tx.Base.Money
+		total.Count++
+	}
+
+	groupLeft := map[string]Money{}
+	for group, limit := range TaxGroupCaps {
+		groupLeft[group] = limit
+	}
+	for i := range report.Items {
+		total := &report.Items[i]
+		total.Deductible = total.Amount
+		if total.Deductible > total.Cap {
+			total.Deductible = total.Cap
+		}
+		if total.Group != "" {
+			if total.Deductible > groupLeft[total.Group] {
+				total.Deductible = groupLeft[total.Group]
+			}
+			groupLeft[total.Group] -= total.Deductible
+		}
+		report.TotalDeductible += total.Deductible
+	}
+	for _, group := range []string{TaxGroupLifeHealth, TaxGroupRetirement} {
+		limit := TaxGroupCaps[group]
+		report.Groups = append(report.Groups, TaxGroupTotal{Group: group, Cap: limit, Deductible: limit - groupLeft[group]})
+	}
+	return report
+}
+
+// TaxYear returns the dates of a Thai tax year, which is the calendar year
+func TaxYear(year int) Period {
+	return Period{From: fmt.Sprintf("%04d-01-01", year), To: fmt.Sprintf("%04d-12-31", year)}
+}
@@ -0,0 +1,84 @@
+package models
+
+import (
+	"database/sql"
+	"testing"
+)
+
+func taxTx(item, day string, amount float64) TaxTransaction {
+	return TaxTransaction{
+		Transaction: Transaction{TxnDate: sql.NullString{String: day, Valid: true}},
+		Item:        item,
+		Day:         day,
+		Base:        SomeMoney(NewMoney(amount)),
+	}
+}
+
+func TestBuildTaxReport(t *testing.T) {
+	transactions := []TaxTransaction{
+		taxTx("life_insurance", "2025-03-01", 90000),
+		taxTx("health_insurance", "2025-04-01", 30000),
+		taxTx("rmf", "2025-06-01", 400000),
+		taxTx("ssf", "2025-06-01", 150000),
+		taxTx("thai_esg", "2025-12-20", 250000),
+		taxTx("easy_e_receipt", "2025-02-01", 20000),
+		taxTx("easy_e_receipt", "2025-03-01", 5000),
+		taxTx("donation", "2025-08-01", 200000),
+		{Item: "home_loan_interest", Day: "2025-12-31"},
+	}
+	report := BuildTaxReport(2025, "THB", NewMoney(1200000), transactions)
+
+	want := map[string]struct{ amount, limit, deductible float64 }{
+		// Life and health share 100,000; health alone is capped at 25,000
+		"life_insurance":   {90000, 100000, 90000},
+		"health_insurance": {30000, 25000, 10000},
+		// 30% of income, then the retirement group's 500,000
+		"rmf": {400000, 360000, 360000},
+		"ssf": {150000, 200000, 140000},
+		// ThaiESG's cap went up in 2025 and is outside the group
+		"thai_esg": {250000, 300000, 250000},
+		// Only purchases during the campaign count
+		"easy_e_receipt": {20000, 50000, 20000},
+		"donation":       {200000, 120000, 120000},
+	}
+	for _, item := range report.Items {
+		w, ok := want[item.Item]
+		if !ok {
+			continue
+		}
+		if item.Amount != NewMoney(w.amount) || item.Cap != NewMoney(w.limit) || item.Deductible != NewMoney(w.deductible) {
+			t.Errorf("%s = %+v, want amount %.0f, cap %.0f, deductible %.0f", item.Item, item, w.amount, w.limit, w.deductible)
+		}
+	}
+	if len(report.Items) != len(TaxItems) {
+		t.Errorf("got %d items, want every item in TaxItems", len(report.Items))
+	}
+	for _, item := range report.Items {
+		switch item.Item {
+		case "easy_e_receipt":
+			if item.Excluded != 1 {
+				t.Errorf("easy_e_receipt excluded = %d, want 1", item.Excluded)
+			}
+		case "home_loan_interest":
+			if item.Unconverted != 1 || item.Amount != 0 {
+				t.Errorf("home_loan_interest = %+v, want one unconverted", item)
+			}
+		}
+	}
+	if report.TotalDeductible != NewMoney(990000) {
+		t.Errorf("total deductible = %s, want 990000.00", report.TotalDeductible)
+	}
+	if report.Groups[1].Group != TaxGroupRetirement || report.Groups[1].Deductible != NewMoney(500000) {
+		t.Errorf("groups = %+v", report.Groups)
+	}
+}
+
+func TestTaxItemCapFor(t *testing.T) {
+	esg, _ := LookupTaxItem("thai_esg")
+	if got := esg.CapFor(2024); got != NewMoney(100000) {
+		t.Errorf("ThaiESG cap 2024 = %s", got)
+	}
+	if got := esg.CapFor(2026); got != NewMoney(300000) {
+		t.Errorf("ThaiESG cap 2026 = %s", got)
+	}
+}
@@ -2,45 +2,88 @@ package models
 
 import (
 	"database/sql"
+	"encoding/json"
+	"strings"
+)
+
+// Processing states of a slip moving through the OCR/LLM pipeline
+const (
+	ProcessingQueued = "queued"
+	ProcessingOCR    = "ocr"
+	ProcessingLLM    = "llm"
+	ProcessingDone   = "done"
+	ProcessingFailed = "failed"
 )
 
 type Transaction struct {
-	ID            int64           `json:"id"`
-	UserID        sql.NullInt64   `json:"user_id"`
-	TxnDate       sql.NullString  `json:"txn_date"`
-	Amount        sql.NullFloat64 `json:"amount"`
-	Currency      string          `json:"currency"`
-	Direction     string          `json:"direction"`
-	Channel       sql.NullString  `json:"channel"`
-	AccountLabel  sql.NullString  `json:"account_label"`
-	Category      sql.NullString  `json:"category"`
-	Description   sql.NullString  `json:"description"`
-	ChatMessage   sql.NullString  `json:"chat_message"`
-	SlipImagePath sql.NullString  `json:"slip_image_path"`
-	RawOCRText    sql.NullString  `json:"raw_ocr_text"`
-	LLMConfidence sql.NullFloat64 `json:"llm_confidence"`
-	Status        string          `json:"status"`
-	CreatedAt     string          `json:"created_at"`
-	UpdatedAt     string          `json:"updated_at"`
+	ID              int64           `json:"id"`
+	UserID          sql.NullInt64   `json:"user_id"`
+	TxnDate         sql.NullString  `json:"txn_date"`
+	Amount          NullMoney       `json:"amount"`
+	Currency        string          `json:"currency"`
+	Direction       string          `json:"direction"`
+	Channel         sql.NullString  `json:"channel"`
+	AccountLabel    sql.NullString  `json:"account_label"`
+	Category        sql.NullString  `json:"category"`
+	Description     sql.NullString  `json:"description"`
+	ChatMessage     sql.NullString  `json:"chat_message"`
+	SlipImagePath   sql.NullString  `json:"slip_image_path"`
+	RawOCRText      sql.NullString  `json:"raw_ocr_text"`
+	OCRBlocks       sql.NullString  `json:"ocr_blocks"`
+	Payee           sql.NullString  `json:"payee"`
+	LLMConfidence   sql.NullFloat64 `json:"llm_confidence"`
+	SlipHash        sql.NullString  `json:"slip_hash"`
+	SlipRef         sql.NullString  `json:"slip_ref"`
+	DuplicateOf     sql.NullInt64   `json:"duplicate_of"`
+	ProcessingState sql.NullString  `json:"processing_state"`
+	ProcessingError sql.NullString  `json:"processing_error"`
+	// UserEditedFields is a comma-separated list of fields the user changed
+	// when confirming; re-parsing never overwrites them.
+	UserEditedFields sql.NullString `json:"user_edited_fields"`
+	// Tags are the names of the transaction's tags, sorted
+	Tags []string `json:"tags"`
+	// Reimbursable expenses are paid back by someone else, usually through
+	// the claim in ClaimID
+	Reimbursable bool          `json:"reimbursable"`
+	ClaimID      sql.NullInt64 `json:"claim_id"`
+	// TaxItem is the tax deduction the transaction counts towards, overriding
+	// its category's; TaxItemNone opts it out
+	TaxItem   sql.NullString `json:"tax_item"`
+	Status    string         `json:"status"`
+	CreatedAt string         `json:"created_at"`
+	UpdatedAt string         `json:"updated_at"`
+	DeletedAt sql.NullString `json:"deleted_at"`
 }
 
 type TransactionView struct {
-	ID            int64   `json:"id"`
-	UserID        int64   `json:"user_id"`
-	TxnDate       string  `json:"txn_date"`
-	Amount        float64 `json:"amount"`
-	Currency      string  `json:"currency"`
-	Direction     string  `json:"direction"`
-	Channel       string  `json:"channel"`
-	AccountLabel  string  `json:"account_label"`
-	Category      string  `json:"category"`
-	Description   string  `json:"description"`
-	ChatMessage   string  `json:"chat_message"`
-	SlipImagePath string  `json:"slip_image_path"`
-	RawOCRText    string  `json:"raw_ocr_text"`
-	LLMConfidence float64 `json:"llm_confidence"`
-	Status        string  `json:"status"`
-	CreatedAt     string  `json:"created_at"`
+	ID               int64           `json:"id"`
+	UserID           int64           `json:"user_id"`
+	TxnDate          string          `json:"txn_date"`
+	Amount           Money           `json:"amount"`
+	Currency         string          `json:"currency"`
+	Direction        string          `json:"direction"`
+	Channel          string          `json:"channel"`
+	AccountLabel     string          `json:"account_label"`
+	Category         string          `json:"category"`
+	Description      string          `json:"description"`
+	ChatMessage      string          `json:"chat_message"`
+	SlipImagePath    string          `json:"slip_image_path"`
+	RawOCRText       string          `json:"raw_ocr_text"`
+	OCRBlocks        json.RawMessage `json:"ocr_blocks,omitempty"`
+	Payee            string          `json:"payee"`
+	LLMConfidence    float64         `json:"llm_confidence"`
+	SlipRef          string          `json:"slip_ref"`
+	DuplicateOf      int64           `json:"duplicate_of"`
+	ProcessingState  string          `json:"processing_state"`
+	ProcessingError  string          `json:"processing_error"`
+	UserEditedFields []string        `json:"user_edited_fields"`
+	Tags             []string        `json:"tags"`
+	Reimbursable     bool            `json:"reimbursable"`
+	ClaimID          int64           `json:"claim_id,omitempty"`
+	TaxItem          string          `json:"tax_item,omitempty"`
+	Status           string          `json:"status"`
+	CreatedAt        string          `json:"created_at"`
+	DeletedAt        string          `json:"deleted_at,omitempty"`
 	// Legacy fields for template compatibility
 	ImagePath       string `json:"image_path"`
 	TransactionDate string `json:"transaction_date"`
@@ -48,11 +91,12 @@ type TransactionView struct {
 
 func (t *Transaction) ToView() TransactionView {
 	view := TransactionView{
-		ID:        t.ID,
-		Currency:  t.Currency,
-		Direction: t.Direction,
-		Status:    t.Status,
-		CreatedAt: t.CreatedAt,
+		ID:           t.ID,
+		Currency:     t.Currency,
+		Direction:    t.Direction,
+		Reimbursable: t.Reimbursable,
+		Status:       t.Status,
+		CreatedAt:    t.CreatedAt,
 	}
 
 	if t.UserID.Valid {
@@ -63,7 +107,7 @@ func (t *Transaction) ToView() TransactionView {
 		view.TransactionDate = t.TxnDate.String // Legacy
 	}
 	if t.Amount.Valid {
-		view.Amount = t.Amount.Float64
+		view.Amount = t.Amount.Money
 	}
 	if t.Channel.Valid {
 		view.Channel = t.Channel.String
@@ -87,19 +131,94 @@ func (t *Transaction) ToView() TransactionView {
 	if t.RawOCRText.Valid {
 		view.RawOCRText = t.RawOCRText.String
 	}
+	if t.OCRBlocks.Valid && t.OCRBlocks.String != "" {
+		view.OCRBlocks = json.RawMessage(t.OCRBlocks.String)
+	}
+	if t.Payee.Valid {
+		view.Payee = t.Payee.String
+	}
 	if t.LLMConfidence.Valid {
 		view.LLMConfidence = t.LLMConfidence.Float64
 	}
+	if t.SlipRef.Valid {
+		view.SlipRef = t.SlipRef.String
+	}
+	if t.DuplicateOf.Valid {
+		view.DuplicateOf = t.DuplicateOf.Int64
+	}
+	if t.ProcessingState.Valid {
+		view.ProcessingState = t.ProcessingState.String
+	}
+	if t.ProcessingError.Valid {
+		view.ProcessingError = t.ProcessingError.String
+	}
+	if t.ClaimID.Valid {
+		view.ClaimID = t.ClaimID.Int64
+	}
+	if t.TaxItem.Valid {
+		view.TaxItem = t.TaxItem.String
+	}
+	if t.DeletedAt.Valid {
+		view.DeletedAt = t.DeletedAt.String
+	}
+	view.UserEditedFields = t.EditedFields()
+	view.Tags = t.Tags
+	if view.Tags == nil {
+		view.Tags = []string{}
+	}
 
 	return view
 }
 
+// EditedFields returns the fields the user has changed by hand
+func (t *Transaction) EditedFields() []string {
+	if !t.UserEditedFields.Valid || t.UserEditedFields.String == "" {
+		return nil
+	}
+	return strings.Split(t.UserEditedFields.String, ",")
+}
+
+// IsUserEdited reports whether the user has changed field by hand
+func (t *Transaction) IsUserEdited(field string) bool {
+	for _, f := range t.EditedFields() {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
 type ConfirmRequest struct {
-	Amount       float64 `json:"amount"`
-	TxnDate      string  `json:"txn_date"`
-	Direction    string  `json:"direction"`
-	Channel      string  `json:"channel"`
-	AccountLabel string  `json:"account_label"`
-	Category     string  `json:"category"`
-	Description  string  `json:"description"`
+	Amount       Money  `json:"amount"`
+	Currency     string `json:"currency"`
+	TxnDate      string `json:"txn_date"`
+	Direction    string `json:"direction"`
+	Channel      string `json:"channel"`
+	AccountLabel string `json:"account_label"`
+	Category     string `json:"category"`
+	Description  string `json:"description"`
+}
+
+// ConfirmRequest returns a request confirming the transaction with the
+// values it already has
+func (t *Transaction) ConfirmRequest() ConfirmRequest {
+	return ConfirmRequest{
+		Amount:       t.Amount.Money,
+		Currency:     t.Currency,
+		TxnDate:      t.TxnDate.String,
+		Direction:    t.Direction,
+		Channel:      t.Channel.String,
+		AccountLabel: t.AccountLabel.String,
+		Category:     t.Category.String,
+		Description:  t.Description.String,
+	}
+}
+
+// IsProcessing reports whether the slip is still waiting for or running OCR/LLM parsing
+func (v TransactionView) IsProcessing() bool {
+	switch v.ProcessingState {
+	case ProcessingQueued, ProcessingOCR, ProcessingLLM:
+		return true
+	}
+	return false
 }
@@ -1,7 +1,8 @@
 package models
 
 type User struct {
-	ID        int64 `json:"id"`
-	Name      string `json:"name"`
-	CutoffDay int    `json:"cutoff_day"`
+	ID           int64  `json:"id"`
+	Name         string `json:"name"`
+	CutoffDay    int    `json:"cutoff_day"`
+	BaseCurrency string `json:"base_currency"`
 }
@@ -2,7 +2,9 @@ package ocr
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"mime"
@@ -11,32 +13,131 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
+)
+
+const (
+	defaultTimeout = 60 * time.Second
+	retryDelay     = 500 * time.Millisecond
 )
 
 type Client struct {
 	endpoint   string
 	httpClient *http.Client
+	timeout    time.Duration
+	retries    int
+}
+
+// Block is a piece of text found on the image. BBox holds the pixel
+// coordinates [x0, y0, x1, y1] of its top-left and bottom-right corners and
+// is zero when the service did not report a position.
+type Block struct {
+	Text       string  `json:"text"`
+	Confidence float64 `json:"confidence"`
+	BBox       [4]int  `json:"bbox"`
+}
+
+// Result is the outcome of running OCR on one image
+type Result struct {
+	Text   string  `json:"text"`
+	Blocks []Block `json:"blocks"`
+}
+
+// LowConfidence returns the blocks whose confidence is below threshold
+func (r *Result) LowConfidence(threshold float64) []Block {
+	var low []Block
+	for _, b := range r.Blocks {
+		if b.Confidence < threshold {
+			low = append(low, b)
+		}
+	}
+	return low
 }
 
 type OCRResponse struct {
-	Text  string `json:"text"`
-	Error string `json:"error,omitempty"`
+	Text   string  `json:"text"`
+	Blocks []Block `json:"blocks"`
+	Error  string  `json:"error,omitempty"`
+}
+
+// Health is the status reported by the OCR service's /health endpoint
+type Health struct {
+	Status       string `json:"status"`
+	ModelsLoaded bool   `json:"models_loaded"`
 }
 
-func NewClient(endpoint string) *Client {
+// Ready reports whether the service can accept OCR requests
+func (h *Health) Ready() bool {
+	return h.Status == "healthy" && h.ModelsLoaded
+}
+
+// NewClient creates a client for the OCR service at endpoint. Each request
+// attempt is limited by timeout (60s when zero) and failed attempts caused by
+// network errors or 5xx responses are retried up to retries times.
+func NewClient(endpoint string, timeout time.Duration, retries int) *Client {
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	if retries < 0 {
+		retries = 0
+	}
 	return &Client{
 		endpoint:   endpoint,
 		httpClient: &http.Client{},
+		timeout:    timeout,
+		retries:    retries,
+	}
+}
+
+// ExtractText runs OCR on the image and returns only the combined text
+func (c *Client) ExtractText(ctx context.Context, imagePath string) (string, error) {
+	result, err := c.Extract(ctx, imagePath)
+	if err != nil {
+		return "", err
 	}
+	return result.Text, nil
 }
 
-func (c *Client) ExtractText(imagePath string) (string, error) {
-	file, err := os.Open(imagePath)
+// Extract runs OCR on the image and returns the text with its blocks
+func (c *Client) Extract(ctx context.Context, imagePath string) (*Result, error) {
+	data, err := os.ReadFile(imagePath)
 	if err != nil {
-		return "", fmt.Errorf("failed to open image: %w", err)
+		return nil, fmt.Errorf("failed to open image: %w", err)
 	}
-	defer file.Close()
 
+	var lastErr error
+	for attempt := 0; attempt <= c.retries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(retryDelay << (attempt - 1)):
+			}
+		}
+
+		result, err := c.extractOnce(ctx, imagePath, data)
+		if err == nil {
+			return result, nil
+		}
+		lastErr = err
+
+		var perm *permanentError
+		if errors.As(err, &perm) || ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, lastErr
+}
+
+// permanentError marks a failure that retrying will not fix
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+func (c *Client) extractOnce(ctx context.Context, imagePath string, data []byte) (*Result, error) {
 	var buf bytes.Buffer
 	writer := multipart.NewWriter(&buf)
 
@@ -54,42 +155,76 @@ func (c *Client) ExtractText(imagePath string) (string, error) {
 
 	part, err := writer.CreatePart(h)
 	if err != nil {
-		return "", fmt.Errorf("failed to create form file: %w", err)
+		return nil, &permanentError{fmt.Errorf("failed to create form file: %w", err)}
 	}
 
-	if _, err := io.Copy(part, file); err != nil {
-		return "", fmt.Errorf("failed to copy file data: %w", err)
+	if _, err := part.Write(data); err != nil {
+		return nil, &permanentError{fmt.Errorf("failed to copy file data: %w", err)}
 	}
 
 	if err := writer.Close(); err != nil {
-		return "", fmt.Errorf("failed to close writer: %w", err)
+		return nil, &permanentError{fmt.Errorf("failed to close writer: %w", err)}
 	}
 
-	req, err := http.NewRequest("POST", c.endpoint+"/ocr", &buf)
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.endpoint+"/ocr", &buf)
 	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
+		return nil, &permanentError{fmt.Errorf("failed to create request: %w", err)}
 	}
 	req.Header.Set("Content-Type", writer.FormDataContentType())
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("OCR request failed: %w", err)
+		return nil, fmt.Errorf("OCR request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("OCR service error (status %d): %s", resp.StatusCode, string(body))
+		err := fmt.Errorf("OCR service error (status %d): %s", resp.StatusCode, string(body))
+		if resp.StatusCode < 500 {
+			return nil, &permanentError{err}
+		}
+		return nil, err
 	}
 
 	var ocrResp OCRResponse
 	if err := json.NewDecoder(resp.Body).Decode(&ocrResp); err != nil {
-		return "", fmt.Errorf("failed to decode OCR response: %w", err)
+		return nil, fmt.Errorf("failed to decode OCR response: %w", err)
 	}
 
 	if ocrResp.Error != "" {
-		return "", fmt.Errorf("OCR error: %s", ocrResp.Error)
+		return nil, &permanentError{fmt.Errorf("OCR error: %s", ocrResp.Error)}
+	}
+
+	return &Result{Text: ocrResp.Text, Blocks: ocrResp.Blocks}, nil
+}
+
+// Health queries the service's /health endpoint
+func (c *Client) Health(ctx context.Context) (*Health, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", c.endpoint+"/health", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("OCR health check failed: %w", err)
 	}
+	defer resp.Body.Close()
 
-	return ocrResp.Text, nil
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("OCR health check returned status %d", resp.StatusCode)
+	}
+
+	var health Health
+	if err := json.NewDecoder(resp.Body).Decode(&health); err != nil {
+		return nil, fmt.Errorf("failed to decode health response: %w", err)
+	}
+	return &health, nil
 }
@@ -0,0 +1,88 @@
+package ocr
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"cash-track/internal/httpfixture"
+)
+
+// fixtureClient returns a client talking to a replay of the named fixture,
+// or to the OCR service when recording
+func fixtureClient(t *testing.T, name string, retries int) *Client {
+	t.Helper()
+	srv := httpfixture.Server(t, "testdata/"+name, os.Getenv("OCR_ENDPOINT"))
+	return NewClient(srv.URL, 10*time.Second, retries)
+}
+
+// slipImage writes a stand-in slip; the replayed service never reads it
+func slipImage(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "slip.png")
+	if err := os.WriteFile(path, []byte("not really a png"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestClientExtractText(t *testing.T) {
+	client := fixtureClient(t, "ocr_slip.json", 0)
+
+	health, err := client.Health(context.Background())
+	if err != nil {
+		t.Fatalf("Health: %v", err)
+	}
+	if !health.Ready() {
+		t.Errorf("health = %+v, want ready", health)
+	}
+
+	text, err := client.ExtractText(context.Background(), slipImage(t))
+	if err != nil {
+		t.Fatalf("ExtractText: %v", err)
+	}
+	slip := ParseSlipText(text)
+	if slip.Reference != "016291094152ATF07634" {
+		t.Errorf("reference = %q in %q", slip.Reference, text)
+	}
+}
+
+func TestClientExtractBlocks(t *testing.T) {
+	client := fixtureClient(t, "ocr_slip.json", 0)
+
+	result, err := client.Extract(context.Background(), slipImage(t))
+	if err != nil {
+		t.Fatalf("Extract: %v", err)
+	}
+	if len(result.Blocks) != 5 {
+		t.Fatalf("got %d blocks, want 5", len(result.Blocks))
+	}
+	if low := result.LowConfidence(0.7); len(low) != 1 || !strings.HasPrefix(low[0].Text, "เลขที่รายการ") {
+		t.Errorf("low confidence blocks = %v", low)
+	}
+}
+
+func TestClientRetriesServerErrors(t *testing.T) {
+	client := fixtureClient(t, "ocr_unavailable.json", 1)
+
+	text, err := client.ExtractText(context.Background(), slipImage(t))
+	if err != nil {
+		t.Fatalf("ExtractText: %v", err)
+	}
+	if !strings.Contains(text, "150.00") {
+		t.Errorf("text = %q", text)
+	}
+}
+
+func TestClientDoesNotRetryClientErrors(t *testing.T) {
+	// The fixture holds one exchange, so a retry would fail the test
+	client := fixtureClient(t, "ocr_bad_image.json", 2)
+
+	_, err := client.ExtractText(context.Background(), slipImage(t))
+	if err == nil || !strings.Contains(err.Error(), "status 400") {
+		t.Fatalf("error = %v, want status 400", err)
+	}
+}
@@ -0,0 +1,51 @@
+package ocr
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Engine names accepted by New
+const (
+	EngineHTTP      = "http"
+	EngineTesseract = "tesseract"
+	EngineFixture   = "fixture"
+)
+
+// OCREngine extracts text from slip images
+type OCREngine interface {
+	// Extract runs OCR on the image and returns the text with its blocks
+	Extract(ctx context.Context, imagePath string) (*Result, error)
+	// Health reports whether the engine is ready to accept images
+	Health(ctx context.Context) (*Health, error)
+}
+
+// Options configures the engine built by New. Each engine only reads the
+// fields that apply to it.
+type Options struct {
+	// HTTP sidecar
+	Endpoint string
+	Timeout  time.Duration
+	Retries  int
+
+	// Tesseract CLI
+	TesseractCmd  string
+	TesseractLang string
+
+	// Fixture directory of canned results keyed by image hash
+	FixtureDir string
+}
+
+// New builds the OCR engine named by engine
+func New(engine string, opts Options) (OCREngine, error) {
+	switch engine {
+	case "", EngineHTTP:
+		return NewClient(opts.Endpoint, opts.Timeout, opts.Retries), nil
+	case EngineTesseract:
+		return NewTesseractEngine(opts.TesseractCmd, opts.TesseractLang, opts.Timeout), nil
+	case EngineFixture:
+		return LoadFixtureEngine(opts.FixtureDir)
+	}
+	return nil, fmt.Errorf("unknown OCR engine %q", engine)
+}
@@ -0,0 +1,72 @@
+package ocr
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseTesseractTSV(t *testing.T) {
+	tsv := "level\tpage_num\tblock_num\tpar_num\tline_num\tword_num\tleft\ttop\twidth\theight\tconf\ttext\n" +
+		"1\t1\t0\t0\t0\t0\t0\t0\t400\t300\t-1\t\n" +
+		"4\t1\t1\t1\t1\t0\t10\t10\t200\t20\t-1\t\n" +
+		"5\t1\t1\t1\t1\t1\t10\t10\t80\t20\t90\tโอนเงิน\n" +
+		"5\t1\t1\t1\t1\t2\t100\t12\t110\t18\t70\tสำเร็จ\n" +
+		"5\t1\t1\t1\t2\t1\t10\t40\t60\t20\t50\t150.00\n" +
+		"5\t1\t1\t1\t2\t2\t80\t40\t30\t20\t-1\t \n"
+
+	result, err := parseTesseractTSV(tsv)
+	if err != nil {
+		t.Fatalf("parseTesseractTSV: %v", err)
+	}
+	if result.Text != "โอนเงิน สำเร็จ\n150.00" {
+		t.Errorf("Text = %q", result.Text)
+	}
+	if len(result.Blocks) != 2 {
+		t.Fatalf("got %d blocks, want 2", len(result.Blocks))
+	}
+	first := result.Blocks[0]
+	if first.Confidence != 0.8 {
+		t.Errorf("confidence = %v, want 0.8", first.Confidence)
+	}
+	if first.BBox != [4]int{10, 10, 210, 30} {
+		t.Errorf("bbox = %v, want [10 10 210 30]", first.BBox)
+	}
+}
+
+func TestFixtureEngine(t *testing.T) {
+	dir := t.TempDir()
+	image := filepath.Join(dir, "slip.png")
+	if err := os.WriteFile(image, []byte("not really a png"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	hash, err := HashImage(image)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fixtures := filepath.Join(dir, "fixtures")
+	os.Mkdir(fixtures, 0o755)
+	if err := os.WriteFile(filepath.Join(fixtures, hash+".txt"), []byte("จำนวนเงิน 150.00 บาท\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	engine, err := New(EngineFixture, Options{FixtureDir: fixtures})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	result, err := engine.Extract(context.Background(), image)
+	if err != nil {
+		t.Fatalf("Extract: %v", err)
+	}
+	if result.Text != "จำนวนเงิน 150.00 บาท" {
+		t.Errorf("Text = %q", result.Text)
+	}
+
+	other := filepath.Join(dir, "other.png")
+	os.WriteFile(other, []byte("different"), 0o644)
+	if _, err := engine.Extract(context.Background(), other); err == nil {
+		t.Error("expected an error for an image without a fixture")
+	}
+}
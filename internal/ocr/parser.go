@@ -2,17 +2,20 @@ package ocr
 
 import (
 	"regexp"
-	"strconv"
 	"strings"
 	"time"
+
+	"cash-track/internal/models"
 )
 
 type ParsedSlip struct {
-	Amount          float64
+	Amount          models.Money
 	TransactionDate time.Time
 	FromAccount     string
 	ToAccount       string
 	Channel         string
+	Reference       string
+	Receipt         models.ReceiptDetails
 }
 
 func ParseSlipText(text string) ParsedSlip {
@@ -23,11 +26,13 @@ func ParseSlipText(text string) ParsedSlip {
 	result.FromAccount = parseFromAccount(text)
 	result.ToAccount = parseToAccount(text)
 	result.Channel = parseChannel(text)
+	result.Reference = parseReference(text)
+	result.Receipt = parseReceipt(text)
 
 	return result
 }
 
-func parseAmount(text string) float64 {
+func parseAmount(text string) models.Money {
 	patterns := []string{
 		`(?i)(?:จำนวนเงิน|amount|THB|฿)\s*[:\s]*([0-9,]+\.?\d*)`,
 		`([0-9,]+\.[0-9]{2})\s*(?:บาท|THB|฿)`,
@@ -38,8 +43,7 @@ func parseAmount(text string) float64 {
 		re := regexp.MustCompile(pattern)
 		matches := re.FindStringSubmatch(text)
 		if len(matches) > 1 {
-			amountStr := strings.ReplaceAll(matches[1], ",", "")
-			if amount, err := strconv.ParseFloat(amountStr, 64); err == nil && amount > 0 {
+			if amount, err := models.ParseMoney(matches[1]); err == nil && amount > 0 {
 				return amount
 			}
 		}
@@ -49,8 +53,7 @@ func parseAmount(text string) float64 {
 	re := regexp.MustCompile(`([0-9,]+\.[0-9]{2})`)
 	matches := re.FindAllStringSubmatch(text, -1)
 	for _, match := range matches {
-		amountStr := strings.ReplaceAll(match[1], ",", "")
-		if amount, err := strconv.ParseFloat(amountStr, 64); err == nil && amount > 0 {
+		if amount, err := models.ParseMoney(match[1]); err == nil && amount > 0 {
 			return amount
 		}
 	}
@@ -145,3 +148,111 @@ func parseChannel(text string) string {
 
 	return ""
 }
+
+// parseReference extracts the bank transaction reference number, which is
+// unique per transfer and therefore identifies re-uploaded slips.
+func parseReference(text string) string {
+	patterns := []string{
+		`(?i)(?:เลขที่รายการ|รหัสอ้างอิง|หมายเลขอ้างอิง|เลขที่อ้างอิง)\s*[:\s]*([A-Za-z0-9]{6,})`,
+		`(?i)\b(?:transaction\s*(?:id|no\.?)|ref(?:erence)?\s*(?:no\.?|number|id)?)[:.\s]+([A-Za-z0-9]{6,})`,
+	}
+
+	for _, pattern := range patterns {
+		re := regexp.MustCompile(pattern)
+		matches := re.FindStringSubmatch(text)
+		if len(matches) > 1 && strings.ContainsAny(matches[1], "0123456789") {
+			return strings.ToUpper(matches[1])
+		}
+	}
+
+	return ""
+}
+
+// parseReceipt extracts the tax invoice fields printed on receipts. Tax IDs
+// with a wrong check digit, usually misread by OCR, are left out.
+func parseReceipt(text string) models.ReceiptDetails {
+	// OCR often writes sara am as nikhahit followed by sara aa
+	text = strings.ReplaceAll(text, "\u0e4d\u0e32", "\u0e33")
+	receipt := models.ReceiptDetails{
+		SellerTaxID:   parseTaxID(text),
+		Branch:        parseBranch(text),
+		InvoiceNumber: parseInvoiceNumber(text),
+	}
+	receipt.VAT, receipt.PreVAT = parseVATAmounts(text)
+	return receipt
+}
+
+func parseTaxID(text string) string {
+	re := regexp.MustCompile(`(?i)(?:เลขประจำตัวผู้เสียภาษี(?:อากร)?|tax\s*id(?:\s*no\.?)?|vat\s*reg(?:istration)?\.?\s*(?:no\.?)?|\btin)\s*[:.]?\s*([0-9][0-9 -]{11,20})`)
+	for _, matches := range re.FindAllStringSubmatch(text, -1) {
+		digits := strings.NewReplacer(" ", "", "-", "").Replace(matches[1])
+		if len(digits) > 13 {
+			digits = digits[:13]
+		}
+		if id := models.NormalizeTaxID(digits); id != "" {
+			return id
+		}
+	}
+	return ""
+}
+
+func parseBranch(text string) string {
+	re := regexp.MustCompile(`(?i)(?:สาขา(?:ที่)?|branch(?:\s*no\.?)?)\s*[:.]?\s*(\d{1,5})\b`)
+	if matches := re.FindStringSubmatch(text); len(matches) > 1 {
+		return models.NormalizeBranch(matches[1])
+	}
+	if regexp.MustCompile(`(?i)สำนักงานใหญ่|head\s*office`).MatchString(text) {
+		return models.HeadOffice
+	}
+	return ""
+}
+
+func parseInvoiceNumber(text string) string {
+	patterns := []string{
+		`เลขที่\s*(?:ใบกำกับภาษี|ใบเสร็จรับเงิน|ใบเสร็จ)(?:\s*/\s*(?:ใบกำกับภาษี|ใบเสร็จรับเงิน))?\s*[:#]?\s*([A-Za-z0-9][A-Za-z0-9/-]{2,})`,
+		`(?i)(?:tax\s*invoice|invoice|receipt)(?:\s*/\s*(?:tax\s*invoice|receipt))?\s*(?:no\.?|number|#)\s*[:#]?\s*([A-Za-z0-9][A-Za-z0-9/-]{2,})`,
+	}
+
+	for _, pattern := range patterns {
+		re := regexp.MustCompile(pattern)
+		matches := re.FindStringSubmatch(text)
+		if len(matches) > 1 && strings.ContainsAny(matches[1], "0123456789") {
+			return strings.ToUpper(matches[1])
+		}
+	}
+
+	return ""
+}
+
+// parseVATAmounts reads the VAT and the amount before VAT from the lines
+// that name them, taking the last amount on each line so "VAT 7%" is not
+// read as the amount
+func parseVATAmounts(text string) (vat, preVAT models.NullMoney) {
+	amountRe := regexp.MustCompile(`[0-9][0-9,]*\.[0-9]{2}`)
+	preVATRe := regexp.MustCompile(`(?i)(?:มูลค่า|ราคา|ยอด)(?:สินค้า)?ก่อนภาษี|before\s*vat|pre-?\s*vat|vatable`)
+	vatRe := regexp.MustCompile(`(?i)ภาษีมูลค่าเพิ่ม|\bvat\b`)
+	// Totals that include VAT name it too
+	inclusiveRe := regexp.MustCompile(`(?i)รวมภาษี|รวมทั้งสิ้น|incl`)
+
+	for _, line := range strings.Split(text, "\n") {
+		amounts := amountRe.FindAllString(line, -1)
+		if len(amounts) == 0 {
+			continue
+		}
+		amount, err := models.ParseMoney(amounts[len(amounts)-1])
+		if err != nil {
+			continue
+		}
+		switch {
+		case preVATRe.MatchString(line):
+			if !preVAT.Valid {
+				preVAT = models.SomeMoney(amount)
+			}
+		case vatRe.MatchString(line) && !inclusiveRe.MatchString(line):
+			if !vat.Valid {
+				vat = models.SomeMoney(amount)
+			}
+		}
+	}
+	return vat, preVAT
+}
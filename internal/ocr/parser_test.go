@@ -0,0 +1,65 @@
+package ocr
+
+import (
+	"testing"
+
+	"cash-track/internal/models"
+)
+
+func TestParseReceipt(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want models.ReceiptDetails
+	}{
+		{
+			name: "thai tax invoice",
+			text: "ใบเสร็จรับเงิน/ใบกำกับภาษีอย่างย่อ\n" +
+				"บริษัท ซีพี ออลล์ จำกัด (มหาชน) สาขาที่ 01234\n" +
+				"เลขประจําตัวผู้เสียภาษี 0-1075-36000-31-5\n" +
+				"เลขที่ใบกำกับภาษี: R0012-3456\n" +
+				"มูลค่าก่อนภาษี 93.46\n" +
+				"ภาษีมูลค่าเพิ่ม 7% 6.54\n" +
+				"รวมทั้งสิ้น (รวมภาษีมูลค่าเพิ่ม) 100.00 บาท",
+			want: models.ReceiptDetails{
+				SellerTaxID:   "0107536000315",
+				Branch:        "01234",
+				InvoiceNumber: "R0012-3456",
+				VAT:           models.SomeMoney(654),
+				PreVAT:        models.SomeMoney(9346),
+			},
+		},
+		{
+			name: "english receipt at head office",
+			text: "TAX INVOICE (ABB) - Head Office\n" +
+				"TAX ID: 0105536092641\n" +
+				"Receipt No. inv-2026/0042\n" +
+				"Amount before VAT 1,000.00\n" +
+				"VAT 7.00% 70.00\n" +
+				"Total incl. VAT 1,070.00",
+			want: models.ReceiptDetails{
+				SellerTaxID:   "0105536092641",
+				Branch:        models.HeadOffice,
+				InvoiceNumber: "INV-2026/0042",
+				VAT:           models.SomeMoney(7000),
+				PreVAT:        models.SomeMoney(100000),
+			},
+		},
+		{
+			name: "misread tax ID",
+			text: "Tax ID 0105536092642\nPrivate sale 50.00",
+			want: models.ReceiptDetails{},
+		},
+		{
+			name: "bank transfer slip",
+			text: "โอนเงินสำเร็จ\n12 ต.ค. 69 10:30\nจำนวนเงิน 150.00 บาท\nเลขที่รายการ 2026101212345678",
+			want: models.ReceiptDetails{},
+		},
+	}
+
+	for _, tt := range tests {
+		if got := ParseSlipText(tt.text).Receipt; got != tt.want {
+			t.Errorf("%s: Receipt = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
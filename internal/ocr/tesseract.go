@@ -0,0 +1,149 @@
+package ocr
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TesseractEngine runs the local tesseract CLI. The Thai language data
+// (tesseract-ocr-tha on Debian) must be installed for Thai slips.
+type TesseractEngine struct {
+	cmd     string
+	lang    string
+	timeout time.Duration
+}
+
+func NewTesseractEngine(cmd, lang string, timeout time.Duration) *TesseractEngine {
+	if cmd == "" {
+		cmd = "tesseract"
+	}
+	if lang == "" {
+		lang = "tha+eng"
+	}
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	return &TesseractEngine{cmd: cmd, lang: lang, timeout: timeout}
+}
+
+// Extract runs tesseract with TSV output and groups the recognised words
+// into one block per line.
+func (e *TesseractEngine) Extract(ctx context.Context, imagePath string) (*Result, error) {
+	ctx, cancel := context.WithTimeout(ctx, e.timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, e.cmd, imagePath, "stdout", "-l", e.lang, "tsv")
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("tesseract failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	return parseTesseractTSV(stdout.String())
+}
+
+// Health checks that tesseract runs and has every configured language
+func (e *TesseractEngine) Health(ctx context.Context) (*Health, error) {
+	ctx, cancel := context.WithTimeout(ctx, e.timeout)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, e.cmd, "--list-langs").CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("tesseract is not available: %w", err)
+	}
+
+	installed := map[string]bool{}
+	for _, line := range strings.Split(string(out), "\n") {
+		installed[strings.TrimSpace(line)] = true
+	}
+	loaded := true
+	for _, lang := range strings.Split(e.lang, "+") {
+		if !installed[lang] {
+			loaded = false
+		}
+	}
+	return &Health{Status: "healthy", ModelsLoaded: loaded}, nil
+}
+
+// tesseractLine accumulates the words of one line of TSV output
+type tesseractLine struct {
+	words      []string
+	confidence float64
+	box        [4]int
+}
+
+// parseTesseractTSV turns tesseract's TSV output into a Result. Word rows
+// (level 5) are joined per line; the line confidence is the mean word
+// confidence scaled to 0..1 and its box is the union of the word boxes.
+func parseTesseractTSV(tsv string) (*Result, error) {
+	var order []string
+	lines := map[string]*tesseractLine{}
+
+	scanner := bufio.NewScanner(strings.NewReader(tsv))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	header := true
+	for scanner.Scan() {
+		if header {
+			header = false
+			continue
+		}
+		cols := strings.Split(scanner.Text(), "\t")
+		if len(cols) < 12 || cols[0] != "5" {
+			continue
+		}
+		text := strings.TrimSpace(cols[11])
+		if text == "" {
+			continue
+		}
+
+		nums := make([]int, 4)
+		for i := range nums {
+			n, err := strconv.Atoi(cols[6+i])
+			if err != nil {
+				return nil, fmt.Errorf("invalid tesseract output: %q", scanner.Text())
+			}
+			nums[i] = n
+		}
+		conf, _ := strconv.ParseFloat(cols[10], 64)
+		left, top, width, height := nums[0], nums[1], nums[2], nums[3]
+
+		key := strings.Join(cols[1:5], ".")
+		line, ok := lines[key]
+		if !ok {
+			line = &tesseractLine{box: [4]int{left, top, left + width, top + height}}
+			lines[key] = line
+			order = append(order, key)
+		}
+		line.words = append(line.words, text)
+		line.confidence += conf
+		line.box = [4]int{
+			min(line.box[0], left), min(line.box[1], top),
+			max(line.box[2], left+width), max(line.box[3], top+height),
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read tesseract output: %w", err)
+	}
+
+	result := &Result{}
+	var texts []string
+	for _, key := range order {
+		line := lines[key]
+		text := strings.Join(line.words, " ")
+		texts = append(texts, text)
+		result.Blocks = append(result.Blocks, Block{
+			Text:       text,
+			Confidence: line.confidence / float64(len(line.words)) / 100,
+			BBox:       line.box,
+		})
+	}
+	result.Text = strings.Join(texts, "\n")
+	return result, nil
+}
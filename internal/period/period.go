@@ -0,0 +1,187 @@
+// Package period works out the date ranges that totals are reported over.
+//
+// Spending is counted in cycles that start on the user's cutoff day, e.g.
+// from the 25th to the 24th of the next month when salary arrives on the
+// 25th. The cutoff day is 1 to 31, clamped to the length of short months
+// (31 starts the cycle on the last day of every month), or LastBusinessDay
+// for salaries paid on the last weekday of the month. Public holidays are
+// not taken into account.
+package period
+
+import (
+	"fmt"
+	"time"
+)
+
+// LastBusinessDay is the cutoff day of cycles starting on the last Monday
+// to Friday of each month
+const LastBusinessDay = -1
+
+// Named periods
+const (
+	CurrentCycle  = "current-cycle"
+	PreviousCycle = "previous-cycle"
+	YearToDate    = "ytd"            // 1 January to today
+	Last12Cycles  = "last-12-cycles" // the current cycle and the 11 before it
+	Custom        = "custom"         // explicit from and to dates
+)
+
+// Names lists the named periods other than Custom
+var Names = []string{CurrentCycle, PreviousCycle, YearToDate, Last12Cycles}
+
+const layout = "2006-01-02"
+
+// Range is an inclusive range of days
+type Range struct {
+	From time.Time
+	To   time.Time
+}
+
+// Dates formats the range as YYYY-MM-DD strings
+func (r Range) Dates() (string, string) {
+	return r.From.Format(layout), r.To.Format(layout)
+}
+
+// Days is the number of days in the range
+func (r Range) Days() int {
+	return int(r.To.Sub(r.From).Hours()/24+0.5) + 1
+}
+
+// Parse reads a range of YYYY-MM-DD dates
+func Parse(from, to string) (Range, error) {
+	f, err := time.Parse(layout, from)
+	if err != nil {
+		return Range{}, fmt.Errorf("invalid from date %q", from)
+	}
+	t, err := time.Parse(layout, to)
+	if err != nil {
+		return Range{}, fmt.Errorf("invalid to date %q", to)
+	}
+	if t.Before(f) {
+		return Range{}, fmt.Errorf("from date is after to date")
+	}
+	return Range{From: f, To: t}, nil
+}
+
+// ValidCutoff reports whether day can be used as a cutoff day
+func ValidCutoff(day int) bool {
+	return day == LastBusinessDay || (day >= 1 && day <= 31)
+}
+
+// Day truncates t to midnight UTC of its calendar day, the form every Range
+// uses
+func Day(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// CycleStart returns the day on which the cycle of year and month starts
+func CycleStart(year int, month time.Month, cutoffDay int) time.Time {
+	first := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	last := first.AddDate(0, 1, -1)
+	if cutoffDay == LastBusinessDay {
+		for last.Weekday() == time.Saturday || last.Weekday() == time.Sunday {
+			last = last.AddDate(0, 0, -1)
+		}
+		return last
+	}
+	if cutoffDay < 1 {
+		cutoffDay = 1
+	}
+	if cutoffDay > last.Day() {
+		return last
+	}
+	return time.Date(year, month, cutoffDay, 0, 0, 0, 0, time.UTC)
+}
+
+// Cycle returns the cycle that day falls in. An invalid cutoff day counts
+// as 1, the calendar month.
+func Cycle(day time.Time, cutoffDay int) Range {
+	if !ValidCutoff(cutoffDay) {
+		cutoffDay = 1
+	}
+	day = Day(day)
+	start := CycleStart(day.Year(), day.Month(), cutoffDay)
+	if day.Before(start) {
+		prev := start.AddDate(0, 0, -start.Day()) // last day of the previous month
+		return Range{From: CycleStart(prev.Year(), prev.Month(), cutoffDay), To: start.AddDate(0, 0, -1)}
+	}
+	next := start.AddDate(0, 0, 1-start.Day()).AddDate(0, 1, 0) // first of the next month
+	return Range{From: start, To: CycleStart(next.Year(), next.Month(), cutoffDay).AddDate(0, 0, -1)}
+}
+
+// Named returns the named period as of now
+func Named(name string, now time.Time, cutoffDay int) (Range, error) {
+	today := Day(now)
+	switch name {
+	case CurrentCycle:
+		return Cycle(today, cutoffDay), nil
+	case PreviousCycle:
+		return Cycle(Cycle(today, cutoffDay).From.AddDate(0, 0, -1), cutoffDay), nil
+	case YearToDate:
+		return Range{From: time.Date(today.Year(), 1, 1, 0, 0, 0, 0, time.UTC), To: today}, nil
+	case Last12Cycles:
+		current := Cycle(today, cutoffDay)
+		first := current
+		for i := 1; i < 12; i++ {
+			first = Cycle(first.From.AddDate(0, 0, -1), cutoffDay)
+		}
+		return Range{From: first.From, To: current.To}, nil
+	default:
+		return Range{}, fmt.Errorf("unknown period %q", name)
+	}
+}
+
+// Resolve returns the named period, or from..to for Custom. Without a name
+// it is from..to when both are given and the current cycle otherwise.
+func Resolve(name, from, to string, now time.Time, cutoffDay int) (Range, error) {
+	switch {
+	case name == Custom:
+		return Parse(from, to)
+	case name != "":
+		return Named(name, now, cutoffDay)
+	case from != "" && to != "":
+		return Parse(from, to)
+	default:
+		return Named(CurrentCycle, now, cutoffDay)
+	}
+}
+
+// IsCycle reports whether r is exactly one cycle
+func (r Range) IsCycle(cutoffDay int) bool {
+	c := Cycle(r.From, cutoffDay)
+	return c.From.Equal(r.From) && c.To.Equal(r.To)
+}
+
+// isWholeMonths reports whether r runs from the first of a month to the
+// last day of a month
+func (r Range) isWholeMonths() bool {
+	return r.From.Day() == 1 && r.To.AddDate(0, 0, 1).Day() == 1
+}
+
+// Previous returns the range of the same kind just before r: the previous
+// cycle, the same number of calendar months, or as many days
+func Previous(r Range, cutoffDay int) Range {
+	switch {
+	case r.IsCycle(cutoffDay):
+		return Cycle(r.From.AddDate(0, 0, -1), cutoffDay)
+	case r.isWholeMonths():
+		end := r.To.AddDate(0, 0, 1)
+		months := (end.Year()-r.From.Year())*12 + int(end.Month()) - int(r.From.Month())
+		return Range{From: r.From.AddDate(0, -months, 0), To: r.From.AddDate(0, 0, -1)}
+	default:
+		return Range{From: r.From.AddDate(0, 0, -r.Days()), To: r.From.AddDate(0, 0, -1)}
+	}
+}
+
+// YearBefore returns the range equivalent to r a year earlier
+func YearBefore(r Range, cutoffDay int) Range {
+	switch {
+	case r.IsCycle(cutoffDay):
+		mid := r.From.Add(r.To.Sub(r.From) / 2)
+		return Cycle(mid.AddDate(-1, 0, 0), cutoffDay)
+	case r.isWholeMonths():
+		return Range{From: r.From.AddDate(-1, 0, 0), To: r.To.AddDate(0, 0, 1).AddDate(-1, 0, -1)}
+	default:
+		return Range{From: r.From.AddDate(-1, 0, 0), To: r.To.AddDate(-1, 0, 0)}
+	}
+}
@@ -0,0 +1,148 @@
+package period
+
+import (
+	"testing"
+	"time"
+)
+
+func day(s string) time.Time {
+	t, err := time.Parse(layout, s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestCycle(t *testing.T) {
+	cases := []struct {
+		day      string
+		cutoff   int
+		from, to string
+	}{
+		{"2026-10-19", 1, "2026-10-01", "2026-10-31"},
+		{"2026-10-19", 25, "2026-09-25", "2026-10-24"},
+		{"2026-10-25", 25, "2026-10-25", "2026-11-24"},
+		{"2026-02-10", 30, "2026-01-30", "2026-02-27"},
+		// 31 starts every cycle on the last day of the month
+		{"2026-02-27", 31, "2026-01-31", "2026-02-27"},
+		{"2026-02-28", 31, "2026-02-28", "2026-03-30"},
+		{"2026-04-30", 31, "2026-04-30", "2026-05-30"},
+		// The last weekday: Fri 30 Jan, Fri 27 Feb, Tue 31 Mar 2026
+		{"2026-02-27", LastBusinessDay, "2026-02-27", "2026-03-30"},
+		{"2026-02-26", LastBusinessDay, "2026-01-30", "2026-02-26"},
+		{"2026-03-31", LastBusinessDay, "2026-03-31", "2026-04-29"},
+		// Invalid cutoff days fall back to the calendar month
+		{"2026-10-19", 0, "2026-10-01", "2026-10-31"},
+		{"2026-10-19", 32, "2026-10-01", "2026-10-31"},
+	}
+
+	for _, tc := range cases {
+		from, to := Cycle(day(tc.day), tc.cutoff).Dates()
+		if from != tc.from || to != tc.to {
+			t.Errorf("Cycle(%s, %d) = %s..%s, want %s..%s", tc.day, tc.cutoff, from, to, tc.from, tc.to)
+		}
+	}
+}
+
+func TestNamed(t *testing.T) {
+	now := time.Date(2026, 10, 19, 15, 30, 0, 0, time.Local)
+	cases := []struct {
+		name     string
+		cutoff   int
+		from, to string
+	}{
+		{CurrentCycle, 25, "2026-09-25", "2026-10-24"},
+		{PreviousCycle, 25, "2026-08-25", "2026-09-24"},
+		{PreviousCycle, 1, "2026-09-01", "2026-09-30"},
+		{YearToDate, 25, "2026-01-01", "2026-10-19"},
+		{Last12Cycles, 25, "2025-10-25", "2026-10-24"},
+		{Last12Cycles, 1, "2025-11-01", "2026-10-31"},
+	}
+
+	for _, tc := range cases {
+		r, err := Named(tc.name, now, tc.cutoff)
+		if err != nil {
+			t.Fatalf("Named(%s): %v", tc.name, err)
+		}
+		if from, to := r.Dates(); from != tc.from || to != tc.to {
+			t.Errorf("Named(%s, %d) = %s..%s, want %s..%s", tc.name, tc.cutoff, from, to, tc.from, tc.to)
+		}
+	}
+	if _, err := Named("fortnight", now, 1); err == nil {
+		t.Error("unknown period accepted")
+	}
+}
+
+func TestResolve(t *testing.T) {
+	now := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name, from, to string
+		want           string
+	}{
+		{"", "", "", "2026-09-25..2026-10-24"},
+		{"", "2026-10-01", "", "2026-09-25..2026-10-24"},
+		{"", "2026-10-01", "2026-10-07", "2026-10-01..2026-10-07"},
+		{Custom, "2026-10-01", "2026-10-07", "2026-10-01..2026-10-07"},
+		// A name wins over dates
+		{PreviousCycle, "2026-10-01", "2026-10-07", "2026-08-25..2026-09-24"},
+	}
+	for _, tc := range cases {
+		r, err := Resolve(tc.name, tc.from, tc.to, now, 25)
+		if err != nil {
+			t.Fatalf("Resolve(%q, %q, %q): %v", tc.name, tc.from, tc.to, err)
+		}
+		if from, to := r.Dates(); from+".."+to != tc.want {
+			t.Errorf("Resolve(%q, %q, %q) = %s..%s, want %s", tc.name, tc.from, tc.to, from, to, tc.want)
+		}
+	}
+
+	for _, bad := range [][3]string{
+		{Custom, "", ""},
+		{"", "2026-10-07", "2026-10-01"},
+		{"", "10/01/2026", "2026-10-07"},
+	} {
+		if _, err := Resolve(bad[0], bad[1], bad[2], now, 25); err == nil {
+			t.Errorf("Resolve(%q, %q, %q) accepted", bad[0], bad[1], bad[2])
+		}
+	}
+}
+
+func TestPreviousAndYearBefore(t *testing.T) {
+	cases := []struct {
+		from, to     string
+		cutoff       int
+		prev, lastYr string
+	}{
+		// A cutoff cycle
+		{"2026-09-25", "2026-10-24", 25, "2026-08-25..2026-09-24", "2025-09-25..2025-10-24"},
+		{"2026-02-27", "2026-03-30", LastBusinessDay, "2026-01-30..2026-02-26", "2025-02-28..2025-03-30"},
+		// A calendar month and a calendar year
+		{"2026-03-01", "2026-03-31", 25, "2026-02-01..2026-02-28", "2025-03-01..2025-03-31"},
+		{"2026-01-01", "2026-12-31", 1, "2025-01-01..2025-12-31", "2025-01-01..2025-12-31"},
+		// Any other range moves back by its length
+		{"2026-10-10", "2026-10-16", 1, "2026-10-03..2026-10-09", "2025-10-10..2025-10-16"},
+	}
+
+	for _, tc := range cases {
+		r, _ := Parse(tc.from, tc.to)
+		if from, to := Previous(r, tc.cutoff).Dates(); from+".."+to != tc.prev {
+			t.Errorf("Previous(%s..%s) = %s..%s, want %s", tc.from, tc.to, from, to, tc.prev)
+		}
+		if from, to := YearBefore(r, tc.cutoff).Dates(); from+".."+to != tc.lastYr {
+			t.Errorf("YearBefore(%s..%s) = %s..%s, want %s", tc.from, tc.to, from, to, tc.lastYr)
+		}
+	}
+}
+
+func TestValidCutoff(t *testing.T) {
+	for _, d := range []int{1, 15, 30, 31, LastBusinessDay} {
+		if !ValidCutoff(d) {
+			t.Errorf("ValidCutoff(%d) = false", d)
+		}
+	}
+	for _, d := range []int{0, 32, -2} {
+		if ValidCutoff(d) {
+			t.Errorf("ValidCutoff(%d) = true", d)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+// Package reparse re-runs OCR and LLM parsing over existing transactions and
+// records which fields the new results would change, so they can be reviewed
+// and applied selectively.
+package reparse
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+
+	"cash-track/internal/database"
+	"cash-track/internal/fx"
+	"cash-track/internal/llm"
+	"cash-track/internal/models"
+	"cash-track/internal/ocr"
+	"cash-track/internal/storage"
+)
+
+// Runner executes re-parse runs
+type Runner struct {
+	repo      *database.Repository
+	storage   *storage.LocalStorage
+	ocrClient ocr.OCREngine
+	llmClient *llm.Client
+}
+
+func NewRunner(repo *database.Repository, storage *storage.LocalStorage, ocrClient ocr.OCREngine, llmClient *llm.Client) *Runner {
+	return &Runner{
+		repo:      repo,
+		storage:   storage,
+		ocrClient: ocrClient,
+		llmClient: llmClient,
+	}
+}
+
+// ValidMode reports whether mode is one of the supported re-parse modes
+func ValidMode(mode string) bool {
+	switch mode {
+	case models.ReparseOCR, models.ReparseLLM, models.ReparseBoth:
+		return true
+	}
+	return false
+}
+
+// Create stores a new run for filter without executing it
+func (r *Runner) Create(mode string, filter models.ReparseFilter) (*models.ReparseRun, error) {
+	if !ValidMode(mode) {
+		return nil, fmt.Errorf("unknown re-parse mode %q", mode)
+	}
+	data, err := json.Marshal(filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode filter: %w", err)
+	}
+	return r.repo.CreateReparseRun(filter.UserID, mode, string(data))
+}
+
+// Run executes a stored run, proposing changes for every matching
+// transaction. Nothing is written to the transactions themselves; use
+// Repository.ApplyReparseChanges for that. A failure on one transaction is
+// logged and does not stop the run.
+func (r *Runner) Run(ctx context.Context, runID int64) error {
+	run, err := r.repo.GetReparseRun(0, runID)
+	if err != nil {
+		return fmt.Errorf("failed to load run %d: %w", runID, err)
+	}
+
+	var filter models.ReparseFilter
+	if err := json.Unmarshal([]byte(run.Filter), &filter); err != nil {
+		r.repo.FinishReparseRun(runID, models.ReparseFailed, "invalid filter")
+		return fmt.Errorf("invalid filter: %w", err)
+	}
+
+	if err := r.repo.StartReparseRun(runID); err != nil {
+		return err
+	}
+
+	transactions, err := r.repo.ListReparseCandidates(run.Mode, filter)
+	if err != nil {
+		r.repo.FinishReparseRun(runID, models.ReparseFailed, err.Error())
+		return err
+	}
+
+	for i := range transactions {
+		if err := ctx.Err(); err != nil {
+			r.repo.FinishReparseRun(runID, models.ReparseFailed, "cancelled")
+			return err
+		}
+
+		tx := &transactions[i]
+		changes, err := r.diff(ctx, run.Mode, tx)
+		if err != nil {
+			log.Printf("Re-parse run %d: transaction %d: %v", runID, tx.ID, err)
+			changes = nil
+		}
+		if err := r.repo.AddReparseChanges(runID, changes); err != nil {
+			r.repo.FinishReparseRun(runID, models.ReparseFailed, err.Error())
+			return err
+		}
+	}
+
+	return r.repo.FinishReparseRun(runID, models.ReparseDone, "")
+}
+
+// diff re-runs the requested steps for tx and compares the result with what
+// is stored. Empty results are treated as "nothing found" rather than as a
+// change to blank out a field.
+func (r *Runner) diff(ctx context.Context, mode string, tx *models.Transaction) ([]models.ReparseChange, error) {
+	proposed := map[string]string{}
+
+	rawText := tx.RawOCRText.String
+	if mode == models.ReparseOCR || mode == models.ReparseBoth {
+		result, err := r.ocrClient.Extract(ctx, r.storage.GetPath(tx.SlipImagePath.String))
+		if err != nil {
+			return nil, fmt.Errorf("OCR failed: %w", err)
+		}
+		rawText = result.Text
+		proposed["raw_ocr_text"] = result.Text
+	}
+
+	if mode == models.ReparseLLM || mode == models.ReparseBoth {
+		parsed, err := r.parse(tx, rawText)
+		if err != nil {
+			return nil, err
+		}
+		if parsed != nil {
+			proposed["txn_date"] = parsed.TxnDate
+			proposed["direction"] = parsed.Direction
+			proposed["channel"] = parsed.Channel
+			proposed["account_label"] = parsed.AccountLabel
+			proposed["category"] = parsed.Category
+			proposed["description"] = parsed.Description
+			if parsed.Currency != "" {
+				proposed["currency"] = fx.Normalize(parsed.Currency)
+			}
+			if parsed.Amount.Valid && parsed.Amount.Money != 0 {
+				proposed["amount"] = parsed.Amount.String()
+			}
+			if parsed.Confidence != 0 {
+				proposed["llm_confidence"] = strconv.FormatFloat(parsed.Confidence, 'f', 2, 64)
+			}
+		}
+	}
+
+	var changes []models.ReparseChange
+	for _, field := range models.ReparseFields {
+		value, ok := proposed[field]
+		if !ok || value == "" || value == tx.FieldValue(field) {
+			continue
+		}
+		changes = append(changes, models.ReparseChange{
+			TransactionID: tx.ID,
+			Field:         field,
+			OldValue:      tx.FieldValue(field),
+			NewValue:      value,
+			Protected:     tx.IsUserEdited(field),
+		})
+	}
+	return changes, nil
+}
+
+// parse runs the LLM over the slip text, or over the chat message for
+// transactions that were typed rather than uploaded. It returns nil when the
+// message no longer parses as a transaction.
+func (r *Runner) parse(tx *models.Transaction, rawText string) (*llm.ParsedTransaction, error) {
+	if rawText != "" {
+		parsed, err := r.llmClient.ParseSlipText(rawText)
+		if err != nil {
+			return nil, fmt.Errorf("LLM parsing failed: %w", err)
+		}
+		return parsed, nil
+	}
+
+	if !tx.ChatMessage.Valid || tx.ChatMessage.String == "" {
+		return nil, nil
+	}
+	resp, err := r.llmClient.ParseChatMessage(tx.ChatMessage.String, nil, "th")
+	if err != nil {
+		return nil, fmt.Errorf("LLM parsing failed: %w", err)
+	}
+	if resp.Transaction == nil {
+		return nil, nil
+	}
+	resp.Transaction.Confidence = resp.Confidence
+	return resp.Transaction, nil
+}
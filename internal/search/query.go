@@ -0,0 +1,182 @@
+// Package search parses the transaction search syntax:
+//
+//	grab "ข้าวมันไก่"           text anywhere in description, chat, OCR text or payee
+//	amount:100 amount:100..500  exact amount or inclusive range (open ends allowed: amount:..500)
+//	amount:>100 amount:<=50     comparisons
+//	date:2026-01 date:2026-01-15 date:2026-01-01..2026-01-31
+//	from:2026-01-01 to:2026-01-31
+//	category:food channel:scb status:pending direction:income
+//	tag:trip #trip              transactions carrying a tag
+//	taxid:0105536092641         receipts from a seller, by tax ID
+//	invoice:INV-0042            receipts whose invoice number contains the text
+//
+// Words that are not filters are search terms and must all match.
+package search
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"cash-track/internal/models"
+)
+
+// Parse turns a search string into a query
+func Parse(input string) (models.SearchQuery, error) {
+	var q models.SearchQuery
+	for _, token := range tokenize(input) {
+		if !token.quoted && len(token.text) > 1 && strings.HasPrefix(token.text, "#") {
+			q.Tag = models.NormalizeTag(token.text)
+			continue
+		}
+		key, value, ok := strings.Cut(token.text, ":")
+		if token.quoted || !ok || value == "" {
+			q.Terms = append(q.Terms, token.text)
+			continue
+		}
+
+		var err error
+		switch strings.ToLower(key) {
+		case "amount":
+			err = parseAmountFilter(&q, value)
+		case "date":
+			err = parseDateFilter(&q, value)
+		case "from":
+			q.From, err = parseDay(value)
+		case "to":
+			q.To, err = parseDay(value)
+		case "status":
+			q.Status = strings.ToLower(value)
+		case "direction":
+			q.Direction = strings.ToLower(value)
+		case "category":
+			q.Category = strings.ToLower(value)
+		case "channel":
+			q.Channel = strings.ToLower(value)
+		case "tag":
+			q.Tag = models.NormalizeTag(value)
+		case "taxid":
+			if q.SellerTaxID = models.NormalizeTaxID(value); q.SellerTaxID == "" {
+				err = fmt.Errorf("invalid tax ID %q", value)
+			}
+		case "invoice":
+			q.InvoiceNumber = strings.ToUpper(value)
+		default:
+			q.Terms = append(q.Terms, token.text)
+		}
+		if err != nil {
+			return q, fmt.Errorf("%s: %w", token.text, err)
+		}
+	}
+	return q, nil
+}
+
+type token struct {
+	text   string
+	quoted bool
+}
+
+// tokenize splits on whitespace, keeping "quoted phrases" together
+func tokenize(input string) []token {
+	var tokens []token
+	var current strings.Builder
+	quoted := false
+	flush := func(wasQuoted bool) {
+		if current.Len() > 0 {
+			tokens = append(tokens, token{text: current.String(), quoted: wasQuoted})
+			current.Reset()
+		}
+	}
+
+	for _, r := range input {
+		switch {
+		case r == '"':
+			flush(quoted)
+			quoted = !quoted
+		case unicode.IsSpace(r) && !quoted:
+			flush(false)
+		default:
+			current.WriteRune(r)
+		}
+	}
+	flush(quoted)
+	return tokens
+}
+
+func parseAmountFilter(q *models.SearchQuery, value string) error {
+	for _, op := range []string{">=", "<=", ">", "<"} {
+		if !strings.HasPrefix(value, op) {
+			continue
+		}
+		amount, err := models.ParseMoney(value[len(op):])
+		if err != nil {
+			return err
+		}
+		switch op {
+		case ">=":
+			q.MinAmount = models.SomeMoney(amount)
+		case ">":
+			q.MinAmount = models.SomeMoney(amount + 1)
+		case "<=":
+			q.MaxAmount = models.SomeMoney(amount)
+		case "<":
+			q.MaxAmount = models.SomeMoney(amount - 1)
+		}
+		return nil
+	}
+
+	low, high, isRange := strings.Cut(value, "..")
+	if !isRange {
+		high = low
+	}
+	if low != "" {
+		amount, err := models.ParseMoney(low)
+		if err != nil {
+			return err
+		}
+		q.MinAmount = models.SomeMoney(amount)
+	}
+	if high != "" {
+		amount, err := models.ParseMoney(high)
+		if err != nil {
+			return err
+		}
+		q.MaxAmount = models.SomeMoney(amount)
+	}
+	return nil
+}
+
+func parseDateFilter(q *models.SearchQuery, value string) error {
+	low, high, isRange := strings.Cut(value, "..")
+	if !isRange {
+		// A single month or day covers the whole of it
+		if month, err := time.Parse("2006-01", value); err == nil {
+			q.From = month.Format("2006-01-02")
+			q.To = month.AddDate(0, 1, -1).Format("2006-01-02")
+			return nil
+		}
+		high = low
+	}
+
+	var err error
+	if low != "" {
+		if q.From, err = parseDay(low); err != nil {
+			return err
+		}
+	}
+	if high != "" {
+		if q.To, err = parseDay(high); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func parseDay(value string) (string, error) {
+	day, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return "", fmt.Errorf("invalid date %q (want YYYY-MM-DD)", value)
+	}
+	return day.Format("2006-01-02"), nil
+}
@@ -0,0 +1,78 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+
+	"cash-track/internal/models"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input string
+		want  models.SearchQuery
+	}{
+		{
+			input: `grab "ข้าว มันไก่"`,
+			want:  models.SearchQuery{Terms: []string{"grab", "ข้าว มันไก่"}},
+		},
+		{
+			input: "amount:100..500 category:Food channel:scb",
+			want: models.SearchQuery{
+				Category:  "food",
+				Channel:   "scb",
+				MinAmount: models.SomeMoney(10000),
+				MaxAmount: models.SomeMoney(50000),
+			},
+		},
+		{
+			input: "amount:>99.99 amount:<1000",
+			want: models.SearchQuery{
+				MinAmount: models.SomeMoney(10000),
+				MaxAmount: models.SomeMoney(99999),
+			},
+		},
+		{
+			input: "amount:..50",
+			want:  models.SearchQuery{MaxAmount: models.SomeMoney(5000)},
+		},
+		{
+			input: "date:2026-02",
+			want:  models.SearchQuery{From: "2026-02-01", To: "2026-02-28"},
+		},
+		{
+			input: "date:2026-01-05..2026-01-10 coffee",
+			want:  models.SearchQuery{Terms: []string{"coffee"}, From: "2026-01-05", To: "2026-01-10"},
+		},
+		{
+			input: "tag:Work grab #trip",
+			want:  models.SearchQuery{Terms: []string{"grab"}, Tag: "trip"},
+		},
+		{
+			input: "taxid:0-1055-36092-64-1 invoice:inv-42",
+			want:  models.SearchQuery{SellerTaxID: "0105536092641", InvoiceNumber: "INV-42"},
+		},
+		{
+			input: "from:2026-03-01 note:x",
+			want:  models.SearchQuery{Terms: []string{"note:x"}, From: "2026-03-01"},
+		},
+	}
+
+	for _, tt := range tests {
+		got, err := Parse(tt.input)
+		if err != nil {
+			t.Fatalf("Parse(%q) error: %v", tt.input, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Parse(%q) = %+v, want %+v", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, input := range []string{"amount:abc", "date:2026-13", "from:yesterday", "taxid:0105536092642"} {
+		if _, err := Parse(input); err == nil {
+			t.Errorf("Parse(%q) succeeded, want error", input)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package storage
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"math/bits"
+	"strconv"
+)
+
+const (
+	hashWidth  = 9
+	hashHeight = 8
+	// maxSamples caps how many source pixels are read per hash cell so that
+	// large phone screenshots stay cheap to hash.
+	maxSamples = 16
+)
+
+// DHash computes a 64-bit difference hash of an image. Visually identical
+// images (re-encoded, resized or re-screenshotted) produce hashes that differ
+// by only a few bits.
+func DHash(r io.Reader) (uint64, error) {
+	img, _, err := image.Decode(r)
+	if err != nil {
+		return 0, fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	var grid [hashHeight][hashWidth]float64
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	if w < hashWidth || h < hashHeight {
+		return 0, fmt.Errorf("image too small to hash (%dx%d)", w, h)
+	}
+
+	for cy := 0; cy < hashHeight; cy++ {
+		y0 := bounds.Min.Y + cy*h/hashHeight
+		y1 := bounds.Min.Y + (cy+1)*h/hashHeight
+		for cx := 0; cx < hashWidth; cx++ {
+			x0 := bounds.Min.X + cx*w/hashWidth
+			x1 := bounds.Min.X + (cx+1)*w/hashWidth
+			grid[cy][cx] = averageGray(img, x0, y0, x1, y1)
+		}
+	}
+
+	var hash uint64
+	for y := 0; y < hashHeight; y++ {
+		for x := 0; x < hashWidth-1; x++ {
+			hash <<= 1
+			if grid[y][x] > grid[y][x+1] {
+				hash |= 1
+			}
+		}
+	}
+	return hash, nil
+}
+
+func averageGray(img image.Image, x0, y0, x1, y1 int) float64 {
+	stepX := max(1, (x1-x0)/maxSamples)
+	stepY := max(1, (y1-y0)/maxSamples)
+
+	var sum float64
+	var n int
+	for y := y0; y < y1; y += stepY {
+		for x := x0; x < x1; x += stepX {
+			sum += float64(color.GrayModel.Convert(img.At(x, y)).(color.Gray).Y)
+			n++
+		}
+	}
+	if n == 0 {
+		return 0
+	}
+	return sum / float64(n)
+}
+
+// HammingDistance returns the number of differing bits between two hashes.
+func HammingDistance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+// FormatHash encodes a hash as a fixed-width hex string for storage.
+func FormatHash(hash uint64) string {
+	return fmt.Sprintf("%016x", hash)
+}
+
+// ParseHash decodes a hash produced by FormatHash.
+func ParseHash(s string) (uint64, error) {
+	return strconv.ParseUint(s, 16, 64)
+}
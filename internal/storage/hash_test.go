@@ -0,0 +1,69 @@
+package storage
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func testImage(w, h int, invert bool) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			v := uint8((x*255/w + y*64/h) % 256)
+			if (x/(w/6)+y/(h/5))%2 == 0 {
+				v /= 3
+			}
+			if invert {
+				v = 255 - v
+			}
+			img.Set(x, y, color.RGBA{v, v, v, 255})
+		}
+	}
+	return img
+}
+
+func TestDHashStableAcrossEncodings(t *testing.T) {
+	var pngBuf, jpgBuf bytes.Buffer
+	if err := png.Encode(&pngBuf, testImage(360, 640, false)); err != nil {
+		t.Fatal(err)
+	}
+	if err := jpeg.Encode(&jpgBuf, testImage(720, 1280, false), &jpeg.Options{Quality: 60}); err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := DHash(&pngBuf)
+	if err != nil {
+		t.Fatalf("DHash(png) error: %v", err)
+	}
+	b, err := DHash(&jpgBuf)
+	if err != nil {
+		t.Fatalf("DHash(jpeg) error: %v", err)
+	}
+	if d := HammingDistance(a, b); d > 4 {
+		t.Fatalf("distance between re-encoded images = %d, want <= 4", d)
+	}
+}
+
+func TestDHashDistinguishesImages(t *testing.T) {
+	var a, b bytes.Buffer
+	png.Encode(&a, testImage(360, 640, false))
+	png.Encode(&b, testImage(360, 640, true))
+
+	ha, _ := DHash(&a)
+	hb, _ := DHash(&b)
+	if d := HammingDistance(ha, hb); d < 16 {
+		t.Fatalf("distance between different images = %d, want >= 16", d)
+	}
+}
+
+func TestFormatParseHash(t *testing.T) {
+	const h uint64 = 0xf00dcafe12345678
+	got, err := ParseHash(FormatHash(h))
+	if err != nil || got != h {
+		t.Fatalf("ParseHash(FormatHash(%x)) = %x, %v", h, got, err)
+	}
+}
@@ -46,3 +46,13 @@ func (s *LocalStorage) GetPath(filename string) string {
 func (s *LocalStorage) Delete(filename string) error {
 	return os.Remove(filepath.Join(s.baseDir, filename))
 }
+
+// Hash returns the perceptual hash of a stored file.
+func (s *LocalStorage) Hash(filename string) (uint64, error) {
+	file, err := os.Open(filepath.Join(s.baseDir, filename))
+	if err != nil {
+		return 0, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+	return DHash(file)
+}
@@ -27,6 +27,13 @@ async def startup_event():
     logger.info("EasyOCR models loaded successfully")
 
 
+def bounding_box(points) -> list:
+    """Collapse EasyOCR's four corner points into [x0, y0, x1, y1] pixels."""
+    xs = [float(p[0]) for p in points]
+    ys = [float(p[1]) for p in points]
+    return [int(min(xs)), int(min(ys)), int(max(xs)), int(max(ys))]
+
+
 @app.get("/health")
 async def health_check():
     return {"status": "healthy", "models_loaded": reader is not None}
@@ -63,7 +70,11 @@ async def extract_text(file: UploadFile = File(...)):
             content={
                 "text": combined_text,
                 "blocks": [
-                    {"text": result[1], "confidence": float(result[2])}
+                    {
+                        "text": result[1],
+                        "confidence": float(result[2]),
+                        "bbox": bounding_box(result[0]),
+                    }
                     for result in results
                 ],
             }